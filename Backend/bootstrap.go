@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"leave-management/internal/bootstrap"
+	"leave-management/internal/config"
+	"leave-management/internal/db"
+	"leave-management/internal/logging"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// commandPool opens a small pool for the one-off commands. It has no
+// statement timeout and ctx is cancelled on SIGINT/SIGTERM.
+func commandPool() (context.Context, *pgxpool.Pool, func()) {
+	cfg := config.Load()
+	logging.SetLevel(cfg.LogLevel)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	pool := db.NewPool(ctx, cfg.DatabaseURL, db.PoolOptions{MaxConns: 2})
+	return ctx, pool, func() {
+		pool.Close()
+		stop()
+	}
+}
+
+// inOrg scopes ctx to the organization with the given slug, for the commands
+// that write one organization's data
+func inOrg(ctx context.Context, pool *pgxpool.Pool, slug string) context.Context {
+	org, err := bootstrap.FindOrganization(ctx, pool, slug)
+	if err != nil {
+		logging.Fatal("organization", "slug", slug, "error", err)
+	}
+	return db.WithOrg(ctx, org.ID)
+}
+
+// runMigrate implements `migrate`: it creates the schema on an empty database
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	schemaPath := fs.String("schema", "../Database/db.sql", "schema script to apply")
+	reset := fs.Bool("reset", false, "drop and recreate the schema even if it exists (deletes all data)")
+	_ = fs.Parse(args)
+
+	schema, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		logging.Fatal("migrate: read schema", "error", err)
+	}
+	ctx, pool, done := commandPool()
+	defer done()
+
+	err = bootstrap.Migrate(ctx, pool, string(schema), *reset)
+	if errors.Is(err, bootstrap.ErrInitialized) {
+		slog.Info("migrate: schema already exists, nothing to do (use -reset to recreate it)")
+		return
+	}
+	if err != nil {
+		done()
+		logging.Fatal("migrate failed, nothing was changed", "error", err)
+	}
+	slog.Info("migrate done", "schema", *schemaPath, "reset", *reset)
+}
+
+// runSeed implements `seed`: it inserts the missing reference data
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	org := fs.String("org", "default", "slug of the organization to seed")
+	_ = fs.Parse(args)
+
+	ctx, pool, done := commandPool()
+	defer done()
+
+	res, err := bootstrap.Seed(inOrg(ctx, pool, *org), pool)
+	if err != nil {
+		done()
+		logging.Fatal("seed failed", "error", err)
+	}
+	slog.Info("seed done", "org", *org, "departments_created", res.Departments, "leave_types_created", res.LeaveTypes)
+}
+
+// runCreateAdmin implements `create-admin`: the first login of a new
+// environment, which can then create everyone else through the API
+func runCreateAdmin(args []string) {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "login email (required)")
+	password := fs.String("password", "", "login password, at least 6 characters (required)")
+	name := fs.String("name", "Administrator", "employee name")
+	department := fs.String("department", "", "department name (default: the first by name)")
+	org := fs.String("org", "default", "slug of the organization the admin belongs to")
+	_ = fs.Parse(args)
+	if *email == "" || *password == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, pool, done := commandPool()
+	defer done()
+
+	admin, err := bootstrap.CreateAdmin(inOrg(ctx, pool, *org), pool, bootstrap.AdminOptions{
+		Email:      *email,
+		Password:   *password,
+		Name:       *name,
+		Department: *department,
+	})
+	if err != nil {
+		done()
+		logging.Fatal("create-admin failed", "error", err)
+	}
+	slog.Info("admin created", "org", *org, "email", *email, "employee_id", admin.EmployeeID, "user_id", admin.UserID)
+}
+
+// runCreateOrg implements `create-org`: a new, empty tenant. Seed it and create
+// its first admin with -org afterwards.
+func runCreateOrg(args []string) {
+	fs := flag.NewFlagSet("create-org", flag.ExitOnError)
+	name := fs.String("name", "", "organization name (required)")
+	slug := fs.String("slug", "", "short unique identifier: lowercase letters, digits and dashes (required)")
+	_ = fs.Parse(args)
+	if *name == "" || *slug == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, pool, done := commandPool()
+	defer done()
+
+	org, err := bootstrap.CreateOrganization(ctx, pool, *name, *slug)
+	if err != nil {
+		done()
+		logging.Fatal("create-org failed", "error", err)
+	}
+	slog.Info("organization created", "id", org.ID, "slug", org.Slug, "name", org.Name)
+}
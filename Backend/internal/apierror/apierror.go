@@ -0,0 +1,255 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"leave-management/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Code is a stable, machine-readable error identifier returned to clients.
+// IDs never change once published; add new codes instead of reusing old ones.
+type Code struct {
+	ID     string // e.g. LMS-1042
+	Type   string // e.g. insufficient_balance
+	Status int    // HTTP status code
+}
+
+var (
+	// 100x request validation
+	InvalidInput         = Code{"LMS-1000", "invalid_input", http.StatusBadRequest}
+	InvalidDate          = Code{"LMS-1001", "invalid_date", http.StatusBadRequest}
+	NoFieldsToUpdate     = Code{"LMS-1002", "no_fields_to_update", http.StatusBadRequest}
+	InvalidQuery         = Code{"LMS-1003", "invalid_query", http.StatusBadRequest}
+	UnsupportedMediaType = Code{"LMS-1004", "unsupported_media_type", http.StatusUnsupportedMediaType}
+	PayloadTooLarge      = Code{"LMS-1005", "payload_too_large", http.StatusRequestEntityTooLarge}
+
+	// 104x leave rules
+	InvalidDateRange    = Code{"LMS-1040", "invalid_date_range", http.StatusBadRequest}
+	BeforeJoiningDate   = Code{"LMS-1041", "before_joining_date", http.StatusBadRequest}
+	InsufficientBalance = Code{"LMS-1042", "insufficient_balance", http.StatusBadRequest}
+	LeaveOverlap        = Code{"LMS-1043", "leave_overlap", http.StatusBadRequest}
+	NoLeaveBalance      = Code{"LMS-1044", "no_leave_balance", http.StatusBadRequest}
+	NoticePeriodLeave   = Code{"LMS-1045", "notice_period_leave", http.StatusBadRequest}
+	NoWorkingDays       = Code{"LMS-1046", "no_working_days", http.StatusBadRequest}
+	InsufficientNotice  = Code{"LMS-1047", "insufficient_notice", http.StatusBadRequest}
+	TooManyDays         = Code{"LMS-1048", "max_consecutive_days_exceeded", http.StatusBadRequest}
+	CompOffWorkingDay   = Code{"LMS-1049", "comp_off_working_day", http.StatusBadRequest}
+
+	// 110x authentication
+	Unauthenticated    = Code{"LMS-1100", "unauthenticated", http.StatusUnauthorized}
+	InvalidToken       = Code{"LMS-1101", "invalid_token", http.StatusUnauthorized}
+	TokenExpired       = Code{"LMS-1102", "token_expired", http.StatusUnauthorized}
+	InvalidCredentials = Code{"LMS-1103", "invalid_credentials", http.StatusUnauthorized}
+	AccountDeactivated = Code{"LMS-1104", "account_deactivated", http.StatusUnauthorized}
+	IncorrectPassword  = Code{"LMS-1105", "incorrect_password", http.StatusBadRequest}
+	TooManyAttempts    = Code{"LMS-1106", "too_many_attempts", http.StatusTooManyRequests}
+
+	// 120x authorization
+	Forbidden          = Code{"LMS-1200", "forbidden", http.StatusForbidden}
+	HRApprovalRequired = Code{"LMS-1201", "hr_approval_required", http.StatusForbidden}
+
+	// 130x resources
+	NotFound            = Code{"LMS-1300", "not_found", http.StatusNotFound}
+	ReferenceNotFound   = Code{"LMS-1301", "reference_not_found", http.StatusBadRequest}
+	AlreadyExists       = Code{"LMS-1302", "already_exists", http.StatusConflict}
+	ConstraintViolation = Code{"LMS-1303", "constraint_violation", http.StatusBadRequest}
+	InvalidState        = Code{"LMS-1304", "invalid_state", http.StatusConflict}
+
+	// 150x server
+	Internal    = Code{"LMS-1500", "internal_error", http.StatusInternalServerError}
+	Unavailable = Code{"LMS-1501", "service_unavailable", http.StatusServiceUnavailable}
+	Timeout     = Code{"LMS-1502", "timeout", http.StatusGatewayTimeout}
+	ReadOnly    = Code{"LMS-1503", "read_only", http.StatusServiceUnavailable}
+)
+
+// Respond writes the standard error envelope and aborts the request:
+//
+//	{"error": "insufficient leave balance", "code": "LMS-1042", "type": "insufficient_balance", "request_id": "..."}
+func Respond(c *gin.Context, code Code, message string) {
+	c.AbortWithStatusJSON(code.Status, envelope(c, code, message))
+}
+
+// RespondWithDetails is Respond with an extra "details" field. Details must be safe
+// to show to clients: never pass raw database errors here.
+func RespondWithDetails(c *gin.Context, code Code, message string, details interface{}) {
+	body := envelope(c, code, message)
+	body["details"] = details
+	c.AbortWithStatusJSON(code.Status, body)
+}
+
+func envelope(c *gin.Context, code Code, message string) gin.H {
+	body := gin.H{
+		"error": i18n.Translate(c.GetString("locale"), message),
+		"code":  code.ID,
+		"type":  code.Type,
+	}
+	// users can quote the request ID in support tickets
+	if id := c.GetString("request_id"); id != "" {
+		body["request_id"] = id
+	}
+	return body
+}
+
+// Database responds to a failed write. Constraint violations are mapped to
+// client errors with a readable message; anything else becomes an internal
+// error using fallback as the message so SQL never leaks to clients.
+func Database(c *gin.Context, err error, fallback string) {
+	// the query was cut off by the request deadline (middleware.Timeout), not
+	// by a database problem
+	if errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		Respond(c, Timeout, "request timed out")
+		return
+	}
+	code, message := FromDatabase(err, fallback)
+	Respond(c, code, message)
+}
+
+// Lookup responds to a failed single-row read. A missing row (or an id that is
+// not even a valid UUID) is reported with code and message: NotFound for the
+// resource in the path, ReferenceNotFound for one named in the request body.
+// Other errors are mapped like Database.
+func Lookup(c *gin.Context, err error, code Code, message, fallback string) {
+	if IsNoRows(err) {
+		Respond(c, code, message)
+		return
+	}
+	Database(c, err, fallback)
+}
+
+// IsNoRows reports whether err means the looked up row doesn't exist
+func IsNoRows(err error) bool {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "22P02" // invalid_text_representation, e.g. a malformed UUID
+}
+
+// FromDatabase maps a database error to a code and client-safe message
+func FromDatabase(err error, fallback string) (Code, string) {
+	// a statement ran past its statement_timeout (db.WithQueryTimeout); a retry
+	// would most likely run as long again
+	var timeoutErr *pgconn.PgError
+	if errors.As(err, &timeoutErr) && timeoutErr.Code == "57014" {
+		return Timeout, "query timed out"
+	}
+	if isTransient(err) {
+		return Unavailable, "service temporarily unavailable, please retry"
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return Internal, fallback
+	}
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		return AlreadyExists, constraintMessage(pgErr.ConstraintName, "value already exists")
+	case "23503": // foreign_key_violation
+		return ReferenceNotFound, constraintMessage(pgErr.ConstraintName, "referenced record not found")
+	case "23514", "23502": // check_violation, not_null_violation
+		return ConstraintViolation, constraintMessage(pgErr.ConstraintName, "value violates a data constraint")
+	case "22P02": // invalid_text_representation
+		return InvalidInput, "invalid value format"
+	case "42501": // insufficient_privilege (RLS)
+		return Forbidden, "operation blocked by row-level security"
+	default:
+		return Internal, fallback
+	}
+}
+
+// isTransient reports failures worth retrying: the database being unreachable
+// or overloaded, connection timeouts, serialization failures and deadlocks
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return true
+	}
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "40001", "40P01", // serialization_failure, deadlock_detected
+		"53300",                   // too_many_connections
+		"55P03",                   // lock_not_available
+		"57P01", "57P02", "57P03": // admin/crash shutdown, cannot_connect_now
+		return true
+	}
+	return strings.HasPrefix(pgErr.Code, "08") // connection_exception class
+}
+
+// constraintMessages gives readable messages for known schema constraints
+var constraintMessages = map[string]string{
+	"employees_email_key":          "email already exists",
+	"employees_employee_id_key":    "employee_id already exists",
+	"check_joining_date":           "joining_date cannot be in the future",
+	"check_resignation_date":       "resignation_date cannot be before joining_date",
+	"check_email_format":           "email format is invalid",
+	"check_phone_format":           "phone format is invalid",
+	"leave_types_name_key":         "leave type name already exists",
+	"users_email_key":              "user already exists",
+	"users_employee_id_key":        "user already exists",
+	"check_used_days_limit":        "used_days cannot exceed allocated plus carried forward days",
+	"check_year_valid":             "year must be between 2020 and 2050",
+	"employees_department_id_fkey": "department_id not found",
+	"employees_manager_id_fkey":    "manager_id not found",
+
+	"leave_requests_leave_type_id_fkey":          "leave_type_id not found",
+	"employee_leave_balances_leave_type_id_fkey": "leave_type_id not found",
+	"attendance_records_employee_id_fkey":        "employee_id not found",
+	"erasure_requests_employee_id_fkey":          "employee_id not found",
+	"idx_erasure_requests_open":                  "an erasure request for this employee is already open",
+	"policy_documents_version_key":               "the policy was published again meanwhile; retry",
+	"check_policy_key_format":                    "policy_key must be lower case letters, digits and dashes",
+	"check_policy_body_not_empty":                "body cannot be empty",
+	"approval_routing_rules_name_key":            "an approval routing rule with this name already exists",
+	"approval_routing_rules_leave_type_id_fkey":  "leave_type_id not found",
+	"approval_routing_rules_department_id_fkey":  "department_id not found",
+	"check_routing_rule_name_not_empty":          "name cannot be empty",
+	"check_routing_rule_days":                    "min_days must be positive and max_days at least min_days",
+	"check_accrual_rate":                         "accrual_rate must be positive",
+	"idx_comp_off_requests_work_date":            "comp-off for this work_date is already logged",
+	"approval_delegations_delegator_id_fkey":     "delegator_id not found",
+	"approval_delegations_delegate_id_fkey":      "delegate_id not found",
+	"check_webhook_events":                       "events must only contain the events a webhook can subscribe to",
+	"chat_integrations_department_id_fkey":       "department_id not found",
+	"idx_chat_integrations_department":           "the department already has a chat integration",
+	"idx_chat_integrations_default":              "the organization already has a chat integration without a department",
+	"check_chat_slack_credentials":               "signing_secret and bot_token only apply to Slack integrations",
+	"api_keys_name_key":                          "an API key with this name already exists",
+	"check_api_key_scopes":                       "scopes must only contain the scopes an API key can have",
+}
+
+func constraintMessage(constraint, fallback string) string {
+	if msg, ok := constraintMessages[constraint]; ok {
+		return msg
+	}
+	return fallback
+}
+
+// FieldError is one entry of the "details" array sent for validation failures:
+//
+//	{"field": "start_date", "error": "must be YYYY-MM-DD"}
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Fields responds with InvalidInput and a details array of per-field errors
+func Fields(c *gin.Context, message string, errs []FieldError) {
+	locale := c.GetString("locale")
+	translated := make([]FieldError, len(errs))
+	for i, e := range errs {
+		translated[i] = FieldError{Field: e.Field, Error: i18n.Translate(locale, e.Error)}
+	}
+	RespondWithDetails(c, InvalidInput, message, translated)
+}
@@ -0,0 +1,114 @@
+// Package apikey issues the API keys integration clients, such as payroll
+// exports and HRIS syncs, send in the X-API-Key header instead of a JWT, and
+// tells which scope each route needs of a key.
+//
+// A key acts as the admin who created it, with that user's current role, on
+// the routes its scopes cover only (see middleware.AuthMiddleware). Only the
+// SHA-256 of a key is stored.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Header carries the key
+const Header = "X-API-Key"
+
+// prefix starts every key, so that leaked keys are easy to scan for
+const prefix = "lms_"
+
+// shownLength is how much of a key is kept in the clear, to tell keys apart
+const shownLength = len(prefix) + 8
+
+// Scopes lists every scope; the check_api_key_scopes constraint allows the
+// same. A read scope covers GET and HEAD, a write scope the other methods.
+var Scopes = []string{
+	"employees:read", "employees:write",
+	"leave_balances:read", "leave_balances:write",
+	"leave_requests:read", "leave_requests:write",
+	"leave_types:read", "leave_types:write",
+	"holidays:read",
+	"comp_off:read", "comp_off:write",
+	"attendance:read", "attendance:write",
+	"reports:read",
+	"audit_logs:read",
+}
+
+// ValidScope reports whether s is one of Scopes
+func ValidScope(s string) bool {
+	for _, v := range Scopes {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// resources maps routes to the resource their scopes are for, by prefix; the
+// longest prefix matching a route wins. Routes under none are closed to API
+// keys: signing in, the caller's own records, administration, and the batch,
+// GraphQL and event stream endpoints.
+var resources = []struct{ prefix, resource string }{
+	{"/employees", "employees"},
+	{"/org-chart", "employees"},
+	{"/employees/leave-balances/export", "leave_balances"},
+	{"/employees/:id/leave-balances", "leave_balances"},
+	{"/leave-balances/query", "leave_balances"},
+	{"/leave-requests", "leave_requests"},
+	{"/approvals/pending", "leave_requests"},
+	{"/calendar/team", "leave_requests"},
+	{"/leave-types", "leave_types"},
+	{"/holidays", "holidays"},
+	{"/comp-off", "comp_off"},
+	{"/attendance", "attendance"},
+	{"/reports/attendance", "attendance"},
+	{"/reports", "reports"},
+	{"/audit-logs", "audit_logs"},
+}
+
+// queries are the POST routes that only read
+var queries = map[string]bool{
+	"/leave-balances/query": true,
+}
+
+// Scope returns the scope a request for route (a gin route pattern, e.g.
+// /employees/:id) with method needs, and false when no scope opens it
+func Scope(method, route string) (string, bool) {
+	resource, longest := "", 0
+	for _, r := range resources {
+		if (route == r.prefix || strings.HasPrefix(route, r.prefix+"/")) && len(r.prefix) > longest {
+			resource, longest = r.resource, len(r.prefix)
+		}
+	}
+	if resource == "" {
+		return "", false
+	}
+	access := "write"
+	if method == http.MethodGet || method == http.MethodHead || (method == http.MethodPost && queries[route]) {
+		access = "read"
+	}
+	scope := resource + ":" + access
+	return scope, ValidScope(scope)
+}
+
+// New returns a new key, the start of it that is kept to tell it apart, and
+// its hash
+func New() (key, shown, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = prefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:shownLength], Hash(key), nil
+}
+
+// Hash is what is stored of key
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,212 @@
+// Package bootstrap prepares a fresh database: it applies the schema, inserts
+// the reference data and creates the first admin login, so a new environment
+// can be brought up without running SQL by hand.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInitialized is returned by Migrate when the schema is already in place
+var ErrInitialized = errors.New("database already has the schema")
+
+// Migrate applies schema, the contents of Database/db.sql, in one transaction.
+// The script starts by dropping the public schema, so on a database that
+// already has it Migrate refuses unless reset is set.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, schema string, reset bool) error {
+	var exists bool
+	if err := pool.QueryRow(ctx, "SELECT to_regclass('public.employees') IS NOT NULL").Scan(&exists); err != nil {
+		return err
+	}
+	if exists && !reset {
+		return ErrInitialized
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// without arguments pgx uses the simple protocol, which runs the whole
+	// multi-statement script
+	if _, err := tx.Exec(ctx, schema); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Organization is a tenant; every other row belongs to one
+type Organization struct {
+	ID   string
+	Name string
+	Slug string
+}
+
+// FindOrganization looks an organization up by slug
+func FindOrganization(ctx context.Context, pool *pgxpool.Pool, slug string) (Organization, error) {
+	o := Organization{Slug: slug}
+	err := pool.QueryRow(db.AsService(ctx),
+		"SELECT id, name FROM organizations WHERE slug = $1", slug).Scan(&o.ID, &o.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return o, fmt.Errorf("organization %q not found", slug)
+	}
+	return o, err
+}
+
+// CreateOrganization adds a tenant. It starts empty: run Seed and CreateAdmin
+// scoped to it (db.WithOrg) to make it usable.
+func CreateOrganization(ctx context.Context, pool *pgxpool.Pool, name, slug string) (Organization, error) {
+	o := Organization{Name: strings.TrimSpace(name), Slug: slug}
+	if o.Name == "" || o.Slug == "" {
+		return o, errors.New("a name and a slug are required")
+	}
+	err := pool.QueryRow(db.AsService(ctx),
+		"INSERT INTO organizations (name, slug) VALUES ($1, $2) RETURNING id", o.Name, o.Slug).Scan(&o.ID)
+	return o, err
+}
+
+// Reference data inserted by Seed, the same rows Database/db.sql starts with
+var (
+	departments = []struct{ name, description string }{
+		{"Human Resources", "Manages employee relations and policies"},
+		{"Engineering", "Software development and technical operations"},
+		{"Marketing", "Brand promotion and customer acquisition"},
+		{"Sales", "Revenue generation and client relations"},
+		{"Finance", "Financial planning and accounting"},
+	}
+	leaveTypes = []struct {
+		name, description   string
+		maxDays             int
+		carryForward        bool
+		maxCarryForwardDays int
+	}{
+		{"Annual Leave", "Yearly vacation days", 21, true, 5},
+		{"Sick Leave", "Medical leave for illness", 12, false, 0},
+		{"Maternity Leave", "Leave for new mothers", 90, false, 0},
+		{"Paternity Leave", "Leave for new fathers", 15, false, 0},
+		{"Emergency Leave", "Urgent personal matters", 5, false, 0},
+		{"Compensatory Leave", "Time off for overtime work", 10, true, 3},
+	}
+)
+
+// SeedResult counts the rows Seed inserted
+type SeedResult struct {
+	Departments int
+	LeaveTypes  int
+}
+
+// Seed inserts the default departments and leave types that are missing in
+// the organization ctx is scoped to. Existing rows with the same name are left as they are, so it is safe to run
+// repeatedly.
+func Seed(ctx context.Context, pool *pgxpool.Pool) (SeedResult, error) {
+	var res SeedResult
+	err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		res = SeedResult{}
+		for _, d := range departments {
+			tag, err := tx.Exec(ctx,
+				"INSERT INTO departments (name, description) VALUES ($1, $2) ON CONFLICT (org_id, name) DO NOTHING",
+				d.name, d.description)
+			if err != nil {
+				return err
+			}
+			res.Departments += int(tag.RowsAffected())
+		}
+		for _, lt := range leaveTypes {
+			tag, err := tx.Exec(ctx,
+				`INSERT INTO leave_types (name, description, max_days_per_year, carry_forward_allowed, max_carry_forward_days)
+				 VALUES ($1, $2, $3, $4, $5) ON CONFLICT (org_id, name) DO NOTHING`,
+				lt.name, lt.description, lt.maxDays, lt.carryForward, lt.maxCarryForwardDays)
+			if err != nil {
+				return err
+			}
+			res.LeaveTypes += int(tag.RowsAffected())
+		}
+		return nil
+	})
+	return res, err
+}
+
+// AdminOptions describes the admin created by CreateAdmin
+type AdminOptions struct {
+	Email      string
+	Password   string
+	Name       string
+	Department string // department name; the first department by name when empty
+}
+
+// Admin is the account CreateAdmin created
+type Admin struct {
+	EmployeeID string // employee code, the employee_id of the login
+	UserID     string
+}
+
+// CreateAdmin creates an admin employee and its login in one transaction, in
+// the organization ctx is scoped to. Logins belong to employees, so the
+// employee record is created too.
+func CreateAdmin(ctx context.Context, pool *pgxpool.Pool, opts AdminOptions) (Admin, error) {
+	opts.Email = strings.TrimSpace(opts.Email)
+	if opts.Email == "" || !strings.Contains(opts.Email, "@") {
+		return Admin{}, errors.New("a valid email is required")
+	}
+	if len(opts.Password) < 6 {
+		return Admin{}, errors.New("the password must be at least 6 characters")
+	}
+	if opts.Name == "" {
+		opts.Name = "Administrator"
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return Admin{}, err
+	}
+
+	var admin Admin
+	err = db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		var taken bool
+		if err := tx.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM employees WHERE email = $1) OR EXISTS (SELECT 1 FROM users WHERE email = $1)",
+			opts.Email).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("an employee or user with email %s already exists", opts.Email)
+		}
+
+		var deptID string
+		err := tx.QueryRow(ctx,
+			"SELECT id FROM departments WHERE $1 = '' OR name = $1 ORDER BY name LIMIT 1",
+			opts.Department).Scan(&deptID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if opts.Department != "" {
+				return fmt.Errorf("department %q not found", opts.Department)
+			}
+			return errors.New("there are no departments; run seed first")
+		}
+		if err != nil {
+			return err
+		}
+
+		// employee_id is left NULL for the generate_employee_id trigger
+		if err := tx.QueryRow(ctx,
+			`INSERT INTO employees (email, name, department_id, role, joining_date)
+			 VALUES ($1, $2, $3, $4, CURRENT_DATE)
+			 RETURNING employee_id`,
+			opts.Email, opts.Name, deptID, models.RoleAdmin).Scan(&admin.EmployeeID); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx,
+			`INSERT INTO users (employee_id, email, password_hash, role, is_active, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, true, NOW(), NOW())
+			 RETURNING id`,
+			admin.EmployeeID, opts.Email, string(hash), models.RoleAdmin).Scan(&admin.UserID)
+	})
+	return admin, err
+}
@@ -0,0 +1,53 @@
+// Package buildinfo identifies the running build. The values are set at link
+// time, e.g.
+//
+//	go build -ldflags "-X leave-management/internal/buildinfo.GitSHA=$(git rev-parse HEAD) \
+//	  -X leave-management/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ) \
+//	  -X leave-management/internal/buildinfo.MigrationVersion=$(git log -1 --format=%h -- ../Database/db.sql)"
+//
+// Without GitSHA, the commit is taken from the VCS stamp go build embeds when
+// run inside the repository.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	GitSHA           string // commit the binary was built from
+	BuildTime        string // RFC 3339, UTC
+	MigrationVersion string // version of Database/db.sql the build expects
+)
+
+// Info is served by GET /version
+type Info struct {
+	GitSHA           string `json:"git_sha"`
+	BuildTime        string `json:"build_time"`
+	MigrationVersion string `json:"migration_version"`
+	GoVersion        string `json:"go_version"`
+	Modified         bool   `json:"modified,omitempty"` // built from a tree with uncommitted changes
+}
+
+// Get returns the build's identity; unknown values read "unknown"
+func Get() Info {
+	info := Info{GitSHA: GitSHA, BuildTime: BuildTime, MigrationVersion: MigrationVersion, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.GitSHA == "" {
+					info.GitSHA = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	for _, v := range []*string{&info.GitSHA, &info.BuildTime, &info.MigrationVersion} {
+		if *v == "" {
+			*v = "unknown"
+		}
+	}
+	return info
+}
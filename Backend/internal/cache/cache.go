@@ -0,0 +1,153 @@
+// Package cache is an optional Redis read-through cache for hot, rarely
+// changing lookups. A nil *Cache is valid and caches nothing, so callers never
+// need to check whether Redis is configured. Redis errors are logged and
+// treated as misses: the database stays the source of truth.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces every key so the Redis instance can be shared
+const keyPrefix = "lms:"
+
+// Keys of the cached lookups. Writers that change the underlying rows delete
+// the matching key. Lists are per organization; rows are keyed by their UUID,
+// which is unique across organizations.
+
+// LeaveTypesKey caches the active leave types of an organization
+func LeaveTypesKey(orgID string) string { return "org:" + orgID + ":leave_types:active" }
+
+// HolidaysKey caches the holidays of one year of an organization
+func HolidaysKey(orgID string, year int) string {
+	return fmt.Sprintf("org:%s:holidays:%d", orgID, year)
+}
+
+// UserKey caches a user's email, role and active flag for token checks
+func UserKey(userID string) string { return "user:" + userID }
+
+// EmployeeManagerKey caches the manager_id of an employee
+func EmployeeManagerKey(employeeID string) string { return "employee:" + employeeID + ":manager" }
+
+// LeaveRequestOwnerKey caches the employee_id a leave request belongs to
+func LeaveRequestOwnerKey(requestID string) string { return "leave_request:" + requestID + ":owner" }
+
+// RateLimitKey counts the hits of one client (an IP or account) on a
+// rate-limited route in the current window
+func RateLimitKey(route, client string) string { return "ratelimit:" + route + ":" + client }
+
+type Cache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// New connects to the Redis server at url (redis://[user:pass@]host:port/db).
+// An empty url disables caching and returns nil. An unreachable server is only
+// logged: lookups miss until it comes back.
+func New(ctx context.Context, url string, ttl time.Duration) (*Cache, error) {
+	if url == "" {
+		return nil, nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		slog.WarnContext(ctx, "redis unreachable, serving from the database until it recovers", "error", err)
+	}
+	return &Cache{client: client, ttl: ttl}, nil
+}
+
+// Get decodes the cached value of key into dst and reports whether it was found
+func (c *Cache) Get(ctx context.Context, key string, dst interface{}) bool {
+	if c == nil {
+		return false
+	}
+	data, err := c.client.Get(ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			slog.WarnContext(ctx, "cache get failed", "key", key, "error", err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		slog.WarnContext(ctx, "cache entry undecodable", "key", key, "error", err)
+		return false
+	}
+	return true
+}
+
+// Set stores v under key for the configured TTL
+func (c *Cache) Set(ctx context.Context, key string, v interface{}) {
+	c.SetTTL(ctx, key, v, 0)
+}
+
+// SetTTL stores v under key for ttl, or the configured TTL when ttl is 0
+func (c *Cache) SetTTL(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	if c == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.WarnContext(ctx, "cache encode failed", "key", key, "error", err)
+		return
+	}
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	if err := c.client.Set(ctx, keyPrefix+key, data, ttl).Err(); err != nil {
+		slog.WarnContext(ctx, "cache set failed", "key", key, "error", err)
+	}
+}
+
+// Delete invalidates keys after the rows behind them changed
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	if c == nil || len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = keyPrefix + k
+	}
+	if err := c.client.Del(ctx, prefixed...).Err(); err != nil {
+		slog.WarnContext(ctx, "cache delete failed", "keys", keys, "error", err)
+	}
+}
+
+// incrScript counts a hit in a fixed window: the first hit starts the window
+// by setting the expiry, so a key never outlives it
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return {n, redis.call('PTTL', KEYS[1])}`)
+
+// Incr adds one to the counter under key, which expires window after its
+// first hit, and returns the new count and the time left until it expires.
+// ok is false when Redis is not configured or failed.
+func (c *Cache) Incr(ctx context.Context, key string, window time.Duration) (n int64, ttl time.Duration, ok bool) {
+	if c == nil {
+		return 0, 0, false
+	}
+	res, err := incrScript.Run(ctx, c.client, []string{keyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil || len(res) != 2 {
+		slog.WarnContext(ctx, "cache incr failed", "key", key, "error", err)
+		return 0, 0, false
+	}
+	return res[0], time.Duration(res[1]) * time.Millisecond, true
+}
+
+// Close releases the Redis connections
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.client.Close()
+}
@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"leave-management/internal/jwks"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// slackMaxSkew is how old a Slack callback may be; older ones are replays
+const slackMaxSkew = 5 * time.Minute
+
+// VerifySlack checks the X-Slack-Signature of a callback's raw body against
+// the app's signing secret, and that its X-Slack-Request-Timestamp is recent
+func VerifySlack(secret string, h http.Header, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(h.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > slackMaxSkew || d < -slackMaxSkew {
+		return errors.New("request timestamp too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + strconv.FormatInt(ts, 10) + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(h.Get("X-Slack-Signature"))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// SlackAction is a click on one of the buttons of a Slack message
+type SlackAction struct {
+	UserID         string // the Slack user who clicked
+	Action         string // Approve or Reject
+	LeaveRequestID string
+	ResponseURL    string // where the answer goes, see SlackReplacement
+}
+
+// ParseSlackAction reads the block_actions payload of a verified callback
+// body (application/x-www-form-urlencoded, payload=<JSON>)
+func ParseSlackAction(body []byte) (SlackAction, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return SlackAction{}, err
+	}
+	var p struct {
+		Type string `json:"type"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+		ResponseURL string `json:"response_url"`
+	}
+	if err := json.Unmarshal([]byte(form.Get("payload")), &p); err != nil {
+		return SlackAction{}, errors.New("invalid payload")
+	}
+	if p.Type != "block_actions" || len(p.Actions) != 1 || p.User.ID == "" {
+		return SlackAction{}, errors.New("not a button click")
+	}
+	return SlackAction{UserID: p.User.ID, Action: p.Actions[0].ActionID, LeaveRequestID: p.Actions[0].Value,
+		ResponseURL: p.ResponseURL}, nil
+}
+
+// slackAPI is the base URL of the Slack Web API
+const slackAPI = "https://slack.com/api/"
+
+// SlackEmail looks up the email address of a Slack user with users.info; the
+// bot token needs the users:read and users:read.email scopes
+func (c *Client) SlackEmail(ctx context.Context, token, userID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, slackAPI+"users.info?user="+url.QueryEscape(userID), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  struct {
+			Profile struct {
+				Email string `json:"email"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("users.info: HTTP %d", resp.StatusCode)
+	}
+	if !out.OK {
+		return "", fmt.Errorf("users.info: %s", out.Error)
+	}
+	if out.User.Profile.Email == "" {
+		return "", errors.New("users.info: the user has no email address, or the token lacks users:read.email")
+	}
+	return out.User.Profile.Email, nil
+}
+
+// What Microsoft's actionable message tokens are signed with and carry
+const (
+	teamsKeysURL = "https://substrate.office.com/sts/common/discovery/keys"
+	teamsIssuer  = "https://substrate.office.com/sts/"
+	teamsAppID   = "48af08dc-f6d2-435f-b2a7-069abd99c086"
+)
+
+// TeamsVerifier checks the bearer token Microsoft sends with the HttpPOST of
+// a Teams button, and tells who clicked
+type TeamsVerifier struct {
+	keys *jwks.Set
+}
+
+func NewTeamsVerifier(client *Client) *TeamsVerifier {
+	return &TeamsVerifier{keys: jwks.New(client.http, teamsKeysURL)}
+}
+
+// Verify checks the Authorization header of a callback whose URL starts with
+// audience (scheme and host, e.g. https://lms.example.com) and returns the
+// email address of the user who clicked
+func (v *TeamsVerifier) Verify(ctx context.Context, authorization, audience string) (string, error) {
+	raw, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return "", errors.New("missing bearer token")
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithIssuer(teamsIssuer), jwt.WithAudience(audience),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	if appID, _ := claims["appid"].(string); appID != teamsAppID {
+		return "", errors.New("token not issued for actionable messages")
+	}
+	email, _ := claims["sub"].(string)
+	if email == "" {
+		return "", errors.New("token has no subject")
+	}
+	return email, nil
+}
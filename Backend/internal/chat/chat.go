@@ -0,0 +1,337 @@
+// Package chat posts leave requests to the Slack and Microsoft Teams channels
+// an organization connects at /chat-integrations, with Approve and Reject
+// buttons for the approvers in the channel.
+//
+// Postgres queues a message when leave is applied for and when a request
+// moves on to its next approver (see queue_chat_message in Database/db.sql).
+// jobs.SendChatMessages builds each one from the request as it is then and
+// posts it to the integration's incoming webhook, retrying like a webhook
+// delivery. A click on a button calls back POST /integrations/chat/{id}/actions:
+// VerifySlack and TeamsVerifier tell who clicked, and the request is decided
+// as that employee, with the same checks as the API.
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"leave-management/internal/webhook"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Providers of an integration
+const (
+	Slack = "slack"
+	Teams = "teams"
+)
+
+// Providers lists every provider; the check_chat_provider constraint allows
+// the same
+var Providers = []string{Slack, Teams}
+
+// Kinds of message
+const (
+	LeaveApplied   = "leave_applied"   // a leave request was created
+	ApprovalNeeded = "approval_needed" // an approval step was approved and the next one waits
+)
+
+// Button actions
+const (
+	Approve = "approve"
+	Reject  = "reject"
+)
+
+// Leave is the leave request a message is about
+type Leave struct {
+	ID           string
+	Employee     string // name
+	LeaveType    string // name
+	StartDate    time.Time
+	EndDate      time.Time
+	TotalDays    float64
+	DurationUnit string
+	Hours        *float64 // for duration_unit hours
+	Status       string
+	Stage        *string // pending_manager or pending_hr while pending
+}
+
+// Querier runs GetLeave: a pool, a connection or a transaction
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// GetLeave reads leave request id as a message shows it
+func GetLeave(ctx context.Context, q Querier, id string) (Leave, error) {
+	var l Leave
+	err := q.QueryRow(ctx, `
+		SELECT lr.id, e.name, lt.name, lr.start_date, lr.end_date, lr.total_days, lr.duration_unit, lr.hours,
+			lr.status, leave_request_stage(lr.id)
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		JOIN leave_types lt ON lt.id = lr.leave_type_id
+		WHERE lr.id = $1`, id).Scan(&l.ID, &l.Employee, &l.LeaveType, &l.StartDate, &l.EndDate, &l.TotalDays,
+		&l.DurationUnit, &l.Hours, &l.Status, &l.Stage)
+	return l, err
+}
+
+// Message is one post to a channel
+type Message struct {
+	Kind  string
+	Leave Leave
+	// ActionURL is where the Teams buttons POST to; "" leaves the buttons
+	// out. Slack sends the clicks to the app's Interactivity Request URL
+	// instead, so for Slack it only needs to be set.
+	ActionURL string
+	Note      string // e.g. who decided the request, shown below it
+}
+
+// Summary is the message's first line
+func (m Message) Summary() string {
+	l := m.Leave
+	span := l.StartDate.Format("2006-01-02")
+	if !l.EndDate.Equal(l.StartDate) {
+		span = "from " + span + " to " + l.EndDate.Format("2006-01-02")
+	} else {
+		span = "on " + span
+	}
+	if m.Kind == ApprovalNeeded {
+		return fmt.Sprintf("%s's %s %s (%s) has a new approver.", l.Employee, l.LeaveType, span, duration(l))
+	}
+	return fmt.Sprintf("%s applied for %s %s (%s).", l.Employee, l.LeaveType, span, duration(l))
+}
+
+// StatusLine says where the request stands
+func (m Message) StatusLine() string {
+	l := m.Leave
+	if l.Status != "pending" {
+		return "Status: " + l.Status
+	}
+	if l.Stage != nil && *l.Stage == "pending_hr" {
+		return "Awaiting HR approval"
+	}
+	return "Awaiting manager approval"
+}
+
+// interactive reports whether the message gets buttons
+func (m Message) interactive() bool {
+	return m.ActionURL != "" && m.Leave.Status == "pending"
+}
+
+func duration(l Leave) string {
+	switch {
+	case l.DurationUnit == "hours" && l.Hours != nil:
+		return plural(*l.Hours, "hour")
+	case l.DurationUnit == "half_day_am":
+		return "half day, morning"
+	case l.DurationUnit == "half_day_pm":
+		return "half day, afternoon"
+	}
+	return plural(l.TotalDays, "day")
+}
+
+func plural(n float64, unit string) string {
+	s := strconv.FormatFloat(n, 'f', -1, 64) + " " + unit
+	if n != 1 {
+		s += "s"
+	}
+	return s
+}
+
+// slackEscape escapes the characters Slack's mrkdwn reserves
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackBody is m as a Slack incoming webhook payload, in Block Kit. The
+// buttons carry the leave request's id as their value.
+func SlackBody(m Message) ([]byte, error) {
+	return json.Marshal(slackPayload(m))
+}
+
+// SlackReplacement is m as the answer to a click, posted to the click's
+// response_url: it replaces the message clicked
+func SlackReplacement(m Message) ([]byte, error) {
+	p := slackPayload(m)
+	p["replace_original"] = true
+	return json.Marshal(p)
+}
+
+func slackPayload(m Message) map[string]interface{} {
+	text := slackEscape.Replace(m.Summary()) + "\n*" + slackEscape.Replace(m.StatusLine()) + "*"
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": text},
+		},
+	}
+	if m.Note != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type":     "context",
+			"elements": []interface{}{map[string]string{"type": "mrkdwn", "text": slackEscape.Replace(m.Note)}},
+		})
+	}
+	if m.interactive() {
+		button := func(action, label, style string) map[string]interface{} {
+			return map[string]interface{}{
+				"type":      "button",
+				"action_id": action,
+				"style":     style,
+				"value":     m.Leave.ID,
+				"text":      map[string]string{"type": "plain_text", "text": label},
+			}
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type":     "actions",
+			"block_id": "leave_request",
+			"elements": []interface{}{button(Approve, "Approve", "primary"), button(Reject, "Reject", "danger")},
+		})
+	}
+	return map[string]interface{}{
+		"text":   m.Summary(), // the notification's text
+		"blocks": blocks,
+	}
+}
+
+// TeamsAction is the body the Teams buttons POST to ActionURL
+type TeamsAction struct {
+	LeaveRequestID string `json:"leave_request_id"`
+	Action         string `json:"action"`
+	Reason         string `json:"reason,omitempty"` // of a rejection
+}
+
+// TeamsBody is m as an Office 365 connector card. Approve POSTs a
+// TeamsAction at once; Reject asks for the reason first.
+func TeamsBody(m Message) ([]byte, error) {
+	l := m.Leave
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    m.Summary(),
+		"themeColor": "0076D7",
+		"title":      m.Summary(),
+		"text":       m.StatusLine(),
+		"sections": []interface{}{map[string]interface{}{
+			"facts": []map[string]string{
+				{"name": "Employee", "value": l.Employee},
+				{"name": "Leave type", "value": l.LeaveType},
+				{"name": "From", "value": l.StartDate.Format("2006-01-02")},
+				{"name": "To", "value": l.EndDate.Format("2006-01-02")},
+				{"name": "Duration", "value": duration(l)},
+			},
+		}},
+	}
+	if m.Note != "" {
+		card["text"] = m.StatusLine() + "\n\n" + m.Note
+	}
+	if m.interactive() {
+		approve, err := json.Marshal(TeamsAction{LeaveRequestID: l.ID, Action: Approve})
+		if err != nil {
+			return nil, err
+		}
+		// Teams puts the input's value into the body as it is typed, so it
+		// is the one member not built with json.Marshal
+		reject := `{"leave_request_id":"` + l.ID + `","action":"` + Reject + `","reason":"{{reason.value}}"}`
+		card["potentialAction"] = []interface{}{
+			map[string]string{"@type": "HttpPOST", "name": "Approve", "target": m.ActionURL, "body": string(approve)},
+			map[string]interface{}{
+				"@type": "ActionCard",
+				"name":  "Reject",
+				"inputs": []interface{}{map[string]interface{}{
+					"@type": "TextInput", "id": "reason", "title": "Reason", "isMultiline": true, "isRequired": true,
+				}},
+				"actions": []interface{}{
+					map[string]string{"@type": "HttpPOST", "name": "Reject", "target": m.ActionURL, "body": reject},
+				},
+			},
+		}
+	}
+	return json.Marshal(card)
+}
+
+// Body is m in the format of provider
+func Body(provider string, m Message) ([]byte, error) {
+	if provider == Teams {
+		return TeamsBody(m)
+	}
+	return SlackBody(m)
+}
+
+// ActionURL is where the buttons of integration id call back, under the
+// API's public base URL
+func ActionURL(base, id string) string {
+	if base == "" {
+		return ""
+	}
+	return strings.TrimRight(base, "/") + "/integrations/chat/" + id + "/actions"
+}
+
+// Message outcomes
+const (
+	Sent     = "sent"
+	Retrying = "retrying" // failed, another attempt is scheduled
+	Failed   = "failed"   // failed and out of attempts
+)
+
+var posts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lms_chat_messages_total",
+	Help: "Slack and Teams message attempts by provider and outcome (sent, retrying, failed).",
+}, []string{"provider", "outcome"})
+
+// Count records the outcome of one attempt in lms_chat_messages_total
+func Count(provider, outcome string) {
+	posts.WithLabelValues(provider, outcome).Inc()
+}
+
+// maxErrorBody is how much of a failed response is kept with the message
+const maxErrorBody = 512
+
+// Client posts messages and talks to the Slack Web API
+type Client struct {
+	http *http.Client
+}
+
+// NewClient returns a Client whose requests give up after timeout. Like the
+// webhook deliveries, it only dials public addresses.
+func NewClient(timeout time.Duration) *Client {
+	return &Client{http: &http.Client{
+		Timeout:   timeout,
+		Transport: webhook.NewTransport(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Post sends body to an incoming webhook or a Slack response_url. Any status
+// but 2xx is an error that carries the start of the response body.
+func (c *Client) Post(ctx context.Context, target string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	msg := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(b)); text != "" {
+		msg += ": " + text
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Live is the configuration of a running server. Reload re-resolves it, and
+// the settings tagged reload:"live" take effect from the next request on.
+// The others size pools, open listeners or register routes at startup, so a
+// changed value is reported and ignored until the next restart.
+type Live struct {
+	cfg atomic.Pointer[AppConfig]
+}
+
+func NewLive(cfg AppConfig) *Live {
+	l := &Live{}
+	l.cfg.Store(&cfg)
+	return l
+}
+
+// Get returns the current configuration
+func (l *Live) Get() AppConfig {
+	return *l.cfg.Load()
+}
+
+// Reload resolves the configuration again and applies the live settings.
+// It returns the names of the settings that changed and of those that changed
+// but need a restart. On error the current configuration is kept.
+func (l *Live) Reload() (changed, restart []string, err error) {
+	next, err := Resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+	cur := l.Get()
+	nv, cv := reflect.ValueOf(&next).Elem(), reflect.ValueOf(cur)
+	t := nv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("env")
+		if name == "" || reflect.DeepEqual(nv.Field(i).Interface(), cv.Field(i).Interface()) {
+			continue
+		}
+		if f.Tag.Get("reload") == "live" {
+			changed = append(changed, name)
+			continue
+		}
+		restart = append(restart, name)
+		nv.Field(i).Set(cv.Field(i))
+		next.origins[name] = cur.origins[name]
+	}
+	l.cfg.Store(&next)
+	return changed, restart, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// redactedValue replaces secrets. Empty secrets stay empty so it is still
+// visible whether they are set.
+const redactedValue = "[redacted]"
+
+// Setting is one resolved value as reported by Redacted
+type Setting struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"` // env, the config file or default
+}
+
+// Redacted returns every setting keyed by its environment variable name, with
+// secrets masked: passwords in URLs are replaced by xxxxx and other secrets
+// are replaced entirely.
+func (c AppConfig) Redacted() map[string]Setting {
+	out := map[string]Setting{}
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		source := c.origins[name]
+		if source == "" {
+			source = "default"
+		}
+		out[name] = Setting{Value: settingValue(v.Field(i).Interface(), f.Tag.Get("secret")), Source: source}
+	}
+	return out
+}
+
+func settingValue(v interface{}, secret string) interface{} {
+	switch v := v.(type) {
+	case string:
+		if v == "" || secret == "" {
+			return v
+		}
+		if secret == "url" {
+			if u, err := url.Parse(v); err == nil && u.Scheme != "" {
+				if q := u.Query(); q.Has("password") {
+					q.Set("password", "xxxxx")
+					u.RawQuery = q.Encode()
+				}
+				return u.Redacted()
+			}
+		}
+		return redactedValue
+	case []string:
+		if len(v) > 0 && secret != "" {
+			return redactedValue
+		}
+		return v
+	case slog.Level:
+		return strings.ToLower(v.String())
+	case fmt.Stringer: // time.Duration
+		return v.String()
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RetentionRule says how long the audit entries of one table are kept. Once
+// older than Days they are deleted, or with Anonymize stripped of who made
+// them and of personal data but kept. Table "*" stands for every table no
+// other rule names.
+type RetentionRule struct {
+	Table     string
+	Days      int
+	Anonymize bool
+}
+
+// RetentionPolicy is AUDIT_RETENTION: comma separated table=days rules, with
+// ":anonymize" after the days for rules that anonymize instead of delete,
+// e.g. "*=2555,refresh_tokens=365,users=365:anonymize".
+type RetentionPolicy []RetentionRule
+
+// ParseRetention parses an AUDIT_RETENTION value. An empty value keeps every
+// entry forever.
+func ParseRetention(spec string) (RetentionPolicy, error) {
+	var p RetentionPolicy
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		table, rest, ok := strings.Cut(part, "=")
+		table = strings.TrimSpace(table)
+		if !ok || table == "" {
+			return nil, fmt.Errorf("%q is not table=days", part)
+		}
+		days, mode, _ := strings.Cut(rest, ":")
+		r := RetentionRule{Table: table}
+		switch strings.TrimSpace(mode) {
+		case "":
+		case "anonymize":
+			r.Anonymize = true
+		default:
+			return nil, fmt.Errorf("%q: the only mode is anonymize", part)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(days))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q: days must be a positive integer", part)
+		}
+		r.Days = n
+		key := r.Table + ":" + strconv.FormatBool(r.Anonymize)
+		if seen[key] {
+			return nil, errors.New("more than one rule for " + table)
+		}
+		seen[key] = true
+		p = append(p, r)
+	}
+	return p, nil
+}
+
+// String formats the policy the way ParseRetention reads it
+func (p RetentionPolicy) String() string {
+	parts := make([]string, len(p))
+	for i, r := range p {
+		parts[i] = r.Table + "=" + strconv.Itoa(r.Days)
+		if r.Anonymize {
+			parts[i] += ":anonymize"
+		}
+	}
+	return strings.Join(parts, ",")
+}
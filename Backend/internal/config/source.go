@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read when CONFIG_FILE is unset, if it exists
+const defaultConfigFile = "config.yaml"
+
+// source looks settings up by their environment variable name, falling back to
+// the config file and then to the caller's default. It records where each
+// value came from and collects validation errors instead of stopping at the
+// first one.
+type source struct {
+	path    string            // config file, empty when none was read
+	file    map[string]string // flattened config file settings
+	origins map[string]string
+	errs    []string
+}
+
+// newSource reads the config file. Nested YAML keys are joined with "_" and
+// upper-cased, so
+//
+//	db:
+//	  max_conns: 20
+//
+// sets DB_MAX_CONNS.
+func newSource() (*source, error) {
+	s := &source{file: map[string]string{}, origins: map[string]string{}}
+	path := os.Getenv("CONFIG_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := flatten("", doc, s.file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.path = path
+	return s, nil
+}
+
+func flatten(prefix string, doc map[string]interface{}, out map[string]string) error {
+	for k, v := range doc {
+		name := strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(name, v, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("%s: lists are not supported", name)
+		case nil:
+			out[name] = ""
+		default:
+			out[name] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// lookup returns the raw value of name and the origin it was read from
+func (s *source) lookup(name string) (string, string) {
+	if v := os.Getenv(name); v != "" {
+		return v, "env"
+	}
+	if v := s.file[name]; v != "" {
+		return v, s.path
+	}
+	return "", "default"
+}
+
+func (s *source) get(name string) string {
+	v, origin := s.lookup(name)
+	s.origins[name] = origin
+	return v
+}
+
+func (s *source) str(name, def string) string {
+	if v := s.get(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// flag reads a boolean; anything other than the opposite of def keeps def
+func (s *source) flag(name string, def bool) bool {
+	v := s.get(name)
+	if def {
+		return v != "false"
+	}
+	return v == "true"
+}
+
+// list reads comma separated values, leaving out empty ones
+func (s *source) list(name string) []string {
+	var out []string
+	for _, v := range strings.Split(s.get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (s *source) duration(name string, def time.Duration, allowZero bool) time.Duration {
+	v := s.get(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	switch {
+	case err != nil || d < 0:
+		if allowZero {
+			s.invalid(name, "must be a non-negative duration such as 30s or 5m")
+		} else {
+			s.invalid(name, "must be a positive duration such as 30s or 5m")
+		}
+	case d == 0 && !allowZero:
+		s.invalid(name, "must be a positive duration such as 30s or 5m")
+	}
+	return d
+}
+
+// integer reads an integer of at least min that fits in bits (0 for int)
+func (s *source) integer(name string, def, min int64, bits int) int64 {
+	v := s.get(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, bits)
+	if err != nil || n < min {
+		switch min {
+		case 0:
+			s.invalid(name, "must be a non-negative integer")
+		case 1:
+			s.invalid(name, "must be a positive integer")
+		default:
+			s.invalid(name, fmt.Sprintf("must be an integer of at least %d", min))
+		}
+	}
+	return n
+}
+
+func (s *source) invalid(name, msg string) {
+	v, origin := s.lookup(name)
+	s.errs = append(s.errs, fmt.Sprintf("invalid %s=%q (from %s): %s", name, v, origin, msg))
+}
+
+// invalidSecret is invalid for settings whose value must not be logged
+func (s *source) invalidSecret(name, msg string) {
+	_, origin := s.lookup(name)
+	s.errs = append(s.errs, fmt.Sprintf("invalid %s (from %s): %s", name, origin, msg))
+}
+
+func (s *source) missing(name, hint string) {
+	where := "set it in the environment"
+	if s.path != "" {
+		where += " or in " + s.path
+	}
+	s.errs = append(s.errs, fmt.Sprintf("missing %s (%s): %s", name, hint, where))
+}
+
+// checkUnknown reports config file keys no setting asked for, which are
+// usually typos
+func (s *source) checkUnknown() {
+	var unknown []string
+	for name := range s.file {
+		if _, ok := s.origins[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		s.errs = append(s.errs, fmt.Sprintf("unknown setting %s in %s", name, s.path))
+	}
+}
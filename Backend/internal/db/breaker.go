@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of dialing while Postgres is considered down
+var ErrCircuitOpen = errors.New("database circuit open")
+
+// Breaker stops the pool from dialing Postgres after threshold consecutive
+// connection failures. While open, new connections fail at once (requests get
+// a 503) instead of each one waiting out a connect timeout; one probe is let
+// through per cooldown and a successful connection closes the breaker again.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	probeAt  time.Time // while open, the next time a dial is let through
+}
+
+// NewBreaker returns a breaker; a non-positive threshold disables it
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *Breaker) allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if now := time.Now(); now.After(b.probeAt) {
+		b.probeAt = now.Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+func (b *Breaker) success() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		slog.Info("database reachable again, circuit closed")
+	}
+	b.failures, b.open = 0, false
+}
+
+func (b *Breaker) failure(err error) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if !b.open && b.failures >= b.threshold {
+		b.open = true
+		b.probeAt = time.Now().Add(b.cooldown)
+		slog.Error("database unreachable, circuit opened", "failures", b.failures, "cooldown", b.cooldown.String(), "error", err)
+	}
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// guardDial wraps the pool's dialer with the breaker
+func (b *Breaker) guardDial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if !b.allow() {
+			return nil, ErrCircuitOpen
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil && ctx.Err() == nil {
+			b.failure(err)
+		}
+		return conn, err
+	}
+}
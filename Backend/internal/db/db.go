@@ -1,43 +1,73 @@
+//go:generate sqlc generate -f ../../sqlc.yaml
+
 package db
 
 import (
 	"context"
-	"log"
+	"strconv"
 	"time"
 
+	"leave-management/internal/logging"
+
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewPool(ctx context.Context, databaseURL string) *pgxpool.Pool {
+// PoolOptions tunes a pool; zero values keep the defaults noted per field,
+// except MinConns where 0 is a valid setting
+type PoolOptions struct {
+	MaxConns         int32         // default 10
+	MinConns         int32         // connections kept open when idle
+	MaxConnIdleTime  time.Duration // default 5m
+	StatementTimeout time.Duration // 0 leaves the server's statement_timeout
+	SlowQuery        time.Duration // queries at least this slow are logged; 0 disables
+	Breaker          *Breaker      // nil disables circuit breaking
+}
+
+func NewPool(ctx context.Context, databaseURL string, opts PoolOptions) *pgxpool.Pool {
 	cfg, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
-		log.Fatalf("parse db url: %v", err)
+		logging.Fatal("parse db url", "error", err)
 	}
+	breaker := opts.Breaker
+	cfg.ConnConfig.DialFunc = pgconn.DialFunc(breaker.guardDial(dialFunc(cfg.ConnConfig.DialFunc)))
+	claims := newClaimsHook()
+	claims.install(cfg)
 
 	// Set session defaults for every new connection in the pool.
 	cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
-		// Mark app name (optional) and set Supabase RLS claims.
-		// We need at least role=admin to pass your RLS insert policies.
-		_, err := conn.Exec(ctx, `
-			SET application_name = 'lms-backend';
-			SET "request.jwt.claims" = '{"role":"admin"}';
-		`)
-		return err
+		// Mark app name (optional). The RLS claims are set per caller by
+		// the claims hook and Begin, see tenant.go.
+		if _, err := conn.Exec(ctx, "SET application_name = 'lms-backend'"); err != nil {
+			return err
+		}
+		breaker.success()
+		return nil
 	}
 
-	// Reasonable pool sizes for dev
 	cfg.MaxConns = 10
-	cfg.MinConns = 1
+	if opts.MaxConns > 0 {
+		cfg.MaxConns = opts.MaxConns
+	}
+	cfg.MinConns = opts.MinConns
 	cfg.MaxConnIdleTime = 5 * time.Minute
+	if opts.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	cfg.ConnConfig.Tracer = &queryTracer{slow: opts.SlowQuery}
+	// sent as a startup parameter, so it applies to every statement on the connection
+	if opts.StatementTimeout > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
-		log.Fatalf("create pool: %v", err)
+		logging.Fatal("create pool", "error", err)
 	}
 	// simple ping
 	if err := pool.Ping(ctx); err != nil {
-		log.Fatalf("db ping failed: %v", err)
+		logging.Fatal("db ping failed", "error", err)
 	}
 	return pool
 }
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	retryAttempts = 3
+	retryBackoff  = 50 * time.Millisecond // doubled after every attempt
+)
+
+// Querier is the statement interface shared by pgxpool.Pool, pgx.Tx and the
+// sqlc-generated queries.DBTX
+type Querier interface {
+	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...interface{}) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...interface{}) pgx.Row
+}
+
+// WithRetry runs each statement again (up to 3 attempts, with backoff) when it
+// failed without reaching the server, e.g. on a reset pooled connection, or
+// was rolled back by a serialization failure or deadlock. Statements inside a
+// transaction cannot be retried one by one: pass the pool, not a pgx.Tx.
+func WithRetry(q Querier) Querier {
+	return retrying{q}
+}
+
+type retrying struct {
+	q Querier
+}
+
+func (r retrying) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := retry(ctx, func() error {
+		var err error
+		tag, err = r.q.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+func (r retrying) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := retry(ctx, func() error {
+		var err error
+		rows, err = r.q.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r retrying) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return retryingRow{ctx: ctx, q: r.q, sql: sql, args: args}
+}
+
+// retryingRow defers the query to Scan, which is where pgx reports its error
+type retryingRow struct {
+	ctx  context.Context
+	q    Querier
+	sql  string
+	args []interface{}
+}
+
+func (r retryingRow) Scan(dest ...interface{}) error {
+	return retry(r.ctx, func() error {
+		return r.q.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+func retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < retryAttempts; attempt++ {
+		if attempt > 0 {
+			t := time.NewTimer(retryBackoff << (attempt - 1))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+		}
+		if err = fn(); err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func retryable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
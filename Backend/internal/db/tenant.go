@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"leave-management/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tenant isolation and the coarse access rules are enforced by Postgres: the
+// row level security policies in Database/db.sql read the caller from the
+// request.jwt.claims setting (org_id, and the user's sub and role). The claims
+// come from the context a database call is made with: the pool sets them on
+// each connection it hands out, and Begin sets them again with SET LOCAL for
+// the transaction, so queries made on a request context act as the
+// authenticated user without every query having to filter on org_id.
+
+// DefaultOrgID is the organization Database/db.sql creates
+const DefaultOrgID = "00000000-0000-0000-0000-000000000001"
+
+// noClaims are used when a context has no caller; they match no organization
+// or user, so an unscoped query sees no tenant rows
+const noClaims = `{}`
+
+// roleService marks work done on no single tenant's behalf
+const roleService = "service"
+
+// Claims identify the caller of a database call. They are marshalled into
+// request.jwt.claims; the field names are the ones the policies read.
+type Claims struct {
+	UserID     string `json:"sub,omitempty"`
+	Role       string `json:"role,omitempty"`
+	OrgID      string `json:"org_id,omitempty"`
+	EmployeeID string `json:"employee_id,omitempty"`
+	// EmployeeUUID is the employees.id of the user (EmployeeID is the
+	// employee code); audit_trigger_function stores it as changed_by
+	EmployeeUUID string `json:"employee_uuid,omitempty"`
+}
+
+type claimsKey struct{}
+
+// WithClaims makes the database calls made with ctx act as the given user
+func WithClaims(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// WithOrg scopes the database calls made with ctx to one organization, with
+// the rights of its admins. It is for trusted callers that are not a user:
+// the internal gRPC API and the command line tools.
+func WithOrg(ctx context.Context, orgID string) context.Context {
+	return WithClaims(ctx, Claims{Role: models.RoleAdmin, OrgID: orgID})
+}
+
+// AsService lets the database calls made with ctx see every organization. It
+// is for work done on no single tenant's behalf: background jobs, looking a
+// user up before their organization is known, and the command line tools.
+// Inserts made this way must set org_id themselves.
+func AsService(ctx context.Context) context.Context {
+	return WithClaims(ctx, Claims{Role: roleService})
+}
+
+// OrgID returns the organization ctx is scoped to, or "" when it has none
+func OrgID(ctx context.Context) string {
+	c, _ := ctx.Value(claimsKey{}).(Claims)
+	return c.OrgID
+}
+
+// RequestInfo describes the API call the database changes made with a
+// context belong to. It travels with the claims, and audit_trigger_function
+// stores it, with the caller, on every audit_logs row the changes produce.
+type RequestInfo struct {
+	IP        string `json:"ip,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"` // method and route, e.g. "PUT /leave-requests/:id/approve"
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo attaches the API call to the database changes made with ctx
+func WithRequestInfo(ctx context.Context, r RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, r)
+}
+
+func claimsFor(ctx context.Context) string {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+	r, hasRequest := ctx.Value(requestInfoKey{}).(RequestInfo)
+	if !ok && !hasRequest {
+		return noClaims
+	}
+	v := struct {
+		Claims
+		Request *RequestInfo `json:"request,omitempty"`
+	}{Claims: c}
+	if hasRequest {
+		v.Request = &r
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout limits each statement run with ctx to d on the server
+// (statement_timeout), so one slow query fails fast with 57014 instead of using
+// up the whole deadline of ctx. A non-positive d keeps the pool's limit
+// (DB_STATEMENT_TIMEOUT).
+func WithQueryTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, d)
+}
+
+// queryTimeoutFor returns the statement_timeout for ctx in milliseconds, or
+// nil to keep the connection's default
+func queryTimeoutFor(ctx context.Context) *string {
+	d, _ := ctx.Value(queryTimeoutKey{}).(time.Duration)
+	if d <= 0 {
+		return nil
+	}
+	ms := strconv.FormatInt(max(d.Milliseconds(), 1), 10)
+	return &ms
+}
+
+// setClaims is the statement both the pool hook and Begin run; local limits
+// the settings to the current transaction (SET LOCAL). A NULL timeout puts
+// statement_timeout back to the value the connection started with.
+const setClaims = "-- name: SetRequestClaims :exec\nSELECT set_config('request.jwt.claims', $1, $2), " +
+	"set_config('statement_timeout', COALESCE($3, (SELECT reset_val FROM pg_settings WHERE name = 'statement_timeout')), $2)"
+
+// Begin starts a transaction acting as the caller in ctx. The claims are set
+// with SET LOCAL, so they end with the transaction whatever the connection
+// carried before, which also holds behind a transaction-pooling proxy.
+func Begin(ctx context.Context, pool *pgxpool.Pool) (pgx.Tx, error) {
+	return BeginTx(ctx, pool, pgx.TxOptions{})
+}
+
+// BeginTx is Begin with transaction options, e.g. a read-only snapshot
+func BeginTx(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, setClaims, claimsFor(ctx), true, queryTimeoutFor(ctx)); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// claimsHook keeps each connection's request.jwt.claims and statement_timeout
+// in line with the context it is acquired with, for the statements run outside
+// a transaction. It remembers what every connection carries, so the extra
+// round trip is only paid when the caller or the limit changes. New
+// connections carry no claims and the pool's limit.
+type claimsHook struct {
+	mu      sync.Mutex
+	current map[*pgx.Conn]string
+}
+
+func newClaimsHook() *claimsHook {
+	return &claimsHook{current: map[*pgx.Conn]string{}}
+}
+
+func (h *claimsHook) install(cfg *pgxpool.Config) {
+	cfg.BeforeAcquire = h.beforeAcquire
+	cfg.BeforeClose = func(conn *pgx.Conn) {
+		h.mu.Lock()
+		delete(h.current, conn)
+		h.mu.Unlock()
+	}
+}
+
+func (h *claimsHook) beforeAcquire(ctx context.Context, conn *pgx.Conn) bool {
+	claims, timeout := claimsFor(ctx), queryTimeoutFor(ctx)
+	want := claims
+	if timeout != nil {
+		want += " statement_timeout=" + *timeout
+	}
+	h.mu.Lock()
+	have, ok := h.current[conn]
+	h.mu.Unlock()
+	if (ok && have == want) || (!ok && want == noClaims) {
+		return true
+	}
+	if _, err := conn.Exec(ctx, setClaims, claims, false, timeout); err != nil {
+		// the pool destroys the connection and acquires another
+		return false
+	}
+	h.mu.Lock()
+	h.current[conn] = want
+	h.mu.Unlock()
+	return true
+}
+
+// BypassesRLS reports whether the pool's database role skips row level
+// security (a superuser or a BYPASSRLS role), which disables tenant isolation
+func BypassesRLS(ctx context.Context, pool *pgxpool.Pool) (role string, bypass bool, err error) {
+	err = pool.QueryRow(ctx,
+		"SELECT current_user, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&role, &bypass)
+	return role, bypass, err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// queryDuration is exported on /metrics. Queries generated by sqlc are labelled
+// with their name; inline SQL shares the "unnamed" label and is identified by
+// the slow query log instead.
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "lms_db_query_duration_seconds",
+	Help:    "Duration of database queries by sqlc query name.",
+	Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}, []string{"query", "status"})
+
+type traceKey struct{}
+
+type traceStart struct {
+	sql   string
+	nargs int
+	at    time.Time
+}
+
+// queryTracer times every statement, records it in queryDuration and logs the
+// ones slower than slow. Only the SQL text and the number of arguments are
+// logged, never the argument values.
+type queryTracer struct {
+	slow time.Duration // 0 disables the log
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceStart{sql: data.SQL, nargs: len(data.Args), at: time.Now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(traceKey{}).(traceStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	name := queryName(start.sql)
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+	}
+	queryDuration.WithLabelValues(name, status).Observe(elapsed.Seconds())
+
+	if t.slow > 0 && elapsed >= t.slow {
+		attrs := []interface{}{
+			"query", name,
+			"duration_ms", elapsed.Milliseconds(),
+			"sql", compactSQL(start.sql),
+			"args", start.nargs,
+			"rows", data.CommandTag.RowsAffected(),
+		}
+		if data.Err != nil {
+			attrs = append(attrs, "error", data.Err)
+		}
+		slog.WarnContext(ctx, "slow query", attrs...)
+	}
+}
+
+// queryName extracts the sqlc name from a "-- name: GetEmployee :one" header
+func queryName(sql string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(sql), "-- name: ")
+	if !ok {
+		return "unnamed"
+	}
+	if i := strings.IndexAny(rest, " \n"); i > 0 {
+		return rest[:i]
+	}
+	return "unnamed"
+}
+
+// compactSQL collapses whitespace so a statement fits on one log line
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WithTx runs fn in a transaction (see Begin) that is committed when fn returns nil and
+// rolled back otherwise. When the transaction fails on a serialization failure
+// or deadlock, or on a connection error before anything reached the server, it
+// is run again from the start (up to 3 attempts, with backoff). fn may
+// therefore run more than once: it must only touch the database through tx and
+// reset any variables it assigns.
+func WithTx(ctx context.Context, pool *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	return retry(ctx, func() error {
+		tx, err := Begin(ctx, pool)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+}
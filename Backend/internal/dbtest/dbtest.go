@@ -0,0 +1,37 @@
+// Package dbtest gives tests a real database: the one LMS_TEST_DATABASE_URL
+// names, created from Database/db.sql with migrate and connected as a role
+// without BYPASSRLS, so row level security applies as in production. Tests
+// that need it are skipped when the variable is not set.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pool returns a pool on the test database, closed when t ends
+func Pool(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("LMS_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("LMS_TEST_DATABASE_URL is not set")
+	}
+	pool := db.NewPool(context.Background(), url, db.PoolOptions{MaxConns: 4})
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// Suffix returns a random string to keep the names and emails a test creates
+// apart from those of other runs
+func Suffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
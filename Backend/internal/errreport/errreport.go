@@ -0,0 +1,48 @@
+// Package errreport forwards unexpected failures, such as recovered panics, to
+// Sentry. Without a DSN it does nothing and the structured log is the only
+// record of the failure.
+package errreport
+
+import (
+	"context"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+var enabled bool
+
+// Init configures the Sentry client. An empty dsn disables reporting.
+func Init(dsn, environment string) error {
+	if dsn == "" {
+		return nil
+	}
+	if err := sentry.Init(sentry.ClientOptions{
+		Dsn:              dsn,
+		Environment:      environment,
+		AttachStacktrace: true,
+	}); err != nil {
+		return err
+	}
+	enabled = true
+	return nil
+}
+
+// Panic reports a recovered panic value with tags such as the request ID
+func Panic(ctx context.Context, recovered interface{}, tags map[string]string) {
+	if !enabled {
+		return
+	}
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+	})
+	hub.RecoverWithContext(ctx, recovered)
+}
+
+// Flush waits up to timeout for queued reports to be sent; call it on shutdown
+func Flush(timeout time.Duration) {
+	if enabled {
+		sentry.Flush(timeout)
+	}
+}
@@ -0,0 +1,53 @@
+package gql
+
+// Employee is an employee's full record, for callers allowed to see it
+type Employee struct {
+	ID           string
+	EmployeeID   string
+	Name         string
+	Email        string
+	DepartmentID string
+	Role         string
+	IsActive     bool
+	JoiningDate  string
+	Phone        *string
+	Address      *string
+	managerID    *string
+}
+
+// EmployeeProfile is what anyone may know of an employee
+type EmployeeProfile struct {
+	ID           string
+	Name         string
+	DepartmentID string
+	Role         string
+}
+
+// LeaveType leaves maxDaysPerYear to its resolver, which looks it up only
+// when it is asked for
+type LeaveType struct {
+	ID          string
+	Name        string
+	Description *string
+}
+
+type LeaveBalance struct {
+	LeaveType          LeaveType
+	Year               int
+	AllocatedDays      float64
+	UsedDays           float64
+	CarriedForwardDays int
+	AvailableDays      float64
+}
+
+type LeaveRequest struct {
+	ID              string
+	LeaveType       LeaveType
+	StartDate       string
+	EndDate         string
+	TotalDays       float64
+	Reason          string
+	Status          string
+	AppliedAt       string
+	RejectionReason *string
+}
@@ -0,0 +1,113 @@
+// Package gql exposes employees, leave balances and leave requests through a
+// GraphQL schema so a client can fetch related data in a single query. The
+// schema is schema.graphqls; gqlgen generates the rest of the package from
+// it (see gqlgen.yml).
+package gql
+
+//go:generate go run github.com/99designs/gqlgen generate
+
+import (
+	"context"
+	"errors"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/models"
+	"leave-management/internal/repository"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// Viewer is the authenticated caller a query runs as
+type Viewer struct {
+	Role       string
+	EmployeeID string // employees.id (UUID)
+}
+
+type viewerKey struct{}
+
+// WithViewer attaches the caller to the context a query is executed with
+func WithViewer(ctx context.Context, v Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, v)
+}
+
+func viewerFrom(ctx context.Context) Viewer {
+	v, _ := ctx.Value(viewerKey{}).(Viewer)
+	return v
+}
+
+var errForbidden = errors.New("access denied to this resource")
+
+// Resolver resolves the schema's fields through the repositories
+type Resolver struct {
+	repos repository.Repos
+}
+
+// New returns the executable schema, reading through repos
+func New(repos repository.Repos) graphql.ExecutableSchema {
+	cfg := Config{Resolvers: &Resolver{repos: repos}}
+	// a list costs its size times what each of its items costs
+	cfg.Complexity.Employee.LeaveRequests = func(childComplexity int, _ *string, limit *int) int {
+		if limit == nil {
+			return childComplexity
+		}
+		return *limit * childComplexity
+	}
+	return NewExecutableSchema(cfg)
+}
+
+// canView applies the same rules as the REST endpoints (see
+// middleware.RequireOwnership): HR/Admin see everyone, managers see their
+// direct reports, everyone sees themselves.
+func canView(v Viewer, e *Employee) bool {
+	switch {
+	case v.Role == models.RoleAdmin || v.Role == models.RoleHR:
+		return true
+	case v.EmployeeID != "" && v.EmployeeID == e.ID:
+		return true
+	case v.Role == models.RoleManager && e.managerID != nil && *e.managerID == v.EmployeeID:
+		return true
+	}
+	return false
+}
+
+// viewableEmployee loads employee id for the caller: nil, nil when there is
+// none, errForbidden when the caller may not see it. Every Employee the
+// schema returns comes from here.
+func (r *Resolver) viewableEmployee(ctx context.Context, id string) (*Employee, error) {
+	e, err := r.loadEmployee(ctx, id)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	if !canView(viewerFrom(ctx), e) {
+		return nil, errForbidden
+	}
+	return e, nil
+}
+
+// loadEmployee returns nil, nil when the employee does not exist
+func (r *Resolver) loadEmployee(ctx context.Context, id string) (*Employee, error) {
+	row, err := r.repos.Employees.GetEmployee(ctx, id)
+	if apierror.IsNoRows(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch employee")
+	}
+	e := &Employee{
+		ID:           id,
+		EmployeeID:   row.EmployeeID,
+		Name:         row.Name,
+		Email:        row.Email,
+		DepartmentID: row.DepartmentID,
+		IsActive:     row.IsActive == nil || *row.IsActive,
+		JoiningDate:  row.JoiningDate.Format("2006-01-02"),
+		Phone:        row.Phone,
+		Address:      row.Address,
+		managerID:    row.ManagerID,
+		Role:         models.RoleEmployee,
+	}
+	if row.Role != nil {
+		e.Role = *row.Role
+	}
+	return e, nil
+}
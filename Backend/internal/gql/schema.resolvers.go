@@ -0,0 +1,138 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.78
+
+import (
+	"context"
+	"errors"
+	"leave-management/internal/apierror"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/models"
+	"time"
+)
+
+// Manager is the resolver for the manager field.
+func (r *employeeResolver) Manager(ctx context.Context, obj *Employee) (*EmployeeProfile, error) {
+	if obj.managerID == nil {
+		return nil, nil
+	}
+	row, err := r.repos.Employees.GetEmployee(ctx, *obj.managerID)
+	if apierror.IsNoRows(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch employee")
+	}
+	p := &EmployeeProfile{ID: *obj.managerID, Name: row.Name, DepartmentID: row.DepartmentID, Role: models.RoleEmployee}
+	if row.Role != nil {
+		p.Role = *row.Role
+	}
+	return p, nil
+}
+
+// LeaveBalances is the resolver for the leaveBalances field.
+func (r *employeeResolver) LeaveBalances(ctx context.Context, obj *Employee, year *int) ([]*LeaveBalance, error) {
+	if !canView(viewerFrom(ctx), obj) {
+		return nil, errForbidden
+	}
+	y := time.Now().Year()
+	if year != nil {
+		y = *year
+	}
+	rows, err := r.repos.LeaveBalances.ListLeaveBalances(ctx, queries.ListLeaveBalancesParams{EmployeeID: obj.ID, Year: int32(y)})
+	if err != nil {
+		return nil, errors.New("failed to fetch leave balances")
+	}
+	balances := make([]*LeaveBalance, 0, len(rows))
+	for _, row := range rows {
+		b := &LeaveBalance{
+			LeaveType:          LeaveType{ID: row.LeaveTypeID, Name: row.LeaveTypeName, Description: row.LeaveTypeDescription},
+			Year:               int(row.Year),
+			AllocatedDays:      row.AllocatedDays,
+			UsedDays:           row.UsedDays,
+			CarriedForwardDays: int(row.CarriedForwardDays),
+		}
+		if row.AvailableDays != nil {
+			b.AvailableDays = *row.AvailableDays
+		}
+		balances = append(balances, b)
+	}
+	return balances, nil
+}
+
+// LeaveRequests is the resolver for the leaveRequests field.
+func (r *employeeResolver) LeaveRequests(ctx context.Context, obj *Employee, status *string, limit *int) ([]*LeaveRequest, error) {
+	if !canView(viewerFrom(ctx), obj) {
+		return nil, errForbidden
+	}
+	if limit == nil || *limit < 1 || *limit > 200 {
+		return nil, errors.New("limit must be between 1 and 200")
+	}
+	if status != nil && *status == "" {
+		status = nil
+	}
+	rows, err := r.repos.LeaveRequests.ListEmployeeLeaveRequests(ctx, queries.ListEmployeeLeaveRequestsParams{
+		EmployeeID: obj.ID,
+		Status:     status,
+		RowLimit:   int32(*limit),
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch leave requests")
+	}
+	requests := make([]*LeaveRequest, 0, len(rows))
+	for _, row := range rows {
+		lr := &LeaveRequest{
+			ID:              row.ID,
+			LeaveType:       LeaveType{ID: row.LeaveTypeID, Name: row.LeaveTypeName, Description: row.LeaveTypeDescription},
+			StartDate:       row.StartDate.Format("2006-01-02"),
+			EndDate:         row.EndDate.Format("2006-01-02"),
+			TotalDays:       row.TotalDays,
+			Reason:          row.Reason,
+			Status:          row.Status,
+			RejectionReason: row.RejectionReason,
+		}
+		if row.AppliedAt != nil {
+			lr.AppliedAt = row.AppliedAt.Format(time.RFC3339)
+		}
+		requests = append(requests, lr)
+	}
+	return requests, nil
+}
+
+// MaxDaysPerYear is the resolver for the maxDaysPerYear field.
+func (r *leaveTypeResolver) MaxDaysPerYear(ctx context.Context, obj *LeaveType) (int, error) {
+	lt, err := r.repos.LeaveTypes.GetLeaveType(ctx, obj.ID)
+	if err != nil {
+		return 0, errors.New("failed to fetch leave type")
+	}
+	return int(lt.MaxDaysPerYear), nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*Employee, error) {
+	v := viewerFrom(ctx)
+	if v.EmployeeID == "" {
+		return nil, nil
+	}
+	return r.viewableEmployee(ctx, v.EmployeeID)
+}
+
+// Employee is the resolver for the employee field.
+func (r *queryResolver) Employee(ctx context.Context, id string) (*Employee, error) {
+	return r.viewableEmployee(ctx, id)
+}
+
+// Employee returns EmployeeResolver implementation.
+func (r *Resolver) Employee() EmployeeResolver { return &employeeResolver{r} }
+
+// LeaveType returns LeaveTypeResolver implementation.
+func (r *Resolver) LeaveType() LeaveTypeResolver { return &leaveTypeResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type employeeResolver struct{ *Resolver }
+type leaveTypeResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"leave-management/internal/grpcapi/lmsv1"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type balanceService struct {
+	lmsv1.UnimplementedBalanceServiceServer
+	pool *pgxpool.Pool
+}
+
+const balanceSelect = `SELECT elb.employee_id, elb.leave_type_id, lt.name, elb.year,
+		elb.allocated_days, elb.used_days, elb.carried_forward_days, elb.available_days
+	FROM employee_leave_balances elb
+	JOIN leave_types lt ON elb.leave_type_id = lt.id`
+
+func (s *balanceService) GetBalances(ctx context.Context, in *lmsv1.GetBalancesRequest) (*lmsv1.GetBalancesResponse, error) {
+	if in.GetEmployeeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "employee_id is required")
+	}
+	rows, err := s.pool.Query(ctx, balanceSelect+" WHERE elb.employee_id=$1 AND elb.year=$2 ORDER BY lt.name",
+		in.GetEmployeeId(), yearOrCurrent(in.GetYear()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch leave balances")
+	}
+	defer rows.Close()
+
+	res := &lmsv1.GetBalancesResponse{}
+	for rows.Next() {
+		b, err := scanBalance(rows)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "row scan failed")
+		}
+		res.Balances = append(res.Balances, b)
+	}
+	return res, nil
+}
+
+func (s *balanceService) ExportBalances(in *lmsv1.ExportBalancesRequest, stream grpc.ServerStreamingServer[lmsv1.Balance]) error {
+	rows, err := s.pool.Query(stream.Context(), balanceSelect+`
+		JOIN employees e ON elb.employee_id = e.id
+		WHERE elb.year=$1 AND e.is_active = TRUE
+		ORDER BY elb.employee_id, lt.name`, yearOrCurrent(in.GetYear()))
+	if err != nil {
+		return status.Error(codes.Internal, "failed to export leave balances")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b, err := scanBalance(rows)
+		if err != nil {
+			return status.Error(codes.Internal, "row scan failed")
+		}
+		if err := stream.Send(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func yearOrCurrent(year int32) int32 {
+	if year == 0 {
+		return int32(time.Now().Year())
+	}
+	return year
+}
+
+func scanBalance(row pgx.Row) (*lmsv1.Balance, error) {
+	var (
+		b             lmsv1.Balance
+		year, carried int
+	)
+	if err := row.Scan(&b.EmployeeId, &b.LeaveTypeId, &b.LeaveTypeName, &year, &b.AllocatedDaysExact, &b.UsedDaysExact, &carried, &b.AvailableDaysExact); err != nil {
+		return nil, err
+	}
+	b.Year = int32(year)
+	b.AllocatedDays = int32(b.AllocatedDaysExact)
+	b.UsedDays = int32(b.UsedDaysExact)
+	b.CarriedForwardDays = int32(carried)
+	b.AvailableDays = int32(b.AvailableDaysExact)
+	return &b, nil
+}
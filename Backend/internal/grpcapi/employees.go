@@ -0,0 +1,121 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"leave-management/internal/grpcapi/lmsv1"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type employeeService struct {
+	lmsv1.UnimplementedEmployeeServiceServer
+	pool *pgxpool.Pool
+}
+
+const employeeColumns = `id, employee_id, name, email, department_id, role, is_active, joining_date, manager_id`
+
+func (s *employeeService) GetEmployee(ctx context.Context, in *lmsv1.GetEmployeeRequest) (*lmsv1.Employee, error) {
+	if in.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	e, err := scanEmployee(s.pool.QueryRow(ctx, "SELECT "+employeeColumns+" FROM employees WHERE id=$1", in.GetId()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "employee not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch employee")
+	}
+	return e, nil
+}
+
+func (s *employeeService) ListEmployees(ctx context.Context, in *lmsv1.ListEmployeesRequest) (*lmsv1.ListEmployeesResponse, error) {
+	limit, offset, err := page(in.GetLimit(), in.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	where, args := employeeFilters(in.GetDepartmentId(), in.GetRole(), in.GetIncludeInactive())
+
+	var total int32
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM employees"+where, args...).Scan(&total); err != nil {
+		return nil, status.Error(codes.Internal, "failed to count employees")
+	}
+
+	args = append(args, limit, offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT %s FROM employees%s ORDER BY name, id LIMIT $%d OFFSET $%d",
+		employeeColumns, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list employees")
+	}
+	defer rows.Close()
+
+	res := &lmsv1.ListEmployeesResponse{Total: total}
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "row scan failed")
+		}
+		res.Employees = append(res.Employees, e)
+	}
+	return res, nil
+}
+
+func (s *employeeService) StreamEmployees(in *lmsv1.StreamEmployeesRequest, stream grpc.ServerStreamingServer[lmsv1.Employee]) error {
+	where, args := employeeFilters(in.GetDepartmentId(), "", in.GetIncludeInactive())
+	rows, err := s.pool.Query(stream.Context(), "SELECT "+employeeColumns+" FROM employees"+where+" ORDER BY name, id", args...)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to list employees")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEmployee(rows)
+		if err != nil {
+			return status.Error(codes.Internal, "row scan failed")
+		}
+		if err := stream.Send(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func employeeFilters(departmentID, role string, includeInactive bool) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if departmentID != "" {
+		args = append(args, departmentID)
+		where += fmt.Sprintf(" AND department_id=$%d", len(args))
+	}
+	if role != "" {
+		args = append(args, role)
+		where += fmt.Sprintf(" AND role=$%d", len(args))
+	}
+	if !includeInactive {
+		where += " AND is_active = TRUE"
+	}
+	return where, args
+}
+
+func scanEmployee(row pgx.Row) (*lmsv1.Employee, error) {
+	var (
+		e         lmsv1.Employee
+		joining   time.Time
+		managerID *string
+	)
+	if err := row.Scan(&e.Id, &e.EmployeeId, &e.Name, &e.Email, &e.DepartmentId, &e.Role, &e.IsActive, &joining, &managerID); err != nil {
+		return nil, err
+	}
+	e.JoiningDate = joining.Format("2006-01-02")
+	if managerID != nil {
+		e.ManagerId = *managerID
+	}
+	return &e, nil
+}
@@ -0,0 +1,156 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"leave-management/internal/grpcapi/lmsv1"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type leaveRequestService struct {
+	lmsv1.UnimplementedLeaveRequestServiceServer
+	pool *pgxpool.Pool
+}
+
+const leaveRequestSelect = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lt.name, lr.start_date, lr.end_date,
+		lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, lr.rejection_reason,
+		lr.duration_unit, lr.hours
+	FROM leave_requests lr
+	JOIN leave_types lt ON lr.leave_type_id = lt.id`
+
+func (s *leaveRequestService) GetLeaveRequest(ctx context.Context, in *lmsv1.GetLeaveRequestRequest) (*lmsv1.LeaveRequest, error) {
+	if in.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	lr, err := scanLeaveRequest(s.pool.QueryRow(ctx, leaveRequestSelect+" WHERE lr.id=$1", in.GetId()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "leave request not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch leave request")
+	}
+	return lr, nil
+}
+
+func (s *leaveRequestService) ListLeaveRequests(ctx context.Context, in *lmsv1.ListLeaveRequestsRequest) (*lmsv1.ListLeaveRequestsResponse, error) {
+	limit, offset, err := page(in.GetLimit(), in.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if in.GetEmployeeId() != "" {
+		args = append(args, in.GetEmployeeId())
+		where += fmt.Sprintf(" AND lr.employee_id=$%d", len(args))
+	}
+	if in.GetStatus() != "" {
+		args = append(args, in.GetStatus())
+		where += fmt.Sprintf(" AND lr.status=$%d", len(args))
+	}
+
+	var total int32
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM leave_requests lr"+where, args...).Scan(&total); err != nil {
+		return nil, status.Error(codes.Internal, "failed to count leave requests")
+	}
+
+	args = append(args, limit, offset)
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("%s%s ORDER BY lr.created_at DESC, lr.id DESC LIMIT $%d OFFSET $%d",
+		leaveRequestSelect, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list leave requests")
+	}
+	defer rows.Close()
+
+	res := &lmsv1.ListLeaveRequestsResponse{Total: total}
+	for rows.Next() {
+		lr, err := scanLeaveRequest(rows)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "row scan failed")
+		}
+		res.LeaveRequests = append(res.LeaveRequests, lr)
+	}
+	return res, nil
+}
+
+func (s *leaveRequestService) ExportLeaveRequests(in *lmsv1.ExportLeaveRequestsRequest, stream grpc.ServerStreamingServer[lmsv1.LeaveRequest]) error {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	for _, f := range []struct{ value, cond string }{
+		{in.GetFrom(), "lr.start_date >= $%d"},
+		{in.GetTo(), "lr.start_date <= $%d"},
+	} {
+		if f.value == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", f.value)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "from/to must be YYYY-MM-DD")
+		}
+		args = append(args, d)
+		where += " AND " + fmt.Sprintf(f.cond, len(args))
+	}
+	if in.GetStatus() != "" {
+		args = append(args, in.GetStatus())
+		where += fmt.Sprintf(" AND lr.status=$%d", len(args))
+	}
+
+	rows, err := s.pool.Query(stream.Context(), leaveRequestSelect+where+" ORDER BY lr.start_date, lr.id", args...)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to export leave requests")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		lr, err := scanLeaveRequest(rows)
+		if err != nil {
+			return status.Error(codes.Internal, "row scan failed")
+		}
+		if err := stream.Send(lr); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanLeaveRequest(row pgx.Row) (*lmsv1.LeaveRequest, error) {
+	var (
+		lr              lmsv1.LeaveRequest
+		start, end      time.Time
+		appliedAt       time.Time
+		approvedBy      *string
+		approvedAt      *time.Time
+		rejectionReason *string
+		hours           *float64
+	)
+	if err := row.Scan(&lr.Id, &lr.EmployeeId, &lr.LeaveTypeId, &lr.LeaveTypeName, &start, &end,
+		&lr.TotalDaysExact, &lr.Reason, &lr.Status, &appliedAt, &approvedBy, &approvedAt, &rejectionReason,
+		&lr.DurationUnit, &hours); err != nil {
+		return nil, err
+	}
+	lr.StartDate = start.Format("2006-01-02")
+	lr.EndDate = end.Format("2006-01-02")
+	lr.TotalDays = int32(lr.TotalDaysExact)
+	if hours != nil {
+		lr.Hours = *hours
+	}
+	lr.AppliedAt = timestamppb.New(appliedAt)
+	if approvedBy != nil {
+		lr.ApprovedBy = *approvedBy
+	}
+	if approvedAt != nil {
+		lr.ApprovedAt = timestamppb.New(*approvedAt)
+	}
+	if rejectionReason != nil {
+		lr.RejectionReason = *rejectionReason
+	}
+	return &lr, nil
+}
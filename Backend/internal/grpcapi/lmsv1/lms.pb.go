@@ -0,0 +1,1194 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: lms/v1/lms.proto
+
+package lmsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Employee struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                   // employees.id (UUID)
+	EmployeeId    string                 `protobuf:"bytes,2,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"` // employee code, e.g. EMP001
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Email         string                 `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	DepartmentId  string                 `protobuf:"bytes,5,opt,name=department_id,json=departmentId,proto3" json:"department_id,omitempty"`
+	Role          string                 `protobuf:"bytes,6,opt,name=role,proto3" json:"role,omitempty"`
+	IsActive      bool                   `protobuf:"varint,7,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	JoiningDate   string                 `protobuf:"bytes,8,opt,name=joining_date,json=joiningDate,proto3" json:"joining_date,omitempty"`
+	ManagerId     string                 `protobuf:"bytes,9,opt,name=manager_id,json=managerId,proto3" json:"manager_id,omitempty"` // empty when the employee has no manager
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Employee) Reset() {
+	*x = Employee{}
+	mi := &file_lms_v1_lms_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Employee) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Employee) ProtoMessage() {}
+
+func (x *Employee) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Employee.ProtoReflect.Descriptor instead.
+func (*Employee) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Employee) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Employee) GetEmployeeId() string {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return ""
+}
+
+func (x *Employee) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Employee) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *Employee) GetDepartmentId() string {
+	if x != nil {
+		return x.DepartmentId
+	}
+	return ""
+}
+
+func (x *Employee) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Employee) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+func (x *Employee) GetJoiningDate() string {
+	if x != nil {
+		return x.JoiningDate
+	}
+	return ""
+}
+
+func (x *Employee) GetManagerId() string {
+	if x != nil {
+		return x.ManagerId
+	}
+	return ""
+}
+
+type GetEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEmployeeRequest) Reset() {
+	*x = GetEmployeeRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEmployeeRequest) ProtoMessage() {}
+
+func (x *GetEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*GetEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetEmployeeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListEmployeesRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DepartmentId    string                 `protobuf:"bytes,1,opt,name=department_id,json=departmentId,proto3" json:"department_id,omitempty"`
+	Role            string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	IncludeInactive bool                   `protobuf:"varint,3,opt,name=include_inactive,json=includeInactive,proto3" json:"include_inactive,omitempty"`
+	Limit           int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"` // default 50, max 200
+	Offset          int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ListEmployeesRequest) Reset() {
+	*x = ListEmployeesRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEmployeesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEmployeesRequest) ProtoMessage() {}
+
+func (x *ListEmployeesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEmployeesRequest.ProtoReflect.Descriptor instead.
+func (*ListEmployeesRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListEmployeesRequest) GetDepartmentId() string {
+	if x != nil {
+		return x.DepartmentId
+	}
+	return ""
+}
+
+func (x *ListEmployeesRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ListEmployeesRequest) GetIncludeInactive() bool {
+	if x != nil {
+		return x.IncludeInactive
+	}
+	return false
+}
+
+func (x *ListEmployeesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListEmployeesRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListEmployeesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Employees     []*Employee            `protobuf:"bytes,1,rep,name=employees,proto3" json:"employees,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEmployeesResponse) Reset() {
+	*x = ListEmployeesResponse{}
+	mi := &file_lms_v1_lms_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEmployeesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEmployeesResponse) ProtoMessage() {}
+
+func (x *ListEmployeesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEmployeesResponse.ProtoReflect.Descriptor instead.
+func (*ListEmployeesResponse) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListEmployeesResponse) GetEmployees() []*Employee {
+	if x != nil {
+		return x.Employees
+	}
+	return nil
+}
+
+func (x *ListEmployeesResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type StreamEmployeesRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DepartmentId    string                 `protobuf:"bytes,1,opt,name=department_id,json=departmentId,proto3" json:"department_id,omitempty"`
+	IncludeInactive bool                   `protobuf:"varint,2,opt,name=include_inactive,json=includeInactive,proto3" json:"include_inactive,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StreamEmployeesRequest) Reset() {
+	*x = StreamEmployeesRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEmployeesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEmployeesRequest) ProtoMessage() {}
+
+func (x *StreamEmployeesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEmployeesRequest.ProtoReflect.Descriptor instead.
+func (*StreamEmployeesRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamEmployeesRequest) GetDepartmentId() string {
+	if x != nil {
+		return x.DepartmentId
+	}
+	return ""
+}
+
+func (x *StreamEmployeesRequest) GetIncludeInactive() bool {
+	if x != nil {
+		return x.IncludeInactive
+	}
+	return false
+}
+
+type LeaveRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	EmployeeId      string                 `protobuf:"bytes,2,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"` // employees.id (UUID)
+	LeaveTypeId     string                 `protobuf:"bytes,3,opt,name=leave_type_id,json=leaveTypeId,proto3" json:"leave_type_id,omitempty"`
+	LeaveTypeName   string                 `protobuf:"bytes,4,opt,name=leave_type_name,json=leaveTypeName,proto3" json:"leave_type_name,omitempty"`
+	StartDate       string                 `protobuf:"bytes,5,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate         string                 `protobuf:"bytes,6,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	TotalDays       int32                  `protobuf:"varint,7,opt,name=total_days,json=totalDays,proto3" json:"total_days,omitempty"` // whole days, rounded down; see total_days_exact
+	Reason          string                 `protobuf:"bytes,8,opt,name=reason,proto3" json:"reason,omitempty"`
+	Status          string                 `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	AppliedAt       *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=applied_at,json=appliedAt,proto3" json:"applied_at,omitempty"`
+	ApprovedBy      string                 `protobuf:"bytes,11,opt,name=approved_by,json=approvedBy,proto3" json:"approved_by,omitempty"`
+	ApprovedAt      *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=approved_at,json=approvedAt,proto3" json:"approved_at,omitempty"`
+	RejectionReason string                 `protobuf:"bytes,13,opt,name=rejection_reason,json=rejectionReason,proto3" json:"rejection_reason,omitempty"`
+	TotalDaysExact  float64                `protobuf:"fixed64,14,opt,name=total_days_exact,json=totalDaysExact,proto3" json:"total_days_exact,omitempty"` // 0.5 for a half day, hours / WORKDAY_HOURS for hours
+	DurationUnit    string                 `protobuf:"bytes,15,opt,name=duration_unit,json=durationUnit,proto3" json:"duration_unit,omitempty"`           // full_day, half_day_am, half_day_pm or hours
+	Hours           float64                `protobuf:"fixed64,16,opt,name=hours,proto3" json:"hours,omitempty"`                                           // with duration_unit hours
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *LeaveRequest) Reset() {
+	*x = LeaveRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveRequest) ProtoMessage() {}
+
+func (x *LeaveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveRequest.ProtoReflect.Descriptor instead.
+func (*LeaveRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LeaveRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetEmployeeId() string {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetLeaveTypeId() string {
+	if x != nil {
+		return x.LeaveTypeId
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetLeaveTypeName() string {
+	if x != nil {
+		return x.LeaveTypeName
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetStartDate() string {
+	if x != nil {
+		return x.StartDate
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetEndDate() string {
+	if x != nil {
+		return x.EndDate
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetTotalDays() int32 {
+	if x != nil {
+		return x.TotalDays
+	}
+	return 0
+}
+
+func (x *LeaveRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetAppliedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AppliedAt
+	}
+	return nil
+}
+
+func (x *LeaveRequest) GetApprovedBy() string {
+	if x != nil {
+		return x.ApprovedBy
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetApprovedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ApprovedAt
+	}
+	return nil
+}
+
+func (x *LeaveRequest) GetRejectionReason() string {
+	if x != nil {
+		return x.RejectionReason
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetTotalDaysExact() float64 {
+	if x != nil {
+		return x.TotalDaysExact
+	}
+	return 0
+}
+
+func (x *LeaveRequest) GetDurationUnit() string {
+	if x != nil {
+		return x.DurationUnit
+	}
+	return ""
+}
+
+func (x *LeaveRequest) GetHours() float64 {
+	if x != nil {
+		return x.Hours
+	}
+	return 0
+}
+
+type GetLeaveRequestRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLeaveRequestRequest) Reset() {
+	*x = GetLeaveRequestRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLeaveRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLeaveRequestRequest) ProtoMessage() {}
+
+func (x *GetLeaveRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLeaveRequestRequest.ProtoReflect.Descriptor instead.
+func (*GetLeaveRequestRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetLeaveRequestRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListLeaveRequestsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmployeeId    string                 `protobuf:"bytes,1,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"` // default 50, max 200
+	Offset        int32                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLeaveRequestsRequest) Reset() {
+	*x = ListLeaveRequestsRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLeaveRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLeaveRequestsRequest) ProtoMessage() {}
+
+func (x *ListLeaveRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLeaveRequestsRequest.ProtoReflect.Descriptor instead.
+func (*ListLeaveRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListLeaveRequestsRequest) GetEmployeeId() string {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return ""
+}
+
+func (x *ListLeaveRequestsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListLeaveRequestsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListLeaveRequestsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListLeaveRequestsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaveRequests []*LeaveRequest        `protobuf:"bytes,1,rep,name=leave_requests,json=leaveRequests,proto3" json:"leave_requests,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLeaveRequestsResponse) Reset() {
+	*x = ListLeaveRequestsResponse{}
+	mi := &file_lms_v1_lms_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLeaveRequestsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLeaveRequestsResponse) ProtoMessage() {}
+
+func (x *ListLeaveRequestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLeaveRequestsResponse.ProtoReflect.Descriptor instead.
+func (*ListLeaveRequestsResponse) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListLeaveRequestsResponse) GetLeaveRequests() []*LeaveRequest {
+	if x != nil {
+		return x.LeaveRequests
+	}
+	return nil
+}
+
+func (x *ListLeaveRequestsResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type ExportLeaveRequestsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"` // start_date on or after, optional
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`     // start_date on or before, optional
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportLeaveRequestsRequest) Reset() {
+	*x = ExportLeaveRequestsRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportLeaveRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportLeaveRequestsRequest) ProtoMessage() {}
+
+func (x *ExportLeaveRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportLeaveRequestsRequest.ProtoReflect.Descriptor instead.
+func (*ExportLeaveRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExportLeaveRequestsRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ExportLeaveRequestsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *ExportLeaveRequestsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type Balance struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	EmployeeId         string                 `protobuf:"bytes,1,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"`
+	LeaveTypeId        string                 `protobuf:"bytes,2,opt,name=leave_type_id,json=leaveTypeId,proto3" json:"leave_type_id,omitempty"`
+	LeaveTypeName      string                 `protobuf:"bytes,3,opt,name=leave_type_name,json=leaveTypeName,proto3" json:"leave_type_name,omitempty"`
+	Year               int32                  `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	AllocatedDays      int32                  `protobuf:"varint,5,opt,name=allocated_days,json=allocatedDays,proto3" json:"allocated_days,omitempty"` // rounded down; see allocated_days_exact
+	UsedDays           int32                  `protobuf:"varint,6,opt,name=used_days,json=usedDays,proto3" json:"used_days,omitempty"`                // rounded down; see used_days_exact
+	CarriedForwardDays int32                  `protobuf:"varint,7,opt,name=carried_forward_days,json=carriedForwardDays,proto3" json:"carried_forward_days,omitempty"`
+	AvailableDays      int32                  `protobuf:"varint,8,opt,name=available_days,json=availableDays,proto3" json:"available_days,omitempty"` // rounded down; see available_days_exact
+	UsedDaysExact      float64                `protobuf:"fixed64,9,opt,name=used_days_exact,json=usedDaysExact,proto3" json:"used_days_exact,omitempty"`
+	AvailableDaysExact float64                `protobuf:"fixed64,10,opt,name=available_days_exact,json=availableDaysExact,proto3" json:"available_days_exact,omitempty"`
+	AllocatedDaysExact float64                `protobuf:"fixed64,11,opt,name=allocated_days_exact,json=allocatedDaysExact,proto3" json:"allocated_days_exact,omitempty"` // fractional for accruing leave types
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Balance) Reset() {
+	*x = Balance{}
+	mi := &file_lms_v1_lms_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Balance) ProtoMessage() {}
+
+func (x *Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Balance.ProtoReflect.Descriptor instead.
+func (*Balance) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Balance) GetEmployeeId() string {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return ""
+}
+
+func (x *Balance) GetLeaveTypeId() string {
+	if x != nil {
+		return x.LeaveTypeId
+	}
+	return ""
+}
+
+func (x *Balance) GetLeaveTypeName() string {
+	if x != nil {
+		return x.LeaveTypeName
+	}
+	return ""
+}
+
+func (x *Balance) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+func (x *Balance) GetAllocatedDays() int32 {
+	if x != nil {
+		return x.AllocatedDays
+	}
+	return 0
+}
+
+func (x *Balance) GetUsedDays() int32 {
+	if x != nil {
+		return x.UsedDays
+	}
+	return 0
+}
+
+func (x *Balance) GetCarriedForwardDays() int32 {
+	if x != nil {
+		return x.CarriedForwardDays
+	}
+	return 0
+}
+
+func (x *Balance) GetAvailableDays() int32 {
+	if x != nil {
+		return x.AvailableDays
+	}
+	return 0
+}
+
+func (x *Balance) GetUsedDaysExact() float64 {
+	if x != nil {
+		return x.UsedDaysExact
+	}
+	return 0
+}
+
+func (x *Balance) GetAvailableDaysExact() float64 {
+	if x != nil {
+		return x.AvailableDaysExact
+	}
+	return 0
+}
+
+func (x *Balance) GetAllocatedDaysExact() float64 {
+	if x != nil {
+		return x.AllocatedDaysExact
+	}
+	return 0
+}
+
+type GetBalancesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EmployeeId    string                 `protobuf:"bytes,1,opt,name=employee_id,json=employeeId,proto3" json:"employee_id,omitempty"`
+	Year          int32                  `protobuf:"varint,2,opt,name=year,proto3" json:"year,omitempty"` // defaults to the current year
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalancesRequest) Reset() {
+	*x = GetBalancesRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalancesRequest) ProtoMessage() {}
+
+func (x *GetBalancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalancesRequest.ProtoReflect.Descriptor instead.
+func (*GetBalancesRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetBalancesRequest) GetEmployeeId() string {
+	if x != nil {
+		return x.EmployeeId
+	}
+	return ""
+}
+
+func (x *GetBalancesRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+type GetBalancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Balances      []*Balance             `protobuf:"bytes,1,rep,name=balances,proto3" json:"balances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalancesResponse) Reset() {
+	*x = GetBalancesResponse{}
+	mi := &file_lms_v1_lms_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalancesResponse) ProtoMessage() {}
+
+func (x *GetBalancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalancesResponse.ProtoReflect.Descriptor instead.
+func (*GetBalancesResponse) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetBalancesResponse) GetBalances() []*Balance {
+	if x != nil {
+		return x.Balances
+	}
+	return nil
+}
+
+type ExportBalancesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Year          int32                  `protobuf:"varint,1,opt,name=year,proto3" json:"year,omitempty"` // defaults to the current year
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportBalancesRequest) Reset() {
+	*x = ExportBalancesRequest{}
+	mi := &file_lms_v1_lms_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportBalancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportBalancesRequest) ProtoMessage() {}
+
+func (x *ExportBalancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lms_v1_lms_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportBalancesRequest.ProtoReflect.Descriptor instead.
+func (*ExportBalancesRequest) Descriptor() ([]byte, []int) {
+	return file_lms_v1_lms_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ExportBalancesRequest) GetYear() int32 {
+	if x != nil {
+		return x.Year
+	}
+	return 0
+}
+
+var File_lms_v1_lms_proto protoreflect.FileDescriptor
+
+const file_lms_v1_lms_proto_rawDesc = "" +
+	"\n" +
+	"\x10lms/v1/lms.proto\x12\x06lms.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfd\x01\n" +
+	"\bEmployee\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vemployee_id\x18\x02 \x01(\tR\n" +
+	"employeeId\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x14\n" +
+	"\x05email\x18\x04 \x01(\tR\x05email\x12#\n" +
+	"\rdepartment_id\x18\x05 \x01(\tR\fdepartmentId\x12\x12\n" +
+	"\x04role\x18\x06 \x01(\tR\x04role\x12\x1b\n" +
+	"\tis_active\x18\a \x01(\bR\bisActive\x12!\n" +
+	"\fjoining_date\x18\b \x01(\tR\vjoiningDate\x12\x1d\n" +
+	"\n" +
+	"manager_id\x18\t \x01(\tR\tmanagerId\"$\n" +
+	"\x12GetEmployeeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xa8\x01\n" +
+	"\x14ListEmployeesRequest\x12#\n" +
+	"\rdepartment_id\x18\x01 \x01(\tR\fdepartmentId\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12)\n" +
+	"\x10include_inactive\x18\x03 \x01(\bR\x0fincludeInactive\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\"]\n" +
+	"\x15ListEmployeesResponse\x12.\n" +
+	"\temployees\x18\x01 \x03(\v2\x10.lms.v1.EmployeeR\temployees\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"h\n" +
+	"\x16StreamEmployeesRequest\x12#\n" +
+	"\rdepartment_id\x18\x01 \x01(\tR\fdepartmentId\x12)\n" +
+	"\x10include_inactive\x18\x02 \x01(\bR\x0fincludeInactive\"\xbd\x04\n" +
+	"\fLeaveRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\vemployee_id\x18\x02 \x01(\tR\n" +
+	"employeeId\x12\"\n" +
+	"\rleave_type_id\x18\x03 \x01(\tR\vleaveTypeId\x12&\n" +
+	"\x0fleave_type_name\x18\x04 \x01(\tR\rleaveTypeName\x12\x1d\n" +
+	"\n" +
+	"start_date\x18\x05 \x01(\tR\tstartDate\x12\x19\n" +
+	"\bend_date\x18\x06 \x01(\tR\aendDate\x12\x1d\n" +
+	"\n" +
+	"total_days\x18\a \x01(\x05R\ttotalDays\x12\x16\n" +
+	"\x06reason\x18\b \x01(\tR\x06reason\x12\x16\n" +
+	"\x06status\x18\t \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"applied_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tappliedAt\x12\x1f\n" +
+	"\vapproved_by\x18\v \x01(\tR\n" +
+	"approvedBy\x12;\n" +
+	"\vapproved_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"approvedAt\x12)\n" +
+	"\x10rejection_reason\x18\r \x01(\tR\x0frejectionReason\x12(\n" +
+	"\x10total_days_exact\x18\x0e \x01(\x01R\x0etotalDaysExact\x12#\n" +
+	"\rduration_unit\x18\x0f \x01(\tR\fdurationUnit\x12\x14\n" +
+	"\x05hours\x18\x10 \x01(\x01R\x05hours\"(\n" +
+	"\x16GetLeaveRequestRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x81\x01\n" +
+	"\x18ListLeaveRequestsRequest\x12\x1f\n" +
+	"\vemployee_id\x18\x01 \x01(\tR\n" +
+	"employeeId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\"n\n" +
+	"\x19ListLeaveRequestsResponse\x12;\n" +
+	"\x0eleave_requests\x18\x01 \x03(\v2\x14.lms.v1.LeaveRequestR\rleaveRequests\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"X\n" +
+	"\x1aExportLeaveRequestsRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\"\xb3\x03\n" +
+	"\aBalance\x12\x1f\n" +
+	"\vemployee_id\x18\x01 \x01(\tR\n" +
+	"employeeId\x12\"\n" +
+	"\rleave_type_id\x18\x02 \x01(\tR\vleaveTypeId\x12&\n" +
+	"\x0fleave_type_name\x18\x03 \x01(\tR\rleaveTypeName\x12\x12\n" +
+	"\x04year\x18\x04 \x01(\x05R\x04year\x12%\n" +
+	"\x0eallocated_days\x18\x05 \x01(\x05R\rallocatedDays\x12\x1b\n" +
+	"\tused_days\x18\x06 \x01(\x05R\busedDays\x120\n" +
+	"\x14carried_forward_days\x18\a \x01(\x05R\x12carriedForwardDays\x12%\n" +
+	"\x0eavailable_days\x18\b \x01(\x05R\ravailableDays\x12&\n" +
+	"\x0fused_days_exact\x18\t \x01(\x01R\rusedDaysExact\x120\n" +
+	"\x14available_days_exact\x18\n" +
+	" \x01(\x01R\x12availableDaysExact\x120\n" +
+	"\x14allocated_days_exact\x18\v \x01(\x01R\x12allocatedDaysExact\"I\n" +
+	"\x12GetBalancesRequest\x12\x1f\n" +
+	"\vemployee_id\x18\x01 \x01(\tR\n" +
+	"employeeId\x12\x12\n" +
+	"\x04year\x18\x02 \x01(\x05R\x04year\"B\n" +
+	"\x13GetBalancesResponse\x12+\n" +
+	"\bbalances\x18\x01 \x03(\v2\x0f.lms.v1.BalanceR\bbalances\"+\n" +
+	"\x15ExportBalancesRequest\x12\x12\n" +
+	"\x04year\x18\x01 \x01(\x05R\x04year2\xe3\x01\n" +
+	"\x0fEmployeeService\x12;\n" +
+	"\vGetEmployee\x12\x1a.lms.v1.GetEmployeeRequest\x1a\x10.lms.v1.Employee\x12L\n" +
+	"\rListEmployees\x12\x1c.lms.v1.ListEmployeesRequest\x1a\x1d.lms.v1.ListEmployeesResponse\x12E\n" +
+	"\x0fStreamEmployees\x12\x1e.lms.v1.StreamEmployeesRequest\x1a\x10.lms.v1.Employee0\x012\x8b\x02\n" +
+	"\x13LeaveRequestService\x12G\n" +
+	"\x0fGetLeaveRequest\x12\x1e.lms.v1.GetLeaveRequestRequest\x1a\x14.lms.v1.LeaveRequest\x12X\n" +
+	"\x11ListLeaveRequests\x12 .lms.v1.ListLeaveRequestsRequest\x1a!.lms.v1.ListLeaveRequestsResponse\x12Q\n" +
+	"\x13ExportLeaveRequests\x12\".lms.v1.ExportLeaveRequestsRequest\x1a\x14.lms.v1.LeaveRequest0\x012\x9c\x01\n" +
+	"\x0eBalanceService\x12F\n" +
+	"\vGetBalances\x12\x1a.lms.v1.GetBalancesRequest\x1a\x1b.lms.v1.GetBalancesResponse\x12B\n" +
+	"\x0eExportBalances\x12\x1d.lms.v1.ExportBalancesRequest\x1a\x0f.lms.v1.Balance0\x01B/Z-leave-management/internal/grpcapi/lmsv1;lmsv1b\x06proto3"
+
+var (
+	file_lms_v1_lms_proto_rawDescOnce sync.Once
+	file_lms_v1_lms_proto_rawDescData []byte
+)
+
+func file_lms_v1_lms_proto_rawDescGZIP() []byte {
+	file_lms_v1_lms_proto_rawDescOnce.Do(func() {
+		file_lms_v1_lms_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_lms_v1_lms_proto_rawDesc), len(file_lms_v1_lms_proto_rawDesc)))
+	})
+	return file_lms_v1_lms_proto_rawDescData
+}
+
+var file_lms_v1_lms_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_lms_v1_lms_proto_goTypes = []any{
+	(*Employee)(nil),                   // 0: lms.v1.Employee
+	(*GetEmployeeRequest)(nil),         // 1: lms.v1.GetEmployeeRequest
+	(*ListEmployeesRequest)(nil),       // 2: lms.v1.ListEmployeesRequest
+	(*ListEmployeesResponse)(nil),      // 3: lms.v1.ListEmployeesResponse
+	(*StreamEmployeesRequest)(nil),     // 4: lms.v1.StreamEmployeesRequest
+	(*LeaveRequest)(nil),               // 5: lms.v1.LeaveRequest
+	(*GetLeaveRequestRequest)(nil),     // 6: lms.v1.GetLeaveRequestRequest
+	(*ListLeaveRequestsRequest)(nil),   // 7: lms.v1.ListLeaveRequestsRequest
+	(*ListLeaveRequestsResponse)(nil),  // 8: lms.v1.ListLeaveRequestsResponse
+	(*ExportLeaveRequestsRequest)(nil), // 9: lms.v1.ExportLeaveRequestsRequest
+	(*Balance)(nil),                    // 10: lms.v1.Balance
+	(*GetBalancesRequest)(nil),         // 11: lms.v1.GetBalancesRequest
+	(*GetBalancesResponse)(nil),        // 12: lms.v1.GetBalancesResponse
+	(*ExportBalancesRequest)(nil),      // 13: lms.v1.ExportBalancesRequest
+	(*timestamppb.Timestamp)(nil),      // 14: google.protobuf.Timestamp
+}
+var file_lms_v1_lms_proto_depIdxs = []int32{
+	0,  // 0: lms.v1.ListEmployeesResponse.employees:type_name -> lms.v1.Employee
+	14, // 1: lms.v1.LeaveRequest.applied_at:type_name -> google.protobuf.Timestamp
+	14, // 2: lms.v1.LeaveRequest.approved_at:type_name -> google.protobuf.Timestamp
+	5,  // 3: lms.v1.ListLeaveRequestsResponse.leave_requests:type_name -> lms.v1.LeaveRequest
+	10, // 4: lms.v1.GetBalancesResponse.balances:type_name -> lms.v1.Balance
+	1,  // 5: lms.v1.EmployeeService.GetEmployee:input_type -> lms.v1.GetEmployeeRequest
+	2,  // 6: lms.v1.EmployeeService.ListEmployees:input_type -> lms.v1.ListEmployeesRequest
+	4,  // 7: lms.v1.EmployeeService.StreamEmployees:input_type -> lms.v1.StreamEmployeesRequest
+	6,  // 8: lms.v1.LeaveRequestService.GetLeaveRequest:input_type -> lms.v1.GetLeaveRequestRequest
+	7,  // 9: lms.v1.LeaveRequestService.ListLeaveRequests:input_type -> lms.v1.ListLeaveRequestsRequest
+	9,  // 10: lms.v1.LeaveRequestService.ExportLeaveRequests:input_type -> lms.v1.ExportLeaveRequestsRequest
+	11, // 11: lms.v1.BalanceService.GetBalances:input_type -> lms.v1.GetBalancesRequest
+	13, // 12: lms.v1.BalanceService.ExportBalances:input_type -> lms.v1.ExportBalancesRequest
+	0,  // 13: lms.v1.EmployeeService.GetEmployee:output_type -> lms.v1.Employee
+	3,  // 14: lms.v1.EmployeeService.ListEmployees:output_type -> lms.v1.ListEmployeesResponse
+	0,  // 15: lms.v1.EmployeeService.StreamEmployees:output_type -> lms.v1.Employee
+	5,  // 16: lms.v1.LeaveRequestService.GetLeaveRequest:output_type -> lms.v1.LeaveRequest
+	8,  // 17: lms.v1.LeaveRequestService.ListLeaveRequests:output_type -> lms.v1.ListLeaveRequestsResponse
+	5,  // 18: lms.v1.LeaveRequestService.ExportLeaveRequests:output_type -> lms.v1.LeaveRequest
+	12, // 19: lms.v1.BalanceService.GetBalances:output_type -> lms.v1.GetBalancesResponse
+	10, // 20: lms.v1.BalanceService.ExportBalances:output_type -> lms.v1.Balance
+	13, // [13:21] is the sub-list for method output_type
+	5,  // [5:13] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_lms_v1_lms_proto_init() }
+func file_lms_v1_lms_proto_init() {
+	if File_lms_v1_lms_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_lms_v1_lms_proto_rawDesc), len(file_lms_v1_lms_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_lms_v1_lms_proto_goTypes,
+		DependencyIndexes: file_lms_v1_lms_proto_depIdxs,
+		MessageInfos:      file_lms_v1_lms_proto_msgTypes,
+	}.Build()
+	File_lms_v1_lms_proto = out.File
+	file_lms_v1_lms_proto_goTypes = nil
+	file_lms_v1_lms_proto_depIdxs = nil
+}
@@ -0,0 +1,533 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: lms/v1/lms.proto
+
+package lmsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	EmployeeService_GetEmployee_FullMethodName     = "/lms.v1.EmployeeService/GetEmployee"
+	EmployeeService_ListEmployees_FullMethodName   = "/lms.v1.EmployeeService/ListEmployees"
+	EmployeeService_StreamEmployees_FullMethodName = "/lms.v1.EmployeeService/StreamEmployees"
+)
+
+// EmployeeServiceClient is the client API for EmployeeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EmployeeServiceClient interface {
+	GetEmployee(ctx context.Context, in *GetEmployeeRequest, opts ...grpc.CallOption) (*Employee, error)
+	ListEmployees(ctx context.Context, in *ListEmployeesRequest, opts ...grpc.CallOption) (*ListEmployeesResponse, error)
+	// StreamEmployees streams every matching employee, for exports.
+	StreamEmployees(ctx context.Context, in *StreamEmployeesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Employee], error)
+}
+
+type employeeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEmployeeServiceClient(cc grpc.ClientConnInterface) EmployeeServiceClient {
+	return &employeeServiceClient{cc}
+}
+
+func (c *employeeServiceClient) GetEmployee(ctx context.Context, in *GetEmployeeRequest, opts ...grpc.CallOption) (*Employee, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Employee)
+	err := c.cc.Invoke(ctx, EmployeeService_GetEmployee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *employeeServiceClient) ListEmployees(ctx context.Context, in *ListEmployeesRequest, opts ...grpc.CallOption) (*ListEmployeesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEmployeesResponse)
+	err := c.cc.Invoke(ctx, EmployeeService_ListEmployees_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *employeeServiceClient) StreamEmployees(ctx context.Context, in *StreamEmployeesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Employee], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EmployeeService_ServiceDesc.Streams[0], EmployeeService_StreamEmployees_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEmployeesRequest, Employee]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EmployeeService_StreamEmployeesClient = grpc.ServerStreamingClient[Employee]
+
+// EmployeeServiceServer is the server API for EmployeeService service.
+// All implementations must embed UnimplementedEmployeeServiceServer
+// for forward compatibility.
+type EmployeeServiceServer interface {
+	GetEmployee(context.Context, *GetEmployeeRequest) (*Employee, error)
+	ListEmployees(context.Context, *ListEmployeesRequest) (*ListEmployeesResponse, error)
+	// StreamEmployees streams every matching employee, for exports.
+	StreamEmployees(*StreamEmployeesRequest, grpc.ServerStreamingServer[Employee]) error
+	mustEmbedUnimplementedEmployeeServiceServer()
+}
+
+// UnimplementedEmployeeServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEmployeeServiceServer struct{}
+
+func (UnimplementedEmployeeServiceServer) GetEmployee(context.Context, *GetEmployeeRequest) (*Employee, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEmployee not implemented")
+}
+func (UnimplementedEmployeeServiceServer) ListEmployees(context.Context, *ListEmployeesRequest) (*ListEmployeesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEmployees not implemented")
+}
+func (UnimplementedEmployeeServiceServer) StreamEmployees(*StreamEmployeesRequest, grpc.ServerStreamingServer[Employee]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEmployees not implemented")
+}
+func (UnimplementedEmployeeServiceServer) mustEmbedUnimplementedEmployeeServiceServer() {}
+func (UnimplementedEmployeeServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeEmployeeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EmployeeServiceServer will
+// result in compilation errors.
+type UnsafeEmployeeServiceServer interface {
+	mustEmbedUnimplementedEmployeeServiceServer()
+}
+
+func RegisterEmployeeServiceServer(s grpc.ServiceRegistrar, srv EmployeeServiceServer) {
+	// If the following call pancis, it indicates UnimplementedEmployeeServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&EmployeeService_ServiceDesc, srv)
+}
+
+func _EmployeeService_GetEmployee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEmployeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmployeeServiceServer).GetEmployee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmployeeService_GetEmployee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmployeeServiceServer).GetEmployee(ctx, req.(*GetEmployeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmployeeService_ListEmployees_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEmployeesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmployeeServiceServer).ListEmployees(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmployeeService_ListEmployees_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmployeeServiceServer).ListEmployees(ctx, req.(*ListEmployeesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmployeeService_StreamEmployees_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEmployeesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EmployeeServiceServer).StreamEmployees(m, &grpc.GenericServerStream[StreamEmployeesRequest, Employee]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EmployeeService_StreamEmployeesServer = grpc.ServerStreamingServer[Employee]
+
+// EmployeeService_ServiceDesc is the grpc.ServiceDesc for EmployeeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EmployeeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lms.v1.EmployeeService",
+	HandlerType: (*EmployeeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetEmployee",
+			Handler:    _EmployeeService_GetEmployee_Handler,
+		},
+		{
+			MethodName: "ListEmployees",
+			Handler:    _EmployeeService_ListEmployees_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEmployees",
+			Handler:       _EmployeeService_StreamEmployees_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lms/v1/lms.proto",
+}
+
+const (
+	LeaveRequestService_GetLeaveRequest_FullMethodName     = "/lms.v1.LeaveRequestService/GetLeaveRequest"
+	LeaveRequestService_ListLeaveRequests_FullMethodName   = "/lms.v1.LeaveRequestService/ListLeaveRequests"
+	LeaveRequestService_ExportLeaveRequests_FullMethodName = "/lms.v1.LeaveRequestService/ExportLeaveRequests"
+)
+
+// LeaveRequestServiceClient is the client API for LeaveRequestService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LeaveRequestServiceClient interface {
+	GetLeaveRequest(ctx context.Context, in *GetLeaveRequestRequest, opts ...grpc.CallOption) (*LeaveRequest, error)
+	ListLeaveRequests(ctx context.Context, in *ListLeaveRequestsRequest, opts ...grpc.CallOption) (*ListLeaveRequestsResponse, error)
+	// ExportLeaveRequests streams every matching request ordered by start date.
+	ExportLeaveRequests(ctx context.Context, in *ExportLeaveRequestsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LeaveRequest], error)
+}
+
+type leaveRequestServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLeaveRequestServiceClient(cc grpc.ClientConnInterface) LeaveRequestServiceClient {
+	return &leaveRequestServiceClient{cc}
+}
+
+func (c *leaveRequestServiceClient) GetLeaveRequest(ctx context.Context, in *GetLeaveRequestRequest, opts ...grpc.CallOption) (*LeaveRequest, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeaveRequest)
+	err := c.cc.Invoke(ctx, LeaveRequestService_GetLeaveRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaveRequestServiceClient) ListLeaveRequests(ctx context.Context, in *ListLeaveRequestsRequest, opts ...grpc.CallOption) (*ListLeaveRequestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLeaveRequestsResponse)
+	err := c.cc.Invoke(ctx, LeaveRequestService_ListLeaveRequests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *leaveRequestServiceClient) ExportLeaveRequests(ctx context.Context, in *ExportLeaveRequestsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LeaveRequest], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LeaveRequestService_ServiceDesc.Streams[0], LeaveRequestService_ExportLeaveRequests_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportLeaveRequestsRequest, LeaveRequest]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LeaveRequestService_ExportLeaveRequestsClient = grpc.ServerStreamingClient[LeaveRequest]
+
+// LeaveRequestServiceServer is the server API for LeaveRequestService service.
+// All implementations must embed UnimplementedLeaveRequestServiceServer
+// for forward compatibility.
+type LeaveRequestServiceServer interface {
+	GetLeaveRequest(context.Context, *GetLeaveRequestRequest) (*LeaveRequest, error)
+	ListLeaveRequests(context.Context, *ListLeaveRequestsRequest) (*ListLeaveRequestsResponse, error)
+	// ExportLeaveRequests streams every matching request ordered by start date.
+	ExportLeaveRequests(*ExportLeaveRequestsRequest, grpc.ServerStreamingServer[LeaveRequest]) error
+	mustEmbedUnimplementedLeaveRequestServiceServer()
+}
+
+// UnimplementedLeaveRequestServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLeaveRequestServiceServer struct{}
+
+func (UnimplementedLeaveRequestServiceServer) GetLeaveRequest(context.Context, *GetLeaveRequestRequest) (*LeaveRequest, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeaveRequest not implemented")
+}
+func (UnimplementedLeaveRequestServiceServer) ListLeaveRequests(context.Context, *ListLeaveRequestsRequest) (*ListLeaveRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLeaveRequests not implemented")
+}
+func (UnimplementedLeaveRequestServiceServer) ExportLeaveRequests(*ExportLeaveRequestsRequest, grpc.ServerStreamingServer[LeaveRequest]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportLeaveRequests not implemented")
+}
+func (UnimplementedLeaveRequestServiceServer) mustEmbedUnimplementedLeaveRequestServiceServer() {}
+func (UnimplementedLeaveRequestServiceServer) testEmbeddedByValue()                             {}
+
+// UnsafeLeaveRequestServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LeaveRequestServiceServer will
+// result in compilation errors.
+type UnsafeLeaveRequestServiceServer interface {
+	mustEmbedUnimplementedLeaveRequestServiceServer()
+}
+
+func RegisterLeaveRequestServiceServer(s grpc.ServiceRegistrar, srv LeaveRequestServiceServer) {
+	// If the following call pancis, it indicates UnimplementedLeaveRequestServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LeaveRequestService_ServiceDesc, srv)
+}
+
+func _LeaveRequestService_GetLeaveRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeaveRequestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaveRequestServiceServer).GetLeaveRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaveRequestService_GetLeaveRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaveRequestServiceServer).GetLeaveRequest(ctx, req.(*GetLeaveRequestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaveRequestService_ListLeaveRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLeaveRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LeaveRequestServiceServer).ListLeaveRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LeaveRequestService_ListLeaveRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LeaveRequestServiceServer).ListLeaveRequests(ctx, req.(*ListLeaveRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LeaveRequestService_ExportLeaveRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportLeaveRequestsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LeaveRequestServiceServer).ExportLeaveRequests(m, &grpc.GenericServerStream[ExportLeaveRequestsRequest, LeaveRequest]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LeaveRequestService_ExportLeaveRequestsServer = grpc.ServerStreamingServer[LeaveRequest]
+
+// LeaveRequestService_ServiceDesc is the grpc.ServiceDesc for LeaveRequestService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LeaveRequestService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lms.v1.LeaveRequestService",
+	HandlerType: (*LeaveRequestServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLeaveRequest",
+			Handler:    _LeaveRequestService_GetLeaveRequest_Handler,
+		},
+		{
+			MethodName: "ListLeaveRequests",
+			Handler:    _LeaveRequestService_ListLeaveRequests_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportLeaveRequests",
+			Handler:       _LeaveRequestService_ExportLeaveRequests_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lms/v1/lms.proto",
+}
+
+const (
+	BalanceService_GetBalances_FullMethodName    = "/lms.v1.BalanceService/GetBalances"
+	BalanceService_ExportBalances_FullMethodName = "/lms.v1.BalanceService/ExportBalances"
+)
+
+// BalanceServiceClient is the client API for BalanceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BalanceServiceClient interface {
+	GetBalances(ctx context.Context, in *GetBalancesRequest, opts ...grpc.CallOption) (*GetBalancesResponse, error)
+	// ExportBalances streams balances of every active employee for a year.
+	ExportBalances(ctx context.Context, in *ExportBalancesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Balance], error)
+}
+
+type balanceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBalanceServiceClient(cc grpc.ClientConnInterface) BalanceServiceClient {
+	return &balanceServiceClient{cc}
+}
+
+func (c *balanceServiceClient) GetBalances(ctx context.Context, in *GetBalancesRequest, opts ...grpc.CallOption) (*GetBalancesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBalancesResponse)
+	err := c.cc.Invoke(ctx, BalanceService_GetBalances_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *balanceServiceClient) ExportBalances(ctx context.Context, in *ExportBalancesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Balance], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BalanceService_ServiceDesc.Streams[0], BalanceService_ExportBalances_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportBalancesRequest, Balance]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BalanceService_ExportBalancesClient = grpc.ServerStreamingClient[Balance]
+
+// BalanceServiceServer is the server API for BalanceService service.
+// All implementations must embed UnimplementedBalanceServiceServer
+// for forward compatibility.
+type BalanceServiceServer interface {
+	GetBalances(context.Context, *GetBalancesRequest) (*GetBalancesResponse, error)
+	// ExportBalances streams balances of every active employee for a year.
+	ExportBalances(*ExportBalancesRequest, grpc.ServerStreamingServer[Balance]) error
+	mustEmbedUnimplementedBalanceServiceServer()
+}
+
+// UnimplementedBalanceServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBalanceServiceServer struct{}
+
+func (UnimplementedBalanceServiceServer) GetBalances(context.Context, *GetBalancesRequest) (*GetBalancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalances not implemented")
+}
+func (UnimplementedBalanceServiceServer) ExportBalances(*ExportBalancesRequest, grpc.ServerStreamingServer[Balance]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportBalances not implemented")
+}
+func (UnimplementedBalanceServiceServer) mustEmbedUnimplementedBalanceServiceServer() {}
+func (UnimplementedBalanceServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeBalanceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BalanceServiceServer will
+// result in compilation errors.
+type UnsafeBalanceServiceServer interface {
+	mustEmbedUnimplementedBalanceServiceServer()
+}
+
+func RegisterBalanceServiceServer(s grpc.ServiceRegistrar, srv BalanceServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBalanceServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BalanceService_ServiceDesc, srv)
+}
+
+func _BalanceService_GetBalances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BalanceServiceServer).GetBalances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BalanceService_GetBalances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BalanceServiceServer).GetBalances(ctx, req.(*GetBalancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BalanceService_ExportBalances_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportBalancesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BalanceServiceServer).ExportBalances(m, &grpc.GenericServerStream[ExportBalancesRequest, Balance]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BalanceService_ExportBalancesServer = grpc.ServerStreamingServer[Balance]
+
+// BalanceService_ServiceDesc is the grpc.ServiceDesc for BalanceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BalanceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lms.v1.BalanceService",
+	HandlerType: (*BalanceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBalances",
+			Handler:    _BalanceService_GetBalances_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportBalances",
+			Handler:       _BalanceService_ExportBalances_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lms/v1/lms.proto",
+}
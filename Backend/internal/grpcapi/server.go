@@ -0,0 +1,116 @@
+// Package grpcapi serves the lms.v1 gRPC services for internal systems. The
+// protobuf definitions live in proto/lms/v1/lms.proto; lmsv1 holds the
+// generated code.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=leave-management --go-grpc_out=../.. --go-grpc_opt=module=leave-management lms/v1/lms.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"leave-management/internal/db"
+	"leave-management/internal/grpcapi/lmsv1"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// NewServer registers the employee, leave request and balance services. Every
+// call must carry "authorization: Bearer <token>" metadata matching token and
+// "x-org-id: <organization id>"; the call sees that organization's data only.
+// Unary calls get a deadline of timeout(), read per call, as HTTP requests do
+// (a client deadline that comes first still wins); a non-positive value
+// disables it. Each query of a unary call is limited to queryTimeout(), as
+// for HTTP requests (middleware.QueryTimeout).
+func NewServer(pool *pgxpool.Pool, token string, timeout, queryTimeout func() time.Duration) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authorize(ctx, pool, token)
+			if err != nil {
+				return nil, err
+			}
+			if d := timeout(); d > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, d)
+				defer cancel()
+			}
+			return handler(db.WithQueryTimeout(ctx, queryTimeout()), req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authorize(ss.Context(), pool, token)
+			if err != nil {
+				return err
+			}
+			return handler(srv, scopedStream{ss, ctx})
+		}),
+	)
+	lmsv1.RegisterEmployeeServiceServer(s, &employeeService{pool: pool})
+	lmsv1.RegisterLeaveRequestServiceServer(s, &leaveRequestService{pool: pool})
+	lmsv1.RegisterBalanceServiceServer(s, &balanceService{pool: pool})
+	return s
+}
+
+// authorize checks the shared service token from the call metadata and
+// returns ctx scoped to the organization named by x-org-id
+func authorize(ctx context.Context, pool *pgxpool.Pool, token string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	valid := false
+	for _, v := range md.Get("authorization") {
+		got := strings.TrimPrefix(v, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, status.Error(codes.Unauthenticated, "invalid service token")
+	}
+
+	orgs := md.Get("x-org-id")
+	if len(orgs) != 1 {
+		return nil, status.Error(codes.InvalidArgument, "x-org-id metadata is required")
+	}
+	var active bool
+	err := pool.QueryRow(db.AsService(ctx),
+		"SELECT EXISTS (SELECT 1 FROM organizations WHERE id::text = $1 AND is_active)", orgs[0]).Scan(&active)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, "could not check the organization")
+	}
+	if !active {
+		return nil, status.Error(codes.PermissionDenied, "unknown or inactive organization")
+	}
+	return db.WithOrg(ctx, orgs[0]), nil
+}
+
+// scopedStream hands the organization scoped context to a streaming handler
+type scopedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s scopedStream) Context() context.Context { return s.ctx }
+
+// page applies the same limit defaults and bounds as the HTTP API
+func page(limit, offset int32) (int32, int32, error) {
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 0 || limit > maxLimit {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxLimit)
+	}
+	if offset < 0 {
+		return 0, 0, status.Error(codes.InvalidArgument, "offset cannot be negative")
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AccrualHandler triggers the leave accrual job by hand
+type AccrualHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewAccrualHandler(pool *pgxpool.Pool) *AccrualHandler {
+	return &AccrualHandler{pool: pool}
+}
+
+// POST /admin/accruals/run
+// Credits the accrual periods of the caller's organization that are due and
+// not credited yet, as the scheduled job does; running it again credits
+// nothing new.
+func (h *AccrualHandler) RunAccruals(c *gin.Context) {
+	asOf := time.Now()
+	res, err := jobs.CreditAccruals(c.Request.Context(), h.pool, asOf)
+	if err != nil {
+		apierror.Database(c, err, "failed to credit accruals")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{
+		"as_of":            asOf.Format("2006-01-02"),
+		"periods_credited": res.Periods,
+		"days_credited":    res.Days,
+		"balances_updated": res.Balances,
+	})
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/apikey"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// APIKeyHandler manages the organization's API keys, with which integration
+// clients call the API without signing in (see internal/apikey)
+type APIKeyHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyHandler(pool *pgxpool.Pool) *APIKeyHandler {
+	return &APIKeyHandler{pool: pool}
+}
+
+type apiKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // the key's first characters
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"` // null: never
+	IsActive   bool       `json:"is_active"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Key        string     `json:"key,omitempty"` // only when created
+	CreatedBy  string     `json:"created_by"`    // the user the key acts as
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+const apiKeyColumns = "id, name, key_prefix, scopes, expires_at, is_active, last_used_at, created_by, created_at, updated_at"
+
+func scanAPIKey(row pgx.Row) (apiKey, error) {
+	var k apiKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.ExpiresAt, &k.IsActive, &k.LastUsedAt, &k.CreatedBy,
+		&k.CreatedAt, &k.UpdatedAt)
+	return k, err
+}
+
+// apiKeyScopes checks a list of scopes and drops the duplicates
+func apiKeyScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("must list at least one scope")
+	}
+	seen := map[string]bool{}
+	out := []string{}
+	for _, s := range scopes {
+		if !apikey.ValidScope(s) {
+			return nil, fmt.Errorf("must only contain %s", strings.Join(apikey.Scopes, ", "))
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// GET /api-keys (paging: limit, offset; sort: name, created_at, expires_at,
+// last_used_at)
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, apiKeySorts, "created_at, id", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM api_keys").Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch API keys")
+		return
+	}
+	rows, err := h.pool.Query(ctx, "SELECT "+apiKeyColumns+` FROM api_keys
+		ORDER BY `+orderBy+` LIMIT $1 OFFSET $2`, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch API keys")
+		return
+	}
+	defer rows.Close()
+	list := make([]apiKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, k)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch API keys")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// POST /api-keys
+// Creates a key that acts as the caller, limited to scopes. The response
+// carries the key, which is not shown again.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var in struct {
+		Name      string     `json:"name" binding:"required,max=100"`
+		Scopes    []string   `json:"scopes" binding:"required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	var fieldErrs []apierror.FieldError
+	if in.Name == "" {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "name", Error: "cannot be empty"})
+	}
+	scopes, err := apiKeyScopes(in.Scopes)
+	if err != nil {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "scopes", Error: err.Error()})
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "expires_at", Error: "must be in the future"})
+	}
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
+	key, prefix, hash, err := apikey.New()
+	if err != nil {
+		apierror.Respond(c, apierror.Internal, "failed to generate API key")
+		return
+	}
+	k, err := scanAPIKey(h.pool.QueryRow(c.Request.Context(), `
+		INSERT INTO api_keys (name, key_prefix, key_hash, scopes, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns, in.Name, prefix, hash, scopes, in.ExpiresAt, c.GetString("user_id")))
+	if err != nil {
+		apierror.Database(c, err, "failed to create API key")
+		return
+	}
+	k.Key = key
+	respond(c, http.StatusCreated, k)
+}
+
+// GET /api-keys/:id
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "API key not found")
+		return
+	}
+	k, err := scanAPIKey(h.pool.QueryRow(c.Request.Context(), "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1", c.Param("id")))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "API key not found", "failed to fetch API key")
+		return
+	}
+	respond(c, http.StatusOK, k)
+}
+
+// apiKeyPatchFields are the members PATCH /api-keys/:id accepts
+var apiKeyPatchFields = map[string]patchField{
+	"name":       {column: "name", parse: patchString(true)},
+	"scopes":     {column: "scopes", parse: patchAPIKeyScopes},
+	"expires_at": {column: "expires_at", nullable: true, parse: patchFutureTime},
+	"is_active":  {column: "is_active", parse: patchBool},
+}
+
+// patchAPIKeyScopes decodes the scopes member, which replaces the list
+func patchAPIKeyScopes(raw json.RawMessage) (interface{}, error) {
+	var scopes []string
+	if err := json.Unmarshal(raw, &scopes); err != nil {
+		return nil, errors.New("must be an array of strings")
+	}
+	return apiKeyScopes(scopes)
+}
+
+// patchFutureTime decodes an RFC 3339 time member that must be in the future
+func patchFutureTime(raw json.RawMessage) (interface{}, error) {
+	var t time.Time
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, errors.New("must be an RFC 3339 time")
+	}
+	if !t.After(time.Now()) {
+		return nil, errors.New("must be in the future")
+	}
+	return t, nil
+}
+
+// PATCH /api-keys/:id (RFC 7386 merge patch; null clears expires_at)
+// Changes apply to the key's next request. is_active false suspends the key.
+func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
+	patch, ok := bindMergePatch(c)
+	if !ok {
+		return
+	}
+	sets, args, fieldErrs := patch.assignments(apiKeyPatchFields, 1)
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
+	if len(sets) == 0 {
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "no fields to update")
+		return
+	}
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "API key not found")
+		return
+	}
+	args = append(args, c.Param("id"))
+	k, err := scanAPIKey(h.pool.QueryRow(c.Request.Context(),
+		"UPDATE api_keys SET "+strings.Join(sets, ", ")+fmt.Sprintf(" WHERE id=$%d RETURNING ", len(args))+apiKeyColumns,
+		args...))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "API key not found", "failed to update API key")
+		return
+	}
+	respond(c, http.StatusOK, k)
+}
+
+// DELETE /api-keys/:id
+// Revokes the key: its next request is refused.
+func (h *APIKeyHandler) DeleteAPIKey(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "API key not found")
+		return
+	}
+	ct, err := h.pool.Exec(c.Request.Context(), "DELETE FROM api_keys WHERE id = $1", c.Param("id"))
+	if err != nil {
+		apierror.Database(c, err, "failed to delete API key")
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		apierror.Respond(c, apierror.NotFound, "API key not found")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "API key deleted"})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ApprovalRuleHandler manages the approval routing rules, which decide when a
+// leave request is created whether it is approved automatically, by a manager,
+// by HR, or by a manager and then HR (the request's chain of approval steps). The first active rule by priority whose leave
+// type, department and day range match the request applies; without one a
+// manager approves.
+type ApprovalRuleHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewApprovalRuleHandler(pool *pgxpool.Pool) *ApprovalRuleHandler {
+	return &ApprovalRuleHandler{pool: pool}
+}
+
+type approvalRule struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Priority     int       `json:"priority"`
+	LeaveTypeID  *string   `json:"leave_type_id"`
+	DepartmentID *string   `json:"department_id"`
+	MinDays      *int      `json:"min_days"`
+	MaxDays      *int      `json:"max_days"`
+	Route        string    `json:"route"`
+	IsActive     bool      `json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const approvalRuleColumns = "id, name, priority, leave_type_id, department_id, min_days, max_days, route, is_active, created_at, updated_at"
+
+func scanApprovalRule(row pgx.Row) (approvalRule, error) {
+	var r approvalRule
+	err := row.Scan(&r.ID, &r.Name, &r.Priority, &r.LeaveTypeID, &r.DepartmentID, &r.MinDays, &r.MaxDays,
+		&r.Route, &r.IsActive, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+var approvalRoutes = []string{service.RouteAuto, service.RouteManager, service.RouteHR, service.RouteManagerHR}
+
+// GET /approval-rules (paging: limit, offset; sort: priority, name, created_at)
+// Every rule, active or not, by default in the order they are evaluated.
+func (h *ApprovalRuleHandler) ListApprovalRules(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, approvalRuleSorts, "priority, created_at, id", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM approval_routing_rules").Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch approval rules")
+		return
+	}
+	rows, err := h.pool.Query(ctx, "SELECT "+approvalRuleColumns+` FROM approval_routing_rules
+		ORDER BY `+orderBy+` LIMIT $1 OFFSET $2`, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch approval rules")
+		return
+	}
+	defer rows.Close()
+	list := make([]approvalRule, 0)
+	for rows.Next() {
+		r, err := scanApprovalRule(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch approval rules")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// POST /approval-rules
+// Omitted leave_type_id, department_id, min_days and max_days match any
+// request.
+func (h *ApprovalRuleHandler) CreateApprovalRule(c *gin.Context) {
+	var in struct {
+		Name         string  `json:"name" binding:"required,max=100"`
+		Priority     *int    `json:"priority" binding:"omitempty,min=0"`
+		LeaveTypeID  *string `json:"leave_type_id" binding:"omitempty,uuid"`
+		DepartmentID *string `json:"department_id" binding:"omitempty,uuid"`
+		MinDays      *int    `json:"min_days" binding:"omitempty,min=1"`
+		MaxDays      *int    `json:"max_days" binding:"omitempty,min=1"`
+		Route        string  `json:"route" binding:"required,oneof=auto manager hr manager_hr"`
+		IsActive     *bool   `json:"is_active"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	r, err := scanApprovalRule(h.pool.QueryRow(c.Request.Context(), `
+		INSERT INTO approval_routing_rules (name, priority, leave_type_id, department_id, min_days, max_days, route, is_active)
+		VALUES ($1, COALESCE($2, 100), $3, $4, $5, $6, $7, COALESCE($8, TRUE))
+		RETURNING `+approvalRuleColumns,
+		strings.TrimSpace(in.Name), in.Priority, in.LeaveTypeID, in.DepartmentID, in.MinDays, in.MaxDays, in.Route, in.IsActive))
+	if err != nil {
+		apierror.Database(c, err, "failed to create approval rule")
+		return
+	}
+	respond(c, http.StatusCreated, r)
+}
+
+// approvalRulePatchFields are the members PATCH /approval-rules/:id accepts
+var approvalRulePatchFields = map[string]patchField{
+	"name":          {column: "name", parse: patchString(true)},
+	"priority":      {column: "priority", parse: patchNonNegativeInt},
+	"leave_type_id": {column: "leave_type_id", nullable: true, parse: patchString(true)},
+	"department_id": {column: "department_id", nullable: true, parse: patchString(true)},
+	"min_days":      {column: "min_days", nullable: true, parse: patchNonNegativeInt},
+	"max_days":      {column: "max_days", nullable: true, parse: patchNonNegativeInt},
+	"route":         {column: "route", parse: patchRoute},
+	"is_active":     {column: "is_active", parse: patchBool},
+}
+
+// patchRoute decodes an approval route member
+func patchRoute(raw json.RawMessage) (interface{}, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("must be a string")
+	}
+	for _, r := range approvalRoutes {
+		if s == r {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("must be one of %s", strings.Join(approvalRoutes, ", "))
+}
+
+// PATCH /approval-rules/:id (RFC 7386 merge patch; null clears leave_type_id,
+// department_id, min_days and max_days)
+// Requests created before the change keep their route.
+func (h *ApprovalRuleHandler) UpdateApprovalRule(c *gin.Context) {
+	patch, ok := bindMergePatch(c)
+	if !ok {
+		return
+	}
+	sets, args, fieldErrs := patch.assignments(approvalRulePatchFields, 1)
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
+	if len(sets) == 0 {
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "no fields to update")
+		return
+	}
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "approval rule not found")
+		return
+	}
+	args = append(args, c.Param("id"))
+	r, err := scanApprovalRule(h.pool.QueryRow(c.Request.Context(),
+		"UPDATE approval_routing_rules SET "+strings.Join(sets, ", ")+fmt.Sprintf(" WHERE id=$%d RETURNING ", len(args))+approvalRuleColumns,
+		args...))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "approval rule not found", "failed to update approval rule")
+		return
+	}
+	respond(c, http.StatusOK, r)
+}
+
+// DELETE /approval-rules/:id
+// Requests it routed keep their route; their history shows the rule without
+// its name.
+func (h *ApprovalRuleHandler) DeleteApprovalRule(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "approval rule not found")
+		return
+	}
+	ct, err := h.pool.Exec(c.Request.Context(), "DELETE FROM approval_routing_rules WHERE id = $1", c.Param("id"))
+	if err != nil {
+		apierror.Database(c, err, "failed to delete approval rule")
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		apierror.Respond(c, apierror.NotFound, "approval rule not found")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "approval rule deleted"})
+}
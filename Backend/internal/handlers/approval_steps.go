@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/models"
+	"leave-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type approvalStep struct {
+	StepNo       int        `json:"step_no"`
+	ApproverRole string     `json:"approver_role"` // manager or hr
+	Status       string     `json:"status"`        // pending, approved, rejected or skipped
+	ActedBy      *string    `json:"acted_by"`
+	ActedAt      *time.Time `json:"acted_at"`
+	Comment      *string    `json:"comment"`
+}
+
+// GET /leave-requests/:id/approval-steps
+// The request's approval chain in order; auto-approved requests have none.
+func (h *LeaveRequestHandler) ListApprovalSteps(c *gin.Context) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "leave request not found")
+		return
+	}
+	ctx := c.Request.Context()
+	var stage *string
+	if err := h.pool.QueryRow(ctx, "SELECT leave_request_stage(id) FROM leave_requests WHERE id = $1", id).Scan(&stage); err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "leave request not found", "failed to fetch approval steps")
+		return
+	}
+	rows, err := h.pool.Query(ctx, `
+		SELECT step_no, approver_role, status, acted_by, acted_at, comment
+		FROM approval_steps WHERE leave_request_id = $1 ORDER BY step_no`, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch approval steps")
+		return
+	}
+	defer rows.Close()
+	steps := make([]approvalStep, 0)
+	for rows.Next() {
+		var s approvalStep
+		if err := rows.Scan(&s.StepNo, &s.ApproverRole, &s.Status, &s.ActedBy, &s.ActedAt, &s.Comment); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		steps = append(steps, s)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch approval steps")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"leave_request_id": id, "approval_stage": stage, "steps": steps})
+}
+
+// PUT /leave-requests/:id/approval-steps/:step/approve
+// As PUT /leave-requests/:id/approve, but only while step is the current one,
+// so an approver cannot act on a step that moved on meanwhile.
+func (h *LeaveRequestHandler) ApproveStep(c *gin.Context) {
+	if step, ok := stepParam(c); ok {
+		h.approve(c, step)
+	}
+}
+
+// PUT /leave-requests/:id/approval-steps/:step/reject
+func (h *LeaveRequestHandler) RejectStep(c *gin.Context) {
+	if step, ok := stepParam(c); ok {
+		h.reject(c, step)
+	}
+}
+
+func stepParam(c *gin.Context) (int, bool) {
+	step, err := strconv.Atoi(c.Param("step"))
+	if err != nil || step < 1 {
+		apierror.Respond(c, apierror.NotFound, "approval step not found")
+		return 0, false
+	}
+	return step, true
+}
+
+// approve approves the current step of the request, which must be step
+// unless step is 0. The approver is the authenticated user; a request body is
+// not read. Unless NOTICE_PERIOD_LEAVE is allow, leave in the employee's
+// notice period needs HR or an admin.
+func (h *LeaveRequestHandler) approve(c *gin.Context, step int) {
+	id := c.Param("id")
+	approvedBy := c.GetString("employee_uuid")
+	if approvedBy == "" {
+		apierror.Respond(c, apierror.Forbidden, "only users with an employee record can approve leave requests")
+		return
+	}
+	role := c.GetString("role")
+	hr := role == models.RoleHR || role == models.RoleAdmin
+	needsHR, err := h.noticeNeedsHR(c.Request.Context(), id, hr)
+	if err != nil {
+		respondWorkflowError(c, err, "failed to approve request")
+		return
+	}
+	if needsHR {
+		apierror.Respond(c, apierror.HRApprovalRequired, "leave during the notice period needs HR approval")
+		return
+	}
+	outcome, err := h.workflow.Approve(c.Request.Context(), id, step, approvedBy, hr)
+	if err != nil {
+		respondWorkflowError(c, err, "failed to approve request")
+		return
+	}
+	if outcome != service.Approved {
+		respond(c, http.StatusOK, gin.H{"message": "approval recorded, awaiting the next approver", "status": "pending", "approval_stage": outcome})
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "leave request approved", "status": "approved", "approval_stage": nil})
+}
+
+// noticeNeedsHR reports whether request id is leave in the employee's notice
+// period, which only HR and admins (hr) may approve unless
+// NOTICE_PERIOD_LEAVE is allow
+func (h *LeaveRequestHandler) noticeNeedsHR(ctx context.Context, id string, hr bool) (bool, error) {
+	notice := h.notice()
+	if notice.Leave == "allow" || hr {
+		return false, nil
+	}
+	var inNoticePeriod bool
+	err := h.pool.QueryRow(ctx, `
+		SELECT in_notice_period(e.resignation_date, $2, lr.start_date, lr.end_date)
+		FROM leave_requests lr JOIN employees e ON e.id = lr.employee_id
+		WHERE lr.id = $1`, id, notice.Days).Scan(&inNoticePeriod)
+	return inNoticePeriod, err
+}
+
+// reject rejects the request at its current step, which must be step unless
+// step is 0
+func (h *LeaveRequestHandler) reject(c *gin.Context, step int) {
+	var in struct {
+		RejectionReason string `json:"rejection_reason" binding:"required"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	role := c.GetString("role")
+	hr := role == models.RoleHR || role == models.RoleAdmin
+	if err := h.workflow.Reject(c.Request.Context(), c.Param("id"), step, in.RejectionReason, c.GetString("employee_uuid"), hr); err != nil {
+		respondWorkflowError(c, err, "failed to reject request")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "leave request rejected"})
+}
+
+type pendingApproval struct {
+	LeaveRequestID string    `json:"leave_request_id"`
+	EmployeeID     string    `json:"employee_id"`
+	EmployeeName   string    `json:"employee_name"`
+	LeaveTypeID    string    `json:"leave_type_id"`
+	LeaveTypeName  string    `json:"leave_type_name"`
+	StartDate      string    `json:"start_date"`
+	EndDate        string    `json:"end_date"`
+	TotalDays      float64   `json:"total_days"`
+	AppliedAt      time.Time `json:"applied_at"`
+	StepNo         int       `json:"step_no"`
+	ApprovalStage  string    `json:"approval_stage"`
+}
+
+// GET /approvals/pending (paging: limit, offset; sort: applied_at, start_date,
+// total_days, employee_name)
+// The requests whose current step waits for the caller, oldest first: for a
+// manager the manager steps of their direct reports, for HR and admins the hr
+// steps and the manager steps of employees without a manager. An approval
+// delegate also gets the manager steps of the delegating manager's reports.
+func (h *LeaveRequestHandler) ListPendingApprovals(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, pendingApprovalSorts, "lr.applied_at, lr.id", "lr.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	var cond string
+	var args []interface{}
+	switch c.GetString("role") {
+	case models.RoleHR, models.RoleAdmin:
+		cond = "(s.approver_role = 'hr' OR e.manager_id IS NULL)"
+	default:
+		cond = "s.approver_role = 'manager' AND (e.manager_id = NULLIF($1, '')::UUID OR is_approval_delegate(NULLIF($1, '')::UUID, e.manager_id))"
+		args = append(args, c.GetString("employee_uuid"))
+	}
+	from := `
+		FROM approval_steps s
+		JOIN leave_requests lr ON lr.id = s.leave_request_id
+		JOIN employees e ON e.id = lr.employee_id
+		JOIN leave_types lt ON lt.id = lr.leave_type_id
+		WHERE s.status = 'pending' AND lr.status = 'pending'
+		  AND s.step_no = (SELECT MIN(p.step_no) FROM approval_steps p WHERE p.leave_request_id = s.leave_request_id AND p.status = 'pending')
+		  AND ` + cond
+	ctx := c.Request.Context()
+	var total int
+	if err := h.read.QueryRow(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch pending approvals")
+		return
+	}
+	n := len(args)
+	rows, err := h.read.Query(ctx, `
+		SELECT lr.id, lr.employee_id, e.name, lr.leave_type_id, lt.name, lr.start_date, lr.end_date, lr.total_days::FLOAT8,
+		       lr.applied_at, s.step_no, 'pending_' || s.approver_role`+from+`
+		ORDER BY `+orderBy+`
+		LIMIT $`+strconv.Itoa(n+1)+` OFFSET $`+strconv.Itoa(n+2), append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch pending approvals")
+		return
+	}
+	defer rows.Close()
+	list := make([]pendingApproval, 0)
+	for rows.Next() {
+		var p pendingApproval
+		var start, end time.Time
+		if err := rows.Scan(&p.LeaveRequestID, &p.EmployeeID, &p.EmployeeName, &p.LeaveTypeID, &p.LeaveTypeName,
+			&start, &end, &p.TotalDays, &p.AppliedAt, &p.StepNo, &p.ApprovalStage); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		p.StartDate, p.EndDate = start.Format("2006-01-02"), end.Format("2006-01-02")
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch pending approvals")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AttendanceHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewAttendanceHandler(pool *pgxpool.Pool) *AttendanceHandler {
+	return &AttendanceHandler{pool: pool}
+}
+
+// POST /attendance/check-in
+func (h *AttendanceHandler) CheckIn(c *gin.Context) {
+	ctx := c.Request.Context()
+	employeeID, err := currentEmployeeID(ctx, h.pool, c)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.InvalidInput, "no employee record linked to this user", "failed to load employee")
+		return
+	}
+
+	var checkIn time.Time
+	err = h.pool.QueryRow(ctx, `
+		INSERT INTO attendance_records (employee_id, work_date, status, check_in_at, source)
+		VALUES ($1, CURRENT_DATE, 'present', NOW(), 'check_in')
+		ON CONFLICT (employee_id, work_date) DO UPDATE
+			SET status='present', check_in_at=COALESCE(attendance_records.check_in_at, EXCLUDED.check_in_at)
+		RETURNING check_in_at
+	`, employeeID).Scan(&checkIn)
+	if err != nil {
+		apierror.Database(c, err, "failed to record check-in")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "checked in", "check_in_at": checkIn})
+}
+
+// POST /attendance/check-out
+func (h *AttendanceHandler) CheckOut(c *gin.Context) {
+	ctx := c.Request.Context()
+	employeeID, err := currentEmployeeID(ctx, h.pool, c)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.InvalidInput, "no employee record linked to this user", "failed to load employee")
+		return
+	}
+
+	var checkOut time.Time
+	err = h.pool.QueryRow(ctx, `
+		UPDATE attendance_records SET check_out_at=NOW()
+		WHERE employee_id=$1 AND work_date=CURRENT_DATE AND check_in_at IS NOT NULL
+		RETURNING check_out_at
+	`, employeeID).Scan(&checkOut)
+	if err == pgx.ErrNoRows {
+		apierror.Respond(c, apierror.InvalidInput, "no check-in recorded for today")
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err, "failed to record check-out")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "checked out", "check_out_at": checkOut})
+}
+
+type attendanceImportRow struct {
+	EmployeeID string `json:"employee_id" binding:"required,uuid"`
+	Date       string `json:"date" binding:"required,datetime=2006-01-02"`
+	Status     string `json:"status" binding:"required"` // present | absent
+}
+
+type attendanceImportDTO struct {
+	Records []attendanceImportRow `json:"records" binding:"required,dive"`
+}
+
+// POST /attendance/import
+// Upserts daily presence records, e.g. exported from a badge system.
+func (h *AttendanceHandler) ImportAttendance(c *gin.Context) {
+	var in attendanceImportDTO
+	if !bindJSON(c, &in) {
+		return
+	}
+	if len(in.Records) == 0 || len(in.Records) > 5000 {
+		apierror.Respond(c, apierror.InvalidInput, "records must contain between 1 and 5000 entries")
+		return
+	}
+
+	// Validate every row up front so the import is all-or-nothing
+	rowErrors := []apierror.FieldError{}
+	dates := make([]time.Time, len(in.Records))
+	for i, r := range in.Records {
+		d, err := time.Parse("2006-01-02", r.Date)
+		if err != nil {
+			rowErrors = append(rowErrors, apierror.FieldError{Field: fmt.Sprintf("records[%d].date", i), Error: "must be YYYY-MM-DD"})
+			continue
+		}
+		if d.After(time.Now()) {
+			rowErrors = append(rowErrors, apierror.FieldError{Field: fmt.Sprintf("records[%d].date", i), Error: "cannot be in the future"})
+			continue
+		}
+		dates[i] = d
+		in.Records[i].Status = strings.ToLower(strings.TrimSpace(r.Status))
+		if !models.IsValidAttendanceStatus(in.Records[i].Status) {
+			rowErrors = append(rowErrors, apierror.FieldError{Field: fmt.Sprintf("records[%d].status", i), Error: "must be present or absent"})
+		}
+	}
+	if len(rowErrors) > 0 {
+		apierror.Fields(c, "invalid attendance records", rowErrors)
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Begin(ctx, h.pool)
+	if err != nil {
+		apierror.Database(c, err, "begin tx failed")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	for i, r := range in.Records {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO attendance_records (employee_id, work_date, status, source)
+			VALUES ($1, $2, $3, 'import')
+			ON CONFLICT (employee_id, work_date) DO UPDATE SET status=EXCLUDED.status, source='import'
+		`, r.EmployeeID, dates[i], r.Status); err != nil {
+			code, msg := apierror.FromDatabase(err, "import failed")
+			apierror.RespondWithDetails(c, code, msg, gin.H{"row": i})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		apierror.Database(c, err, "commit failed")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "attendance imported", "imported": len(in.Records)})
+}
+
+// GET /reports/attendance/discrepancies?from=&to=
+// Reconciles attendance against approved leave for working days (Mon-Fri):
+//   - absent_without_leave: marked absent (or missing on a day attendance was taken) with no approved leave
+//   - present_while_on_leave: marked present on a day covered by approved leave
+//
+// Managers only see their direct reports; HR/Admin see everyone.
+func (h *AttendanceHandler) GetDiscrepancies(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now.Truncate(24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			apierror.Respond(c, apierror.InvalidQuery, "from must be YYYY-MM-DD")
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			apierror.Respond(c, apierror.InvalidQuery, "to must be YYYY-MM-DD")
+			return
+		}
+		to = t
+	}
+	if from.After(to) {
+		apierror.Respond(c, apierror.InvalidQuery, "from cannot be after to")
+		return
+	}
+	if to.Sub(from) > 92*24*time.Hour {
+		apierror.Respond(c, apierror.InvalidQuery, "date range cannot exceed 92 days")
+		return
+	}
+
+	ctx := c.Request.Context()
+	query := `
+		SELECT employee_id, employee_name, manager_id, day, status, kind FROM (
+			SELECT e.id AS employee_id, e.name AS employee_name, e.manager_id, days.day, a.status,
+				CASE
+					WHEN on_leave.yes AND a.status = 'present' THEN 'present_while_on_leave'
+					WHEN NOT on_leave.yes AND COALESCE(a.status, 'absent') = 'absent' THEN 'absent_without_leave'
+				END AS kind
+			FROM employees e
+			CROSS JOIN (
+				SELECT d::DATE AS day FROM generate_series($1::DATE, $2::DATE, interval '1 day') AS d
+				WHERE EXTRACT(ISODOW FROM d) < 6
+			) days
+			LEFT JOIN attendance_records a ON a.employee_id = e.id AND a.work_date = days.day
+			CROSS JOIN LATERAL (
+				SELECT EXISTS (
+					SELECT 1 FROM leave_requests lr
+					WHERE lr.employee_id = e.id AND lr.status = 'approved'
+					  AND days.day BETWEEN lr.start_date AND lr.end_date
+				) AS yes
+			) on_leave
+			WHERE e.is_active = true AND e.joining_date <= days.day
+			  AND (a.id IS NOT NULL OR EXISTS (SELECT 1 FROM attendance_records t WHERE t.work_date = days.day))`
+	args := []interface{}{from, to}
+
+	if role, _ := c.Get("role"); role == models.RoleManager {
+		managerID, err := currentEmployeeID(ctx, h.pool, c)
+		if err != nil {
+			apierror.Lookup(c, err, apierror.Forbidden, "no employee record linked to this user", "failed to load employee")
+			return
+		}
+		query += " AND e.manager_id = $3"
+		args = append(args, managerID)
+	}
+	query += `
+		) x WHERE kind IS NOT NULL
+		ORDER BY day, employee_name`
+
+	rows, err := h.pool.Query(ctx, query, args...)
+	if err != nil {
+		apierror.Database(c, err, "failed to compute attendance discrepancies")
+		return
+	}
+	defer rows.Close()
+
+	result := make([]gin.H, 0)
+	counts := map[string]int{"absent_without_leave": 0, "present_while_on_leave": 0}
+	for rows.Next() {
+		var (
+			employeeID   string
+			employeeName string
+			managerID    *string
+			day          time.Time
+			status       *string
+			kind         string
+		)
+		if err := rows.Scan(&employeeID, &employeeName, &managerID, &day, &status, &kind); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		counts[kind]++
+		result = append(result, gin.H{
+			"employee_id":       employeeID,
+			"employee_name":     employeeName,
+			"manager_id":        managerID,
+			"date":              day.Format("2006-01-02"),
+			"attendance_status": status,
+			"discrepancy":       kind,
+		})
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"from":          from.Format("2006-01-02"),
+		"to":            to.Format("2006-01-02"),
+		"counts":        counts,
+		"discrepancies": result,
+	})
+}
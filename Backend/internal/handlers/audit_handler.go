@@ -1,27 +1,64 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
 	"time"
 
+	"leave-management/internal/apierror"
+	"leave-management/internal/config"
+	"leave-management/internal/jobs"
+	"leave-management/internal/service"
+
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type AuditHandler struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	read      *pgxpool.Pool                  // replica for listing and exporting; same as pool without one
+	retention func() config.RetentionPolicy // AUDIT_RETENTION
+	chains    *service.AuditChains
 }
 
-func NewAuditHandler(pool *pgxpool.Pool) *AuditHandler {
-	return &AuditHandler{pool: pool}
+func NewAuditHandler(pool, read *pgxpool.Pool, retention func() config.RetentionPolicy) *AuditHandler {
+	return &AuditHandler{pool: pool, read: read, retention: retention, chains: service.NewAuditChains(read)}
 }
 
-// GET /audit-logs?table_name=&record_id=&action=&changed_by=&from=&to=&limit=
-func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
-	q := `SELECT id, table_name, record_id, action, old_values, new_values, changed_by, changed_at
-	      FROM audit_logs WHERE 1=1`
+// auditActor is who made an audited change and through which API call; all
+// NULL for changes made outside the API
+type auditActor struct {
+	UserID    *string
+	Role      *string
+	IP        *string
+	Endpoint  *string
+	RequestID *string
+}
+
+// auditColumns are read by the list and export endpoints
+const auditColumns = `id, table_name, record_id, action, old_values, new_values, changed_by, changed_at,
+	actor_user_id, actor_role, ip_address, endpoint, request_id, chain_seq, row_hash`
+
+// auditSource returns the relation the list and export endpoints read: the
+// live audit_logs, or with ?include_archived=true also the entries the
+// archival job has moved to audit_logs_archive, so the full history can be
+// walked in one pass.
+func auditSource(c *gin.Context) (string, error) {
+	archived, err := parseBoolQuery(c, "include_archived")
+	if err != nil || !archived {
+		return "audit_logs", err
+	}
+	return "(SELECT " + auditColumns + " FROM audit_logs UNION ALL SELECT " + auditColumns + " FROM audit_logs_archive) a", nil
+}
+
+// auditFilters builds the WHERE clause shared by the list and export endpoints
+// from the table_name, record_id, action, changed_by, actor_user_id,
+// request_id, from and to query params.
+func auditFilters(c *gin.Context) (string, []interface{}) {
+	q := ""
 	args := []interface{}{}
 	idx := 1
 
@@ -45,6 +82,16 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 		args = append(args, v)
 		idx++
 	}
+	if v := c.Query("actor_user_id"); v != "" {
+		q += " AND actor_user_id=$" + strconv.Itoa(idx)
+		args = append(args, v)
+		idx++
+	}
+	if v := c.Query("request_id"); v != "" {
+		q += " AND request_id=$" + strconv.Itoa(idx)
+		args = append(args, v)
+		idx++
+	}
 	// time range filters (ISO8601 expected)
 	if v := c.Query("from"); v != "" {
 		if t, err := time.Parse(time.RFC3339, v); err == nil {
@@ -60,19 +107,55 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 			idx++
 		}
 	}
+	return q, args
+}
 
-	q += " ORDER BY changed_at DESC"
-	limit := 50
-	if v := c.Query("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			if n > 0 && n <= 200 { limit = n }
+// GET /audit-logs?table_name=&record_id=&action=&changed_by=&actor_user_id=&request_id=&from=&to=&limit=&offset=&cursor=&sort=&include_archived=&count=
+// Walking the whole history is done with cursor pages; count=true adds the
+// total to cursor pages too, which offset pages always carry.
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	page, err := parseKeysetPagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	source, err := auditSource(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	count, err := parseBoolQuery(c, "count")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, customSort, err := parseSort(c, auditLogSorts, "changed_at DESC, id DESC", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	if customSort && page.Cursor != nil {
+		apierror.Respond(c, apierror.InvalidQuery, "cursor pagination only supports the default sort")
+		return
+	}
+	filters, args := auditFilters(c)
+
+	var total int
+	if page.Cursor == nil || count {
+		if err := h.read.QueryRow(c.Request.Context(), "SELECT COUNT(*) FROM "+source+" WHERE 1=1"+filters, args...).Scan(&total); err != nil {
+			apierror.Database(c, err, "failed to count audit logs")
+			return
 		}
 	}
-	q += " LIMIT " + strconv.Itoa(limit)
 
-	rows, err := h.pool.Query(context.Background(), q, args...)
+	keyset, args := page.keyset("changed_at", "id", args)
+	q := "SELECT " + auditColumns + " FROM " + source + " WHERE 1=1" + filters + keyset + " ORDER BY " + orderBy
+	limitClause, args := page.clause(args)
+	q += limitClause
+
+	rows, err := h.read.Query(c.Request.Context(), q, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch audit logs"})
+		apierror.Database(c, err, "failed to fetch audit logs")
 		return
 	}
 	defer rows.Close()
@@ -88,9 +171,13 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 			newValues map[string]interface{}
 			changedBy *string
 			changedAt time.Time
+			actor auditActor
+			chainSeq int64
+			rowHash string
 		)
-		if err := rows.Scan(&id, &tableName, &recordID, &action, &oldValues, &newValues, &changedBy, &changedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "row scan failed"})
+		if err := rows.Scan(&id, &tableName, &recordID, &action, &oldValues, &newValues, &changedBy, &changedAt,
+			&actor.UserID, &actor.Role, &actor.IP, &actor.Endpoint, &actor.RequestID, &chainSeq, &rowHash); err != nil {
+			apierror.Database(c, err, "row scan failed")
 			return
 		}
 		res = append(res, gin.H{
@@ -102,8 +189,181 @@ func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
 			"new_values": newValues,
 			"changed_by": changedBy,
 			"changed_at": changedAt,
+			"actor_user_id": actor.UserID,
+			"actor_role": actor.Role,
+			"ip_address": actor.IP,
+			"endpoint": actor.Endpoint,
+			"request_id": actor.RequestID,
+			"chain_seq": chainSeq,
+			"row_hash": rowHash,
 		})
 	}
 
-	c.JSON(http.StatusOK, res)
+	if customSort {
+		c.JSON(http.StatusOK, gin.H{"data": res, "meta": page.meta(total, len(res))})
+		return
+	}
+	n, meta := page.keysetMeta(total, len(res), func(i int) (time.Time, string) {
+		return res[i]["changed_at"].(time.Time), res[i]["id"].(string)
+	})
+	if page.Cursor != nil && count {
+		meta["total"] = total
+	}
+	c.JSON(http.StatusOK, gin.H{"data": res[:n], "meta": meta})
+}
+
+// auditChange is one field of an audited row that differs between the old and
+// the new image. Old is null for an INSERT and New for a DELETE.
+type auditChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// diffValues compares two row images field by field and returns the fields
+// that differ, ordered by name. Nested JSON values are compared as a whole.
+func diffValues(before, after map[string]interface{}) []auditChange {
+	fields := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		fields = append(fields, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := make([]auditChange, 0)
+	for _, f := range fields {
+		o, n := before[f], after[f]
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, auditChange{Field: f, Old: o, New: n})
+	}
+	return changes
+}
+
+// GET /audit-logs/:id/diff
+// Field-level changes of one audit entry, live or archived: the fields whose
+// value differs between old_values and new_values. An INSERT lists every
+// field with a null old value and a DELETE every field with a null new value.
+func (h *AuditHandler) GetAuditLogDiff(c *gin.Context) {
+	var (
+		tableName, recordID, action string
+		oldValues, newValues        map[string]interface{}
+		changedAt                   time.Time
+		actor                       auditActor
+	)
+	err := h.read.QueryRow(c.Request.Context(), `
+		SELECT table_name, record_id, action, old_values, new_values, changed_at, actor_user_id, endpoint
+		FROM (SELECT `+auditColumns+` FROM audit_logs WHERE id = $1
+		      UNION ALL
+		      SELECT `+auditColumns+` FROM audit_logs_archive WHERE id = $1) a
+		LIMIT 1`, c.Param("id")).Scan(&tableName, &recordID, &action, &oldValues, &newValues, &changedAt, &actor.UserID, &actor.Endpoint)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "audit log not found", "failed to load audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":            c.Param("id"),
+		"table_name":    tableName,
+		"record_id":     recordID,
+		"action":        action,
+		"changed_at":    changedAt,
+		"actor_user_id": actor.UserID,
+		"endpoint":      actor.Endpoint,
+		"changes":       diffValues(oldValues, newValues),
+	})
+}
+
+// GET /audit-logs/verify?table_name=leave_requests
+// Checks the hash chains of the audit trail, live and archived, so auditors
+// can show that no entry was altered, removed or inserted after the fact.
+// Keep head_seq and head_hash from each run: if the entry with that
+// chain_seq later lists a different row_hash, the chain was rewritten.
+func (h *AuditHandler) VerifyAuditLogs(c *gin.Context) {
+	chains, err := h.chains.Verify(c.Request.Context(), c.Query("table_name"))
+	if err != nil {
+		apierror.Database(c, err, "failed to verify audit logs")
+		return
+	}
+	valid := true
+	for _, ch := range chains {
+		valid = valid && ch.Valid
+	}
+	respond(c, http.StatusOK, gin.H{"valid": valid, "verified_at": time.Now().UTC(), "chains": chains})
+}
+
+// POST /audit-logs/retention/run?dry_run=true
+// Applies the audit retention policy now rather than at the next scheduled
+// run. With dry_run=true nothing is changed and the report counts the entries
+// each rule would delete or anonymize.
+func (h *AuditHandler) RunRetention(c *gin.Context) {
+	dryRun, err := parseBoolQuery(c, "dry_run")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	policy := h.retention()
+	results, err := jobs.ApplyAuditRetention(c.Request.Context(), h.pool, policy, dryRun)
+	if err != nil {
+		apierror.Database(c, err, "audit retention failed")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"dry_run": dryRun, "policy": policy.String(), "results": results})
+}
+
+// GET /audit-logs/export (same filters as GET /audit-logs, no row cap)
+// Streams the full filtered result set as CSV for compliance reviews.
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	source, err := auditSource(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	filters, args := auditFilters(c)
+	q := "SELECT " + auditColumns + " FROM " + source + " WHERE 1=1" + filters + " ORDER BY changed_at DESC, id DESC"
+
+	rows, err := h.read.Query(c.Request.Context(), q, args...)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch audit logs")
+		return
+	}
+	defer rows.Close()
+
+	writeCSV(c, "audit-logs", []string{"id", "table_name", "record_id", "action", "old_values", "new_values", "changed_by",
+		"changed_at", "actor_user_id", "actor_role", "ip_address", "endpoint", "request_id", "chain_seq", "row_hash"},
+		rows, func(rows pgx.Rows) ([]string, error) {
+			var (
+				id        string
+				tableName string
+				recordID  string
+				action    string
+				oldValues []byte
+				newValues []byte
+				changedBy *string
+				changedAt time.Time
+				actor     auditActor
+				chainSeq  int64
+				rowHash   string
+			)
+			if err := rows.Scan(&id, &tableName, &recordID, &action, &oldValues, &newValues, &changedBy, &changedAt,
+				&actor.UserID, &actor.Role, &actor.IP, &actor.Endpoint, &actor.RequestID, &chainSeq, &rowHash); err != nil {
+				return nil, err
+			}
+			return []string{id, tableName, recordID, action, string(oldValues), string(newValues), deref(changedBy),
+				changedAt.Format(time.RFC3339), deref(actor.UserID), deref(actor.Role), deref(actor.IP), deref(actor.Endpoint),
+				deref(actor.RequestID), strconv.FormatInt(chainSeq, 10), rowHash}, nil
+		})
+}
+
+// deref returns the string s points to, or "" for NULL
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
@@ -3,26 +3,49 @@ package handlers
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
+	"unicode/utf8"
 
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/jwtkeys"
 	"leave-management/internal/models"
+	"leave-management/internal/siem"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	events *siem.Forwarder          // sign-ins and other auth events; nil when no SIEM is configured
+	keys   func() *jwtkeys.Keyring // signs access tokens, read per request
 }
 
-func NewAuthHandler(pool *pgxpool.Pool) *AuthHandler {
-	return &AuthHandler{pool: pool}
+func NewAuthHandler(pool *pgxpool.Pool, events *siem.Forwarder, keys func() *jwtkeys.Keyring) *AuthHandler {
+	return &AuthHandler{pool: pool, events: events, keys: keys}
+}
+
+// authEvent forwards an authentication event about user to the SIEM
+func (h *AuthHandler) authEvent(c *gin.Context, action, outcome string, user models.User, details map[string]interface{}) {
+	h.events.Publish(siem.Event{
+		Category: "auth", Action: action, Outcome: outcome,
+		OrgID: user.OrgID, UserID: user.ID, Role: user.Role,
+		IP: c.ClientIP(), RequestID: c.GetString("request_id"), Details: details,
+	})
+}
+
+// authenticatedUser is the caller of an authenticated request, as far as
+// authEvent needs it
+func authenticatedUser(c *gin.Context) models.User {
+	return models.User{ID: c.GetString("user_id"), OrgID: c.GetString("org_id"), Role: c.GetString("role")}
 }
 
 // Register creates a new user account
@@ -35,64 +58,65 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Name       string `json:"name" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
+	// the caller has no organization yet: it comes from the employee record
+	ctx := db.AsService(c.Request.Context())
 
 	// Check if employee exists
-	var employeeID string
-	err := h.pool.QueryRow(context.Background(),
-		"SELECT id FROM employees WHERE employee_id = $1 AND email = $2",
-		input.EmployeeID, input.Email).Scan(&employeeID)
+	var employeeID, orgID string
+	err := h.pool.QueryRow(ctx,
+		"SELECT id, org_id FROM employees WHERE employee_id = $1 AND email = $2",
+		input.EmployeeID, input.Email).Scan(&employeeID, &orgID)
 	
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Employee not found or email mismatch"})
+		apierror.Lookup(c, err, apierror.ReferenceNotFound, "Employee not found or email mismatch", "Failed to verify employee")
 		return
 	}
 
 	// Check if user already exists
 	var existingUser string
-	err = h.pool.QueryRow(context.Background(),
-		"SELECT id FROM users WHERE email = $1 OR employee_id = $2",
-		input.Email, input.EmployeeID).Scan(&existingUser)
+	err = h.pool.QueryRow(ctx,
+		"SELECT id FROM users WHERE email = $1 OR (org_id = $2 AND employee_id = $3)",
+		input.Email, orgID, input.EmployeeID).Scan(&existingUser)
 	
 	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+		apierror.Respond(c, apierror.AlreadyExists, "User already exists")
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Respond(c, apierror.Internal, "Failed to hash password")
 		return
 	}
 
 	// Get employee role
 	var role string
-	err = h.pool.QueryRow(context.Background(),
+	err = h.pool.QueryRow(ctx,
 		"SELECT role FROM employees WHERE id = $1", employeeID).Scan(&role)
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get employee role"})
+		apierror.Database(c, err, "Failed to get employee role")
 		return
 	}
 
 	// Create user
 	var userID string
-	err = h.pool.QueryRow(context.Background(),
-		`INSERT INTO users (employee_id, email, password_hash, role, is_active, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, true, NOW(), NOW())
+	err = h.pool.QueryRow(ctx,
+		`INSERT INTO users (org_id, employee_id, email, password_hash, role, is_active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, true, NOW(), NOW())
 		 RETURNING id`,
-		input.EmployeeID, input.Email, string(hashedPassword), role).Scan(&userID)
+		orgID, input.EmployeeID, input.Email, string(hashedPassword), role).Scan(&userID)
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user", "details": err.Error()})
+		apierror.Database(c, err, "Failed to create user")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	respond(c, http.StatusCreated, gin.H{
 		"message": "User registered successfully",
 		"user_id": userID,
 	})
@@ -103,140 +127,188 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var input models.LoginRequest
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
+	// emails are unique across organizations, so the user is found before
+	// the request is scoped to one
+	ctx := db.AsService(c.Request.Context())
 
 	// Get user by email
 	var user models.User
-	err := h.pool.QueryRow(context.Background(),
-		`SELECT id, employee_id, email, password_hash, role, is_active, last_login_at, created_at, updated_at
+	err := h.pool.QueryRow(ctx,
+		`SELECT id, org_id, employee_id, email, password_hash, role,
+		        is_active AND (SELECT o.is_active FROM organizations o WHERE o.id = org_id), last_login_at, created_at, updated_at
 		 FROM users WHERE email = $1`,
 		input.Email).Scan(
-		&user.ID, &user.EmployeeID, &user.Email, &user.PasswordHash,
+		&user.ID, &user.OrgID, &user.EmployeeID, &user.Email, &user.PasswordHash,
 		&user.Role, &user.IsActive, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt)
 	
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if apierror.IsNoRows(err) {
+			h.authEvent(c, "login", "failure", user, gin.H{"email": input.Email, "reason": "unknown_user"})
+		}
+		apierror.Lookup(c, err, apierror.InvalidCredentials, "Invalid credentials", "Failed to load user")
 		return
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+		h.authEvent(c, "login", "failure", user, gin.H{"email": input.Email, "reason": "account_deactivated"})
+		apierror.Respond(c, apierror.AccountDeactivated, "Account is deactivated")
 		return
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		h.authEvent(c, "login", "failure", user, gin.H{"email": input.Email, "reason": "invalid_password"})
+		apierror.Respond(c, apierror.InvalidCredentials, "Invalid credentials")
 		return
 	}
 
+	h.signIn(ctx, c, user, gin.H{"email": user.Email})
+}
+
+// signIn answers a successful login of user with a new access token and the
+// first refresh token of a new family. details go with the auth event.
+func (h *AuthHandler) signIn(ctx context.Context, c *gin.Context, user models.User, details gin.H) {
 	// Generate JWT token
 	token, err := h.generateJWTToken(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Respond(c, apierror.Internal, "Failed to generate token")
 		return
 	}
 
-	// Generate refresh token
-	refreshToken, err := h.generateRefreshToken(user.ID)
+	// Generate refresh token, the first of a new family
+	refreshToken, err := h.generateRefreshToken(ctx, h.pool, user, session{
+		UserAgent: truncate(c.Request.UserAgent(), maxUserAgentLength), IP: c.ClientIP(), SignedInAt: time.Now(),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		apierror.Respond(c, apierror.Internal, "Failed to generate refresh token")
 		return
 	}
 
 	// Update last login time
-	_, err = h.pool.Exec(context.Background(),
+	_, err = h.pool.Exec(ctx,
 		"UPDATE users SET last_login_at = NOW() WHERE id = $1", user.ID)
 	
 	if err != nil {
 		// Log error but don't fail the login
-		fmt.Printf("Failed to update last login time: %v\n", err)
+		slog.WarnContext(ctx, "failed to update last login time", "error", err, "request_id", c.GetString("request_id"))
 	}
+	h.authEvent(c, "login", "success", user, details)
 
-	c.JSON(http.StatusOK, models.LoginResponse{
+	respond(c, http.StatusOK, models.LoginResponse{
 		Token:        token,
 		RefreshToken: refreshToken,
 		User:         user,
 	})
 }
 
+// errInvalidRefreshToken aborts a refresh with an unknown or revoked token
+var errInvalidRefreshToken = errors.New("invalid refresh token")
+
 // RefreshToken generates a new JWT token using refresh token
 // POST /auth/refresh
+// The refresh token is rotated: the response carries its successor and the
+// old one stops working. Presenting a rotated token again revokes every token
+// of its family, so a stolen token is only good until either party uses it.
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var input models.RefreshTokenRequest
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
+	ctx := db.AsService(c.Request.Context())
 
-	// Validate refresh token
-	var userID string
-	var expiresAt time.Time
-	err := h.pool.QueryRow(context.Background(),
-		"SELECT user_id, expires_at FROM refresh_tokens WHERE token = $1 AND is_revoked = false",
-		input.RefreshToken).Scan(&userID, &expiresAt)
-	
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
-		return
-	}
+	var user models.User
+	var token, refreshToken string
+	var reused, expired bool
+	err := db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		user, reused, expired = models.User{}, false, false
+		// Validate refresh token; the row lock makes concurrent refreshes with
+		// the same token take turns, so only the first one rotates it
+		var tokenID string
+		var s session
+		var expiresAt time.Time
+		var revoked bool
+		var rotatedAt *time.Time
+		err := tx.QueryRow(ctx,
+			`SELECT id, family_id, user_id, expires_at, COALESCE(is_revoked, false), rotated_at,
+			        COALESCE(user_agent, ''), COALESCE(ip_address, ''), signed_in_at
+			 FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`,
+			hashRefreshToken(input.RefreshToken)).Scan(&tokenID, &s.FamilyID, &user.ID, &expiresAt, &revoked, &rotatedAt,
+			&s.UserAgent, &s.IP, &s.SignedInAt)
+		if apierror.IsNoRows(err) {
+			return errInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if rotatedAt != nil {
+			// committed, unlike an error, so the revocation sticks
+			reused = true
+			_, err := tx.Exec(ctx, "UPDATE refresh_tokens SET is_revoked = true WHERE family_id = $1", s.FamilyID)
+			return err
+		}
+		if revoked {
+			return errInvalidRefreshToken
+		}
+		// Check if refresh token is expired
+		if time.Now().After(expiresAt) {
+			expired = true
+			return nil
+		}
+
+		// Get user details
+		err = tx.QueryRow(ctx,
+			`SELECT id, org_id, employee_id, email, password_hash, role, is_active, last_login_at, created_at, updated_at
+			 FROM users WHERE id = $1`,
+			user.ID).Scan(
+			&user.ID, &user.OrgID, &user.EmployeeID, &user.Email, &user.PasswordHash,
+			&user.Role, &user.IsActive, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil || !user.IsActive {
+			return err
+		}
+
+		// Generate new JWT token
+		if token, err = h.generateJWTToken(user); err != nil {
+			return err
+		}
+		// Replace the refresh token with the next one of its family
+		if refreshToken, err = h.generateRefreshToken(ctx, tx, user, s); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			"UPDATE refresh_tokens SET is_revoked = true, rotated_at = NOW() WHERE id = $1", tokenID)
+		return err
+	})
 
-	// Check if refresh token is expired
-	if time.Now().After(expiresAt) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+	switch {
+	case errors.Is(err, errInvalidRefreshToken):
+		h.authEvent(c, "refresh", "failure", models.User{ID: user.ID}, gin.H{"reason": "invalid_token"})
+		apierror.Respond(c, apierror.InvalidToken, "Invalid refresh token")
 		return
-	}
-
-	// Get user details
-	var user models.User
-	err = h.pool.QueryRow(context.Background(),
-		`SELECT id, employee_id, email, password_hash, role, is_active, last_login_at, created_at, updated_at
-		 FROM users WHERE id = $1`,
-		userID).Scan(
-		&user.ID, &user.EmployeeID, &user.Email, &user.PasswordHash,
-		&user.Role, &user.IsActive, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt)
-	
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	case err != nil:
+		apierror.Lookup(c, err, apierror.Unauthenticated, "User not found", "Failed to refresh token")
 		return
-	}
-
-	// Check if user is active
-	if !user.IsActive {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deactivated"})
+	case reused:
+		h.authEvent(c, "refresh", "failure", models.User{ID: user.ID}, gin.H{"reason": "token_reused"})
+		apierror.Respond(c, apierror.InvalidToken, "Refresh token was already used; the session has been revoked")
 		return
-	}
-
-	// Generate new JWT token
-	token, err := h.generateJWTToken(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	case expired:
+		h.authEvent(c, "refresh", "failure", models.User{ID: user.ID}, gin.H{"reason": "token_expired"})
+		apierror.Respond(c, apierror.TokenExpired, "Refresh token expired")
 		return
-	}
-
-	// Generate new refresh token
-	refreshToken, err := h.generateRefreshToken(user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+	case !user.IsActive:
+		h.authEvent(c, "refresh", "failure", user, gin.H{"reason": "account_deactivated"})
+		apierror.Respond(c, apierror.AccountDeactivated, "Account is deactivated")
 		return
 	}
+	h.authEvent(c, "refresh", "success", user, nil)
 
-	// Revoke old refresh token
-	_, err = h.pool.Exec(context.Background(),
-		"UPDATE refresh_tokens SET is_revoked = true WHERE token = $1", input.RefreshToken)
-	
-	if err != nil {
-		// Log error but don't fail the refresh
-		fmt.Printf("Failed to revoke old refresh token: %v\n", err)
-	}
-
-	c.JSON(http.StatusOK, models.LoginResponse{
+	respond(c, http.StatusOK, models.LoginResponse{
 		Token:        token,
 		RefreshToken: refreshToken,
 		User:         user,
@@ -249,55 +321,56 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	
 	var input models.ChangePasswordRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
 	// Get current password hash
 	var currentPasswordHash string
-	err := h.pool.QueryRow(context.Background(),
+	err := h.pool.QueryRow(c.Request.Context(),
 		"SELECT password_hash FROM users WHERE id = $1", userID).Scan(&currentPasswordHash)
 	
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		apierror.Lookup(c, err, apierror.NotFound, "User not found", "Failed to load user")
 		return
 	}
 
 	// Verify current password
 	err = bcrypt.CompareHashAndPassword([]byte(currentPasswordHash), []byte(input.CurrentPassword))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		h.authEvent(c, "change_password", "failure", authenticatedUser(c), gin.H{"reason": "incorrect_password"})
+		apierror.Respond(c, apierror.IncorrectPassword, "Current password is incorrect")
 		return
 	}
 
 	// Hash new password
 	newPasswordHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Respond(c, apierror.Internal, "Failed to hash password")
 		return
 	}
 
 	// Update password
-	_, err = h.pool.Exec(context.Background(),
+	_, err = h.pool.Exec(c.Request.Context(),
 		"UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2",
 		string(newPasswordHash), userID)
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		apierror.Database(c, err, "Failed to update password")
 		return
 	}
 
 	// Revoke all refresh tokens for this user
-	_, err = h.pool.Exec(context.Background(),
+	_, err = h.pool.Exec(c.Request.Context(),
 		"UPDATE refresh_tokens SET is_revoked = true WHERE user_id = $1", userID)
 	
 	if err != nil {
 		// Log error but don't fail the password change
-		fmt.Printf("Failed to revoke refresh tokens: %v\n", err)
+		slog.WarnContext(c.Request.Context(), "failed to revoke refresh tokens", "error", err, "request_id", c.GetString("request_id"))
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	h.authEvent(c, "change_password", "success", authenticatedUser(c), nil)
+	respond(c, http.StatusOK, gin.H{"message": "Password changed successfully"})
 }
 
 // Logout revokes the current refresh token
@@ -310,22 +383,88 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
 	// Revoke refresh token
-	_, err := h.pool.Exec(context.Background(),
-		"UPDATE refresh_tokens SET is_revoked = true WHERE token = $1 AND user_id = $2",
-		input.RefreshToken, userID)
+	_, err := h.pool.Exec(c.Request.Context(),
+		"UPDATE refresh_tokens SET is_revoked = true WHERE token_hash = $1 AND user_id = $2",
+		hashRefreshToken(input.RefreshToken), userID)
 	
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		apierror.Database(c, err, "Failed to logout")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	h.authEvent(c, "logout", "success", authenticatedUser(c), nil)
+	respond(c, http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// sessionItem is a login of the caller that can still be refreshed; its ID
+// stays the same across refreshes
+type sessionItem struct {
+	ID              string    `json:"id"`
+	Device          *string   `json:"device"` // the User-Agent of the login
+	IPAddress       *string   `json:"ip_address"`
+	CreatedAt       time.Time `json:"created_at"`        // the login
+	LastRefreshedAt time.Time `json:"last_refreshed_at"` // the login, until the first refresh
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// ListSessions lists the caller's active sessions, most recent login first
+// GET /auth/sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	rows, err := h.pool.Query(c.Request.Context(),
+		`SELECT family_id, user_agent, ip_address, signed_in_at, created_at, expires_at
+		 FROM refresh_tokens
+		 WHERE user_id = $1 AND is_revoked IS NOT TRUE AND expires_at > NOW()
+		 ORDER BY signed_in_at DESC, family_id`,
+		c.GetString("user_id"))
+	if err != nil {
+		apierror.Database(c, err, "Failed to list sessions")
+		return
+	}
+	defer rows.Close()
+	sessions := make([]sessionItem, 0)
+	for rows.Next() {
+		var s sessionItem
+		if err := rows.Scan(&s.ID, &s.Device, &s.IPAddress, &s.CreatedAt, &s.LastRefreshedAt, &s.ExpiresAt); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "Failed to list sessions")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"data": sessions})
+}
+
+// RevokeSession signs the caller out of one session: its refresh token is
+// revoked, so the device cannot get new access tokens; the one it holds
+// works until it expires
+// DELETE /auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "Session not found")
+		return
+	}
+	ct, err := h.pool.Exec(c.Request.Context(),
+		"UPDATE refresh_tokens SET is_revoked = true WHERE family_id = $1 AND user_id = $2 AND is_revoked IS NOT TRUE AND expires_at > NOW()",
+		id, c.GetString("user_id"))
+	if err != nil {
+		apierror.Database(c, err, "Failed to revoke session")
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		apierror.Respond(c, apierror.NotFound, "Session not found")
+		return
+	}
+	h.authEvent(c, "logout", "success", authenticatedUser(c), gin.H{"session_id": id})
+	respond(c, http.StatusOK, gin.H{"message": "Session revoked"})
 }
 
 // GetProfile returns the current user's profile
@@ -334,53 +473,51 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	
 	var user models.User
-	err := h.pool.QueryRow(context.Background(),
-		`SELECT id, employee_id, email, password_hash, role, is_active, last_login_at, created_at, updated_at
+	err := h.pool.QueryRow(c.Request.Context(),
+		`SELECT id, org_id, employee_id, email, password_hash, role, is_active, last_login_at, created_at, updated_at
 		 FROM users WHERE id = $1`,
 		userID).Scan(
-		&user.ID, &user.EmployeeID, &user.Email, &user.PasswordHash,
+		&user.ID, &user.OrgID, &user.EmployeeID, &user.Email, &user.PasswordHash,
 		&user.Role, &user.IsActive, &user.LastLoginAt, &user.CreatedAt, &user.UpdatedAt)
 	
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		apierror.Lookup(c, err, apierror.NotFound, "User not found", "Failed to load user")
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	respond(c, http.StatusOK, user)
 }
 
-// generateJWTToken creates a new JWT token for the user
+// generateJWTToken creates a new JWT token for the user, signed with the
+// current secret and valid for ACCESS_TOKEN_TTL
 func (h *AuthHandler) generateJWTToken(user models.User) (string, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key-change-in-production" // Default for development
-	}
-
-	// Create claims
+	keys := h.keys()
 	claims := models.JWTClaims{
-		UserID:     user.ID,
-		Email:      user.Email,
-		Role:       user.Role,
-		EmployeeID: user.EmployeeID,
-		Exp:        time.Now().Add(24 * time.Hour).Unix(), // 24 hours
-		Iat:        time.Now().Unix(),
+		UserID:           user.ID,
+		OrgID:            user.OrgID,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmployeeID:       user.EmployeeID,
+		RegisteredClaims: keys.Registered(user.ID),
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	
-	// Sign token
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	if err != nil {
-		return "", err
-	}
+	return keys.Sign(claims)
+}
+
+// maxUserAgentLength bounds the User-Agent kept with a session
+const maxUserAgentLength = 512
 
-	return tokenString, nil
+// session is the login a refresh token belongs to
+type session struct {
+	FamilyID   string // empty for a new login
+	UserAgent  string
+	IP         string
+	SignedInAt time.Time
 }
 
-// generateRefreshToken creates a new refresh token for the user
-func (h *AuthHandler) generateRefreshToken(userID string) (string, error) {
+// generateRefreshToken creates a new refresh token for the user in session s,
+// starting a new family when s has none
+func (h *AuthHandler) generateRefreshToken(ctx context.Context, q db.Querier, user models.User, s session) (string, error) {
 	// Generate random token
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -388,15 +525,32 @@ func (h *AuthHandler) generateRefreshToken(userID string) (string, error) {
 	}
 	token := hex.EncodeToString(bytes)
 
-	// Store refresh token in database
-	_, err := h.pool.Exec(context.Background(),
-		`INSERT INTO refresh_tokens (token, user_id, expires_at, is_revoked, created_at)
-		 VALUES ($1, $2, $3, false, NOW())`,
-		token, userID, time.Now().Add(7*24*time.Hour)) // 7 days
-	
+	// Store the token's hash in database
+	_, err := q.Exec(ctx,
+		`INSERT INTO refresh_tokens (org_id, token_hash, family_id, user_id, expires_at, is_revoked,
+		                             user_agent, ip_address, signed_in_at, created_at)
+		 VALUES ($1, $2, COALESCE(NULLIF($3, '')::UUID, gen_random_uuid()), $4, $5, false,
+		         NULLIF($6, ''), NULLIF($7, ''), $8, NOW())`,
+		user.OrgID, hashRefreshToken(token), s.FamilyID, user.ID, time.Now().Add(7*24*time.Hour), // 7 days
+		s.UserAgent, s.IP, s.SignedInAt)
 	if err != nil {
 		return "", err
 	}
 
 	return token, nil
 }
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// hashRefreshToken is the form a refresh token is stored and looked up in.
+// Tokens are random, so an unsalted hash suffices.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
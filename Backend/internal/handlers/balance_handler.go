@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/repository"
+	"leave-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BalanceHandler reconciles the leave balances with the leave requests
+type BalanceHandler struct {
+	balances *service.Balances
+}
+
+func NewBalanceHandler(pool *pgxpool.Pool) *BalanceHandler {
+	return &BalanceHandler{balances: service.NewBalances(repository.New(pool))}
+}
+
+// POST /admin/leave-balances/recalculate (year, default this year;
+// dry_run=true reports the discrepancies without fixing them;
+// overwrite_used_days=true replaces used_days that differ)
+// Recomputes the pending_days of the caller's organization's balances of
+// year from the pending leave requests reserved in them and lists every
+// balance that did not add up. used_days that differ from the approved
+// requests charged are only reported, as HR may have set them by hand,
+// unless overwrite_used_days is set.
+func (h *BalanceHandler) RecalculateBalances(c *gin.Context) {
+	dryRun, err := parseBoolQuery(c, "dry_run")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	overwriteUsed, err := parseBoolQuery(c, "overwrite_used_days")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	year := time.Now().Year()
+	if v := c.Query("year"); v != "" {
+		current := year
+		if year, err = strconv.Atoi(v); err != nil || year < 2020 || year > current {
+			apierror.Respond(c, apierror.InvalidQuery, "year must be between 2020 and the current year")
+			return
+		}
+	}
+	res, err := h.balances.Recalculate(c.Request.Context(), year, dryRun, overwriteUsed)
+	if err != nil {
+		apierror.Database(c, err, "leave balance recalculation failed")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"dry_run": dryRun, "overwrite_used_days": overwriteUsed, "recalculation": res})
+}
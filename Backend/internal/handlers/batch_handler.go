@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatchHandler replays sub-requests against the router so several API calls
+// can be made in one round trip
+type BatchHandler struct {
+	router      http.Handler
+	maxRequests func() int
+}
+
+func NewBatchHandler(router http.Handler, maxRequests func() int) *BatchHandler {
+	return &BatchHandler{router: router, maxRequests: maxRequests}
+}
+
+type batchOperation struct {
+	Method string          `json:"method" binding:"required"`
+	Path   string          `json:"path" binding:"required"`
+	Body   json.RawMessage `json:"body"`
+}
+
+var batchMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// POST /batch
+// Runs up to maxRequests sub-requests in order with the caller's credentials and
+// returns their results in the same order. A failing sub-request does not stop
+// the rest; check each result's status.
+func (h *BatchHandler) Batch(c *gin.Context) {
+	var in struct {
+		Requests []batchOperation `json:"requests" binding:"required,dive"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	if limit := h.maxRequests(); len(in.Requests) == 0 || len(in.Requests) > limit {
+		apierror.Respond(c, apierror.InvalidInput, fmt.Sprintf("requests must contain between 1 and %d operations", limit))
+		return
+	}
+	for i, op := range in.Requests {
+		method := strings.ToUpper(op.Method)
+		if !batchMethods[method] {
+			apierror.Respond(c, apierror.InvalidInput, fmt.Sprintf("requests[%d]: unsupported method %s", i, op.Method))
+			return
+		}
+		if !strings.HasPrefix(op.Path, "/") || strings.HasPrefix(op.Path, "//") {
+			apierror.Respond(c, apierror.InvalidInput, fmt.Sprintf("requests[%d]: path must be relative to the API root", i))
+			return
+		}
+		if p := strings.SplitN(op.Path, "?", 2)[0]; p == "/batch" || strings.HasPrefix(p, "/batch/") {
+			apierror.Respond(c, apierror.InvalidInput, fmt.Sprintf("requests[%d]: batches cannot be nested", i))
+			return
+		}
+		in.Requests[i].Method = method
+	}
+
+	results := make([]gin.H, 0, len(in.Requests))
+	for _, op := range in.Requests {
+		results = append(results, h.run(c, op))
+	}
+	respond(c, http.StatusOK, gin.H{"results": results})
+}
+
+// run executes one sub-request, forwarding the caller's credentials, envelope
+// choice and language
+func (h *BatchHandler) run(c *gin.Context, op batchOperation) gin.H {
+	req, err := http.NewRequestWithContext(c.Request.Context(), op.Method, op.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		return gin.H{"method": op.Method, "path": op.Path, "status": http.StatusBadRequest, "body": gin.H{"error": "invalid request"}}
+	}
+	req.Header.Set("Authorization", c.GetHeader("Authorization"))
+	for _, name := range []string{middleware.EnvelopeHeader, "Accept-Language"} {
+		if v := c.GetHeader(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+	if len(op.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.RemoteAddr = c.Request.RemoteAddr
+
+	rec := &batchRecorder{header: http.Header{}, status: http.StatusOK}
+	h.router.ServeHTTP(rec, req)
+
+	var payload interface{}
+	if raw := rec.body.Bytes(); len(raw) > 0 {
+		if json.Valid(raw) {
+			payload = json.RawMessage(raw)
+		} else {
+			payload = string(raw)
+		}
+	}
+	return gin.H{"method": op.Method, "path": op.Path, "status": rec.status, "body": payload}
+}
+
+// batchRecorder is a minimal in-memory http.ResponseWriter
+type batchRecorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func (r *batchRecorder) Header() http.Header { return r.header }
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
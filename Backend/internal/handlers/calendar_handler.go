@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CalendarHandler serves the team leave calendar
+type CalendarHandler struct {
+	read *pgxpool.Pool // replica when one is configured
+}
+
+func NewCalendarHandler(read *pgxpool.Pool) *CalendarHandler {
+	return &CalendarHandler{read: read}
+}
+
+type calendarEntry struct {
+	LeaveRequestID string `json:"leave_request_id"`
+	EmployeeID     string `json:"employee_id"`
+	EmployeeName   string `json:"employee_name"`
+	LeaveTypeName  string `json:"leave_type_name"`
+	Status         string `json:"status"` // approved or pending
+	DurationUnit   string `json:"duration_unit"`
+}
+
+type calendarDay struct {
+	Date     string          `json:"date"`
+	Weekend  bool            `json:"weekend"`
+	Holiday  *string         `json:"holiday"`  // its name
+	OnLeave  int             `json:"on_leave"` // employees with approved leave
+	Pending  int             `json:"pending"`  // employees with pending requests
+	Entries  []calendarEntry `json:"entries"`
+	employee map[string]bool // counted in OnLeave
+}
+
+// GET /calendar/team?month=YYYY-MM (default the current month; HR and admins
+// may add department_id)
+// Every day of the month with the approved and pending leave on it: of the
+// caller's direct reports for a manager, of the whole organization for HR and
+// admins. team_size is the number of active employees covered, so that
+// team_size - on_leave is who is left on a day.
+func (h *CalendarHandler) GetTeamCalendar(c *gin.Context) {
+	now := time.Now()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := c.Query("month"); v != "" {
+		t, err := time.Parse("2006-01", v)
+		if err != nil || t.Year() < 2020 || t.Year() > 2050 {
+			apierror.Respond(c, apierror.InvalidQuery, "month must be YYYY-MM")
+			return
+		}
+		first = t
+	}
+	last := first.AddDate(0, 1, -1)
+
+	scope := "team"
+	cond := "e.manager_id = NULLIF($3, '')::UUID"
+	arg := c.GetString("employee_uuid")
+	if role := c.GetString("role"); role == models.RoleHR || role == models.RoleAdmin {
+		scope = "organization"
+		cond = "($3 = '' OR e.department_id = NULLIF($3, '')::UUID)"
+		arg = c.Query("department_id")
+		if arg != "" && !isUUID(arg) {
+			apierror.Respond(c, apierror.InvalidQuery, "department_id must be a UUID")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	var teamSize int
+	if err := h.read.QueryRow(ctx, "SELECT COUNT(*) FROM employees e WHERE e.is_active AND $1::DATE <= $2::DATE AND "+cond,
+		first, last, arg).Scan(&teamSize); err != nil {
+		apierror.Database(c, err, "failed to fetch team calendar")
+		return
+	}
+
+	days := make([]*calendarDay, 0, last.Day())
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		days = append(days, &calendarDay{
+			Date:     d.Format("2006-01-02"),
+			Weekend:  d.Weekday() == time.Saturday || d.Weekday() == time.Sunday,
+			Entries:  make([]calendarEntry, 0),
+			employee: map[string]bool{},
+		})
+	}
+
+	rows, err := h.read.Query(ctx, "SELECT holiday_date, name FROM holidays WHERE holiday_date BETWEEN $1 AND $2", first, last)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch team calendar")
+		return
+	}
+	for rows.Next() {
+		var date time.Time
+		var name string
+		if err := rows.Scan(&date, &name); err != nil {
+			rows.Close()
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		days[date.Day()-1].Holiday = &name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch team calendar")
+		return
+	}
+
+	rows, err = h.read.Query(ctx, `
+		SELECT d::DATE, lr.id, lr.employee_id, e.name, lt.name, lr.status::TEXT, lr.duration_unit::TEXT
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		JOIN leave_types lt ON lt.id = lr.leave_type_id
+		CROSS JOIN generate_series(GREATEST(lr.start_date, $1::DATE), LEAST(lr.end_date, $2::DATE), INTERVAL '1 day') d
+		WHERE lr.status IN ('approved', 'pending') AND lr.start_date <= $2 AND lr.end_date >= $1
+		  AND e.is_active AND `+cond+`
+		ORDER BY d, lr.status, e.name, lr.id`, first, last, arg)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch team calendar")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var date time.Time
+		var e calendarEntry
+		if err := rows.Scan(&date, &e.LeaveRequestID, &e.EmployeeID, &e.EmployeeName, &e.LeaveTypeName, &e.Status, &e.DurationUnit); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		day := days[date.Day()-1]
+		day.Entries = append(day.Entries, e)
+		if e.Status == "approved" {
+			if !day.employee[e.EmployeeID] {
+				day.employee[e.EmployeeID] = true
+				day.OnLeave++
+			}
+		} else {
+			day.Pending++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch team calendar")
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"month":     first.Format("2006-01"),
+		"scope":     scope,
+		"team_size": teamSize,
+		"days":      days,
+	})
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/chat"
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+	"leave-management/internal/service"
+	"leave-management/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ChatHandler manages the organization's Slack and Microsoft Teams
+// integrations, which are posted the leave requests that wait for an
+// approver, and takes the clicks on the messages' Approve and Reject buttons
+// (see internal/chat)
+type ChatHandler struct {
+	pool        *pgxpool.Pool
+	leave       *LeaveRequestHandler
+	client      *chat.Client
+	teams       *chat.TeamsVerifier
+	callbackURL func() string // CHAT_CALLBACK_URL
+}
+
+func NewChatHandler(pool *pgxpool.Pool, leave *LeaveRequestHandler, timeout time.Duration, callbackURL func() string) *ChatHandler {
+	client := chat.NewClient(timeout)
+	return &ChatHandler{pool: pool, leave: leave, client: client, teams: chat.NewTeamsVerifier(client), callbackURL: callbackURL}
+}
+
+// chatIntegration leaves out the webhook URL, signing secret and bot token,
+// which are credentials
+type chatIntegration struct {
+	ID               string    `json:"id"`
+	DepartmentID     *string   `json:"department_id"` // null for the organization's default
+	Provider         string    `json:"provider"`
+	HasSigningSecret bool      `json:"has_signing_secret"`
+	HasBotToken      bool      `json:"has_bot_token"`
+	IsActive         bool      `json:"is_active"`
+	ActionURL        *string   `json:"action_url"` // where the buttons call back; null without CHAT_CALLBACK_URL
+	CreatedBy        *string   `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+const chatIntegrationColumns = `id, department_id, provider, signing_secret IS NOT NULL, bot_token IS NOT NULL, is_active,
+	created_by, created_at, updated_at`
+
+func (h *ChatHandler) scan(row pgx.Row) (chatIntegration, error) {
+	var ci chatIntegration
+	err := row.Scan(&ci.ID, &ci.DepartmentID, &ci.Provider, &ci.HasSigningSecret, &ci.HasBotToken, &ci.IsActive,
+		&ci.CreatedBy, &ci.CreatedAt, &ci.UpdatedAt)
+	if u := chat.ActionURL(h.callbackURL(), ci.ID); u != "" {
+		ci.ActionURL = &u
+	}
+	return ci, err
+}
+
+// GET /chat-integrations (paging: limit, offset; sort: provider, created_at)
+func (h *ChatHandler) ListChatIntegrations(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, chatIntegrationSorts, "created_at, id", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM chat_integrations").Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch chat integrations")
+		return
+	}
+	rows, err := h.pool.Query(ctx, "SELECT "+chatIntegrationColumns+` FROM chat_integrations
+		ORDER BY `+orderBy+` LIMIT $1 OFFSET $2`, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch chat integrations")
+		return
+	}
+	defer rows.Close()
+	list := make([]chatIntegration, 0)
+	for rows.Next() {
+		ci, err := h.scan(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, ci)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch chat integrations")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// POST /chat-integrations
+// Connects an incoming webhook of provider (slack or teams). With
+// department_id it takes the requests of that department's employees,
+// without one those of the departments that have none. A Slack integration
+// needs the app's signing_secret and a bot_token for the buttons.
+func (h *ChatHandler) CreateChatIntegration(c *gin.Context) {
+	var in struct {
+		DepartmentID  *string `json:"department_id" binding:"omitempty,uuid"`
+		Provider      string  `json:"provider" binding:"required,oneof=slack teams"`
+		WebhookURL    string  `json:"webhook_url" binding:"required,max=2000"`
+		SigningSecret *string `json:"signing_secret" binding:"omitempty,max=255"`
+		BotToken      *string `json:"bot_token" binding:"omitempty,max=255"`
+		IsActive      *bool   `json:"is_active"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	in.WebhookURL = strings.TrimSpace(in.WebhookURL)
+	if err := webhook.ValidateURL(in.WebhookURL); err != nil {
+		apierror.Fields(c, "invalid input", []apierror.FieldError{{Field: "webhook_url", Error: err.Error()}})
+		return
+	}
+	ci, err := h.scan(h.pool.QueryRow(c.Request.Context(), `
+		INSERT INTO chat_integrations (department_id, provider, webhook_url, signing_secret, bot_token, is_active, created_by)
+		VALUES ($1, $2, $3, NULLIF(TRIM($4), ''), NULLIF(TRIM($5), ''), COALESCE($6, TRUE), $7)
+		RETURNING `+chatIntegrationColumns,
+		in.DepartmentID, in.Provider, in.WebhookURL, in.SigningSecret, in.BotToken, in.IsActive, c.GetString("user_id")))
+	if err != nil {
+		apierror.Database(c, err, "failed to create chat integration")
+		return
+	}
+	respond(c, http.StatusCreated, ci)
+}
+
+// GET /chat-integrations/:id
+func (h *ChatHandler) GetChatIntegration(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "chat integration not found")
+		return
+	}
+	ci, err := h.scan(h.pool.QueryRow(c.Request.Context(),
+		"SELECT "+chatIntegrationColumns+" FROM chat_integrations WHERE id = $1", c.Param("id")))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "chat integration not found", "failed to fetch chat integration")
+		return
+	}
+	respond(c, http.StatusOK, ci)
+}
+
+// chatIntegrationPatchFields are the members PATCH /chat-integrations/:id
+// accepts
+var chatIntegrationPatchFields = map[string]patchField{
+	"department_id":  {column: "department_id", nullable: true, parse: patchString(true)},
+	"webhook_url":    {column: "webhook_url", parse: patchWebhookURL},
+	"signing_secret": {column: "signing_secret", nullable: true, parse: patchString(true)},
+	"bot_token":      {column: "bot_token", nullable: true, parse: patchString(true)},
+	"is_active":      {column: "is_active", parse: patchBool},
+}
+
+// PATCH /chat-integrations/:id (RFC 7386 merge patch; null clears
+// department_id, signing_secret and bot_token)
+// Messages not sent yet go to the new webhook_url.
+func (h *ChatHandler) UpdateChatIntegration(c *gin.Context) {
+	patch, ok := bindMergePatch(c)
+	if !ok {
+		return
+	}
+	sets, args, fieldErrs := patch.assignments(chatIntegrationPatchFields, 1)
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
+	if len(sets) == 0 {
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "no fields to update")
+		return
+	}
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "chat integration not found")
+		return
+	}
+	args = append(args, c.Param("id"))
+	ci, err := h.scan(h.pool.QueryRow(c.Request.Context(),
+		"UPDATE chat_integrations SET "+strings.Join(sets, ", ")+fmt.Sprintf(" WHERE id=$%d RETURNING ", len(args))+chatIntegrationColumns,
+		args...))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "chat integration not found", "failed to update chat integration")
+		return
+	}
+	respond(c, http.StatusOK, ci)
+}
+
+// DELETE /chat-integrations/:id
+// Its messages, sent or not, go with it.
+func (h *ChatHandler) DeleteChatIntegration(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "chat integration not found")
+		return
+	}
+	ct, err := h.pool.Exec(c.Request.Context(), "DELETE FROM chat_integrations WHERE id = $1", c.Param("id"))
+	if err != nil {
+		apierror.Database(c, err, "failed to delete chat integration")
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		apierror.Respond(c, apierror.NotFound, "chat integration not found")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "chat integration deleted"})
+}
+
+// chatCallbackTimeout bounds the work done for a click: Slack gives up on a
+// callback after 3 seconds
+const chatCallbackTimeout = 10 * time.Second
+
+// POST /integrations/chat/:id/actions (not authenticated: Slack signs the
+// request with the app's signing secret, Microsoft with its own keys)
+// A click on Approve or Reject in a message of integration id. The user who
+// clicked is matched by email address to an active user of the
+// organization, and decides the request as they would through the API.
+func (h *ChatHandler) HandleAction(c *gin.Context) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "chat integration not found")
+		return
+	}
+	var orgID, provider, secret, token string
+	err := h.pool.QueryRow(db.AsService(c.Request.Context()), `
+		SELECT org_id, provider, COALESCE(signing_secret, ''), COALESCE(bot_token, '')
+		FROM chat_integrations
+		WHERE id = $1 AND is_active`, id).Scan(&orgID, &provider, &secret, &token)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "chat integration not found", "failed to fetch chat integration")
+		return
+	}
+	if provider == chat.Teams {
+		h.teamsAction(c, id, orgID)
+		return
+	}
+	h.slackAction(c, id, orgID, secret, token)
+}
+
+// slackAction answers Slack at once and posts the outcome to the click's
+// response_url: the message as it now stands, or to the user alone why
+// nothing happened
+func (h *ChatHandler) slackAction(c *gin.Context, id, orgID, secret, token string) {
+	if secret == "" || token == "" {
+		apierror.Respond(c, apierror.Forbidden, "the chat integration has no signing secret or bot token")
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidInput, "failed to read request body")
+		return
+	}
+	if err := chat.VerifySlack(secret, c.Request.Header, body, time.Now()); err != nil {
+		apierror.Respond(c, apierror.Unauthenticated, "invalid Slack request signature")
+		return
+	}
+	a, err := chat.ParseSlackAction(body)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidInput, "invalid Slack interaction payload")
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), chatCallbackTimeout)
+	defer cancel()
+
+	var answer []byte
+	email, err := h.client.SlackEmail(ctx, token, a.UserID)
+	if err != nil {
+		slog.WarnContext(ctx, "slack user lookup failed", "integration_id", id, "error", err)
+		answer, err = json.Marshal(gin.H{"response_type": "ephemeral", "replace_original": false,
+			"text": "your Slack account could not be matched to an employee"})
+	} else if m, problem := h.decide(ctx, id, orgID, email, a.Action, a.LeaveRequestID, "Rejected in Slack"); problem != "" {
+		answer, err = json.Marshal(gin.H{"response_type": "ephemeral", "replace_original": false, "text": problem})
+	} else {
+		answer, err = chat.SlackReplacement(m)
+	}
+	if err == nil && a.ResponseURL != "" {
+		err = h.client.Post(ctx, a.ResponseURL, answer)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "slack response failed", "integration_id", id, "error", err)
+	}
+	c.Status(http.StatusOK)
+}
+
+// teamsAction answers Teams with the outcome in the CARD-ACTION-STATUS
+// header and, after a decision, the card as it now stands
+func (h *ChatHandler) teamsAction(c *gin.Context, id, orgID string) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), chatCallbackTimeout)
+	defer cancel()
+	base, err := url.Parse(h.callbackURL())
+	if err != nil || base.Host == "" {
+		apierror.Respond(c, apierror.Forbidden, "chat callbacks are not configured")
+		return
+	}
+	email, err := h.teams.Verify(ctx, c.GetHeader("Authorization"), base.Scheme+"://"+base.Host)
+	if err != nil {
+		slog.WarnContext(ctx, "teams action token rejected", "integration_id", id, "error", err)
+		apierror.Respond(c, apierror.Unauthenticated, "invalid Teams action token")
+		return
+	}
+	var a chat.TeamsAction
+	if err := json.NewDecoder(c.Request.Body).Decode(&a); err != nil {
+		c.Header("CARD-ACTION-STATUS", "the request could not be read; avoid quotes in the reason")
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(a.Reason)
+	if reason == "" {
+		reason = "Rejected in Microsoft Teams"
+	}
+	m, problem := h.decide(ctx, id, orgID, email, a.Action, a.LeaveRequestID, reason)
+	if problem != "" {
+		c.Header("CARD-ACTION-STATUS", problem)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	card, err := chat.TeamsBody(m)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("CARD-ACTION-STATUS", m.Note)
+	c.Header("CARD-UPDATE-IN-BODY", "true")
+	c.Data(http.StatusOK, "application/json", card)
+}
+
+// decide approves or rejects leave request requestID as the user of orgID
+// with email, with the checks of PUT /leave-requests/:id/approve and
+// /reject. It returns the message about the request as it then stands, or
+// why it did nothing, for the user who clicked.
+func (h *ChatHandler) decide(ctx context.Context, integrationID, orgID, email, action, requestID, reason string) (chat.Message, string) {
+	if action != chat.Approve && action != chat.Reject {
+		return chat.Message{}, "unknown action"
+	}
+	if !isUUID(requestID) {
+		return chat.Message{}, "leave request not found"
+	}
+	var claims db.Claims
+	var name string
+	err := h.pool.QueryRow(db.WithOrg(ctx, orgID), `
+		SELECT u.id, u.role, u.employee_id, e.id, e.name
+		FROM users u
+		JOIN employees e ON e.org_id = u.org_id AND e.employee_id = u.employee_id
+		JOIN organizations o ON o.id = u.org_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.is_active AND e.is_active AND o.is_active`, email).Scan(
+		&claims.UserID, &claims.Role, &claims.EmployeeID, &claims.EmployeeUUID, &name)
+	if apierror.IsNoRows(err) {
+		return chat.Message{}, "your account's email address does not belong to an active employee"
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "chat action user lookup failed", "integration_id", integrationID, "error", err)
+		return chat.Message{}, "the leave request could not be decided, please retry"
+	}
+	claims.OrgID = orgID
+	ctx = db.WithClaims(ctx, claims)
+	hr := claims.Role == models.RoleHR || claims.Role == models.RoleAdmin
+
+	var note string
+	if action == chat.Approve {
+		var needsHR bool
+		if needsHR, err = h.leave.noticeNeedsHR(ctx, requestID, hr); err == nil && needsHR {
+			return chat.Message{}, "leave during the notice period needs HR approval"
+		}
+		var outcome string
+		if err == nil {
+			outcome, err = h.leave.workflow.Approve(ctx, requestID, 0, claims.EmployeeUUID, hr)
+		}
+		note = "Approved by " + name
+		if outcome != "" && outcome != service.Approved {
+			note = "Approval recorded by " + name
+		}
+	} else {
+		err = h.leave.workflow.Reject(ctx, requestID, 0, reason, claims.EmployeeUUID, hr)
+		note = "Rejected by " + name + ": " + reason
+	}
+	if err != nil {
+		if _, msg, ok := workflowError(err); ok {
+			return chat.Message{}, msg
+		}
+		slog.ErrorContext(ctx, "chat action failed", "integration_id", integrationID, "action", action, "error", err)
+		return chat.Message{}, "the leave request could not be decided, please retry"
+	}
+	m := chat.Message{Kind: chat.LeaveApplied, ActionURL: chat.ActionURL(h.callbackURL(), integrationID), Note: note}
+	if m.Leave, err = chat.GetLeave(ctx, h.pool, requestID); err != nil {
+		// decided all the same; the message just cannot show it
+		slog.ErrorContext(ctx, "chat message refresh failed", "integration_id", integrationID, "error", err)
+		return chat.Message{}, note
+	}
+	return m, ""
+}
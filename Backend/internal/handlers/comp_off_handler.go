@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/leavepolicy"
+	"leave-management/internal/models"
+	"leave-management/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// compOffLeaveType is the leave type approved comp-offs are credited to. It
+// is created on the first approval in an organization and taken as leave
+// through POST /leave-requests like any other.
+const compOffLeaveType = "Comp Off"
+
+// CompOffHandler serves the comp-off workflow: an employee logs a weekend or
+// holiday they worked, their manager or HR approves it, and the approval
+// credits the days to the employee's Comp Off balance. The comp-off job
+// (jobs.ExpireCompOffs) takes back what is left of a credit once it expires.
+type CompOffHandler struct {
+	pool   *pgxpool.Pool
+	repos  repository.Repos
+	cache  *cache.Cache
+	expiry func() int // days an approved comp-off can be taken
+}
+
+func NewCompOffHandler(pool *pgxpool.Pool, rc *cache.Cache, expiry func() int) *CompOffHandler {
+	return &CompOffHandler{pool: pool, repos: repository.New(pool).Repos(), cache: rc, expiry: expiry}
+}
+
+type compOff struct {
+	ID              string     `json:"id"`
+	EmployeeID      string     `json:"employee_id"`
+	EmployeeName    string     `json:"employee_name"`
+	WorkDate        string     `json:"work_date"`
+	Days            float64    `json:"days"`
+	Reason          string     `json:"reason"`
+	Status          string     `json:"status"`
+	ReviewedBy      *string    `json:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	RejectionReason *string    `json:"rejection_reason"`
+	LeaveTypeID     *string    `json:"leave_type_id"`
+	CreditedYear    *int       `json:"credited_year"`
+	ExpiresOn       *string    `json:"expires_on"`
+	ExpiredDays     *float64   `json:"expired_days"`
+	ExpiredAt       *time.Time `json:"expired_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+const compOffSelect = `
+	SELECT c.id, c.employee_id, e.name, c.work_date, c.days::FLOAT8, c.reason, c.status, c.reviewed_by, c.reviewed_at,
+	       c.rejection_reason, c.leave_type_id, c.credited_year, c.expires_on, c.expired_days::FLOAT8, c.expired_at, c.created_at
+	FROM comp_off_requests c
+	JOIN employees e ON e.id = c.employee_id`
+
+func scanCompOff(row pgx.Row) (compOff, error) {
+	var r compOff
+	var workDate time.Time
+	var expiresOn *time.Time
+	err := row.Scan(&r.ID, &r.EmployeeID, &r.EmployeeName, &workDate, &r.Days, &r.Reason, &r.Status, &r.ReviewedBy, &r.ReviewedAt,
+		&r.RejectionReason, &r.LeaveTypeID, &r.CreditedYear, &expiresOn, &r.ExpiredDays, &r.ExpiredAt, &r.CreatedAt)
+	r.WorkDate = workDate.Format("2006-01-02")
+	if expiresOn != nil {
+		s := expiresOn.Format("2006-01-02")
+		r.ExpiresOn = &s
+	}
+	return r, err
+}
+
+func (h *CompOffHandler) get(ctx context.Context, id string) (compOff, error) {
+	return scanCompOff(h.pool.QueryRow(ctx, compOffSelect+" WHERE c.id = $1", id))
+}
+
+// POST /comp-off
+// Logs a weekend or holiday the caller worked, for their manager or HR to
+// review. days is 1 (the default) or 0.5 for half a day.
+func (h *CompOffHandler) LogCompOff(c *gin.Context) {
+	var in struct {
+		WorkDate string   `json:"work_date" binding:"required,datetime=2006-01-02"`
+		Days     *float64 `json:"days"`
+		Reason   string   `json:"reason" binding:"required"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	employeeID := c.GetString("employee_uuid")
+	if employeeID == "" {
+		apierror.Respond(c, apierror.Forbidden, "only users with an employee record can log comp-off")
+		return
+	}
+	workDate, err := time.Parse("2006-01-02", in.WorkDate)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidDate, "Invalid work_date format, use YYYY-MM-DD")
+		return
+	}
+	days := 1.0
+	if in.Days != nil {
+		days = *in.Days
+	}
+	if days != 1 && days != 0.5 {
+		apierror.Respond(c, apierror.InvalidInput, "days must be 1 or 0.5")
+		return
+	}
+	if strings.TrimSpace(in.Reason) == "" {
+		apierror.Respond(c, apierror.InvalidInput, "reason is required")
+		return
+	}
+	now := time.Now()
+	if workDate.After(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		apierror.Respond(c, apierror.InvalidDate, "work_date cannot be in the future")
+		return
+	}
+
+	ctx := c.Request.Context()
+	holidays, err := h.repos.Holidays.ListHolidayDatesBetween(ctx, queries.ListHolidayDatesBetweenParams{FromDate: workDate, ToDate: workDate})
+	if err != nil {
+		apierror.Database(c, err, "failed to log comp-off")
+		return
+	}
+	if leavepolicy.NewCalendar(holidays).Working(workDate) {
+		apierror.Respond(c, apierror.CompOffWorkingDay, "comp-off can only be logged for a weekend or holiday")
+		return
+	}
+
+	var id string
+	if err := h.pool.QueryRow(ctx, `
+		INSERT INTO comp_off_requests (employee_id, work_date, days, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`, employeeID, workDate, days, in.Reason).Scan(&id); err != nil {
+		apierror.Database(c, err, "failed to log comp-off")
+		return
+	}
+	r, err := h.get(ctx, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to log comp-off")
+		return
+	}
+	respond(c, http.StatusCreated, r)
+}
+
+// GET /comp-off?status=pending&employee_id=uuid (paging: limit, offset; sort:
+// work_date, created_at, status, employee_name)
+// Employees see their own comp-offs, managers also those of their direct
+// reports, HR and admins everyone's.
+func (h *CompOffHandler) ListCompOffs(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, compOffSorts, "c.created_at DESC, c.id DESC", "c.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "pending", "approved", "rejected", "cancelled":
+	default:
+		apierror.Respond(c, apierror.InvalidQuery, "status must be pending, approved, rejected or cancelled")
+		return
+	}
+	employeeFilter := c.Query("employee_id")
+	if employeeFilter != "" && !isUUID(employeeFilter) {
+		apierror.Respond(c, apierror.InvalidQuery, "employee_id must be a valid UUID")
+		return
+	}
+
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	switch c.GetString("role") {
+	case models.RoleHR, models.RoleAdmin:
+	case models.RoleManager:
+		self := arg(c.GetString("employee_uuid"))
+		conds = append(conds, "(c.employee_id = NULLIF("+self+", '')::UUID OR e.manager_id = NULLIF("+self+", '')::UUID)")
+	default:
+		conds = append(conds, "c.employee_id = NULLIF("+arg(c.GetString("employee_uuid"))+", '')::UUID")
+	}
+	if status != "" {
+		conds = append(conds, "c.status = "+arg(status))
+	}
+	if employeeFilter != "" {
+		conds = append(conds, "c.employee_id = "+arg(employeeFilter))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "\n\tWHERE " + strings.Join(conds, " AND ")
+	}
+	n := len(args)
+
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM comp_off_requests c
+		JOIN employees e ON e.id = c.employee_id`+where, args...).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch comp-offs")
+		return
+	}
+	rows, err := h.pool.Query(ctx, compOffSelect+where+`
+		ORDER BY `+orderBy+`
+		LIMIT $`+strconv.Itoa(n+1)+` OFFSET $`+strconv.Itoa(n+2), append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch comp-offs")
+		return
+	}
+	defer rows.Close()
+
+	list := make([]compOff, 0)
+	for rows.Next() {
+		r, err := scanCompOff(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch comp-offs")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+var (
+	errCompOffNotPending = errors.New("comp-off not pending")
+	errOwnCompOff        = errors.New("own comp-off")
+	errNotCompOffManager = errors.New("not the employee's manager")
+)
+
+// review decides a pending comp-off. HR and admins review any, managers
+// those of their direct reports, and nobody their own. Approval credits the
+// days to the employee's Comp Off balance of the current year, creating the
+// leave type on the first approval, and sets the day the credit expires.
+func (h *CompOffHandler) review(c *gin.Context, approve bool, reason *string) (compOff, error) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	reviewer := c.GetString("employee_uuid")
+	role := c.GetString("role")
+	hr := role == models.RoleHR || role == models.RoleAdmin
+	var createdType bool
+	err := db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		createdType = false
+		var employeeID, status string
+		var managerID *string
+		var days float64
+		if err := tx.QueryRow(ctx, `
+			SELECT c.employee_id, c.status, c.days::FLOAT8, e.manager_id
+			FROM comp_off_requests c
+			JOIN employees e ON e.id = c.employee_id
+			WHERE c.id = $1
+			FOR UPDATE OF c`, id).Scan(&employeeID, &status, &days, &managerID); err != nil {
+			return err
+		}
+		switch {
+		case status != "pending":
+			return errCompOffNotPending
+		case employeeID == reviewer:
+			return errOwnCompOff
+		case !hr && (managerID == nil || *managerID != reviewer):
+			return errNotCompOffManager
+		}
+
+		if !approve {
+			_, err := tx.Exec(ctx, `
+				UPDATE comp_off_requests SET status = 'rejected', reviewed_by = NULLIF($2, '')::UUID, reviewed_at = NOW(),
+					rejection_reason = $3
+				WHERE id = $1`, id, reviewer, reason)
+			return err
+		}
+		var leaveTypeID string
+		if err := tx.QueryRow(ctx, `
+			WITH created AS (
+				INSERT INTO leave_types (name, description, max_days_per_year)
+				VALUES ($1, 'Days credited for approved comp-off', 0)
+				ON CONFLICT ON CONSTRAINT leave_types_name_key DO NOTHING
+				RETURNING id
+			)
+			SELECT id, TRUE FROM created
+			UNION ALL
+			SELECT id, FALSE FROM leave_types WHERE name = $1
+			LIMIT 1`, compOffLeaveType).Scan(&leaveTypeID, &createdType); err != nil {
+			return err
+		}
+		year := time.Now().Year()
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO employee_leave_balances (employee_id, leave_type_id, year, allocated_days)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (employee_id, leave_type_id, year) DO UPDATE
+			SET allocated_days = employee_leave_balances.allocated_days + EXCLUDED.allocated_days`,
+			employeeID, leaveTypeID, year, days); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			UPDATE comp_off_requests SET status = 'approved', reviewed_by = NULLIF($2, '')::UUID, reviewed_at = NOW(),
+				leave_type_id = $3, credited_year = $4, expires_on = CURRENT_DATE + $5::INT
+			WHERE id = $1`, id, reviewer, leaveTypeID, year, h.expiry())
+		return err
+	})
+	if err != nil {
+		return compOff{}, err
+	}
+	if createdType {
+		h.cache.Delete(ctx, cache.LeaveTypesKey(db.OrgID(ctx)))
+	}
+	return h.get(ctx, id)
+}
+
+// respondCompOffReview answers a review, which only pending comp-offs accept
+func respondCompOffReview(c *gin.Context, r compOff, err error, fallback string) {
+	switch {
+	case errors.Is(err, errCompOffNotPending):
+		apierror.Respond(c, apierror.InvalidState, "comp-off is not pending")
+	case errors.Is(err, errOwnCompOff):
+		apierror.Respond(c, apierror.Forbidden, "you cannot review your own comp-off")
+	case errors.Is(err, errNotCompOffManager):
+		apierror.Respond(c, apierror.Forbidden, "only the employee's manager or HR can review this comp-off")
+	case err != nil:
+		apierror.Lookup(c, err, apierror.NotFound, "comp-off not found", fallback)
+	default:
+		respond(c, http.StatusOK, r)
+	}
+}
+
+// PUT /comp-off/:id/approve
+// Credits the days to the employee's Comp Off balance; they expire after
+// COMP_OFF_EXPIRY_DAYS.
+func (h *CompOffHandler) ApproveCompOff(c *gin.Context) {
+	r, err := h.review(c, true, nil)
+	respondCompOffReview(c, r, err, "failed to approve comp-off")
+}
+
+// PUT /comp-off/:id/reject
+func (h *CompOffHandler) RejectCompOff(c *gin.Context) {
+	var in struct {
+		RejectionReason string `json:"rejection_reason" binding:"required"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	r, err := h.review(c, false, &in.RejectionReason)
+	respondCompOffReview(c, r, err, "failed to reject comp-off")
+}
+
+// PUT /comp-off/:id/cancel
+// Withdraws one of the caller's own comp-offs while it is pending.
+func (h *CompOffHandler) CancelCompOff(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	err := db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		var status string
+		if err := tx.QueryRow(ctx, `
+			SELECT status FROM comp_off_requests
+			WHERE id = $1 AND employee_id = NULLIF($2, '')::UUID
+			FOR UPDATE`, id, c.GetString("employee_uuid")).Scan(&status); err != nil {
+			return err
+		}
+		if status != "pending" {
+			return errCompOffNotPending
+		}
+		_, err := tx.Exec(ctx, "UPDATE comp_off_requests SET status = 'cancelled' WHERE id = $1", id)
+		return err
+	})
+	var r compOff
+	if err == nil {
+		r, err = h.get(ctx, id)
+	}
+	respondCompOffReview(c, r, err, "failed to cancel comp-off")
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"leave-management/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+	live *config.Live
+}
+
+func NewConfigHandler(live *config.Live) *ConfigHandler {
+	return &ConfigHandler{live: live}
+}
+
+// GET /admin/config
+// Returns every resolved setting with where it came from; secrets are redacted.
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	cfg := h.live.Get()
+	c.JSON(http.StatusOK, gin.H{
+		"config_file": cfg.ConfigFile,
+		"settings":    cfg.Redacted(),
+	})
+}
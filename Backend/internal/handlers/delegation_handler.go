@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DelegationHandler serves approval delegations: a manager hands the
+// decisions on their direct reports' leave requests to another employee for
+// a date range, e.g. while on leave themselves. While the delegation is
+// active the delegate approves and rejects those requests as the manager
+// would (see service.LeaveRequests.Approve) and finds them in
+// GET /leave-requests and GET /approvals/pending.
+type DelegationHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewDelegationHandler(pool *pgxpool.Pool) *DelegationHandler {
+	return &DelegationHandler{pool: pool}
+}
+
+type approvalDelegation struct {
+	ID            string     `json:"id"`
+	DelegatorID   string     `json:"delegator_id"`
+	DelegatorName string     `json:"delegator_name"`
+	DelegateID    string     `json:"delegate_id"`
+	DelegateName  string     `json:"delegate_name"`
+	StartDate     string     `json:"start_date"`
+	EndDate       string     `json:"end_date"`
+	Reason        *string    `json:"reason"`
+	CreatedBy     *string    `json:"created_by"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	Active        bool       `json:"active"` // in effect today
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+const delegationSelect = `
+	SELECT d.id, d.delegator_id, m.name, d.delegate_id, e.name, d.start_date, d.end_date, d.reason, d.created_by,
+	       d.revoked_at, d.revoked_at IS NULL AND CURRENT_DATE BETWEEN d.start_date AND d.end_date, d.created_at
+	FROM approval_delegations d
+	JOIN employees m ON m.id = d.delegator_id
+	JOIN employees e ON e.id = d.delegate_id`
+
+func scanDelegation(row pgx.Row) (approvalDelegation, error) {
+	var d approvalDelegation
+	var start, end time.Time
+	err := row.Scan(&d.ID, &d.DelegatorID, &d.DelegatorName, &d.DelegateID, &d.DelegateName, &start, &end, &d.Reason, &d.CreatedBy,
+		&d.RevokedAt, &d.Active, &d.CreatedAt)
+	d.StartDate, d.EndDate = start.Format("2006-01-02"), end.Format("2006-01-02")
+	return d, err
+}
+
+func (h *DelegationHandler) get(ctx context.Context, id string) (approvalDelegation, error) {
+	return scanDelegation(h.pool.QueryRow(ctx, delegationSelect+" WHERE d.id = $1", id))
+}
+
+var (
+	errDelegateNotFound  = errors.New("delegate not found")
+	errDelegateInactive  = errors.New("delegate not active")
+	errDelegationOverlap = errors.New("delegation overlaps another")
+	errNotDelegator      = errors.New("not the delegator")
+	errDelegationEnded   = errors.New("delegation revoked or ended")
+)
+
+// POST /approval-delegations
+// Delegates the caller's approvals to delegate_id from start_date to
+// end_date, inclusive; HR and admins may delegate those of any manager with
+// delegator_id. The delegations of a manager cannot overlap.
+func (h *DelegationHandler) CreateDelegation(c *gin.Context) {
+	var in struct {
+		DelegatorID string  `json:"delegator_id" binding:"omitempty,uuid"`
+		DelegateID  string  `json:"delegate_id" binding:"required,uuid"`
+		StartDate   string  `json:"start_date" binding:"required,datetime=2006-01-02"`
+		EndDate     string  `json:"end_date" binding:"required,datetime=2006-01-02"`
+		Reason      *string `json:"reason"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	role := c.GetString("role")
+	if in.DelegatorID == "" || (role != models.RoleHR && role != models.RoleAdmin) {
+		own := c.GetString("employee_uuid")
+		if own == "" {
+			apierror.Respond(c, apierror.Forbidden, "only users with an employee record can delegate their approvals")
+			return
+		}
+		if in.DelegatorID != "" && in.DelegatorID != own {
+			apierror.Respond(c, apierror.Forbidden, "you can only delegate your own approvals")
+			return
+		}
+		in.DelegatorID = own
+	}
+	if in.DelegateID == in.DelegatorID {
+		apierror.Respond(c, apierror.InvalidInput, "approvals cannot be delegated to the delegator")
+		return
+	}
+	start, err := time.Parse("2006-01-02", in.StartDate)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidDate, "Invalid start_date format, use YYYY-MM-DD")
+		return
+	}
+	end, err := time.Parse("2006-01-02", in.EndDate)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidDate, "Invalid end_date format, use YYYY-MM-DD")
+		return
+	}
+	if end.Before(start) {
+		apierror.Respond(c, apierror.InvalidDateRange, "start_date cannot be after end_date")
+		return
+	}
+	now := time.Now()
+	if end.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		apierror.Respond(c, apierror.InvalidDate, "end_date cannot be in the past")
+		return
+	}
+
+	ctx := c.Request.Context()
+	var id string
+	err = db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		// the delegator's row serializes their delegations, so two that
+		// overlap cannot both pass the check below
+		if _, err := tx.Exec(ctx, "SELECT 1 FROM employees WHERE id = $1 FOR UPDATE", in.DelegatorID); err != nil {
+			return err
+		}
+		var active bool
+		err := tx.QueryRow(ctx, "SELECT is_active FROM employees WHERE id = $1", in.DelegateID).Scan(&active)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errDelegateNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !active {
+			return errDelegateInactive
+		}
+		var overlap bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM approval_delegations
+				WHERE delegator_id = $1 AND revoked_at IS NULL AND start_date <= $3 AND end_date >= $2
+			)`, in.DelegatorID, start, end).Scan(&overlap); err != nil {
+			return err
+		}
+		if overlap {
+			return errDelegationOverlap
+		}
+		return tx.QueryRow(ctx, `
+			INSERT INTO approval_delegations (delegator_id, delegate_id, start_date, end_date, reason, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`, in.DelegatorID, in.DelegateID, start, end, in.Reason, c.GetString("user_id")).Scan(&id)
+	})
+	switch {
+	case errors.Is(err, errDelegateNotFound):
+		apierror.Respond(c, apierror.ReferenceNotFound, "delegate_id not found")
+		return
+	case errors.Is(err, errDelegateInactive):
+		apierror.Respond(c, apierror.InvalidInput, "the delegate must be an active employee")
+		return
+	case errors.Is(err, errDelegationOverlap):
+		apierror.Respond(c, apierror.AlreadyExists, "the delegator already has an approval delegation for these dates")
+		return
+	case err != nil:
+		apierror.Database(c, err, "failed to create approval delegation")
+		return
+	}
+	d, err := h.get(ctx, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to create approval delegation")
+		return
+	}
+	respond(c, http.StatusCreated, d)
+}
+
+// GET /approval-delegations?active=true (paging: limit, offset; sort:
+// start_date, end_date, created_at)
+// HR and admins see every delegation, others those they gave or received.
+// active=true lists only those in effect today.
+func (h *DelegationHandler) ListDelegations(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, delegationSorts, "d.start_date DESC, d.id DESC", "d.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	active, err := parseBoolQuery(c, "active")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+
+	var conds []string
+	var args []interface{}
+	switch c.GetString("role") {
+	case models.RoleHR, models.RoleAdmin:
+	default:
+		args = append(args, c.GetString("employee_uuid"))
+		conds = append(conds, "(d.delegator_id = NULLIF($1, '')::UUID OR d.delegate_id = NULLIF($1, '')::UUID)")
+	}
+	if active {
+		conds = append(conds, "d.revoked_at IS NULL AND CURRENT_DATE BETWEEN d.start_date AND d.end_date")
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "\n\tWHERE " + strings.Join(conds, " AND ")
+	}
+	n := len(args)
+
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM approval_delegations d"+where, args...).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch approval delegations")
+		return
+	}
+	rows, err := h.pool.Query(ctx, delegationSelect+where+`
+		ORDER BY `+orderBy+`
+		LIMIT $`+strconv.Itoa(n+1)+` OFFSET $`+strconv.Itoa(n+2), append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch approval delegations")
+		return
+	}
+	defer rows.Close()
+
+	list := make([]approvalDelegation, 0)
+	for rows.Next() {
+		d, err := scanDelegation(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch approval delegations")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// DELETE /approval-delegations/:id
+// Revokes a delegation that has not ended, from now on. The delegator, whoever
+// created it, HR and admins may revoke it.
+func (h *DelegationHandler) RevokeDelegation(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	role := c.GetString("role")
+	hr := role == models.RoleHR || role == models.RoleAdmin
+	err := db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		var delegatorID string
+		var createdBy *string
+		var ended bool
+		if err := tx.QueryRow(ctx, `
+			SELECT delegator_id, created_by, revoked_at IS NOT NULL OR end_date < CURRENT_DATE
+			FROM approval_delegations
+			WHERE id = $1
+			FOR UPDATE`, id).Scan(&delegatorID, &createdBy, &ended); err != nil {
+			return err
+		}
+		switch {
+		case !hr && delegatorID != c.GetString("employee_uuid") && (createdBy == nil || *createdBy != c.GetString("user_id")):
+			return errNotDelegator
+		case ended:
+			return errDelegationEnded
+		}
+		_, err := tx.Exec(ctx, "UPDATE approval_delegations SET revoked_at = NOW() WHERE id = $1", id)
+		return err
+	})
+	var d approvalDelegation
+	if err == nil {
+		d, err = h.get(ctx, id)
+	}
+	switch {
+	case errors.Is(err, errNotDelegator):
+		apierror.Respond(c, apierror.Forbidden, "only the delegator or HR can revoke this approval delegation")
+	case errors.Is(err, errDelegationEnded):
+		apierror.Respond(c, apierror.InvalidState, "approval delegation is already revoked or ended")
+	case err != nil:
+		apierror.Lookup(c, err, apierror.NotFound, "approval delegation not found", "failed to revoke approval delegation")
+	default:
+		respond(c, http.StatusOK, d)
+	}
+}
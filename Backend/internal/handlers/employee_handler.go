@@ -2,35 +2,62 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/models"
+	"leave-management/internal/repository"
+	"leave-management/internal/service"
+
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type EmployeeHandler struct {
-	Pool *pgxpool.Pool
+	Pool  *pgxpool.Pool
+	store repository.Store
+	repos repository.Repos
+
+	// read serves ListEmployees; it is a replica when one is configured
+	read      *pgxpool.Pool
+	readRepos repository.Repos
+
+	cache *cache.Cache
 }
 
-func NewEmployeeHandler(pool *pgxpool.Pool) *EmployeeHandler {
-	return &EmployeeHandler{Pool: pool}
+func NewEmployeeHandler(pool, read *pgxpool.Pool, rc *cache.Cache) *EmployeeHandler {
+	store := repository.New(pool)
+	return &EmployeeHandler{
+		Pool:      pool,
+		cache:     rc,
+		store:     store,
+		repos:     store.Repos(),
+		read:      read,
+		readRepos: repository.New(read).Repos(),
+	}
 }
 
 type createEmployeeDTO struct {
 	Name         string `json:"name" binding:"required"`
-	Email        string `json:"email" binding:"required"`
-	DepartmentID string `json:"department_id" binding:"required"`
-	JoiningDate  string `json:"joining_date" binding:"required"` // "YYYY-MM-DD"
-	EmployeeID   string `json:"employee_id"`                     // optional
+	Email        string `json:"email" binding:"required,email"`
+	DepartmentID string `json:"department_id" binding:"required,uuid"`
+	JoiningDate  string `json:"joining_date" binding:"required,datetime=2006-01-02"`
+	EmployeeID   string `json:"employee_id" binding:"omitempty,max=20"` // optional
 }
 
 func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	var in createEmployeeDTO
-	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input", "details": err.Error()})
+	if !bindJSON(c, &in) {
 		return
 	}
 
@@ -38,16 +65,16 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	in.Name = strings.TrimSpace(in.Name)
 	in.Email = strings.TrimSpace(strings.ToLower(in.Email))
 	if in.Name == "" || in.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name and email are required"})
+		apierror.Respond(c, apierror.InvalidInput, "name and email are required")
 		return
 	}
 	joinDate, err := time.Parse("2006-01-02", in.JoiningDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "joining_date must be YYYY-MM-DD"})
+		apierror.Respond(c, apierror.InvalidDate, "joining_date must be YYYY-MM-DD")
 		return
 	}
 	if joinDate.After(time.Now().Truncate(24 * time.Hour)) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "joining_date cannot be in the future"})
+		apierror.Respond(c, apierror.InvalidInput, "joining_date cannot be in the future")
 		return
 	}
 	empID := strings.TrimSpace(in.EmployeeID)
@@ -55,60 +82,23 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 		empID = generateEmployeeID()
 	}
 
-	ctx := context.Background()
-	tx, err := h.Pool.Begin(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "begin tx failed", "details": err.Error()})
-		return
-	}
-	defer tx.Rollback(ctx)
-
-	// 1) Ensure department exists
-	var depExists bool
-	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM departments WHERE id=$1)`, in.DepartmentID).
-		Scan(&depExists); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "dept check failed", "details": err.Error()})
-		return
-	}
-	if !depExists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "department_id not found"})
-		return
-	}
-
-	// 2) Insert employee (RLS requires role in ('hr','admin') -> set via db.AfterConnect)
+	ctx := c.Request.Context()
 	var newID string
-	err = tx.QueryRow(ctx, `
-		INSERT INTO employees (employee_id, email, name, department_id, joining_date, role)
-		VALUES ($1, $2, $3, $4, $5, 'employee')
-		RETURNING id
-	`, empID, in.Email, in.Name, in.DepartmentID, joinDate).Scan(&newID)
-	if err != nil {
-		msg := parsePgErr(err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "insert employee failed", "details": msg})
+	err = h.store.InTx(ctx, func(r repository.Repos) error {
+		var err error
+		newID, err = createEmployee(ctx, r, in, empID, joinDate)
+		return err
+	})
+	if errors.Is(err, errDepartmentNotFound) {
+		apierror.Respond(c, apierror.ReferenceNotFound, "department_id not found")
 		return
 	}
-
-	// 3) Allocate current-year leave balances for all active leave types
-	year := time.Now().Year()
-	_, err = tx.Exec(ctx, `
-		INSERT INTO employee_leave_balances (employee_id, leave_type_id, year, allocated_days, used_days, carried_forward_days)
-		SELECT $1, lt.id, $2, lt.max_days_per_year, 0, 0
-		FROM leave_types lt
-		WHERE lt.is_active = true
-		ON CONFLICT (employee_id, leave_type_id, year) DO NOTHING
-	`, newID, year)
 	if err != nil {
-		msg := parsePgErr(err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "allocate leave balances failed", "details": msg})
+		apierror.Database(c, err, "failed to create employee")
 		return
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "commit failed", "details": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
+	respond(c, http.StatusCreated, gin.H{
 		"id":            newID,
 		"employee_id":   empID,
 		"name":          in.Name,
@@ -119,68 +109,114 @@ func (h *EmployeeHandler) CreateEmployee(c *gin.Context) {
 	})
 }
 
+var errDepartmentNotFound = errors.New("department not found")
+
+// createEmployee adds the employee in and allocates their current-year leave
+// balances, using r's transaction
+func createEmployee(ctx context.Context, r repository.Repos, in createEmployeeDTO, empID string, joinDate time.Time) (string, error) {
+	// 1) Ensure department exists
+	depExists, err := r.Employees.DepartmentExists(ctx, in.DepartmentID)
+	if err != nil {
+		return "", err
+	}
+	if !depExists {
+		return "", errDepartmentNotFound
+	}
+
+	// 2) Insert employee (RLS requires role in ('hr','admin') -> set via db.AfterConnect)
+	newID, err := r.Employees.CreateEmployee(ctx, queries.CreateEmployeeParams{
+		EmployeeID:   empID,
+		Email:        in.Email,
+		Name:         in.Name,
+		DepartmentID: in.DepartmentID,
+		JoiningDate:  joinDate,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 3) Allocate current-year leave balances for all active leave types
+	return newID, r.LeaveBalances.AllocateLeaveBalances(ctx, queries.AllocateLeaveBalancesParams{EmployeeID: newID, Year: int32(time.Now().Year())})
+}
+
 func generateEmployeeID() string {
 	// Simple random ID like EMP-2025-xxxxx
 	return "EMP-" + time.Now().Format("20060102-150405")
 }
 
-func parsePgErr(err error) string {
-	// Keep it simple; surface useful messages for common constraints.
-	msg := err.Error()
-	// helpful hints
-	if strings.Contains(msg, "employees_email_key") {
-		return "email already exists"
+// GET /employees
+// Optional filters: department_id, role, active (true/false); paging: limit, offset; sort=field:asc|desc
+func (h *EmployeeHandler) ListEmployees(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
 	}
-	if strings.Contains(msg, "employees_employee_id_key") {
-		return "employee_id already exists"
+	orderBy, _, err := parseSort(c, employeeSorts, "created_at DESC", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
 	}
-	if strings.Contains(msg, "check_joining_date") {
-		return "joining_date cannot be in the future"
+	fields, err := parseFields(c, employeeFields)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
 	}
-	if strings.Contains(strings.ToLower(msg), "violates row-level security") {
-		return "RLS blocked the operation (ensure admin/hr role is set in DB session)"
+	include, err := parseInclude(c, employeeIncludes)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
 	}
-	return msg
-}
-
-// GET /employees
-// Optional filters: department_id, role, active (true/false)
-func (h *EmployeeHandler) ListEmployees(c *gin.Context) {
-	departmentID := c.Query("department_id")
-	role := c.Query("role")
-	active := c.Query("active")
-
-	query := `SELECT id, employee_id, email, name, department_id, role, is_active, joining_date, phone, address
-	          FROM employees WHERE 1=1`
-	args := []interface{}{}
-	argIdx := 1
-	if departmentID != "" {
-		query += " AND department_id=" + fmt.Sprintf("$%d", argIdx)
-		args = append(args, departmentID)
-		argIdx++
-	}
-	if role != "" {
-		query += " AND role=" + fmt.Sprintf("$%d", argIdx)
-		args = append(args, role)
-		argIdx++
-	}
-	if active != "" {
+	withBalances := include["balance_summary"]
+	includeInactive, err := parseIncludeInactive(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	filter := queries.CountEmployeesParams{}
+	if v := c.Query("department_id"); v != "" {
+		filter.DepartmentID = &v
+	}
+	if v := c.Query("role"); v != "" {
+		filter.Role = &v
+	}
+	if v := c.Query("active"); v != "" {
 		// accept true/false (case-insensitive)
-		val := strings.ToLower(active) == "true"
-		query += " AND is_active=" + fmt.Sprintf("$%d", argIdx)
-		args = append(args, val)
-		argIdx++
+		active := strings.ToLower(v) == "true"
+		filter.IsActive = &active
+		// an explicit active filter decides on its own, so active=false works
+		includeInactive = true
 	}
-	query += " ORDER BY created_at DESC"
+	filter.IncludeInactive = includeInactive
 
-	rows, err := h.Pool.Query(context.Background(), query, args...)
+	total, err := h.readRepos.Employees.CountEmployees(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list employees"})
+		apierror.Database(c, err, "failed to count employees")
+		return
+	}
+
+	// sqlc cannot parameterize ORDER BY, so the listing keeps CountEmployees'
+	// filters at fixed placeholders and appends only the whitelisted sort
+	query := employeeListQuery
+	args := []interface{}{filter.DepartmentID, filter.Role, filter.IsActive, filter.IncludeInactive}
+	year := time.Now().Year()
+	if withBalances {
+		query = employeeListWithBalancesQuery
+		args = append(args, year)
+	}
+	query += " ORDER BY " + orderBy
+	limitClause, args := page.clause(args)
+	query += limitClause
+
+	rows, err := h.read.Query(c.Request.Context(), query, args...)
+	if err != nil {
+		apierror.Database(c, err, "failed to list employees")
 		return
 	}
 	defer rows.Close()
 
 	result := make([]map[string]interface{}, 0)
+	summaries := make([]gin.H, 0)
 	for rows.Next() {
 		var (
 			id string
@@ -193,9 +229,14 @@ func (h *EmployeeHandler) ListEmployees(c *gin.Context) {
 			joiningDate time.Time
 			phone *string
 			address *string
+			summary balanceSummary
 		)
-		if err := rows.Scan(&id, &empID, &email, &name, &deptID, &roleVal, &isActive, &joiningDate, &phone, &address); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "row scan failed"})
+		dest := []interface{}{&id, &empID, &email, &name, &deptID, &roleVal, &isActive, &joiningDate, &phone, &address}
+		if withBalances {
+			dest = append(dest, &summary.EntitledDays, &summary.UsedDays, &summary.AvailableDays, &summary.LeaveTypes)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			apierror.Database(c, err, "row scan failed")
 			return
 		}
 		item := gin.H{
@@ -211,121 +252,254 @@ func (h *EmployeeHandler) ListEmployees(c *gin.Context) {
 		if phone != nil { item["phone"] = *phone }
 		if address != nil { item["address"] = *address }
 		result = append(result, item)
+		if withBalances {
+			summaries = append(summaries, gin.H{
+				"year":           year,
+				"entitled_days":  summary.EntitledDays,
+				"used_days":      summary.UsedDays,
+				"available_days": summary.AvailableDays,
+				"leave_types":    summary.LeaveTypes,
+			})
+		}
+	}
+	data := fields.project(result)
+	// included blocks are asked for explicitly, so ?fields= does not drop them
+	for i, s := range summaries {
+		data[i]["balance_summary"] = s
 	}
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{"data": data, "meta": page.meta(int(total), len(result))})
 }
 
+// balanceSummary is an employee's leave balance for the year, totalled over
+// leave types, as returned by ?include=balance_summary
+type balanceSummary struct {
+	EntitledDays  float64
+	UsedDays      float64
+	AvailableDays float64
+	LeaveTypes    []balanceSummaryLeaveType
+}
+
+type balanceSummaryLeaveType struct {
+	LeaveTypeID   string  `json:"leave_type_id"`
+	LeaveTypeName string  `json:"leave_type_name"`
+	AvailableDays float64 `json:"available_days"`
+}
+
+// employeeListQuery selects the rows CountEmployees counts
+const employeeListQuery = `SELECT id, employee_id, email, name, department_id, role, is_active, joining_date, phone, address
+	FROM employees
+	WHERE ($1::uuid IS NULL OR department_id = $1::uuid)
+	  AND ($2::employee_role IS NULL OR role = $2::employee_role)
+	  AND ($3::bool IS NULL OR is_active = $3::bool)
+	  AND ($4::bool OR is_active)`
+
+// employeeListWithBalancesQuery is employeeListQuery plus each employee's
+// balances for year $5, aggregated in the same statement so listing a page
+// with balances costs one round trip
+const employeeListWithBalancesQuery = `SELECT e.id, e.employee_id, e.email, e.name, e.department_id, e.role, e.is_active, e.joining_date, e.phone, e.address,
+		COALESCE(b.entitled, 0), COALESCE(b.used, 0), COALESCE(b.available, 0), COALESCE(b.by_type, '[]')
+	FROM employees e
+	LEFT JOIN LATERAL (
+		SELECT SUM(elb.allocated_days + elb.carried_forward_days)::FLOAT8 AS entitled,
+			SUM(elb.used_days)::FLOAT8 AS used,
+			SUM(elb.available_days)::FLOAT8 AS available,
+			json_agg(json_build_object(
+				'leave_type_id', lt.id,
+				'leave_type_name', lt.name,
+				'available_days', elb.available_days
+			) ORDER BY lt.name) AS by_type
+		FROM employee_leave_balances elb
+		JOIN leave_types lt ON lt.id = elb.leave_type_id
+		WHERE elb.employee_id = e.id AND elb.year = $5
+	) b ON true
+	WHERE ($1::uuid IS NULL OR e.department_id = $1::uuid)
+	  AND ($2::employee_role IS NULL OR e.role = $2::employee_role)
+	  AND ($3::bool IS NULL OR e.is_active = $3::bool)
+	  AND ($4::bool OR e.is_active)`
+
 // GET /employees/:id
 func (h *EmployeeHandler) GetEmployeeByID(c *gin.Context) {
 	id := c.Param("id")
-	var (
-		empID string
-		email string
-		name string
-		deptID string
-		roleVal string
-		isActive bool
-		joiningDate time.Time
-		phone *string
-		address *string
-	)
-	err := h.Pool.QueryRow(context.Background(), `
-		SELECT employee_id, email, name, department_id, role, is_active, joining_date, phone, address
-		FROM employees WHERE id=$1`, id,
-	).Scan(&empID, &email, &name, &deptID, &roleVal, &isActive, &joiningDate, &phone, &address)
+	e, err := h.repos.Employees.GetEmployee(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{
-		"id": id,
-		"employee_id": empID,
-		"email": email,
-		"name": name,
-		"department_id": deptID,
-		"role": roleVal,
-		"is_active": isActive,
-		"joining_date": joiningDate.Format("2006-01-02"),
-		"phone": phone,
-		"address": address,
+		apierror.Lookup(c, err, apierror.NotFound, "employee not found", "failed to load employee")
+		return
+	}
+	var resignationDate *string
+	if e.ResignationDate != nil {
+		d := e.ResignationDate.Format("2006-01-02")
+		resignationDate = &d
+	}
+	respond(c, http.StatusOK, gin.H{
+		"id":               id,
+		"employee_id":      e.EmployeeID,
+		"email":            e.Email,
+		"name":             e.Name,
+		"department_id":    e.DepartmentID,
+		"role":             e.Role,
+		"is_active":        e.IsActive,
+		"joining_date":     e.JoiningDate.Format("2006-01-02"),
+		"resignation_date": resignationDate,
+		"phone":            e.Phone,
+		"address":          e.Address,
+		"manager_id":       e.ManagerID,
 	})
 }
 
-type updateEmployeeDTO struct {
-	Email        *string `json:"email"`
-	Phone        *string `json:"phone"`
-	DepartmentID *string `json:"department_id"`
-	Role         *string `json:"role"`
+// GET|HEAD /employees/exists?email= or ?employee_id=
+// Lets forms check uniqueness without fetching the employee.
+func (h *EmployeeHandler) EmployeeExists(c *gin.Context) {
+	email := strings.TrimSpace(c.Query("email"))
+	code := strings.TrimSpace(c.Query("employee_id"))
+	if (email == "") == (code == "") {
+		apierror.Respond(c, apierror.InvalidQuery, "provide exactly one of email or employee_id")
+		return
+	}
+	var exists bool
+	var err error
+	if code != "" {
+		exists, err = h.repos.Employees.EmployeeCodeExists(c.Request.Context(), code)
+	} else {
+		exists, err = h.repos.Employees.EmployeeEmailExists(c.Request.Context(), strings.ToLower(email))
+	}
+	if err != nil {
+		apierror.Database(c, err, "Failed to check employee")
+		return
+	}
+	respondExists(c, exists)
 }
 
-// PUT /employees/:id
-func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
+// HEAD /employees/:id
+func (h *EmployeeHandler) HeadEmployee(c *gin.Context) {
 	id := c.Param("id")
-	var in updateEmployeeDTO
-	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input", "details": err.Error()})
+	if !isUUID(id) {
+		respondExists(c, false)
 		return
 	}
+	exists, err := h.repos.Employees.EmployeeExists(c.Request.Context(), id)
+	if err != nil {
+		apierror.Database(c, err, "Failed to check employee")
+		return
+	}
+	respondExists(c, exists)
+}
 
-	updates := []string{}
-	args := []interface{}{}
-	argIdx := 1
+// employeePatchFields lists the employee columns that PATCH may change
+var employeePatchFields = map[string]patchField{
+	"email": {column: "email", parse: func(raw json.RawMessage) (interface{}, error) {
+		v, err := patchString(true)(raw)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToLower(v.(string)), nil
+	}},
+	"phone":         {column: "phone", nullable: true, parse: patchString(false)},
+	"address":       {column: "address", nullable: true, parse: patchString(false)},
+	"department_id": {column: "department_id", parse: patchString(true)},
+	// starts the notice period; null withdraws the resignation
+	"resignation_date": {column: "resignation_date", nullable: true, parse: patchDate},
+	"role": {column: "role", parse: func(raw json.RawMessage) (interface{}, error) {
+		v, err := patchString(true)(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !models.IsValidRole(v.(string)) {
+			return nil, errors.New("must be one of: employee, manager, hr, admin")
+		}
+		return v, nil
+	}},
+}
 
-	if in.Email != nil {
-		if strings.TrimSpace(*in.Email) == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "email cannot be empty"}); return }
-		updates = append(updates, fmt.Sprintf("email=$%d", argIdx))
-		args = append(args, strings.ToLower(strings.TrimSpace(*in.Email)))
-		argIdx++
-	}
-	if in.Phone != nil {
-		updates = append(updates, fmt.Sprintf("phone=$%d", argIdx))
-		args = append(args, strings.TrimSpace(*in.Phone))
-		argIdx++
-	}
-	if in.DepartmentID != nil {
-		updates = append(updates, fmt.Sprintf("department_id=$%d", argIdx))
-		args = append(args, *in.DepartmentID)
-		argIdx++
-	}
-	if in.Role != nil {
-		updates = append(updates, fmt.Sprintf("role=$%d", argIdx))
-		args = append(args, *in.Role)
-		argIdx++
+// PATCH /employees/:id (RFC 7386 merge patch; null clears phone/address/resignation_date)
+func (h *EmployeeHandler) UpdateEmployee(c *gin.Context) {
+	id := c.Param("id")
+	patch, ok := bindMergePatch(c)
+	if !ok {
+		return
 	}
 
+	updates, args, fieldErrs := patch.assignments(employeePatchFields, 1)
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
 	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "no fields to update")
 		return
 	}
 
-	query := "UPDATE employees SET " + strings.Join(updates, ", ") + ", updated_at=NOW() WHERE id=$" + fmt.Sprintf("%d", argIdx)
+	_, roleChange := patch["role"]
+	if roleChange && c.GetString("role") != models.RoleAdmin {
+		var role string
+		if json.Unmarshal(patch["role"], &role) == nil && role == models.RoleAdmin {
+			apierror.Respond(c, apierror.Forbidden, "only admins can grant the admin role")
+			return
+		}
+	}
+
+	query := "UPDATE employees SET " + strings.Join(updates, ", ") + ", updated_at=NOW() WHERE id=$" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, id)
 
-	ct, err := h.Pool.Exec(context.Background(), query, args...)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "update failed", "details": parsePgErr(err)})
+	// the login's role follows the employee's in the same transaction, so
+	// the permissions never disagree with it
+	ctx := c.Request.Context()
+	err := db.WithTx(ctx, h.Pool, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		if ct.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if roleChange {
+			return service.SyncUserRole(ctx, h.store.Bind(tx), id)
+		}
+		return nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
 		return
 	}
-	if ct.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
+	if err != nil {
+		apierror.Database(c, err, "update failed")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "employee updated"})
+	if roleChange {
+		h.forgetUserStatus(ctx, id)
+	}
+	respond(c, http.StatusOK, gin.H{"message": "employee updated"})
 }
 
 // DELETE /employees/:id (soft-delete: set is_active=false)
 func (h *EmployeeHandler) DeactivateEmployee(c *gin.Context) {
 	id := c.Param("id")
-	ct, err := h.Pool.Exec(context.Background(), `UPDATE employees SET is_active=false, updated_at=NOW() WHERE id=$1`, id)
+	n, err := h.repos.Employees.DeactivateEmployee(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deactivate employee"})
+		apierror.Database(c, err, "failed to deactivate employee")
 		return
 	}
-	if ct.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
+	if n == 0 {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "employee deactivated"})
+	h.forgetUserStatus(c.Request.Context(), id)
+	respond(c, http.StatusOK, gin.H{"message": "employee deactivated"})
+}
+
+// forgetUserStatus drops the cached auth status of the login linked to an
+// employee so the next request sees the change instead of waiting for the TTL
+func (h *EmployeeHandler) forgetUserStatus(ctx context.Context, employeeID string) {
+	if h.cache == nil {
+		return
+	}
+	userID, err := h.repos.Employees.GetEmployeeUserID(ctx, employeeID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.WarnContext(ctx, "cached user status not invalidated", "employee_id", employeeID, "error", err)
+		}
+		return
+	}
+	h.cache.Delete(ctx, cache.UserKey(userID))
 }
 
 // GET /employees/:id/leave-balances
@@ -333,64 +507,36 @@ func (h *EmployeeHandler) GetLeaveBalances(c *gin.Context) {
 	employeeID := c.Param("id")
 	
 	// Validate employee exists
-	var employeeName string
-	if err := h.Pool.QueryRow(context.Background(), "SELECT name FROM employees WHERE id=$1", employeeID).Scan(&employeeName); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
+	employeeName, err := h.repos.Employees.GetEmployeeName(c.Request.Context(), employeeID)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "employee not found", "failed to load employee")
 		return
 	}
 
 	// Get leave balances for current year
 	currentYear := time.Now().Year()
-	rows, err := h.Pool.Query(context.Background(), `
-		SELECT 
-			lt.id as leave_type_id,
-			lt.name as leave_type_name,
-			lt.description as leave_type_description,
-			elb.allocated_days,
-			elb.used_days,
-			elb.carried_forward_days,
-			elb.available_days,
-			elb.year
-		FROM employee_leave_balances elb
-		JOIN leave_types lt ON elb.leave_type_id = lt.id
-		WHERE elb.employee_id = $1 AND elb.year = $2
-		ORDER BY lt.name
-	`, employeeID, currentYear)
+	rows, err := h.repos.LeaveBalances.ListLeaveBalances(c.Request.Context(), queries.ListLeaveBalancesParams{EmployeeID: employeeID, Year: int32(currentYear)})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch leave balances"})
+		apierror.Database(c, err, "failed to fetch leave balances")
 		return
 	}
-	defer rows.Close()
 
 	var balances []map[string]interface{}
-	for rows.Next() {
-		var (
-			leaveTypeID          string
-			leaveTypeName        string
-			leaveTypeDescription string
-			allocatedDays        int
-			usedDays             int
-			carriedForwardDays   int
-			availableDays        int
-			year                 int
-		)
-		if err := rows.Scan(&leaveTypeID, &leaveTypeName, &leaveTypeDescription, &allocatedDays, &usedDays, &carriedForwardDays, &availableDays, &year); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "row scan failed"})
-			return
-		}
+	for _, b := range rows {
 		balances = append(balances, gin.H{
-			"leave_type_id":          leaveTypeID,
-			"leave_type_name":        leaveTypeName,
-			"leave_type_description": leaveTypeDescription,
-			"allocated_days":         allocatedDays,
-			"used_days":              usedDays,
-			"carried_forward_days":   carriedForwardDays,
-			"available_days":         availableDays,
-			"year":                   year,
+			"leave_type_id":          b.LeaveTypeID,
+			"leave_type_name":        b.LeaveTypeName,
+			"leave_type_description": b.LeaveTypeDescription,
+			"allocated_days":         b.AllocatedDays,
+			"used_days":              b.UsedDays,
+			"carried_forward_days":   b.CarriedForwardDays,
+			"available_days":         b.AvailableDays,
+			"pending_days":           b.PendingDays,
+			"year":                   b.Year,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, gin.H{
 		"employee_id": employeeID,
 		"employee_name": employeeName,
 		"year": currentYear,
@@ -399,11 +545,11 @@ func (h *EmployeeHandler) GetLeaveBalances(c *gin.Context) {
 }
 
 type UpdateLeaveBalanceDTO struct {
-	LeaveTypeID        string `json:"leave_type_id" binding:"required"`
-	AllocatedDays      *int   `json:"allocated_days"`
-	UsedDays           *int   `json:"used_days"`
-	CarriedForwardDays *int   `json:"carried_forward_days"`
-	Year               *int   `json:"year"`
+	LeaveTypeID        string   `json:"leave_type_id" binding:"required"`
+	AllocatedDays      *float64 `json:"allocated_days"` // fractional for accruing leave types
+	UsedDays           *float64 `json:"used_days"` // fractional for half days and hours
+	CarriedForwardDays *int     `json:"carried_forward_days"`
+	Year               *int     `json:"year"`
 }
 
 // PUT /employees/:id/leave-balances
@@ -411,22 +557,21 @@ func (h *EmployeeHandler) UpdateLeaveBalances(c *gin.Context) {
 	employeeID := c.Param("id")
 	
 	// Validate employee exists
-	var employeeName string
-	if err := h.Pool.QueryRow(context.Background(), "SELECT name FROM employees WHERE id=$1", employeeID).Scan(&employeeName); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "employee not found"})
+	employeeName, err := h.repos.Employees.GetEmployeeName(c.Request.Context(), employeeID)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "employee not found", "failed to load employee")
 		return
 	}
 
 	var input UpdateLeaveBalanceDTO
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
 	// Validate leave type exists
-	var leaveTypeName string
-	if err := h.Pool.QueryRow(context.Background(), "SELECT name FROM leave_types WHERE id=$1", input.LeaveTypeID).Scan(&leaveTypeName); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "leave_type_id not found"})
+	leaveTypeName, err := h.repos.LeaveTypes.GetLeaveTypeName(c.Request.Context(), input.LeaveTypeID)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.ReferenceNotFound, "leave_type_id not found", "failed to load leave type")
 		return
 	}
 
@@ -438,89 +583,53 @@ func (h *EmployeeHandler) UpdateLeaveBalances(c *gin.Context) {
 
 	// Validate year is reasonable
 	if year < 2020 || year > 2050 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "year must be between 2020 and 2050"})
+		apierror.Respond(c, apierror.InvalidInput, "year must be between 2020 and 2050")
 		return
 	}
 
-	// Build dynamic update query
-	query := `UPDATE employee_leave_balances SET `
-	args := []interface{}{}
-	argIdx := 1
-	updates := []string{}
-
-	if input.AllocatedDays != nil {
-		if *input.AllocatedDays < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "allocated_days cannot be negative"})
-			return
-		}
-		updates = append(updates, fmt.Sprintf("allocated_days=$%d", argIdx))
-		args = append(args, *input.AllocatedDays)
-		argIdx++
+	if input.AllocatedDays == nil && input.UsedDays == nil && input.CarriedForwardDays == nil {
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "at least one field must be provided for update")
+		return
 	}
-
-	if input.UsedDays != nil {
-		if *input.UsedDays < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "used_days cannot be negative"})
+	for _, f := range []struct {
+		name  string
+		value *int
+	}{
+		{"carried_forward_days", input.CarriedForwardDays},
+	} {
+		if f.value != nil && *f.value < 0 {
+			apierror.Respond(c, apierror.InvalidInput, f.name+" cannot be negative")
 			return
 		}
-		updates = append(updates, fmt.Sprintf("used_days=$%d", argIdx))
-		args = append(args, *input.UsedDays)
-		argIdx++
 	}
-
-	if input.CarriedForwardDays != nil {
-		if *input.CarriedForwardDays < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "carried_forward_days cannot be negative"})
+	for _, f := range []struct {
+		name  string
+		value *float64
+	}{
+		{"allocated_days", input.AllocatedDays},
+		{"used_days", input.UsedDays},
+	} {
+		if f.value != nil && *f.value < 0 {
+			apierror.Respond(c, apierror.InvalidInput, f.name+" cannot be negative")
 			return
 		}
-		updates = append(updates, fmt.Sprintf("carried_forward_days=$%d", argIdx))
-		args = append(args, *input.CarriedForwardDays)
-		argIdx++
-	}
-
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one field must be provided for update"})
-		return
 	}
 
-	query += strings.Join(updates, ", ")
-	query += fmt.Sprintf(" WHERE employee_id=$%d AND leave_type_id=$%d AND year=$%d", argIdx, argIdx+1, argIdx+2)
-	args = append(args, employeeID, input.LeaveTypeID, year)
-
-	// Execute update
-	result, err := h.Pool.Exec(context.Background(), query, args...)
+	// Fields left out keep their current value; a missing balance row is created
+	err = h.repos.LeaveBalances.UpsertLeaveBalance(c.Request.Context(), queries.UpsertLeaveBalanceParams{
+		EmployeeID:         employeeID,
+		LeaveTypeID:        input.LeaveTypeID,
+		Year:               int32(year),
+		AllocatedDays:      input.AllocatedDays,
+		UsedDays:           input.UsedDays,
+		CarriedForwardDays: optionalInt32(input.CarriedForwardDays),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leave balance", "details": err.Error()})
+		apierror.Database(c, err, "failed to update leave balance")
 		return
 	}
 
-	if result.RowsAffected() == 0 {
-		// Try to insert if record doesn't exist
-		allocatedDays := 0
-		usedDays := 0
-		carriedForwardDays := 0
-		
-		if input.AllocatedDays != nil {
-			allocatedDays = *input.AllocatedDays
-		}
-		if input.UsedDays != nil {
-			usedDays = *input.UsedDays
-		}
-		if input.CarriedForwardDays != nil {
-			carriedForwardDays = *input.CarriedForwardDays
-		}
-		
-		_, err = h.Pool.Exec(context.Background(), `
-			INSERT INTO employee_leave_balances (employee_id, leave_type_id, year, allocated_days, used_days, carried_forward_days)
-			VALUES ($1, $2, $3, $4, $5, $6)
-		`, employeeID, input.LeaveTypeID, year, allocatedDays, usedDays, carriedForwardDays)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create leave balance", "details": err.Error()})
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, gin.H{
 		"message": "leave balance updated successfully",
 		"employee_id": employeeID,
 		"employee_name": employeeName,
@@ -529,3 +638,11 @@ func (h *EmployeeHandler) UpdateLeaveBalances(c *gin.Context) {
 		"year": year,
 	})
 }
+
+func optionalInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
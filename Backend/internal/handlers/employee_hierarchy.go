@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxOrgChartDepth caps how many levels below its roots an org chart goes
+const maxOrgChartDepth = 20
+
+var (
+	errManagerNotFound = errors.New("manager not found")
+	errManagerInactive = errors.New("manager inactive")
+	errManagerCycle    = errors.New("manager cycle")
+)
+
+// PUT /employees/:id/manager
+// {"manager_id": "uuid"} sets who the employee reports to; null (or no
+// manager_id) removes their manager. The manager must be an active employee
+// who does not report to the employee, directly or through others.
+func (h *EmployeeHandler) SetManager(c *gin.Context) {
+	var in struct {
+		ManagerID *string `json:"manager_id" binding:"omitempty,uuid"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
+		return
+	}
+	if in.ManagerID != nil && *in.ManagerID == id {
+		apierror.Respond(c, apierror.InvalidInput, "an employee cannot be their own manager")
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := db.WithTx(ctx, h.Pool, func(tx pgx.Tx) error {
+		// Two concurrent changes could each pass the cycle check and close a
+		// loop together, so changes within an organization take turns
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('employee_hierarchy'), hashtext(current_org_id()::TEXT))"); err != nil {
+			return err
+		}
+		if in.ManagerID != nil {
+			var active bool
+			err := tx.QueryRow(ctx, "SELECT COALESCE(is_active, FALSE) FROM employees WHERE id = $1", *in.ManagerID).Scan(&active)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errManagerNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if !active {
+				return errManagerInactive
+			}
+			var cycle bool
+			if err := tx.QueryRow(ctx, `
+				WITH RECURSIVE chain AS (
+					SELECT id, manager_id FROM employees WHERE id = $1
+					UNION
+					SELECT e.id, e.manager_id FROM employees e JOIN chain ON e.id = chain.manager_id
+				)
+				SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)`, *in.ManagerID, id).Scan(&cycle); err != nil {
+				return err
+			}
+			if cycle {
+				return errManagerCycle
+			}
+		}
+		ct, err := tx.Exec(ctx, "UPDATE employees SET manager_id = $2, updated_at = NOW() WHERE id = $1", id, in.ManagerID)
+		if err != nil {
+			return err
+		}
+		if ct.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errManagerNotFound):
+		apierror.Respond(c, apierror.ReferenceNotFound, "manager_id not found")
+	case errors.Is(err, errManagerInactive):
+		apierror.Respond(c, apierror.InvalidInput, "manager must be an active employee")
+	case errors.Is(err, errManagerCycle):
+		apierror.Respond(c, apierror.ConstraintViolation, "the manager reports to this employee; the change would create a reporting cycle")
+	case err != nil:
+		apierror.Lookup(c, err, apierror.NotFound, "employee not found", "failed to update manager")
+	default:
+		// RequireOwnership reads the manager from the cache: the old manager
+		// must lose access, and the new one gain it, now rather than at its TTL
+		h.cache.Delete(ctx, cache.EmployeeManagerKey(id))
+		respond(c, http.StatusOK, gin.H{"message": "manager updated", "id": id, "manager_id": in.ManagerID})
+	}
+}
+
+// canViewHierarchy reports whether the caller may see the reports of employee
+// id: HR and admins anyone's, managers their own and those of anyone below
+// them, everyone else their own
+func (h *EmployeeHandler) canViewHierarchy(ctx context.Context, c *gin.Context, id string) (bool, error) {
+	role, self := c.GetString("role"), c.GetString("employee_uuid")
+	switch {
+	case role == models.RoleHR || role == models.RoleAdmin:
+		return true, nil
+	case self != "" && self == id:
+		return true, nil
+	case role != models.RoleManager || self == "":
+		return false, nil
+	}
+	var below bool
+	err := h.read.QueryRow(ctx, `
+		WITH RECURSIVE chain AS (
+			SELECT id, manager_id FROM employees WHERE id = $1
+			UNION
+			SELECT e.id, e.manager_id FROM employees e JOIN chain ON e.id = chain.manager_id
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE manager_id = $2)`, id, self).Scan(&below)
+	return below, err
+}
+
+type teamMember struct {
+	ID           string  `json:"id"`
+	EmployeeID   string  `json:"employee_id"`
+	Name         string  `json:"name"`
+	Email        string  `json:"email"`
+	Role         *string `json:"role"`
+	DepartmentID string  `json:"department_id"`
+	JoiningDate  string  `json:"joining_date"`
+	ReportsCount int     `json:"reports_count"` // their own active direct reports
+}
+
+// GET /employees/:id/team (paging: limit, offset)
+// The employee's active direct reports, by name.
+func (h *EmployeeHandler) GetTeam(c *gin.Context) {
+	id := c.Param("id")
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, teamSorts, "e.name, e.id", "e.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
+		return
+	}
+	ctx := c.Request.Context()
+	if ok, err := h.canViewHierarchy(ctx, c, id); err != nil {
+		apierror.Database(c, err, "failed to fetch team")
+		return
+	} else if !ok {
+		apierror.Respond(c, apierror.Forbidden, "Access denied to this resource")
+		return
+	}
+	exists, err := h.readRepos.Employees.EmployeeExists(ctx, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch team")
+		return
+	}
+	if !exists {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
+		return
+	}
+
+	var total int
+	if err := h.read.QueryRow(ctx, "SELECT COUNT(*) FROM employees WHERE manager_id = $1 AND is_active", id).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch team")
+		return
+	}
+	rows, err := h.read.Query(ctx, `
+		SELECT e.id, e.employee_id, e.name, e.email, e.role, e.department_id, e.joining_date,
+		       (SELECT COUNT(*) FROM employees r WHERE r.manager_id = e.id AND r.is_active)
+		FROM employees e
+		WHERE e.manager_id = $1 AND e.is_active
+		ORDER BY `+orderBy+`
+		LIMIT $2 OFFSET $3`, id, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch team")
+		return
+	}
+	defer rows.Close()
+	team := make([]teamMember, 0)
+	for rows.Next() {
+		var m teamMember
+		var joiningDate time.Time
+		if err := rows.Scan(&m.ID, &m.EmployeeID, &m.Name, &m.Email, &m.Role, &m.DepartmentID, &joiningDate, &m.ReportsCount); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		m.JoiningDate = joiningDate.Format("2006-01-02")
+		team = append(team, m)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch team")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": team, "meta": page.meta(total, len(team))})
+}
+
+// orgChartNode is one employee of an org chart, with everyone reporting to them
+type orgChartNode struct {
+	ID           string          `json:"id"`
+	EmployeeID   string          `json:"employee_id"`
+	Name         string          `json:"name"`
+	Email        string          `json:"email"`
+	Role         *string         `json:"role"`
+	DepartmentID string          `json:"department_id"`
+	Reports      []*orgChartNode `json:"reports"`
+}
+
+// GET /employees/:id/org-chart (optional depth, default and at most 20)
+// The employee and, recursively, the active employees reporting to them,
+// depth levels down.
+func (h *EmployeeHandler) GetOrgChart(c *gin.Context) {
+	id := c.Param("id")
+	depth, ok := orgChartDepth(c)
+	if !ok {
+		return
+	}
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
+		return
+	}
+	if ok, err := h.canViewHierarchy(c.Request.Context(), c, id); err != nil {
+		apierror.Database(c, err, "failed to fetch org chart")
+		return
+	} else if !ok {
+		apierror.Respond(c, apierror.Forbidden, "Access denied to this resource")
+		return
+	}
+	roots, err := h.orgChart(c.Request.Context(), "id = $2", depth, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch org chart")
+		return
+	}
+	if len(roots) == 0 {
+		apierror.Respond(c, apierror.NotFound, "employee not found")
+		return
+	}
+	respond(c, http.StatusOK, roots[0])
+}
+
+// GET /org-chart (optional depth, default and at most 20)
+// The whole organization: every active employee without an active manager,
+// with the employees reporting to them.
+func (h *EmployeeHandler) GetFullOrgChart(c *gin.Context) {
+	depth, ok := orgChartDepth(c)
+	if !ok {
+		return
+	}
+	roots, err := h.orgChart(c.Request.Context(), `is_active AND NOT EXISTS (
+		SELECT 1 FROM employees m WHERE m.id = employees.manager_id AND m.is_active)`, depth)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch org chart")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": roots})
+}
+
+func orgChartDepth(c *gin.Context) (int, bool) {
+	v := c.Query("depth")
+	if v == "" {
+		return maxOrgChartDepth, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > maxOrgChartDepth {
+		apierror.Respond(c, apierror.InvalidQuery, "depth must be between 0 and 20")
+		return 0, false
+	}
+	return n, true
+}
+
+// orgChart builds the trees below the employees matching rootCond, which may
+// use $2 onwards. Employees already on the path are skipped, so a reporting
+// cycle left in old data cannot loop.
+func (h *EmployeeHandler) orgChart(ctx context.Context, rootCond string, depth int, args ...interface{}) ([]*orgChartNode, error) {
+	rows, err := h.read.Query(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id, manager_id, 0 AS depth, ARRAY[id] AS path
+			FROM employees WHERE `+rootCond+`
+			UNION ALL
+			SELECT e.id, e.manager_id, t.depth + 1, t.path || e.id
+			FROM employees e JOIN tree t ON e.manager_id = t.id
+			WHERE e.is_active AND t.depth < $1 AND NOT e.id = ANY(t.path)
+		)
+		SELECT t.id, t.depth, t.manager_id, e.employee_id, e.name, e.email, e.role, e.department_id
+		FROM tree t JOIN employees e ON e.id = t.id
+		ORDER BY t.depth, e.name, e.id`, append([]interface{}{depth}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roots := make([]*orgChartNode, 0)
+	nodes := map[string]*orgChartNode{}
+	for rows.Next() {
+		n := &orgChartNode{Reports: make([]*orgChartNode, 0)}
+		var level int
+		var managerID *string
+		if err := rows.Scan(&n.ID, &level, &managerID, &n.EmployeeID, &n.Name, &n.Email, &n.Role, &n.DepartmentID); err != nil {
+			return nil, err
+		}
+		nodes[n.ID] = n
+		if level == 0 {
+			roots = append(roots, n)
+		} else if parent := nodes[*managerID]; parent != nil {
+			parent.Reports = append(parent.Reports, n)
+		}
+	}
+	return roots, rows.Err()
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/dbtest"
+	"leave-management/internal/jwtkeys"
+	"leave-management/internal/middleware"
+	"leave-management/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// A reassigned employee is visible to the new manager, and no longer to the
+// old one, on the very next request, although the manager is cached
+func TestSetManagerAppliesToOwnershipAtOnce(t *testing.T) {
+	pool := dbtest.Pool(t)
+	ctx := db.AsService(context.Background())
+	rc, err := cache.New(context.Background(), "redis://"+miniredis.RunT(t).Addr(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	suffix := dbtest.Suffix()
+	var deptID string
+	if err := pool.QueryRow(ctx, "INSERT INTO departments (org_id, name) VALUES ($1, $2) RETURNING id",
+		db.DefaultOrgID, "hierarchy-test-"+suffix).Scan(&deptID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(ctx, "DELETE FROM users WHERE org_id = $1 AND email LIKE $2", db.DefaultOrgID, "%-"+suffix+"@example.com")
+		pool.Exec(ctx, "DELETE FROM employees WHERE department_id = $1", deptID)
+		pool.Exec(ctx, "DELETE FROM departments WHERE id = $1", deptID)
+	})
+
+	keys := jwtkeys.New(jwtkeys.Options{Secret: "hierarchy-test-secret-0123456789abcdef", Issuer: "lms", Audience: "lms", TTL: time.Hour})
+	// person creates an employee with a login and returns their employees.id
+	// and access token
+	person := func(code, role string, managerID *string) (string, string) {
+		t.Helper()
+		email := code + "-" + suffix + "@example.com"
+		var id, userID string
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO employees (org_id, employee_id, email, name, department_id, role, joining_date, manager_id)
+			VALUES ($1, $2, $3, $2, $4, $5, CURRENT_DATE, $6) RETURNING id`,
+			db.DefaultOrgID, code+suffix, email, deptID, role, managerID).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		if err := pool.QueryRow(ctx, `
+			INSERT INTO users (org_id, employee_id, email, password_hash, role) VALUES ($1, $2, $3, 'x', $4) RETURNING id`,
+			db.DefaultOrgID, code+suffix, email, role).Scan(&userID); err != nil {
+			t.Fatal(err)
+		}
+		token, err := keys.Sign(models.JWTClaims{UserID: userID, OrgID: db.DefaultOrgID, Email: email, Role: role,
+			EmployeeID: code + suffix, RegisteredClaims: keys.Registered(userID)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id, token
+	}
+	_, hrToken := person("hr", models.RoleHR, nil)
+	oldID, oldToken := person("old", models.RoleManager, nil)
+	newID, newToken := person("new", models.RoleManager, nil)
+	empID, _ := person("emp", models.RoleEmployee, &oldID)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	am := middleware.NewAuthMiddleware(pool, rc, time.Hour, func() *jwtkeys.Keyring { return keys })
+	eh := NewEmployeeHandler(pool, pool, rc)
+	r.GET("/employees/:id", am.Authenticate(), am.RequireOwnership("employee"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.PUT("/employees/:id/manager", am.Authenticate(), am.RequireRole(models.RoleAdmin, models.RoleHR), eh.SetManager)
+
+	call := func(method, path, token string, body []byte) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// caches the employee's manager
+	if code := call("GET", "/employees/"+empID, oldToken, nil); code != http.StatusNoContent {
+		t.Fatalf("old manager before the change = %d, want 204", code)
+	}
+	if code := call("GET", "/employees/"+empID, newToken, nil); code != http.StatusForbidden {
+		t.Fatalf("new manager before the change = %d, want 403", code)
+	}
+	if code := call("PUT", "/employees/"+empID+"/manager", hrToken, []byte(`{"manager_id": "`+newID+`"}`)); code != http.StatusOK {
+		t.Fatalf("PUT manager = %d, want 200", code)
+	}
+	if code := call("GET", "/employees/"+empID, oldToken, nil); code != http.StatusForbidden {
+		t.Errorf("old manager after the change = %d, want 403", code)
+	}
+	if code := call("GET", "/employees/"+empID, newToken, nil); code != http.StatusNoContent {
+		t.Errorf("new manager after the change = %d, want 204", code)
+	}
+}
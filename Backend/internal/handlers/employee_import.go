@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/repository"
+	"leave-management/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxImportRows is the most employees one import creates; the generated
+// employee IDs number the rows with three digits
+const maxImportRows = 1000
+
+// importColumns are the header names an import file may use; all but
+// employee_id are required
+var importColumns = []string{"name", "email", "department_id", "joining_date", "employee_id"}
+
+type importedEmployee struct {
+	Row        int    `json:"row"`
+	ID         string `json:"id"`
+	EmployeeID string `json:"employee_id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+}
+
+type importRow struct {
+	line     int // in the file, the header being line 1
+	in       createEmployeeDTO
+	empID    string
+	joinDate time.Time
+}
+
+// importRowError is a database error while creating the employee of a row
+type importRowError struct {
+	line int
+	err  error
+}
+
+func (e importRowError) Error() string { return e.err.Error() }
+func (e importRowError) Unwrap() error { return e.err }
+
+var (
+	errImportInvalid = errors.New("invalid employee import")
+	errImportDryRun  = errors.New("dry run")
+)
+
+// POST /employees/import (multipart/form-data with a CSV or XLSX file in
+// "file"; dry_run=true validates without saving)
+// Creates one employee per row of the file, or of the first worksheet, as
+// POST /employees does: the first row names the columns name, email,
+// department_id, joining_date and optionally employee_id, in any order. The
+// import is all-or-nothing: every row is checked first, and any errors are
+// returned together, per row, with nothing created.
+func (h *EmployeeHandler) ImportEmployees(c *gin.Context) {
+	dryRun, err := parseBoolQuery(c, "dry_run")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Respond(c, apierror.PayloadTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+			return
+		}
+		apierror.Respond(c, apierror.InvalidInput, "a CSV or XLSX file is required in the file field")
+		return
+	}
+	format := spreadsheet.FormatOf(fh.Filename)
+	if format == "" {
+		apierror.Fields(c, "invalid employee import", []apierror.FieldError{{Field: "file", Error: "must be a .csv or .xlsx file"}})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidInput, "a CSV or XLSX file is required in the file field")
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidInput, "a CSV or XLSX file is required in the file field")
+		return
+	}
+	table, err := spreadsheet.Read(data, format)
+	if err != nil {
+		apierror.Fields(c, "invalid employee import", []apierror.FieldError{{Field: "file", Error: "could not be read as " + strings.ToUpper(string(format))}})
+		return
+	}
+
+	n := 0
+	for i := 1; i < len(table); i++ {
+		if !isBlankRecord(table[i]) {
+			n++
+		}
+	}
+	if n == 0 || n > maxImportRows {
+		apierror.Respond(c, apierror.InvalidInput, fmt.Sprintf("the file must contain between 1 and %d employees", maxImportRows))
+		return
+	}
+	rows, rowErrors := parseImportRows(table, format)
+	if len(rowErrors) > 0 {
+		apierror.Fields(c, "invalid employee import", rowErrors)
+		return
+	}
+
+	ctx := c.Request.Context()
+	var created []importedEmployee
+	err = h.store.InTx(ctx, func(r repository.Repos) error {
+		// Check the rows against the database before inserting any, since
+		// a failed insert aborts the transaction
+		rowErrors = rowErrors[:0]
+		departments := map[string]bool{}
+		for _, row := range rows {
+			exists, ok := departments[row.in.DepartmentID]
+			if !ok {
+				var err error
+				if exists, err = r.Employees.DepartmentExists(ctx, row.in.DepartmentID); err != nil {
+					return err
+				}
+				departments[row.in.DepartmentID] = exists
+			}
+			if !exists {
+				rowErrors = append(rowErrors, apierror.FieldError{Field: fmt.Sprintf("rows[%d].department_id", row.line), Error: "does not exist"})
+			}
+			taken, err := r.Employees.EmployeeEmailExists(ctx, row.in.Email)
+			if err != nil {
+				return err
+			}
+			if taken {
+				rowErrors = append(rowErrors, apierror.FieldError{Field: fmt.Sprintf("rows[%d].email", row.line), Error: "is already used by another employee"})
+			}
+			if row.in.EmployeeID != "" {
+				if taken, err = r.Employees.EmployeeCodeExists(ctx, row.empID); err != nil {
+					return err
+				}
+				if taken {
+					rowErrors = append(rowErrors, apierror.FieldError{Field: fmt.Sprintf("rows[%d].employee_id", row.line), Error: "is already used by another employee"})
+				}
+			}
+		}
+		if len(rowErrors) > 0 {
+			return errImportInvalid
+		}
+
+		created = make([]importedEmployee, 0, len(rows))
+		for _, row := range rows {
+			id, err := createEmployee(ctx, r, row.in, row.empID, row.joinDate)
+			if err != nil {
+				return importRowError{line: row.line, err: err}
+			}
+			created = append(created, importedEmployee{Row: row.line, ID: id, EmployeeID: row.empID, Name: row.in.Name, Email: row.in.Email})
+		}
+		if dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	var rowErr importRowError
+	switch {
+	case errors.Is(err, errImportDryRun):
+		respond(c, http.StatusOK, gin.H{"dry_run": true, "message": "no errors found; nothing was imported", "rows": len(rows)})
+		return
+	case errors.Is(err, errImportInvalid):
+		apierror.Fields(c, "invalid employee import", rowErrors)
+		return
+	case errors.As(err, &rowErr):
+		code, msg := apierror.FromDatabase(rowErr.err, "import failed")
+		if errors.Is(rowErr.err, errDepartmentNotFound) {
+			code, msg = apierror.ReferenceNotFound, "department_id not found"
+		}
+		apierror.RespondWithDetails(c, code, msg, gin.H{"row": rowErr.line})
+		return
+	case err != nil:
+		apierror.Database(c, err, "import failed")
+		return
+	}
+	respond(c, http.StatusCreated, gin.H{"message": "employees imported", "imported": len(created), "employees": created})
+}
+
+// parseImportRows validates the rows of an import file as POST /employees
+// validates its body, and assigns the employee IDs of rows without one.
+// Blank rows are skipped. table has a header and at most maxImportRows rows.
+func parseImportRows(table [][]string, format spreadsheet.Format) ([]importRow, []apierror.FieldError) {
+	var fileErrors []apierror.FieldError
+	columns := map[string]int{}
+	for i, name := range table[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !containsString(importColumns, name) {
+			fileErrors = append(fileErrors, apierror.FieldError{Field: "file", Error: fmt.Sprintf("unknown column %q", name)})
+			continue
+		}
+		if _, dup := columns[name]; dup {
+			fileErrors = append(fileErrors, apierror.FieldError{Field: "file", Error: fmt.Sprintf("column %q appears more than once", name)})
+			continue
+		}
+		columns[name] = i
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok && name != "employee_id" {
+			fileErrors = append(fileErrors, apierror.FieldError{Field: "file", Error: fmt.Sprintf("column %q is missing", name)})
+		}
+	}
+	if fileErrors != nil {
+		return nil, fileErrors
+	}
+
+	value := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rowErrors := []apierror.FieldError{}
+	var rows []importRow
+	emails := map[string]bool{}
+	codes := map[string]bool{}
+	today := time.Now().Truncate(24 * time.Hour)
+	stamp := time.Now().Format("060102150405")
+	for i, record := range table[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		row := importRow{line: i + 2, in: createEmployeeDTO{
+			Name:         value(record, "name"),
+			Email:        strings.ToLower(value(record, "email")),
+			DepartmentID: value(record, "department_id"),
+			JoiningDate:  value(record, "joining_date"),
+			EmployeeID:   value(record, "employee_id"),
+		}}
+		if format == spreadsheet.XLSX {
+			// a date cell holds a serial number rather than text
+			if d, ok := spreadsheet.ExcelDate(row.in.JoiningDate); ok {
+				row.in.JoiningDate = d.Format("2006-01-02")
+			}
+		}
+		field := func(name string) string { return fmt.Sprintf("rows[%d].%s", row.line, name) }
+		before := len(rowErrors)
+		if err := binding.Validator.ValidateStruct(&row.in); err != nil {
+			for _, fe := range fieldErrors(err, &row.in) {
+				rowErrors = append(rowErrors, apierror.FieldError{Field: field(fe.Field), Error: fe.Error})
+			}
+		}
+		if d, err := time.Parse("2006-01-02", row.in.JoiningDate); err == nil {
+			if d.After(today) {
+				rowErrors = append(rowErrors, apierror.FieldError{Field: field("joining_date"), Error: "cannot be in the future"})
+			}
+			row.joinDate = d
+		}
+		if row.in.Email != "" {
+			if emails[row.in.Email] {
+				rowErrors = append(rowErrors, apierror.FieldError{Field: field("email"), Error: "appears more than once in the file"})
+			}
+			emails[row.in.Email] = true
+		}
+		if row.in.EmployeeID != "" {
+			if codes[row.in.EmployeeID] {
+				rowErrors = append(rowErrors, apierror.FieldError{Field: field("employee_id"), Error: "appears more than once in the file"})
+			}
+			codes[row.in.EmployeeID] = true
+		}
+		if len(rowErrors) > before {
+			continue
+		}
+		row.empID = row.in.EmployeeID
+		if row.empID == "" {
+			// unlike generateEmployeeID, unique within the import
+			row.empID = fmt.Sprintf("EMP-%s-%03d", stamp, len(rows))
+		}
+		rows = append(rows, row)
+	}
+	if len(rowErrors) == 0 {
+		rowErrors = nil
+	}
+	return rows, rowErrors
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
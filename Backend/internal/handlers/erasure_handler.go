@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErasureHandler serves the erasure request workflow: a request is filed by
+// the employee or by HR, approved or rejected by an admin, and carried out by
+// the erasure job (jobs.ProcessErasureRequests)
+type ErasureHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewErasureHandler(pool *pgxpool.Pool) *ErasureHandler {
+	return &ErasureHandler{pool: pool}
+}
+
+type erasureRequest struct {
+	ID              string     `json:"id"`
+	EmployeeID      string     `json:"employee_id"`
+	Reason          *string    `json:"reason"`
+	Status          string     `json:"status"`
+	RequestedBy     string     `json:"requested_by"`
+	RequestedAt     time.Time  `json:"requested_at"`
+	ReviewedBy      *string    `json:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	RejectionReason *string    `json:"rejection_reason"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	LastError       *string    `json:"last_error"`
+}
+
+const erasureColumns = `id, employee_id, reason, status, requested_by, requested_at, reviewed_by, reviewed_at,
+	rejection_reason, completed_at, last_error`
+
+func scanErasureRequest(row pgx.Row) (erasureRequest, error) {
+	var r erasureRequest
+	err := row.Scan(&r.ID, &r.EmployeeID, &r.Reason, &r.Status, &r.RequestedBy, &r.RequestedAt, &r.ReviewedBy, &r.ReviewedAt,
+		&r.RejectionReason, &r.CompletedAt, &r.LastError)
+	return r, err
+}
+
+// POST /erasure-requests
+// Employees file for themselves (employee_id may be omitted); HR and admins
+// for any employee of the organization.
+func (h *ErasureHandler) CreateErasureRequest(c *gin.Context) {
+	var in struct {
+		EmployeeID string  `json:"employee_id" binding:"omitempty,uuid"`
+		Reason     *string `json:"reason"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	ctx := c.Request.Context()
+	role := c.GetString("role")
+	if in.EmployeeID == "" || (role != models.RoleHR && role != models.RoleAdmin) {
+		own, err := currentEmployeeID(ctx, h.pool, c)
+		if err != nil {
+			apierror.Lookup(c, err, apierror.NotFound, "employee not found", "failed to create erasure request")
+			return
+		}
+		if in.EmployeeID != "" && in.EmployeeID != own {
+			apierror.Respond(c, apierror.Forbidden, "you can only request erasure of your own data")
+			return
+		}
+		in.EmployeeID = own
+	}
+
+	r, err := scanErasureRequest(h.pool.QueryRow(ctx, `
+		INSERT INTO erasure_requests (employee_id, reason, requested_by)
+		VALUES ($1, $2, $3)
+		RETURNING `+erasureColumns, in.EmployeeID, in.Reason, c.GetString("user_id")))
+	if err != nil {
+		apierror.Database(c, err, "failed to create erasure request")
+		return
+	}
+	respond(c, http.StatusCreated, r)
+}
+
+// GET /erasure-requests?status=pending (paging: limit, offset; sort: requested_at, status)
+func (h *ErasureHandler) ListErasureRequests(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, erasureRequestSorts, "requested_at DESC, id DESC", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	status := c.Query("status")
+	switch status {
+	case "", "pending", "approved", "rejected", "completed":
+	default:
+		apierror.Respond(c, apierror.InvalidQuery, "status must be pending, approved, rejected or completed")
+		return
+	}
+
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM erasure_requests WHERE $1 = '' OR status = $1", status).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch erasure requests")
+		return
+	}
+	rows, err := h.pool.Query(ctx, `
+		SELECT `+erasureColumns+` FROM erasure_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY `+orderBy+`
+		LIMIT $2 OFFSET $3`, status, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch erasure requests")
+		return
+	}
+	defer rows.Close()
+
+	list := make([]erasureRequest, 0)
+	for rows.Next() {
+		r, err := scanErasureRequest(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch erasure requests")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// GET /erasure-requests/:id (HR/Admin, or whoever filed it)
+func (h *ErasureHandler) GetErasureRequest(c *gin.Context) {
+	r, err := scanErasureRequest(h.pool.QueryRow(c.Request.Context(),
+		"SELECT "+erasureColumns+" FROM erasure_requests WHERE id = $1", c.Param("id")))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "erasure request not found", "failed to fetch erasure request")
+		return
+	}
+	respond(c, http.StatusOK, r)
+}
+
+var (
+	// errOwnErasureRequest stops an admin from reviewing a request they filed
+	errOwnErasureRequest = errors.New("own request")
+	errNotPending        = errors.New("not pending")
+)
+
+// review moves a pending request to status, unless the reviewer filed it
+func (h *ErasureHandler) review(ctx context.Context, id, reviewer, status string, reason *string) (erasureRequest, error) {
+	var r erasureRequest
+	err := db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		var err error
+		r, err = scanErasureRequest(tx.QueryRow(ctx,
+			"SELECT "+erasureColumns+" FROM erasure_requests WHERE id = $1 FOR UPDATE", id))
+		if err != nil {
+			return err
+		}
+		if r.Status != "pending" {
+			return errNotPending
+		}
+		if r.RequestedBy == reviewer {
+			return errOwnErasureRequest
+		}
+		r, err = scanErasureRequest(tx.QueryRow(ctx, `
+			UPDATE erasure_requests SET status = $2, reviewed_by = $3, reviewed_at = NOW(), rejection_reason = $4
+			WHERE id = $1
+			RETURNING `+erasureColumns, id, status, reviewer, reason))
+		return err
+	})
+	return r, err
+}
+
+// respondReview answers a review, which only pending requests accept
+func respondReview(c *gin.Context, r erasureRequest, err error, fallback string) {
+	switch {
+	case errors.Is(err, errOwnErasureRequest):
+		apierror.Respond(c, apierror.Forbidden, "you cannot review your own erasure request")
+	case errors.Is(err, errNotPending):
+		apierror.Respond(c, apierror.InvalidState, "erasure request is not pending")
+	case err != nil:
+		apierror.Lookup(c, err, apierror.NotFound, "erasure request not found", fallback)
+	default:
+		respond(c, http.StatusOK, r)
+	}
+}
+
+// PUT /erasure-requests/:id/approve
+// The erasure job carries the request out on its next run.
+func (h *ErasureHandler) ApproveErasureRequest(c *gin.Context) {
+	r, err := h.review(c.Request.Context(), c.Param("id"), c.GetString("user_id"), "approved", nil)
+	respondReview(c, r, err, "failed to approve erasure request")
+}
+
+// PUT /erasure-requests/:id/reject
+func (h *ErasureHandler) RejectErasureRequest(c *gin.Context) {
+	var in struct {
+		RejectionReason string `json:"rejection_reason" binding:"required"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	r, err := h.review(c.Request.Context(), c.Param("id"), c.GetString("user_id"), "rejected", &in.RejectionReason)
+	respondReview(c, r, err, "failed to reject erasure request")
+}
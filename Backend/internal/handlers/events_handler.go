@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"io"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/models"
+	"leave-management/internal/stream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventsHeartbeat keeps idle streams from being closed by proxies
+const eventsHeartbeat = 25 * time.Second
+
+type EventsHandler struct {
+	broker *stream.Broker
+}
+
+func NewEventsHandler(broker *stream.Broker) *EventsHandler {
+	return &EventsHandler{broker: broker}
+}
+
+// GET /events
+// Server-Sent Events: new leave requests, status changes and comments as they
+// commit. HR and admins get every request of their organization, managers
+// those of their direct reports and their own, everyone else their own.
+// Nobody gets their own comments back.
+func (h *EventsHandler) Stream(c *gin.Context) {
+	orgID, role, self := c.GetString("org_id"), c.GetString("role"), c.GetString("employee_uuid")
+	sub, ok := h.broker.Subscribe(func(e stream.Event) bool {
+		if e.OrgID != orgID || (e.Type == stream.Commented && e.AuthorID == self) {
+			return false
+		}
+		switch role {
+		case models.RoleHR, models.RoleAdmin:
+			return true
+		case models.RoleManager:
+			return self != "" && (e.ManagerID == self || e.EmployeeID == self)
+		default:
+			return self != "" && e.EmployeeID == self
+		}
+	})
+	if !ok {
+		apierror.Respond(c, apierror.Unavailable, "server is shutting down")
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx would hold the events back
+	c.SSEvent("ready", gin.H{"heartbeat_seconds": int(eventsHeartbeat.Seconds())})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return // shutting down, or the client fell behind
+			}
+			c.SSEvent(e.Type, e)
+		case <-heartbeat.C:
+			io.WriteString(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
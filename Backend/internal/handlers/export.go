@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// writeCSV streams rows as a CSV attachment named prefix-<timestamp>.csv.
+// record scans the current row into the values of one line. A failure once
+// the 200 is out aborts the response (see abortCSV).
+func writeCSV(c *gin.Context, prefix string, header []string, rows pgx.Rows, record func(pgx.Rows) ([]string, error)) {
+	filename := prefix + "-" + time.Now().Format("20060102-150405") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(header)
+	n := 0
+	for rows.Next() {
+		values, err := record(rows)
+		if err != nil {
+			abortCSV(c, err)
+		}
+		if err := w.Write(values); err != nil {
+			abortCSV(c, err)
+		}
+		// flush periodically so large exports are streamed instead of buffered
+		n++
+		if n%500 == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		abortCSV(c, err)
+	}
+	w.Flush()
+	c.Writer.Flush()
+}
+
+// abortCSV ends a CSV download that failed after its 200 went out: the
+// connection is dropped instead of the file being ended, so the client sees
+// the download fail rather than a file that looks complete
+func abortCSV(c *gin.Context, err error) {
+	slog.ErrorContext(c.Request.Context(), "CSV export aborted", "route", c.FullPath(), "error", err,
+		"request_id", c.GetString("request_id"))
+	panic(http.ErrAbortHandler)
+}
+
+// csvText guards free text typed in by users against being run as a formula
+// when the file is opened in a spreadsheet
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func formatDays(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// GET /leave-requests/export (same filters and sort as GET /leave-requests, no
+// paging)
+// Streams every matching request the caller can see as CSV, e.g. for monthly
+// reporting: ?from=2025-06-01&to=2025-06-30&status=approved.
+func (h *LeaveRequestHandler) ExportLeaveRequests(c *gin.Context) {
+	orderBy, _, err := parseSort(c, leaveRequestSorts, "lr.start_date, lr.id", "lr.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	query, args, ok := h.leaveRequestQuery(c, false)
+	if !ok {
+		return
+	}
+	rows, err := h.read.Query(c.Request.Context(), query+" ORDER BY "+orderBy, args...)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch leave requests")
+		return
+	}
+	defer rows.Close()
+
+	writeCSV(c, "leave-requests", []string{"id", "employee_id", "employee_name", "employee_email", "leave_type_id", "leave_type_name",
+		"start_date", "end_date", "total_days", "duration_unit", "hours", "status", "approval_route", "approval_stage",
+		"reason", "applied_at", "approved_by", "approved_at", "rejection_reason", "comments", "in_notice_period",
+		"created_at", "updated_at"}, rows, func(rows pgx.Rows) ([]string, error) {
+		var (
+			id, empID, leaveTypeID, reason, status string
+			startDate, endDate, appliedAt          time.Time
+			totalDays                              float64
+			approvedBy, rejectionReason, comments  *string
+			approvedAt                             *time.Time
+			createdAt, updatedAt                   time.Time
+			approvalRoute, durationUnit            string
+			hours                                  *float64
+			approvalStage                          *string
+			employeeName, employeeEmail            string
+			leaveTypeName                          string
+			inNoticePeriod                         bool
+		)
+		if err := rows.Scan(&id, &empID, &leaveTypeID, &startDate, &endDate, &totalDays, &reason, &status, &appliedAt, &approvedBy, &approvedAt,
+			&rejectionReason, &comments, &createdAt, &updatedAt, &approvalRoute, &durationUnit, &hours, &approvalStage,
+			&employeeName, &employeeEmail, &leaveTypeName, &inNoticePeriod); err != nil {
+			return nil, err
+		}
+		var hoursText, approvedAtText string
+		if hours != nil {
+			hoursText = formatDays(*hours)
+		}
+		if approvedAt != nil {
+			approvedAtText = approvedAt.Format(time.RFC3339)
+		}
+		return []string{id, empID, csvText(employeeName), employeeEmail, leaveTypeID, csvText(leaveTypeName),
+			startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), formatDays(totalDays), durationUnit, hoursText,
+			status, approvalRoute, deref(approvalStage),
+			csvText(reason), appliedAt.Format(time.RFC3339), deref(approvedBy), approvedAtText, csvText(deref(rejectionReason)),
+			csvText(deref(comments)), strconv.FormatBool(inNoticePeriod),
+			createdAt.Format(time.RFC3339), updatedAt.Format(time.RFC3339)}, nil
+	})
+}
+
+// GET /employees/leave-balances/export (optional year, default the current
+// year; department_id; employee_id; include_inactive)
+// Streams the balances of the employees as CSV, one line per employee and
+// leave type, ordered by employee name. Managers get their direct reports.
+func (h *EmployeeHandler) ExportLeaveBalances(c *gin.Context) {
+	year := time.Now().Year()
+	if v := c.Query("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2020 || y > 2050 {
+			apierror.Respond(c, apierror.InvalidQuery, "year must be between 2020 and 2050")
+			return
+		}
+		year = y
+	}
+	departmentID, employeeID := c.Query("department_id"), c.Query("employee_id")
+	if departmentID != "" && !isUUID(departmentID) {
+		apierror.Respond(c, apierror.InvalidQuery, "department_id must be a UUID")
+		return
+	}
+	if employeeID != "" && !isUUID(employeeID) {
+		apierror.Respond(c, apierror.InvalidQuery, "employee_id must be a UUID")
+		return
+	}
+	includeInactive, err := parseBoolQuery(c, "include_inactive")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	var managerID string
+	if c.GetString("role") == models.RoleManager {
+		managerID = c.GetString("employee_uuid")
+		if managerID == "" {
+			apierror.Respond(c, apierror.Forbidden, "only users with an employee record can query their team's balances")
+			return
+		}
+	}
+
+	rows, err := h.read.Query(c.Request.Context(), `
+		SELECT e.id, e.employee_id, e.name, e.email, e.department_id, COALESCE(e.is_active, TRUE), lt.id, lt.name, elb.year,
+		       elb.allocated_days::FLOAT8, elb.used_days::FLOAT8, elb.carried_forward_days, elb.available_days::FLOAT8
+		FROM employee_leave_balances elb
+		JOIN employees e ON e.id = elb.employee_id
+		JOIN leave_types lt ON lt.id = elb.leave_type_id
+		WHERE elb.year = $1
+		  AND ($2 = '' OR e.department_id = NULLIF($2, '')::UUID)
+		  AND ($3 = '' OR e.id = NULLIF($3, '')::UUID)
+		  AND ($4 = '' OR e.manager_id = NULLIF($4, '')::UUID)
+		  AND ($5 OR COALESCE(e.is_active, TRUE))
+		ORDER BY e.name, e.id, lt.name`, year, departmentID, employeeID, managerID, includeInactive)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch leave balances")
+		return
+	}
+	defer rows.Close()
+
+	writeCSV(c, "leave-balances-"+strconv.Itoa(year), []string{"employee_id", "employee_code", "employee_name", "employee_email",
+		"department_id", "is_active", "leave_type_id", "leave_type_name", "year",
+		"allocated_days", "used_days", "carried_forward_days", "available_days"}, rows, func(rows pgx.Rows) ([]string, error) {
+		var (
+			id, code, name, email, departmentID, leaveTypeID, leaveTypeName string
+			isActive                                                        bool
+			year, carriedForward                                            int
+			allocated, used, available                                      float64
+		)
+		if err := rows.Scan(&id, &code, &name, &email, &departmentID, &isActive, &leaveTypeID, &leaveTypeName, &year,
+			&allocated, &used, &carriedForward, &available); err != nil {
+			return nil, err
+		}
+		return []string{id, csvText(code), csvText(name), email, departmentID, strconv.FormatBool(isActive), leaveTypeID, csvText(leaveTypeName),
+			strconv.Itoa(year), formatDays(allocated), formatDays(used), strconv.Itoa(carriedForward), formatDays(available)}, nil
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fields that may be requested with ?fields= on list endpoints
+var (
+	employeeFields = []string{
+		"id", "employee_id", "email", "name", "department_id", "role",
+		"is_active", "joining_date", "phone", "address",
+	}
+	leaveRequestFields = []string{
+		"id", "employee_id", "leave_type_id", "start_date", "end_date", "total_days",
+		"reason", "status", "applied_at", "approved_by", "approved_at", "rejection_reason",
+		"comments", "created_at", "updated_at", "employee_name", "employee_email", "leave_type_name",
+		"in_notice_period", "approval_route", "approval_stage", "duration_unit", "hours",
+	}
+)
+
+// Relations that GET /leave-requests/:id can embed with ?expand=
+var leaveRequestExpansions = []string{"employee", "leave_type", "approver"}
+
+// Computed blocks that GET /employees can add with ?include=
+var employeeIncludes = []string{"balance_summary"}
+
+// fieldSet is a sparse fieldset requested by the client; nil means every field
+type fieldSet map[string]bool
+
+// parseFields reads ?fields=id,name,status and validates it against allowed
+func parseFields(c *gin.Context, allowed []string) (fieldSet, error) {
+	set, err := parseNames(c, "fields", "field", allowed)
+	if set == nil {
+		return nil, err
+	}
+	return fieldSet(set), nil
+}
+
+// parseExpand reads ?expand=employee,leave_type and validates it against allowed
+func parseExpand(c *gin.Context, allowed []string) (map[string]bool, error) {
+	return parseNames(c, "expand", "relation", allowed)
+}
+
+// parseInclude reads ?include=balance_summary and validates it against allowed
+func parseInclude(c *gin.Context, allowed []string) (map[string]bool, error) {
+	return parseNames(c, "include", "include", allowed)
+}
+
+// parseNames parses a comma separated query parameter whose items must all be in
+// allowed. It returns nil when the parameter is absent.
+func parseNames(c *gin.Context, param, noun string, allowed []string) (map[string]bool, error) {
+	raw := strings.TrimSpace(c.Query(param))
+	if raw == "" {
+		return nil, nil
+	}
+	known := make(map[string]bool, len(allowed))
+	for _, f := range allowed {
+		known[f] = true
+	}
+	set := map[string]bool{}
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !known[f] {
+			sorted := append([]string(nil), allowed...)
+			sort.Strings(sorted)
+			return nil, fmt.Errorf("unknown %s %q, allowed: %s", noun, f, strings.Join(sorted, ", "))
+		}
+		set[f] = true
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("%s must list at least one %s", param, noun)
+	}
+	return set, nil
+}
+
+// project drops every key not in the fieldset. Call it after anything that
+// still needs the full items, like building the next cursor.
+func (f fieldSet) project(items []map[string]interface{}) []map[string]interface{} {
+	if f == nil {
+		return items
+	}
+	out := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		p := make(map[string]interface{}, len(f))
+		for k := range f {
+			if v, ok := item[k]; ok {
+				p[k] = v
+			}
+		}
+		out[i] = p
+	}
+	return out
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// filterKind decides how a filter value is parsed before it is bound as an argument
+type filterKind int
+
+const (
+	filterString filterKind = iota
+	filterDate
+	filterNumber
+)
+
+type filterField struct {
+	column string
+	kind   filterKind
+	values []string // allowed values, if restricted
+}
+
+// leaveRequestFilters are the fields usable in GET /leave-requests?filter=
+var leaveRequestFilters = map[string]filterField{
+	"status":          {column: "lr.status", values: []string{"pending", "approved", "rejected", "cancelled"}},
+	"start_date":      {column: "lr.start_date", kind: filterDate},
+	"end_date":        {column: "lr.end_date", kind: filterDate},
+	"applied_at":      {column: "lr.applied_at::DATE", kind: filterDate},
+	"total_days":      {column: "lr.total_days", kind: filterNumber},
+	"leave_type_id":   {column: "lr.leave_type_id::TEXT"},
+	"leave_type_name": {column: "lt.name"},
+	"employee_id":     {column: "lr.employee_id::TEXT"},
+	"employee_name":   {column: "e.name"},
+	"approval_route":  {column: "lr.approval_route", values: []string{"auto", "manager", "hr", "manager_hr"}},
+	"approval_stage":  {column: "leave_request_stage(lr.id)", values: []string{"pending_manager", "pending_hr"}},
+}
+
+const (
+	maxFilterLength     = 1000
+	maxFilterConditions = 20
+)
+
+// parseFilter compiles a filter expression into a parameterized SQL condition.
+// Placeholders continue after the existing args, which are returned extended.
+//
+//	expr   := and { OR and }
+//	and    := term { AND term }
+//	term   := "(" expr ")" | field op value | field [NOT] IN "(" value { "," value } ")"
+//	op     := = | != | > | >= | < | <=
+//
+// Keywords are case-insensitive; values may be bare words or quoted with ' or ",
+// and a quote inside a quoted value is written twice, as in SQL.
+// Example: status in (pending,approved) AND start_date>=2025-01-01
+func parseFilter(expr string, fields map[string]filterField, args []interface{}) (string, []interface{}, error) {
+	if len(expr) > maxFilterLength {
+		return "", nil, fmt.Errorf("filter must be at most %d characters", maxFilterLength)
+	}
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	p := &filterParser{tokens: tokens, fields: fields, args: args}
+	sql, err := p.parseOr()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return "", nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+	return sql, p.args, nil
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(s); {
+		ch := rune(s[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(' || ch == ')' || ch == ',':
+			tokens = append(tokens, filterToken{text: string(ch)})
+			i++
+		case ch == '=' || ch == '!' || ch == '<' || ch == '>':
+			op := string(ch)
+			if i+1 < len(s) && s[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errors.New("unexpected ! in filter, use !=")
+			}
+			tokens = append(tokens, filterToken{text: op})
+			i += len(op)
+		case ch == '\'' || ch == '"':
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(s) {
+					return nil, errors.New("unterminated quoted value in filter")
+				}
+				if s[i] == byte(ch) {
+					if i+1 < len(s) && s[i+1] == byte(ch) {
+						i++ // doubled quote
+					} else {
+						break
+					}
+				}
+				value.WriteByte(s[i])
+			}
+			tokens = append(tokens, filterToken{text: value.String(), quoted: true})
+			i++
+		default:
+			start := i
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && !strings.ContainsRune("()=!<>,'\"", rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{text: s[start:i]})
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("filter is empty")
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens     []filterToken
+	pos        int
+	fields     map[string]filterField
+	args       []interface{}
+	conditions int
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// keyword reports whether the next token is the unquoted keyword kw and consumes it
+func (p *filterParser) keyword(kw string) bool {
+	t, ok := p.peek()
+	if ok && !t.quoted && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(text string) error {
+	t, ok := p.peek()
+	if !ok || t.quoted || t.text != text {
+		return fmt.Errorf("expected %q in filter", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{left}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, right)
+	}
+	if len(parts) == 1 {
+		return left, nil
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", nil
+}
+
+func (p *filterParser) parseAnd() (string, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{left}
+	for p.keyword("AND") {
+		right, err := p.parseTerm()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, right)
+	}
+	if len(parts) == 1 {
+		return left, nil
+	}
+	return "(" + strings.Join(parts, " AND ") + ")", nil
+}
+
+func (p *filterParser) parseTerm() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", errors.New("filter ends unexpectedly")
+	}
+	if !t.quoted && t.text == "(" {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		return inner, nil
+	}
+
+	p.conditions++
+	if p.conditions > maxFilterConditions {
+		return "", fmt.Errorf("filter may contain at most %d conditions", maxFilterConditions)
+	}
+
+	name := strings.ToLower(t.text)
+	field, known := p.fields[name]
+	if t.quoted || !known {
+		return "", fmt.Errorf("unknown filter field %q", t.text)
+	}
+	p.pos++
+
+	negate := p.keyword("NOT")
+	if p.keyword("IN") {
+		return p.parseIn(name, field, negate)
+	}
+	if negate {
+		return "", errors.New("NOT is only supported before IN")
+	}
+
+	opTok, ok := p.peek()
+	if !ok || opTok.quoted {
+		return "", fmt.Errorf("expected an operator after %s", name)
+	}
+	switch opTok.text {
+	case "=", "!=", "<", "<=", ">", ">=":
+	default:
+		return "", fmt.Errorf("unsupported operator %q", opTok.text)
+	}
+	p.pos++
+	if field.kind == filterString && opTok.text != "=" && opTok.text != "!=" {
+		return "", fmt.Errorf("%s only supports =, != and IN", name)
+	}
+
+	placeholder, err := p.value(name, field)
+	if err != nil {
+		return "", err
+	}
+	op := opTok.text
+	if op == "!=" {
+		op = "<>"
+	}
+	return fmt.Sprintf("%s %s %s", field.column, op, placeholder), nil
+}
+
+func (p *filterParser) parseIn(name string, field filterField, negate bool) (string, error) {
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+	placeholders := []string{}
+	for {
+		ph, err := p.value(name, field)
+		if err != nil {
+			return "", err
+		}
+		placeholders = append(placeholders, ph)
+		if p.expect(",") != nil {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return "", err
+	}
+	op := "IN"
+	if negate {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("%s %s (%s)", field.column, op, strings.Join(placeholders, ", ")), nil
+}
+
+// value consumes one value token, converts it for the field and binds it
+func (p *filterParser) value(name string, field filterField) (string, error) {
+	t, ok := p.peek()
+	if !ok || (!t.quoted && strings.ContainsAny(t.text, "(),=<>!")) {
+		return "", fmt.Errorf("expected a value for %s", name)
+	}
+	p.pos++
+
+	var v interface{}
+	switch field.kind {
+	case filterDate:
+		d, err := time.Parse("2006-01-02", t.text)
+		if err != nil {
+			return "", fmt.Errorf("%s values must be YYYY-MM-DD", name)
+		}
+		v = d
+	case filterNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", fmt.Errorf("%s values must be numbers", name)
+		}
+		v = n
+	default:
+		if len(field.values) > 0 && !containsString(field.values, t.text) {
+			return "", fmt.Errorf("%s must be one of: %s", name, strings.Join(field.values, ", "))
+		}
+		v = t.text
+	}
+	p.args = append(p.args, v)
+	return fmt.Sprintf("$%d", len(p.args)), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		expr    string
+		tokens  []filterToken
+		wantErr bool
+	}{
+		{expr: "status=pending", tokens: []filterToken{{text: "status"}, {text: "="}, {text: "pending"}}},
+		{expr: "total_days >= 2", tokens: []filterToken{{text: "total_days"}, {text: ">="}, {text: "2"}}},
+		{expr: "status!=approved", tokens: []filterToken{{text: "status"}, {text: "!="}, {text: "approved"}}},
+		{expr: "employee_name='Jane Doe'", tokens: []filterToken{{text: "employee_name"}, {text: "="}, {text: "Jane Doe", quoted: true}}},
+		{expr: `employee_name="Jane Doe"`, tokens: []filterToken{{text: "employee_name"}, {text: "="}, {text: "Jane Doe", quoted: true}}},
+		{expr: "employee_name='O''Brien'", tokens: []filterToken{{text: "employee_name"}, {text: "="}, {text: "O'Brien", quoted: true}}},
+		{expr: `employee_name="say ""hi"""`, tokens: []filterToken{{text: "employee_name"}, {text: "="}, {text: `say "hi"`, quoted: true}}},
+		{expr: `employee_name="O'Brien"`, tokens: []filterToken{{text: "employee_name"}, {text: "="}, {text: "O'Brien", quoted: true}}},
+		{expr: "leave_type_name=''", tokens: []filterToken{{text: "leave_type_name"}, {text: "="}, {text: "", quoted: true}}},
+		{expr: "x='a,(b)=c'", tokens: []filterToken{{text: "x"}, {text: "="}, {text: "a,(b)=c", quoted: true}}},
+		{expr: "status in (pending,approved)", tokens: []filterToken{{text: "status"}, {text: "in"}, {text: "("},
+			{text: "pending"}, {text: ","}, {text: "approved"}, {text: ")"}}},
+		{expr: "", wantErr: true},
+		{expr: "   ", wantErr: true},
+		{expr: "employee_name='Jane", wantErr: true},
+		{expr: "employee_name='O''", wantErr: true},
+		{expr: "status ! pending", wantErr: true},
+	}
+	for _, tt := range tests {
+		tokens, err := tokenizeFilter(tt.expr)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("tokenizeFilter(%q) = %v, want an error", tt.expr, tokens)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("tokenizeFilter(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(tokens, tt.tokens) {
+			t.Errorf("tokenizeFilter(%q) = %v, want %v", tt.expr, tokens, tt.tokens)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		expr string
+		args []interface{} // arguments before the filter's
+		sql  string
+		want []interface{} // all arguments after
+	}{
+		{
+			expr: "status=pending",
+			sql:  "lr.status = $1",
+			want: []interface{}{"pending"},
+		},
+		{
+			expr: "STATUS != cancelled",
+			sql:  "lr.status <> $1",
+			want: []interface{}{"cancelled"},
+		},
+		{
+			expr: "total_days>5 and start_date<=2025-06-30",
+			sql:  "(lr.total_days > $1 AND lr.start_date <= $2)",
+			want: []interface{}{5.0, date("2025-06-30")},
+		},
+		{
+			expr: "employee_name='O''Brien'",
+			sql:  "e.name = $1",
+			want: []interface{}{"O'Brien"},
+		},
+		// AND binds tighter than OR
+		{
+			expr: "status=pending OR status=approved AND total_days>3",
+			sql:  "(lr.status = $1 OR (lr.status = $2 AND lr.total_days > $3))",
+			want: []interface{}{"pending", "approved", 3.0},
+		},
+		{
+			expr: "status=pending AND total_days>3 OR status=approved",
+			sql:  "((lr.status = $1 AND lr.total_days > $2) OR lr.status = $3)",
+			want: []interface{}{"pending", 3.0, "approved"},
+		},
+		// parentheses override it
+		{
+			expr: "(status=pending OR status=approved) AND total_days>3",
+			sql:  "((lr.status = $1 OR lr.status = $2) AND lr.total_days > $3)",
+			want: []interface{}{"pending", "approved", 3.0},
+		},
+		{
+			expr: "((status=pending))",
+			sql:  "lr.status = $1",
+			want: []interface{}{"pending"},
+		},
+		// placeholders continue after the arguments already bound
+		{
+			expr: "status in (pending,approved)",
+			args: []interface{}{"org", 7},
+			sql:  "lr.status IN ($3, $4)",
+			want: []interface{}{"org", 7, "pending", "approved"},
+		},
+		{
+			expr: "total_days>1 AND status not in ('rejected', cancelled) AND end_date<2025-07-01",
+			args: []interface{}{"org"},
+			sql:  "(lr.total_days > $2 AND lr.status NOT IN ($3, $4) AND lr.end_date < $5)",
+			want: []interface{}{"org", 1.0, "rejected", "cancelled", date("2025-07-01")},
+		},
+		{
+			expr: "approval_route IN (manager) OR approval_stage NOT IN (pending_hr)",
+			args: []interface{}{"a", "b", "c"},
+			sql:  "(lr.approval_route IN ($4) OR leave_request_stage(lr.id) NOT IN ($5))",
+			want: []interface{}{"a", "b", "c", "manager", "pending_hr"},
+		},
+	}
+	for _, tt := range tests {
+		sql, args, err := parseFilter(tt.expr, leaveRequestFilters, tt.args)
+		if err != nil {
+			t.Errorf("parseFilter(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("parseFilter(%q) = %q, want %q", tt.expr, sql, tt.sql)
+		}
+		if !reflect.DeepEqual(args, tt.want) {
+			t.Errorf("parseFilter(%q) args = %v, want %v", tt.expr, args, tt.want)
+		}
+	}
+}
+
+func TestParseFilterRejects(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string // part of the error
+	}{
+		{"salary>100", `unknown filter field "salary"`},
+		{"'status'=pending", "unknown filter field"},
+		{"status=pending AND lr.status=approved", "unknown filter field"},
+		{"status ~ pending", "unsupported operator"},
+		{"status LIKE pending", "unsupported operator"},
+		{"status==pending", `unsupported operator "=="`},
+		{"employee_name>Jane", "only supports =, != and IN"},
+		{"status NOT = pending", "NOT is only supported before IN"},
+		{"status=archived", "status must be one of"},
+		{"status in (pending,archived)", "status must be one of"},
+		{"start_date>=01/02/2025", "YYYY-MM-DD"},
+		{"total_days>many", "must be numbers"},
+		{"total_days>NaN", "must be numbers"},
+		{"status in pending", `expected "("`},
+		{"status in (pending", `expected ")"`},
+		{"status in ()", "expected a value"},
+		{"(status=pending", `expected ")"`},
+		{"status=pending)", `unexpected ")"`},
+		{"status=pending AND", "filter ends unexpectedly"},
+		{"status=pending status=approved", `unexpected "status"`},
+		{"status", "expected an operator"},
+		{strings.Repeat("status=pending OR ", 20) + "status=pending", "at most 20 conditions"},
+		{"employee_name='" + strings.Repeat("a", maxFilterLength) + "'", "at most 1000 characters"},
+	}
+	for _, tt := range tests {
+		sql, _, err := parseFilter(tt.expr, leaveRequestFilters, nil)
+		if err == nil {
+			t.Errorf("parseFilter(%q) = %q, want an error containing %q", tt.expr, sql, tt.err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseFilter(%q) error %q, want one containing %q", tt.expr, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"leave-management/internal/gql"
+	"leave-management/internal/repository"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// graphQLMaxComplexity caps the cost of a query: each field costs 1, and a
+// list costs its limit times the cost of an item
+const graphQLMaxComplexity = 1000
+
+type GraphQLHandler struct {
+	pool *pgxpool.Pool
+	srv  *handler.Server
+}
+
+func NewGraphQLHandler(pool *pgxpool.Pool) *GraphQLHandler {
+	srv := handler.New(gql.New(repository.New(pool).Repos()))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](100))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(graphQLMaxComplexity))
+	return &GraphQLHandler{pool: pool, srv: srv}
+}
+
+// POST /graphql
+// Body: {"query": "...", "variables": {...}, "operationName": "..."}.
+// GET /graphql?query= is accepted for read-only tooling. Errors while
+// resolving a query are reported in the GraphQL "errors" array with status
+// 200; a query that does not parse, validate or fit graphQLMaxComplexity
+// answers 422.
+func (h *GraphQLHandler) Serve(c *gin.Context) {
+	// the caller may not have an employee record (e.g. a system admin)
+	employeeID, _ := currentEmployeeID(c.Request.Context(), h.pool, c)
+	ctx := gql.WithViewer(c.Request.Context(), gql.Viewer{Role: c.GetString("role"), EmployeeID: employeeID})
+	h.srv.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// currentEmployeeID resolves the employees.id (UUID) of the authenticated user.
+// The JWT only carries the employee code (users.employee_id), so look it up.
+func currentEmployeeID(ctx context.Context, pool *pgxpool.Pool, c *gin.Context) (string, error) {
+	code, _ := c.Get("employee_id")
+	var id string
+	err := pool.QueryRow(ctx, "SELECT id FROM employees WHERE employee_id=$1", code).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HolidayHandler struct {
+	pool     *pgxpool.Pool
+	holidays repository.HolidayRepo
+	cache    *cache.Cache
+}
+
+func NewHolidayHandler(pool *pgxpool.Pool, rc *cache.Cache) *HolidayHandler {
+	return &HolidayHandler{pool: pool, holidays: repository.New(pool).Repos().Holidays, cache: rc}
+}
+
+type holiday struct {
+	ID   string `json:"id"`
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// holidaysOf returns the holidays of year in date order. The calendar is
+// maintained in the database directly, so cached years expire by TTL only.
+func (h *HolidayHandler) holidaysOf(ctx context.Context, year int) ([]holiday, error) {
+	var list []holiday
+	if h.cache.Get(ctx, cache.HolidaysKey(db.OrgID(ctx), year), &list) {
+		return list, nil
+	}
+	rows, err := h.holidays.ListHolidaysByYear(ctx, int32(year))
+	if err != nil {
+		return nil, err
+	}
+	list = make([]holiday, 0, len(rows))
+	for _, r := range rows {
+		list = append(list, holiday{ID: r.ID, Date: r.HolidayDate.Format("2006-01-02"), Name: r.Name})
+	}
+	h.cache.Set(ctx, cache.HolidaysKey(db.OrgID(ctx), year), list)
+	return list, nil
+}
+
+// GET /holidays?year= (paging: limit, offset; year defaults to the current year)
+func (h *HolidayHandler) ListHolidays(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	year := time.Now().Year()
+	if v := c.Query("year"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2020 || n > 2050 {
+			apierror.Respond(c, apierror.InvalidQuery, "year must be between 2020 and 2050")
+			return
+		}
+		year = n
+	}
+
+	holidays, err := h.holidaysOf(c.Request.Context(), year)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch holidays")
+		return
+	}
+	total := len(holidays)
+
+	result := make([]map[string]interface{}, 0)
+	for i := page.Offset; i < total && i < page.Offset+page.Limit; i++ {
+		result = append(result, gin.H{
+			"id":   holidays[i].ID,
+			"date": holidays[i].Date,
+			"name": holidays[i].Name,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result, "meta": page.meta(total, len(result))})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// POST /leave-balances/query
+// Balances of several employees in one call: the listed employee_ids, or the
+// active employees of department_id, for year (default: current year).
+// Managers only get their direct reports; requested ids that were not
+// returned are listed under not_found.
+func (h *EmployeeHandler) QueryLeaveBalances(c *gin.Context) {
+	var in struct {
+		EmployeeIDs  []string `json:"employee_ids" binding:"omitempty,max=500,dive,uuid"`
+		DepartmentID string   `json:"department_id" binding:"omitempty,uuid"`
+		Year         *int     `json:"year"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	if (len(in.EmployeeIDs) == 0) == (in.DepartmentID == "") {
+		apierror.Respond(c, apierror.InvalidInput, "provide exactly one of employee_ids or department_id")
+		return
+	}
+	year := time.Now().Year()
+	if in.Year != nil {
+		year = *in.Year
+	}
+	if year < 2020 || year > 2050 {
+		apierror.Respond(c, apierror.InvalidInput, "year must be between 2020 and 2050")
+		return
+	}
+
+	for i, id := range in.EmployeeIDs {
+		in.EmployeeIDs[i] = strings.ToLower(id) // as the database returns them
+	}
+	params := queries.QueryLeaveBalancesParams{Year: int32(year), EmployeeIds: in.EmployeeIDs}
+	if in.DepartmentID != "" {
+		params.DepartmentID = &in.DepartmentID
+	}
+	if c.GetString("role") == models.RoleManager {
+		managerID := c.GetString("employee_uuid")
+		if managerID == "" {
+			apierror.Respond(c, apierror.Forbidden, "only users with an employee record can query their team's balances")
+			return
+		}
+		params.ManagerID = &managerID
+	}
+	rows, err := h.readRepos.LeaveBalances.QueryLeaveBalances(c.Request.Context(), params)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch leave balances")
+		return
+	}
+
+	// one entry per employee, in the query's order
+	data := make([]gin.H, 0)
+	found := map[string]bool{}
+	for _, r := range rows {
+		if !found[r.EmployeeID] {
+			found[r.EmployeeID] = true
+			data = append(data, gin.H{
+				"employee_id":    r.EmployeeID,
+				"employee_code":  r.EmployeeCode,
+				"employee_name":  r.EmployeeName,
+				"department_id":  r.DepartmentID,
+				"leave_balances": []gin.H{},
+			})
+		}
+		if r.LeaveTypeID == nil {
+			continue
+		}
+		last := data[len(data)-1]
+		last["leave_balances"] = append(last["leave_balances"].([]gin.H), gin.H{
+			"leave_type_id":        r.LeaveTypeID,
+			"leave_type_name":      r.LeaveTypeName,
+			"allocated_days":       r.AllocatedDays,
+			"used_days":            r.UsedDays,
+			"carried_forward_days": r.CarriedForwardDays,
+			"available_days":       r.AvailableDays,
+			"pending_days":         r.PendingDays,
+		})
+	}
+	notFound := make([]string, 0)
+	for _, id := range in.EmployeeIDs {
+		if !found[id] {
+			notFound = append(notFound, id)
+			found[id] = true // listed once
+		}
+	}
+
+	respond(c, http.StatusOK, gin.H{"year": year, "data": data, "not_found": notFound})
+}
@@ -2,150 +2,301 @@ package handlers
 
 import (
 	"context"
-	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"time"
 
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/leavepolicy"
 	"leave-management/internal/models"
+	"leave-management/internal/repository"
+	"leave-management/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type LeaveRequestHandler struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	read     *pgxpool.Pool // replica for list queries; same as pool without one
+	repos    repository.Repos
+	workflow *service.LeaveRequests
+	notice   func() NoticePeriodRule
+	workday  func() int // hours in a working day, for leave taken in hours
+}
+
+func NewLeaveRequestHandler(pool, read *pgxpool.Pool, notice func() NoticePeriodRule, workday func() int) *LeaveRequestHandler {
+	store := repository.New(pool)
+	return &LeaveRequestHandler{pool: pool, read: read, repos: store.Repos(), workflow: service.NewLeaveRequests(store), notice: notice, workday: workday}
 }
 
-func NewLeaveRequestHandler(pool *pgxpool.Pool) *LeaveRequestHandler {
-	return &LeaveRequestHandler{pool: pool}
+// NoticePeriodRule is how leave is treated between an employee's
+// resignation_date and the end of their notice period
+type NoticePeriodRule struct {
+	Days  int
+	Leave string // allow, hr_approval (HR or an admin must approve it) or block (cannot be applied for)
 }
 
 type LeaveRequestInput struct {
 	EmployeeID  string `json:"employee_id" binding:"required"`
 	LeaveTypeID string `json:"leave_type_id" binding:"required"`
-	StartDate   string `json:"start_date" binding:"required"` // YYYY-MM-DD
-	EndDate     string `json:"end_date" binding:"required"`   // YYYY-MM-DD
+	StartDate   string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate     string `json:"end_date" binding:"required,datetime=2006-01-02"`
 	Reason      string `json:"reason" binding:"required"`
 }
 
+// Units a leave request can be taken in
+const (
+	unitFullDay   = "full_day"
+	unitHalfDayAM = "half_day_am"
+	unitHalfDayPM = "half_day_pm"
+	unitHours     = "hours"
+)
+
 // POST /leave-requests
+// duration_unit defaults to full_day. Half days and hours are taken on a
+// single date (start_date = end_date); a half day is charged as 0.5 days and
+// hours as hours / WORKDAY_HOURS days, rounded to two decimals.
 func (h *LeaveRequestHandler) ApplyLeave(c *gin.Context) {
 	var input struct {
-		LeaveTypeID string `json:"leave_type_id" binding:"required"`
-		StartDate   string `json:"start_date" binding:"required"`
-		EndDate     string `json:"end_date" binding:"required"`
-		Reason      string `json:"reason" binding:"required"`
+		LeaveTypeID  string   `json:"leave_type_id" binding:"required"`
+		StartDate    string   `json:"start_date" binding:"required,datetime=2006-01-02"`
+		EndDate      string   `json:"end_date" binding:"required,datetime=2006-01-02"`
+		Reason       string   `json:"reason" binding:"required"`
+		DurationUnit string   `json:"duration_unit" binding:"omitempty,oneof=full_day half_day_am half_day_pm hours"`
+		Hours        *float64 `json:"hours"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	// Get authenticated user's employee ID
-	employeeID, exists := c.Get("employee_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	// Get authenticated user's employee record
+	employeeID := c.GetString("employee_uuid")
+	if employeeID == "" {
+		apierror.Respond(c, apierror.Forbidden, "only users with an employee record can apply for leave")
 		return
 	}
 
 	// Parse dates
 	start, err := time.Parse("2006-01-02", input.StartDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use YYYY-MM-DD"})
+		apierror.Respond(c, apierror.InvalidDate, "Invalid start_date format, use YYYY-MM-DD")
 		return
 	}
 
 	end, err := time.Parse("2006-01-02", input.EndDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format, use YYYY-MM-DD"})
+		apierror.Respond(c, apierror.InvalidDate, "Invalid end_date format, use YYYY-MM-DD")
 		return
 	}
 
 	// Validate dates
 	if start.After(end) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date cannot be after end_date"})
+		apierror.Respond(c, apierror.InvalidDateRange, "start_date cannot be after end_date")
 		return
 	}
 
-	// Validate employee joining date is not after requested start date
-	var joiningDate time.Time
-	if err := h.pool.QueryRow(context.Background(), "SELECT joining_date FROM employees WHERE id=$1", employeeID).Scan(&joiningDate); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid employee_id"})
+	// Calculate total days
+	unit := input.DurationUnit
+	if unit == "" {
+		unit = unitFullDay
+	}
+	if unit != unitFullDay && !start.Equal(end) {
+		apierror.Respond(c, apierror.InvalidDateRange, "half days and hours must start and end on the same date")
 		return
 	}
-	if joiningDate.After(start) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date cannot be before employee's joining date"})
+	if unit != unitHours && input.Hours != nil {
+		apierror.Respond(c, apierror.InvalidInput, "hours is only allowed with duration_unit hours")
 		return
 	}
 
-	// Ensure leave balance is available in the current year for the leave type
-	var availableDays int
-	currentYear := time.Now().Year()
-	if err := h.pool.QueryRow(
-		context.Background(),
-		`SELECT available_days FROM employee_leave_balances
-		 WHERE employee_id=$1 AND leave_type_id=$2 AND year=$3`,
-		employeeID, input.LeaveTypeID, currentYear,
-	).Scan(&availableDays); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no leave balance found for this leave type/year"})
+	// The leave type's policy decides which days are charged and whether the
+	// leave may be taken at all
+	rules, cal, err := h.leavePolicy(c.Request.Context(), input.LeaveTypeID, start, end)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.ReferenceNotFound, "leave_type_id not found", "Failed to load leave policy")
+		return
+	}
+	days, err := rules.Days(start, end, cal)
+	if err != nil {
+		respondPolicyViolation(c, err)
+		return
+	}
+	var totalDays float64
+	switch unit {
+	case unitFullDay:
+		totalDays = days
+	case unitHalfDayAM, unitHalfDayPM:
+		totalDays = 0.5
+	case unitHours:
+		workday := h.workday()
+		if input.Hours == nil || *input.Hours <= 0 || *input.Hours > float64(workday) || math.Mod(*input.Hours*4, 1) != 0 {
+			apierror.Respond(c, apierror.InvalidInput, fmt.Sprintf("hours must be a multiple of 0.25 between 0.25 and %d", workday))
+			return
+		}
+		totalDays = math.Max(math.Round(*input.Hours/float64(workday)*100)/100, 0.01)
+	}
+	now := time.Now()
+	if err := rules.Check(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), start, totalDays); err != nil {
+		respondPolicyViolation(c, err)
 		return
 	}
 
-	// Calculate total days
-	totalDays := int(end.Sub(start).Hours()/24) + 1
-
-	if totalDays > availableDays {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient leave balance"})
+	// Validate employee joining date is not after requested start date
+	var joiningDate time.Time
+	var inNoticePeriod bool
+	notice := h.notice()
+	if err := h.pool.QueryRow(c.Request.Context(),
+		"SELECT joining_date, in_notice_period(resignation_date, $2, $3, $4) FROM employees WHERE id=$1",
+		employeeID, notice.Days, start, end).Scan(&joiningDate, &inNoticePeriod); err != nil {
+		apierror.Lookup(c, err, apierror.ReferenceNotFound, "invalid employee_id", "Failed to load employee")
+		return
+	}
+	if joiningDate.After(start) {
+		apierror.Respond(c, apierror.BeforeJoiningDate, "start_date cannot be before employee's joining date")
+		return
+	}
+	if inNoticePeriod && notice.Leave == "block" {
+		apierror.Respond(c, apierror.NoticePeriodLeave, "leave cannot be taken during the notice period")
 		return
 	}
 
 	// Check for overlapping leave requests
 	var hasOverlap bool
 	if err := h.pool.QueryRow(
-		context.Background(),
-		"SELECT check_leave_overlap($1, $2, $3, NULL)",
-		employeeID, start, end,
+		c.Request.Context(),
+		"SELECT check_leave_overlap($1, $2, $3, NULL, $4)",
+		employeeID, start, end, unit,
 	).Scan(&hasOverlap); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check leave overlap"})
+		apierror.Database(c, err, "Failed to check leave overlap")
 		return
 	}
 
 	if hasOverlap {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "leave request overlaps with an existing request"})
+		apierror.Respond(c, apierror.LeaveOverlap, "leave request overlaps with an existing request")
 		return
 	}
 
-	// Insert leave request
-	var requestID string
-	if err := h.pool.QueryRow(
-		context.Background(),
-		`INSERT INTO leave_requests (employee_id, leave_type_id, start_date, end_date, total_days, reason, status, applied_at, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, 'pending', NOW(), NOW(), NOW())
-		 RETURNING id`,
-		employeeID, input.LeaveTypeID, start, end, totalDays, input.Reason,
-	).Scan(&requestID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create leave request", "details": err.Error()})
+	// Insert leave request, routed by the first matching approval routing
+	// rule, and reserve its days in the current year's balance; auto-routed
+	// requests are approved in the same transaction
+	var requestID, route string
+	ctx := c.Request.Context()
+	err = db.WithTx(ctx, h.pool, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `
+			WITH rule AS (
+				SELECT r.id, r.route FROM approval_routing_rules r, employees e
+				WHERE e.id = $1 AND r.is_active
+				  AND (r.leave_type_id IS NULL OR r.leave_type_id = $2)
+				  AND (r.department_id IS NULL OR r.department_id = e.department_id)
+				  AND (r.min_days IS NULL OR r.min_days <= $5::numeric)
+				  AND (r.max_days IS NULL OR r.max_days >= $5::numeric)
+				ORDER BY r.priority, r.created_at, r.id
+				LIMIT 1
+			)
+			INSERT INTO leave_requests (employee_id, leave_type_id, start_date, end_date, total_days, reason, status, applied_at, created_at, updated_at, approval_route, routing_rule_id,
+			                            duration_unit, hours)
+			SELECT $1, $2, $3::date, $4::date, $5::numeric, $6::text, 'pending', NOW(), NOW(), NOW(),
+			       COALESCE((SELECT route FROM rule), 'manager'), (SELECT id FROM rule),
+			       $7::leave_duration_unit, $8::numeric
+			RETURNING id, approval_route`,
+			employeeID, input.LeaveTypeID, start, end, totalDays, input.Reason, unit, input.Hours,
+		).Scan(&requestID, &route); err != nil {
+			return err
+		}
+		if err := h.workflow.Reserve(ctx, tx, requestID); err != nil {
+			return err
+		}
+		if route != service.RouteAuto {
+			return nil
+		}
+		return h.workflow.AutoApprove(ctx, tx, requestID)
+	})
+	if err != nil {
+		respondWorkflowError(c, err, "Failed to create leave request")
 		return
 	}
+	// the first step of the chain the insert trigger created
+	status, stage := "pending", service.StagePendingManager
+	switch route {
+	case service.RouteAuto:
+		status = "approved"
+	case service.RouteHR:
+		stage = service.StagePendingHR
+	}
+	var approvalStage *string
+	if status == "pending" {
+		approvalStage = &stage
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	respond(c, http.StatusCreated, gin.H{
 		"message": "Leave request created successfully",
 		"request_id": requestID,
 		"total_days": totalDays,
+		"duration_unit": unit,
+		"hours": input.Hours,
+		"in_notice_period": inNoticePeriod,
+		"approval_route": route,
+		"approval_stage": approvalStage,
+		"status": status,
 	})
 }
 
-// GET /leave-requests/:id
+// leavePolicy returns the policy rules of a leave type and the holidays from
+// start to end
+func (h *LeaveRequestHandler) leavePolicy(ctx context.Context, leaveTypeID string, start, end time.Time) (leavepolicy.Rules, leavepolicy.Calendar, error) {
+	p, err := h.repos.LeaveTypes.GetLeavePolicy(ctx, leaveTypeID)
+	if err != nil {
+		return leavepolicy.Rules{}, nil, err
+	}
+	rules := leavepolicy.Rules{Sandwich: p.SandwichRule, MinNoticeDays: int(p.MinNoticeDays)}
+	if p.MaxConsecutiveDays != nil {
+		rules.MaxConsecutiveDays = int(*p.MaxConsecutiveDays)
+	}
+	holidays, err := h.repos.Holidays.ListHolidayDatesBetween(ctx, queries.ListHolidayDatesBetweenParams{FromDate: start, ToDate: end})
+	if err != nil {
+		return rules, nil, err
+	}
+	return rules, leavepolicy.NewCalendar(holidays), nil
+}
+
+// policyViolationCodes are the error codes of the leave policy rules
+var policyViolationCodes = map[string]apierror.Code{
+	leavepolicy.RuleWorkingDays:    apierror.NoWorkingDays,
+	leavepolicy.RuleMinNotice:      apierror.InsufficientNotice,
+	leavepolicy.RuleMaxConsecutive: apierror.TooManyDays,
+}
+
+// respondPolicyViolation answers with the error code of the rule err breaks
+func respondPolicyViolation(c *gin.Context, err error) {
+	var v *leavepolicy.Violation
+	if !errors.As(err, &v) {
+		apierror.Database(c, err, "Failed to apply leave policy")
+		return
+	}
+	apierror.RespondWithDetails(c, policyViolationCodes[v.Rule], v.Message, gin.H{"rule": v.Rule})
+}
+
+// GET /leave-requests/:id (optional expand=employee,leave_type,approver)
 func (h *LeaveRequestHandler) GetLeaveRequestByID(c *gin.Context) {
     id := c.Param("id")
+    expand, err := parseExpand(c, leaveRequestExpansions)
+    if err != nil {
+        apierror.Respond(c, apierror.InvalidQuery, err.Error())
+        return
+    }
     var (
         employeeID string
         leaveTypeID string
         startDate time.Time
         endDate time.Time
-        totalDays int
+        totalDays float64
         reason string
         status string
         appliedAt time.Time
@@ -153,23 +304,34 @@ func (h *LeaveRequestHandler) GetLeaveRequestByID(c *gin.Context) {
         approvedAt *time.Time
         rejectionReason *string
         comments *string
+        approvalRoute string
+        routingRuleID *string
+        managerApprovedBy *string
+        managerApprovedAt *time.Time
+        durationUnit string
+        hours *float64
+        approvalStage *string
     )
-    err := h.pool.QueryRow(
-        context.Background(),
-        `SELECT employee_id, leave_type_id, start_date, end_date, total_days, reason, status, applied_at, approved_by, approved_at, rejection_reason, comments
+    err = h.pool.QueryRow(
+        c.Request.Context(),
+        `SELECT employee_id, leave_type_id, start_date, end_date, total_days, reason, status, applied_at, approved_by, approved_at, rejection_reason, comments,
+                approval_route, routing_rule_id, manager_approved_by, manager_approved_at, duration_unit, hours, leave_request_stage(id)
          FROM leave_requests WHERE id=$1`, id,
-    ).Scan(&employeeID, &leaveTypeID, &startDate, &endDate, &totalDays, &reason, &status, &appliedAt, &approvedBy, &approvedAt, &rejectionReason, &comments)
+    ).Scan(&employeeID, &leaveTypeID, &startDate, &endDate, &totalDays, &reason, &status, &appliedAt, &approvedBy, &approvedAt, &rejectionReason, &comments,
+        &approvalRoute, &routingRuleID, &managerApprovedBy, &managerApprovedAt, &durationUnit, &hours, &approvalStage)
     if err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "leave request not found"})
+        apierror.Lookup(c, err, apierror.NotFound, "leave request not found", "Failed to load leave request")
         return
     }
-    c.JSON(http.StatusOK, gin.H{
+    resp := gin.H{
         "id": id,
         "employee_id": employeeID,
         "leave_type_id": leaveTypeID,
         "start_date": startDate.Format("2006-01-02"),
         "end_date": endDate.Format("2006-01-02"),
         "total_days": totalDays,
+        "duration_unit": durationUnit,
+        "hours": hours,
         "reason": reason,
         "status": status,
         "applied_at": appliedAt,
@@ -177,89 +339,141 @@ func (h *LeaveRequestHandler) GetLeaveRequestByID(c *gin.Context) {
         "approved_at": approvedAt,
         "rejection_reason": rejectionReason,
         "comments": comments,
-    })
-}
+        "approval_route": approvalRoute,
+        "approval_stage": approvalStage,
+        "routing_rule_id": routingRuleID,
+        "manager_approved_by": managerApprovedBy,
+        "manager_approved_at": managerApprovedAt,
+    }
 
-// GET /leave-requests (optional filters: employee_id, status)
-func (h *LeaveRequestHandler) ListLeaveRequests(c *gin.Context) {
-	// Get user context from middleware
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("role")
-	employeeID, _ := c.Get("employee_id")
+    // Embed related objects so the UI doesn't need a call per foreign key
+    if expand["employee"] {
+        emp, err := h.expandEmployee(c.Request.Context(), employeeID)
+        if err != nil {
+            apierror.Database(c, err, "Failed to load employee")
+            return
+        }
+        resp["employee"] = emp
+    }
+    if expand["approver"] {
+        resp["approver"] = nil
+        if approvedBy != nil {
+            approver, err := h.expandEmployee(c.Request.Context(), *approvedBy)
+            if err != nil {
+                apierror.Database(c, err, "Failed to load approver")
+                return
+            }
+            resp["approver"] = approver
+        }
+    }
+    if expand["leave_type"] {
+        lt, err := h.repos.LeaveTypes.GetLeaveType(c.Request.Context(), leaveTypeID)
+        if err != nil {
+            apierror.Database(c, err, "Failed to load leave type")
+            return
+        }
+        resp["leave_type"] = gin.H{
+            "id": leaveTypeID,
+            "name": lt.Name,
+            "description": lt.Description,
+            "max_days_per_year": lt.MaxDaysPerYear,
+            "carry_forward_allowed": lt.CarryForwardAllowed,
+            "max_carry_forward_days": lt.MaxCarryForwardDays,
+            "is_active": lt.IsActive,
+        }
+    }
 
-	// Build query based on user role
-	var query string
-	var args []interface{}
-	argIdx := 1
+    respond(c, http.StatusOK, resp)
+}
 
-	switch userRole.(string) {
-	case models.RoleAdmin, models.RoleHR:
-		// Admin and HR can see all requests
-		query = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lr.start_date, lr.end_date, 
-			lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, 
-			lr.rejection_reason, lr.comments, lr.created_at, lr.updated_at,
-			e.name as employee_name, e.email as employee_email,
-			lt.name as leave_type_name
-			FROM leave_requests lr
-			JOIN employees e ON lr.employee_id = e.id
-			JOIN leave_types lt ON lr.leave_type_id = lt.id
-			WHERE 1=1`
+// HEAD /leave-requests/:id
+func (h *LeaveRequestHandler) HeadLeaveRequest(c *gin.Context) {
+    id := c.Param("id")
+    if !isUUID(id) {
+        respondExists(c, false)
+        return
+    }
+    exists, err := h.repos.LeaveRequests.LeaveRequestExists(c.Request.Context(), id)
+    if err != nil {
+        apierror.Database(c, err, "Failed to check leave request")
+        return
+    }
+    respondExists(c, exists)
+}
 
-	case models.RoleManager:
-		// Managers can see their team's requests
-		query = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lr.start_date, lr.end_date, 
-			lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, 
-			lr.rejection_reason, lr.comments, lr.created_at, lr.updated_at,
-			e.name as employee_name, e.email as employee_email,
-			lt.name as leave_type_name
-			FROM leave_requests lr
-			JOIN employees e ON lr.employee_id = e.id
-			JOIN leave_types lt ON lr.leave_type_id = lt.id
-			WHERE e.manager_id = $` + fmt.Sprint(argIdx)
-		args = append(args, userID)
-		argIdx++
+// expandEmployee loads the public summary of an employee embedded by ?expand=
+func (h *LeaveRequestHandler) expandEmployee(ctx context.Context, id string) (gin.H, error) {
+    e, err := h.repos.Employees.GetEmployee(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    return gin.H{
+        "id": id,
+        "employee_id": e.EmployeeID,
+        "name": e.Name,
+        "email": e.Email,
+        "department_id": e.DepartmentID,
+        "role": e.Role,
+        "is_active": e.IsActive,
+    }, nil
+}
 
-	case models.RoleEmployee:
-		// Employees can only see their own requests
-		query = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lr.start_date, lr.end_date, 
-			lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, 
-			lr.rejection_reason, lr.comments, lr.created_at, lr.updated_at,
-			e.name as employee_name, e.email as employee_email,
-			lt.name as leave_type_name
-			FROM leave_requests lr
-			JOIN employees e ON lr.employee_id = e.id
-			JOIN leave_types lt ON lr.leave_type_id = lt.id
-			WHERE lr.employee_id = $` + fmt.Sprint(argIdx)
-		args = append(args, employeeID)
-		argIdx++
+// GET /leave-requests (optional filters: employee_id, status, from/to, filter expression; paging: limit, offset or cursor; sort=field:asc|desc)
+func (h *LeaveRequestHandler) ListLeaveRequests(c *gin.Context) {
+	h.listLeaveRequests(c, false)
+}
+
+// listLeaveRequests lists the requests the caller's role can see, or with own
+// only the caller's, whatever the role
+func (h *LeaveRequestHandler) listLeaveRequests(c *gin.Context, own bool) {
+	page, err := parseKeysetPagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, customSort, err := parseSort(c, leaveRequestSorts, "lr.created_at DESC, lr.id DESC", "lr.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	if customSort && page.Cursor != nil {
+		apierror.Respond(c, apierror.InvalidQuery, "cursor pagination only supports the default sort")
+		return
+	}
+	fields, err := parseFields(c, leaveRequestFields)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
 	}
 
-	// Add filters
-	if status := c.Query("status"); status != "" {
-		query += " AND lr.status = $" + fmt.Sprint(argIdx)
-		args = append(args, status)
-		argIdx++
+	query, args, ok := h.leaveRequestQuery(c, own)
+	if !ok {
+		return
 	}
 
-	// For Admin/HR, allow filtering by employee_id
-	if userRole.(string) == models.RoleAdmin || userRole.(string) == models.RoleHR {
-		if employeeIDFilter := c.Query("employee_id"); employeeIDFilter != "" {
-			query += " AND lr.employee_id = $" + fmt.Sprint(argIdx)
-			args = append(args, employeeIDFilter)
-			argIdx++
+	// Counting is skipped when paging by cursor, that's the point of keyset pagination
+	var total int
+	if page.Cursor == nil {
+		if err := h.read.QueryRow(c.Request.Context(), "SELECT COUNT(*) FROM ("+query+") t", args...).Scan(&total); err != nil {
+			apierror.Database(c, err, "Failed to count leave requests")
+			return
 		}
 	}
 
-	query += " ORDER BY lr.created_at DESC"
+	keyset, args := page.keyset("lr.created_at", "lr.id", args)
+	query += keyset
+	query += " ORDER BY " + orderBy
+	limitClause, args := page.clause(args)
+	query += limitClause
 
-	rows, err := h.pool.Query(context.Background(), query, args...)
+	rows, err := h.read.Query(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leave requests", "details": err.Error()})
+		apierror.Database(c, err, "Failed to fetch leave requests")
 		return
 	}
 	defer rows.Close()
 
-	var requests []map[string]interface{}
+	requests := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		var (
 			id              string
@@ -267,7 +481,7 @@ func (h *LeaveRequestHandler) ListLeaveRequests(c *gin.Context) {
 			leaveTypeID     string
 			startDate       time.Time
 			endDate         time.Time
-			totalDays       int
+			totalDays       float64
 			reason          string
 			status          string
 			appliedAt       time.Time
@@ -280,10 +494,15 @@ func (h *LeaveRequestHandler) ListLeaveRequests(c *gin.Context) {
 			employeeName    string
 			employeeEmail   string
 			leaveTypeName   string
+			inNoticePeriod  bool
+			approvalRoute   string
+			durationUnit    string
+			hours           *float64
+			approvalStage   *string
 		)
 
-		if err := rows.Scan(&id, &empID, &leaveTypeID, &startDate, &endDate, &totalDays, &reason, &status, &appliedAt, &approvedBy, &approvedAt, &rejectionReason, &comments, &createdAt, &updatedAt, &employeeName, &employeeEmail, &leaveTypeName); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan leave request", "details": err.Error()})
+		if err := rows.Scan(&id, &empID, &leaveTypeID, &startDate, &endDate, &totalDays, &reason, &status, &appliedAt, &approvedBy, &approvedAt, &rejectionReason, &comments, &createdAt, &updatedAt, &approvalRoute, &durationUnit, &hours, &approvalStage, &employeeName, &employeeEmail, &leaveTypeName, &inNoticePeriod); err != nil {
+			apierror.Database(c, err, "Failed to scan leave request")
 			return
 		}
 
@@ -303,87 +522,229 @@ func (h *LeaveRequestHandler) ListLeaveRequests(c *gin.Context) {
 			"comments":        comments,
 			"created_at":      createdAt,
 			"updated_at":      updatedAt,
+			"approval_route":  approvalRoute,
+			"approval_stage":  approvalStage,
+			"duration_unit":   durationUnit,
+			"hours":           hours,
 			"employee_name":   employeeName,
 			"employee_email":  employeeEmail,
 			"leave_type_name": leaveTypeName,
+			"in_notice_period": inNoticePeriod,
 		}
 		requests = append(requests, request)
 	}
 
-	c.JSON(http.StatusOK, requests)
+	if customSort {
+		c.JSON(http.StatusOK, gin.H{"data": fields.project(requests), "meta": page.meta(total, len(requests))})
+		return
+	}
+	n, meta := page.keysetMeta(total, len(requests), func(i int) (time.Time, string) {
+		return requests[i]["created_at"].(time.Time), requests[i]["id"].(string)
+	})
+	c.JSON(http.StatusOK, gin.H{"data": fields.project(requests[:n]), "meta": meta})
 }
 
-// PUT /leave-requests/:id/approve
-func (h *LeaveRequestHandler) ApproveLeaveRequest(c *gin.Context) {
-    id := c.Param("id")
-    var in struct { ApprovedBy string `json:"approved_by" binding:"required"` }
-    if err := c.ShouldBindJSON(&in); err != nil || in.ApprovedBy == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "approved_by is required"})
-        return
-    }
+// leaveRequestQuery builds the SELECT of the leave requests the caller's role
+// can see, or with own only the caller's, narrowed by the filters of the query
+// string (status, employee_id, from/to, filter), without ORDER BY. On an
+// invalid filter it responds and returns false.
+func (h *LeaveRequestHandler) leaveRequestQuery(c *gin.Context, own bool) (string, []interface{}, bool) {
+	// Get user context from middleware
+	role := c.GetString("role")
+	employeeID := c.GetString("employee_uuid")
+	if own {
+		role = models.RoleEmployee
+	}
 
-    var employeeID, leaveTypeID string
-    var totalDays int
-    if err := h.pool.QueryRow(context.Background(), `SELECT employee_id, leave_type_id, total_days FROM leave_requests WHERE id=$1`, id).Scan(&employeeID, &leaveTypeID, &totalDays); err != nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "leave request not found"})
-        return
-    }
+	// Requests that fall in the employee's notice period are flagged
+	noticeColumn := fmt.Sprintf("in_notice_period(e.resignation_date, %d, lr.start_date, lr.end_date)", h.notice().Days)
 
-    tx, err := h.pool.Begin(context.Background())
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "begin tx failed"})
-        return
-    }
-    defer tx.Rollback(context.Background())
+	// Build query based on user role
+	var query string
+	var args []interface{}
+	argIdx := 1
 
-    if _, err := tx.Exec(context.Background(),
-        `UPDATE leave_requests SET status='approved', approved_by=$1, approved_at=NOW() WHERE id=$2`, in.ApprovedBy, id,
-    ); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to approve request"})
-        return
-    }
+	switch role {
+	case models.RoleAdmin, models.RoleHR:
+		// Admin and HR can see all requests
+		query = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lr.start_date, lr.end_date, 
+			lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, 
+			lr.rejection_reason, lr.comments, lr.created_at, lr.updated_at, lr.approval_route,
+			lr.duration_unit, lr.hours, leave_request_stage(lr.id) as approval_stage,
+			e.name as employee_name, e.email as employee_email,
+			lt.name as leave_type_name, ` + noticeColumn + ` as in_notice_period
+			FROM leave_requests lr
+			JOIN employees e ON lr.employee_id = e.id
+			JOIN leave_types lt ON lr.leave_type_id = lt.id
+			WHERE 1=1`
 
-    currentYear := time.Now().Year()
-    if _, err := tx.Exec(context.Background(),
-        `UPDATE employee_leave_balances SET used_days = used_days + $1 WHERE employee_id=$2 AND leave_type_id=$3 AND year=$4`,
-        totalDays, employeeID, leaveTypeID, currentYear,
-    ); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update leave balance"})
-        return
-    }
+	case models.RoleManager:
+		// Managers can see their team's requests, and those of the managers
+		// who delegated their approvals to them
+		query = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lr.start_date, lr.end_date, 
+			lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, 
+			lr.rejection_reason, lr.comments, lr.created_at, lr.updated_at, lr.approval_route,
+			lr.duration_unit, lr.hours, leave_request_stage(lr.id) as approval_stage,
+			e.name as employee_name, e.email as employee_email,
+			lt.name as leave_type_name, ` + noticeColumn + ` as in_notice_period
+			FROM leave_requests lr
+			JOIN employees e ON lr.employee_id = e.id
+			JOIN leave_types lt ON lr.leave_type_id = lt.id
+			WHERE (e.manager_id = $` + fmt.Sprint(argIdx) + ` OR is_approval_delegate($` + fmt.Sprint(argIdx) + `, e.manager_id))`
+		args = append(args, employeeID)
+		argIdx++
 
-    if err := tx.Commit(context.Background()); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "commit failed"})
-        return
-    }
-    c.JSON(http.StatusOK, gin.H{"message": "leave request approved"})
+	case models.RoleEmployee:
+		// Employees see their own requests and, unless own, those they
+		// decide as a manager's approval delegate
+		query = `SELECT lr.id, lr.employee_id, lr.leave_type_id, lr.start_date, lr.end_date, 
+			lr.total_days, lr.reason, lr.status, lr.applied_at, lr.approved_by, lr.approved_at, 
+			lr.rejection_reason, lr.comments, lr.created_at, lr.updated_at, lr.approval_route,
+			lr.duration_unit, lr.hours, leave_request_stage(lr.id) as approval_stage,
+			e.name as employee_name, e.email as employee_email,
+			lt.name as leave_type_name, ` + noticeColumn + ` as in_notice_period
+			FROM leave_requests lr
+			JOIN employees e ON lr.employee_id = e.id
+			JOIN leave_types lt ON lr.leave_type_id = lt.id
+			WHERE (lr.employee_id = $` + fmt.Sprint(argIdx)
+		if !own {
+			query += ` OR is_approval_delegate($` + fmt.Sprint(argIdx) + `, e.manager_id)`
+		}
+		query += `)`
+		args = append(args, employeeID)
+		argIdx++
+	}
+
+	// Add filters
+	if status := c.Query("status"); status != "" {
+		query += " AND lr.status = $" + fmt.Sprint(argIdx)
+		args = append(args, status)
+		argIdx++
+	}
+
+	// For Admin/HR, allow filtering by employee_id
+	if role == models.RoleAdmin || role == models.RoleHR {
+		if employeeIDFilter := c.Query("employee_id"); employeeIDFilter != "" {
+			query += " AND lr.employee_id = $" + fmt.Sprint(argIdx)
+			args = append(args, employeeIDFilter)
+			argIdx++
+		}
+	}
+
+	// Date range: requests touching [from, to], i.e. overlapping it
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return "", nil, false
+	}
+	if from != nil && to != nil && from.After(*to) {
+		apierror.Respond(c, apierror.InvalidQuery, "from cannot be after to")
+		return "", nil, false
+	}
+	if from != nil {
+		query += " AND lr.end_date >= $" + fmt.Sprint(argIdx)
+		args = append(args, *from)
+		argIdx++
+	}
+	if to != nil {
+		query += " AND lr.start_date <= $" + fmt.Sprint(argIdx)
+		args = append(args, *to)
+		argIdx++
+	}
+
+	// Free-form filter expression, e.g. status in (pending,approved) AND start_date>=2025-01-01
+	if expr := c.Query("filter"); expr != "" {
+		cond, filterArgs, err := parseFilter(expr, leaveRequestFilters, args)
+		if err != nil {
+			apierror.Respond(c, apierror.InvalidQuery, err.Error())
+			return "", nil, false
+		}
+		query += " AND (" + cond + ")"
+		args = filterArgs
+	}
+
+	return query, args, true
+}
+
+// PUT /leave-requests/:id/approve
+// Approves the request's current approval step (see approval_steps.go).
+func (h *LeaveRequestHandler) ApproveLeaveRequest(c *gin.Context) {
+    h.approve(c, 0)
 }
 
 // PUT /leave-requests/:id/reject
+// Rejects the request at its current approval step.
 func (h *LeaveRequestHandler) RejectLeaveRequest(c *gin.Context) {
+    h.reject(c, 0)
+}
+
+// PUT /leave-requests/:id/cancel
+// Approved leave can be cancelled until its end_date and is refunded to the
+// balance.
+func (h *LeaveRequestHandler) CancelLeaveRequest(c *gin.Context) {
     id := c.Param("id")
-    var in struct { RejectionReason string `json:"rejection_reason" binding:"required"` }
-    if err := c.ShouldBindJSON(&in); err != nil || in.RejectionReason == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "rejection_reason is required"})
+    if err := h.workflow.Cancel(c.Request.Context(), id); err != nil {
+        respondWorkflowError(c, err, "failed to cancel request")
         return
     }
-    if _, err := h.pool.Exec(context.Background(),
-        `UPDATE leave_requests SET status='rejected', rejection_reason=$1 WHERE id=$2`, in.RejectionReason, id,
-    ); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reject request"})
+    respond(c, http.StatusOK, gin.H{"message": "leave request cancelled"})
+}
+
+// respondWorkflowError maps an error from the leave request service
+func respondWorkflowError(c *gin.Context, err error, fallback string) {
+    if code, msg, ok := workflowError(err); ok {
+        apierror.Respond(c, code, msg)
         return
     }
-    c.JSON(http.StatusOK, gin.H{"message": "leave request rejected"})
+    apierror.Database(c, err, fallback)
 }
 
-// PUT /leave-requests/:id/cancel
-func (h *LeaveRequestHandler) CancelLeaveRequest(c *gin.Context) {
-    id := c.Param("id")
-    if _, err := h.pool.Exec(context.Background(),
-        `UPDATE leave_requests SET status='cancelled' WHERE id=$1`, id,
-    ); err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel request"})
-        return
+// workflowError is the code and message of an error from the leave request
+// service; ok is false for the others, such as database errors
+func workflowError(err error) (code apierror.Code, msg string, ok bool) {
+    if errors.Is(err, service.ErrNotFound) || apierror.IsNoRows(err) {
+        return apierror.NotFound, "leave request not found", true
+    }
+    switch {
+    case errors.Is(err, service.ErrAwaitingManager):
+        return apierror.InvalidState, "the employee's manager has to approve this request first", true
+    case errors.Is(err, service.ErrAlreadyManagerApproved):
+        return apierror.HRApprovalRequired, "manager approval is recorded, HR has to decide on this request", true
+    case errors.Is(err, service.ErrNotPending):
+        return apierror.InvalidState, "the leave request is no longer pending", true
+    case errors.Is(err, service.ErrStepNotCurrent):
+        return apierror.InvalidState, "this approval step is not awaiting a decision", true
+    case errors.Is(err, service.ErrNotCancellable):
+        return apierror.InvalidState, "only pending or approved leave requests can be cancelled", true
+    case errors.Is(err, service.ErrLeaveEnded):
+        return apierror.InvalidState, "leave that has already ended cannot be cancelled", true
+    case errors.Is(err, service.ErrOwnRequest):
+        return apierror.Forbidden, "you cannot approve or reject your own leave request", true
+    case errors.Is(err, service.ErrNotManager):
+        return apierror.Forbidden, "only the employee's manager, their approval delegate or HR can decide this leave request", true
+    case errors.Is(err, service.ErrNoBalance):
+        return apierror.NoLeaveBalance, "no leave balance found for this leave type/year", true
+    case errors.Is(err, service.ErrInsufficientBalance):
+        return apierror.InsufficientBalance, "insufficient leave balance", true
     }
-    c.JSON(http.StatusOK, gin.H{"message": "leave request cancelled"})
+    return apierror.Code{}, "", false
+}
+
+// parseDateRange reads the optional from/to (YYYY-MM-DD) query parameters
+func parseDateRange(c *gin.Context) (from, to *time.Time, err error) {
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s must be YYYY-MM-DD", p.name)
+		}
+		*p.dst = &d
+	}
+	return from, to, nil
 }
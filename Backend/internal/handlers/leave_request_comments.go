@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type leaveRequestComment struct {
+	ID         string    `json:"id"`
+	AuthorID   string    `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	Comment    string    `json:"comment"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// inThread reports whether the caller takes part in the comments of request
+// id: HR and admins, the employee, their manager and the manager's active
+// approval delegate. A missing request fails with pgx.ErrNoRows.
+func (h *LeaveRequestHandler) inThread(ctx context.Context, c *gin.Context, id string) (bool, error) {
+	role := c.GetString("role")
+	var ok bool
+	err := h.pool.QueryRow(ctx, `
+		SELECT COALESCE($3 OR lr.employee_id = NULLIF($2, '')::UUID OR e.manager_id = NULLIF($2, '')::UUID
+			OR is_approval_delegate(NULLIF($2, '')::UUID, e.manager_id), false)
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		WHERE lr.id = $1`, id, c.GetString("employee_uuid"), role == models.RoleHR || role == models.RoleAdmin).Scan(&ok)
+	return ok, err
+}
+
+// GET /leave-requests/:id/comments (paging: limit, offset)
+// The request's comment thread, oldest first.
+func (h *LeaveRequestHandler) ListComments(c *gin.Context) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "leave request not found")
+		return
+	}
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	ok, err := h.inThread(ctx, c, id)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "leave request not found", "failed to fetch comments")
+		return
+	}
+	if !ok {
+		apierror.Respond(c, apierror.Forbidden, "Access denied to this resource")
+		return
+	}
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM leave_request_comments WHERE leave_request_id = $1", id).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch comments")
+		return
+	}
+	rows, err := h.pool.Query(ctx, `
+		SELECT c.id, c.author_id, e.name, c.comment, c.created_at
+		FROM leave_request_comments c
+		JOIN employees e ON e.id = c.author_id
+		WHERE c.leave_request_id = $1
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3`, id, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch comments")
+		return
+	}
+	defer rows.Close()
+	list := make([]leaveRequestComment, 0)
+	for rows.Next() {
+		var m leaveRequestComment
+		if err := rows.Scan(&m.ID, &m.AuthorID, &m.AuthorName, &m.Comment, &m.CreatedAt); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch comments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// POST /leave-requests/:id/comments
+// Adds {"comment": "..."} to the request's thread, whatever its status. The
+// other participants are notified on GET /events (leave_request.commented).
+func (h *LeaveRequestHandler) AddComment(c *gin.Context) {
+	var in struct {
+		Comment string `json:"comment" binding:"required"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "leave request not found")
+		return
+	}
+	author := c.GetString("employee_uuid")
+	if author == "" {
+		apierror.Respond(c, apierror.Forbidden, "only users with an employee record can comment on leave requests")
+		return
+	}
+	if strings.TrimSpace(in.Comment) == "" {
+		apierror.Respond(c, apierror.InvalidInput, "comment cannot be empty")
+		return
+	}
+	ctx := c.Request.Context()
+	ok, err := h.inThread(ctx, c, id)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "leave request not found", "failed to add comment")
+		return
+	}
+	if !ok {
+		apierror.Respond(c, apierror.Forbidden, "Access denied to this resource")
+		return
+	}
+	var m leaveRequestComment
+	if err := h.pool.QueryRow(ctx, `
+		WITH c AS (
+			INSERT INTO leave_request_comments (leave_request_id, author_id, comment)
+			VALUES ($1, $2, $3)
+			RETURNING id, author_id, comment, created_at
+		)
+		SELECT c.id, c.author_id, e.name, c.comment, c.created_at
+		FROM c JOIN employees e ON e.id = c.author_id`, id, author, in.Comment).Scan(
+		&m.ID, &m.AuthorID, &m.AuthorName, &m.Comment, &m.CreatedAt); err != nil {
+		apierror.Database(c, err, "failed to add comment")
+		return
+	}
+	respond(c, http.StatusCreated, m)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// decisionSnapshot is what was in front of the approver when a leave request
+// was approved or rejected (see leave_decision_snapshots in Database/db.sql)
+type decisionSnapshot struct {
+	ID                  string          `json:"id"`
+	Decision            string          `json:"decision"`
+	DecidedBy           *string         `json:"decided_by"`
+	DecidedAt           time.Time       `json:"decided_at"`
+	Balance             json.RawMessage `json:"balance"`
+	Policy              json.RawMessage `json:"policy"`
+	OverlappingRequests json.RawMessage `json:"overlapping_requests"`
+}
+
+// GET /leave-requests/:id/history
+// The request's audit trail, live and archived, with the changed fields of
+// each entry, and the snapshots taken when it was approved or rejected,
+// oldest first, along with the approval route the request was given and the
+// rule that chose it. Works for archived requests too.
+func (h *LeaveRequestHandler) GetLeaveRequestHistory(c *gin.Context) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "leave request not found")
+		return
+	}
+	ctx := c.Request.Context()
+	// how the request was routed for approval; no row means no request
+	var (
+		route             string
+		ruleID, ruleName  *string
+		managerApprovedBy *string
+		managerApprovedAt *time.Time
+	)
+	err := h.pool.QueryRow(ctx, `
+		SELECT lr.approval_route, lr.routing_rule_id, r.name, lr.manager_approved_by, lr.manager_approved_at
+		FROM (SELECT approval_route, routing_rule_id, manager_approved_by, manager_approved_at FROM leave_requests WHERE id = $1
+		      UNION ALL
+		      SELECT approval_route, routing_rule_id, manager_approved_by, manager_approved_at FROM leave_requests_archive WHERE id = $1) lr
+		LEFT JOIN approval_routing_rules r ON r.id = lr.routing_rule_id`, id).
+		Scan(&route, &ruleID, &ruleName, &managerApprovedBy, &managerApprovedAt)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "leave request not found", "failed to load leave request history")
+		return
+	}
+	routing := gin.H{
+		"route":               route,
+		"rule":                nil, // the default route, no rule matched
+		"manager_approved_by": managerApprovedBy,
+		"manager_approved_at": managerApprovedAt,
+	}
+	if ruleID != nil {
+		routing["rule"] = gin.H{"id": *ruleID, "name": ruleName} // name is null once the rule is deleted
+	}
+
+	rows, err := h.pool.Query(ctx, `
+		SELECT id, action, old_values, new_values, changed_by, changed_at, actor_user_id, actor_role, request_id
+		FROM (SELECT `+auditColumns+` FROM audit_logs WHERE table_name = 'leave_requests' AND record_id = $1
+		      UNION ALL
+		      SELECT `+auditColumns+` FROM audit_logs_archive WHERE table_name = 'leave_requests' AND record_id = $1) a
+		ORDER BY chain_seq`, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to load leave request history")
+		return
+	}
+	defer rows.Close()
+	changes := make([]gin.H, 0)
+	for rows.Next() {
+		var (
+			entryID, action      string
+			oldValues, newValues map[string]interface{}
+			changedBy            *string
+			changedAt            time.Time
+			actor                auditActor
+		)
+		if err := rows.Scan(&entryID, &action, &oldValues, &newValues, &changedBy, &changedAt,
+			&actor.UserID, &actor.Role, &actor.RequestID); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		changes = append(changes, gin.H{
+			"id":            entryID,
+			"action":        action,
+			"changed_by":    changedBy,
+			"changed_at":    changedAt,
+			"actor_user_id": actor.UserID,
+			"actor_role":    actor.Role,
+			"request_id":    actor.RequestID,
+			"changes":       diffValues(oldValues, newValues),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to load leave request history")
+		return
+	}
+
+	rows, err = h.pool.Query(ctx, `
+		SELECT id, decision, decided_by, decided_at, balance, policy, overlapping_requests
+		FROM leave_decision_snapshots WHERE leave_request_id = $1
+		ORDER BY decided_at, id`, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to load leave request history")
+		return
+	}
+	defer rows.Close()
+	decisions := make([]decisionSnapshot, 0)
+	for rows.Next() {
+		var d decisionSnapshot
+		if err := rows.Scan(&d.ID, &d.Decision, &d.DecidedBy, &d.DecidedAt, &d.Balance, &d.Policy, &d.OverlappingRequests); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		decisions = append(decisions, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to load leave request history")
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{"leave_request_id": id, "routing": routing, "changes": changes, "decisions": decisions})
+}
@@ -2,48 +2,141 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/repository"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type LeaveTypeHandler struct {
-	pool *pgxpool.Pool
+	pool       *pgxpool.Pool
+	leaveTypes repository.LeaveTypeRepo
+	cache      *cache.Cache
+}
+
+func NewLeaveTypeHandler(pool *pgxpool.Pool, rc *cache.Cache) *LeaveTypeHandler {
+	return &LeaveTypeHandler{pool: pool, leaveTypes: repository.New(pool).Repos().LeaveTypes, cache: rc}
 }
 
-func NewLeaveTypeHandler(pool *pgxpool.Pool) *LeaveTypeHandler {
-	return &LeaveTypeHandler{pool: pool}
+type activeLeaveType struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Description    *string `json:"description"`
+	MaxDaysPerYear int     `json:"max_days_per_year"`
+	// annual grants max_days_per_year on January 1st, monthly and quarterly
+	// leave the balance to the accrual job
+	AccrualFrequency string   `json:"accrual_frequency"`
+	AccrualRate      *float64 `json:"accrual_rate"`
+	// policy rules checked when leave is applied for
+	SandwichRule       bool `json:"sandwich_rule"`
+	MinNoticeDays      int  `json:"min_notice_days"`
+	MaxConsecutiveDays *int `json:"max_consecutive_days"`
+	// only set by allLeaveTypes; never cached, so cached entries read as active
+	Inactive bool `json:"-"`
 }
 
-// GET /leave-types
+// activeLeaveTypes returns every active leave type ordered by name. The list
+// is small and read on almost every screen, so it is cached as a whole and
+// invalidated by the mutations below.
+func (h *LeaveTypeHandler) activeLeaveTypes(ctx context.Context) ([]activeLeaveType, error) {
+	var types []activeLeaveType
+	if h.cache.Get(ctx, cache.LeaveTypesKey(db.OrgID(ctx)), &types) {
+		return types, nil
+	}
+	types, err := h.listLeaveTypes(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	h.cache.Set(ctx, cache.LeaveTypesKey(db.OrgID(ctx)), types)
+	return types, nil
+}
+
+// allLeaveTypes includes the deactivated leave types; it is rarely used and
+// therefore not cached
+func (h *LeaveTypeHandler) allLeaveTypes(ctx context.Context) ([]activeLeaveType, error) {
+	return h.listLeaveTypes(ctx, true)
+}
+
+func (h *LeaveTypeHandler) listLeaveTypes(ctx context.Context, includeInactive bool) ([]activeLeaveType, error) {
+	rows, err := h.leaveTypes.ListLeaveTypes(ctx, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	types := make([]activeLeaveType, 0, len(rows))
+	for _, r := range rows {
+		t := activeLeaveType{
+			ID:               r.ID,
+			Name:             r.Name,
+			Description:      r.Description,
+			MaxDaysPerYear:   int(r.MaxDaysPerYear),
+			AccrualFrequency: r.AccrualFrequency,
+			AccrualRate:      r.AccrualRate,
+			SandwichRule:     r.SandwichRule,
+			MinNoticeDays:    int(r.MinNoticeDays),
+			Inactive:         r.IsActive == nil || !*r.IsActive,
+		}
+		if r.MaxConsecutiveDays != nil {
+			n := int(*r.MaxConsecutiveDays)
+			t.MaxConsecutiveDays = &n
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// GET /leave-types (paging: limit, offset; include_inactive=true adds deactivated types)
 func (h *LeaveTypeHandler) GetLeaveTypes(c *gin.Context) {
-	rows, err := h.pool.Query(context.Background(), "SELECT id, name, description, max_days_per_year FROM leave_types WHERE is_active = TRUE")
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	includeInactive, err := parseIncludeInactive(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch leave types"})
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
 		return
 	}
-	defer rows.Close()
 
-	var result []map[string]interface{}
-	for rows.Next() {
-		var id, name, desc string
-		var maxDays int
-		if err := rows.Scan(&id, &name, &desc, &maxDays); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "row scan failed"})
-			return
-		}
+	var types []activeLeaveType
+	if includeInactive {
+		types, err = h.allLeaveTypes(c.Request.Context())
+	} else {
+		types, err = h.activeLeaveTypes(c.Request.Context())
+	}
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch leave types")
+		return
+	}
+	total := len(types)
+
+	result := make([]map[string]interface{}, 0)
+	for i := page.Offset; i < total && i < page.Offset+page.Limit; i++ {
+		t := types[i]
 		result = append(result, gin.H{
-			"id":                id,
-			"name":              name,
-			"description":       desc,
-			"max_days_per_year": maxDays,
+			"id":                   t.ID,
+			"name":                 t.Name,
+			"description":          t.Description,
+			"max_days_per_year":    t.MaxDaysPerYear,
+			"accrual_frequency":    t.AccrualFrequency,
+			"accrual_rate":         t.AccrualRate,
+			"sandwich_rule":        t.SandwichRule,
+			"min_notice_days":      t.MinNoticeDays,
+			"max_consecutive_days": t.MaxConsecutiveDays,
+			"is_active":            !t.Inactive,
 		})
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{"data": result, "meta": page.meta(total, len(result))})
 }
 
 type createLeaveTypeDTO struct {
@@ -53,144 +146,173 @@ type createLeaveTypeDTO struct {
 	CarryForwardAllowed bool   `json:"carry_forward_allowed"`
 	MaxCarryForwardDays int    `json:"max_carry_forward_days"`
 	IsActive            *bool  `json:"is_active"`
+	// defaults to annual; accrual_rate is days per period and only applies to
+	// monthly and quarterly types
+	AccrualFrequency string   `json:"accrual_frequency" binding:"omitempty,oneof=annual monthly quarterly"`
+	AccrualRate      *float64 `json:"accrual_rate"`
+	// policy rules; by default only working days are charged, without notice
+	// or length limits
+	SandwichRule       bool `json:"sandwich_rule"`
+	MinNoticeDays      int  `json:"min_notice_days"`
+	MaxConsecutiveDays *int `json:"max_consecutive_days"`
 }
 
 // POST /leave-types
 func (h *LeaveTypeHandler) CreateLeaveType(c *gin.Context) {
 	var in createLeaveTypeDTO
-	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input", "details": err.Error()})
+	if !bindJSON(c, &in) {
 		return
 	}
 	name := strings.TrimSpace(in.Name)
 	if name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		apierror.Respond(c, apierror.InvalidInput, "name is required")
 		return
 	}
 	if in.MaxDaysPerYear < 0 || in.MaxCarryForwardDays < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "days cannot be negative"})
+		apierror.Respond(c, apierror.InvalidInput, "days cannot be negative")
+		return
+	}
+	if in.MinNoticeDays < 0 {
+		apierror.Respond(c, apierror.InvalidInput, "min_notice_days cannot be negative")
+		return
+	}
+	if in.MaxConsecutiveDays != nil && *in.MaxConsecutiveDays <= 0 {
+		apierror.Respond(c, apierror.InvalidInput, "max_consecutive_days must be positive")
 		return
 	}
 	if !in.CarryForwardAllowed {
 		in.MaxCarryForwardDays = 0
 	}
+	if in.AccrualFrequency == "" {
+		in.AccrualFrequency = accrualAnnual
+	}
+	if in.AccrualRate != nil && (*in.AccrualRate <= 0 || in.AccrualFrequency == accrualAnnual) {
+		apierror.Respond(c, apierror.InvalidInput, "accrual_rate must be positive and needs a monthly or quarterly accrual_frequency")
+		return
+	}
 	isActive := true
 	if in.IsActive != nil {
 		isActive = *in.IsActive
 	}
-	var id string
-	if err := h.pool.QueryRow(
-		context.Background(),
-		`INSERT INTO leave_types (name, description, max_days_per_year, carry_forward_allowed, max_carry_forward_days, is_active)
-		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
-		name, in.Description, in.MaxDaysPerYear, in.CarryForwardAllowed, in.MaxCarryForwardDays, isActive,
-	).Scan(&id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "create leave type failed", "details": err.Error()})
+	maxCarryForward := int32(in.MaxCarryForwardDays)
+	var maxConsecutive *int32
+	if in.MaxConsecutiveDays != nil {
+		n := int32(*in.MaxConsecutiveDays)
+		maxConsecutive = &n
+	}
+	id, err := h.leaveTypes.CreateLeaveType(c.Request.Context(), queries.CreateLeaveTypeParams{
+		Name:                name,
+		Description:         &in.Description,
+		MaxDaysPerYear:      int32(in.MaxDaysPerYear),
+		CarryForwardAllowed: &in.CarryForwardAllowed,
+		MaxCarryForwardDays: &maxCarryForward,
+		AccrualFrequency:    in.AccrualFrequency,
+		AccrualRate:         in.AccrualRate,
+		SandwichRule:        in.SandwichRule,
+		MinNoticeDays:       int32(in.MinNoticeDays),
+		MaxConsecutiveDays:  maxConsecutive,
+		IsActive:            &isActive,
+	})
+	if err != nil {
+		apierror.Database(c, err, "create leave type failed")
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{
+	h.cache.Delete(c.Request.Context(), cache.LeaveTypesKey(db.OrgID(c.Request.Context())))
+	respond(c, http.StatusCreated, gin.H{
 		"id":                   id,
 		"name":                 name,
 		"description":          in.Description,
 		"max_days_per_year":    in.MaxDaysPerYear,
 		"carry_forward_allowed": in.CarryForwardAllowed,
 		"max_carry_forward_days": in.MaxCarryForwardDays,
+		"accrual_frequency":    in.AccrualFrequency,
+		"accrual_rate":         in.AccrualRate,
+		"sandwich_rule":        in.SandwichRule,
+		"min_notice_days":      in.MinNoticeDays,
+		"max_consecutive_days": in.MaxConsecutiveDays,
 		"is_active":            isActive,
 	})
 }
 
-type updateLeaveTypeDTO struct {
-	Name                *string `json:"name"`
-	Description         *string `json:"description"`
-	MaxDaysPerYear      *int    `json:"max_days_per_year"`
-	CarryForwardAllowed *bool   `json:"carry_forward_allowed"`
-	MaxCarryForwardDays *int    `json:"max_carry_forward_days"`
-	IsActive            *bool   `json:"is_active"`
+// leaveTypePatchFields lists the leave type columns that PATCH may change
+var leaveTypePatchFields = map[string]patchField{
+	"name":                   {column: "name", parse: patchString(true)},
+	"description":            {column: "description", nullable: true, parse: patchString(false)},
+	"max_days_per_year":      {column: "max_days_per_year", parse: patchNonNegativeInt},
+	"carry_forward_allowed":  {column: "carry_forward_allowed", parse: patchBool},
+	"max_carry_forward_days": {column: "max_carry_forward_days", parse: patchNonNegativeInt},
+	"is_active":              {column: "is_active", parse: patchBool},
+	"accrual_frequency":      {column: "accrual_frequency", parse: patchAccrualFrequency},
+	"accrual_rate":           {column: "accrual_rate", nullable: true, parse: patchPositiveNumber},
+	"sandwich_rule":          {column: "sandwich_rule", parse: patchBool},
+	"min_notice_days":        {column: "min_notice_days", parse: patchNonNegativeInt},
+	"max_consecutive_days":   {column: "max_consecutive_days", nullable: true, parse: patchPositiveInt},
 }
 
-// PUT /leave-types/:id
+const accrualAnnual = "annual"
+
+var accrualFrequencies = []string{accrualAnnual, "monthly", "quarterly"}
+
+// patchAccrualFrequency decodes an accrual_frequency member
+func patchAccrualFrequency(raw json.RawMessage) (interface{}, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("must be a string")
+	}
+	for _, f := range accrualFrequencies {
+		if s == f {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("must be one of %s", strings.Join(accrualFrequencies, ", "))
+}
+
+// PATCH /leave-types/:id (RFC 7386 merge patch; null clears description,
+// accrual_rate and max_consecutive_days)
+// A new accrual_frequency applies from the next period the accrual job
+// credits; days already allocated or accrued stay.
 func (h *LeaveTypeHandler) UpdateLeaveType(c *gin.Context) {
 	id := c.Param("id")
-	var in updateLeaveTypeDTO
-	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input", "details": err.Error()})
-		return
-	}
-	sets := []string{}
-	args := []interface{}{}
-	idx := 1
-	if in.Name != nil {
-		name := strings.TrimSpace(*in.Name)
-		if name == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
-			return
-		}
-		sets = append(sets, fmt.Sprintf("name=$%d", idx))
-		args = append(args, name)
-		idx++
-	}
-	if in.Description != nil {
-		sets = append(sets, fmt.Sprintf("description=$%d", idx))
-		args = append(args, *in.Description)
-		idx++
-	}
-	if in.MaxDaysPerYear != nil {
-		if *in.MaxDaysPerYear < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "max_days_per_year cannot be negative"})
-			return
-		}
-		sets = append(sets, fmt.Sprintf("max_days_per_year=$%d", idx))
-		args = append(args, *in.MaxDaysPerYear)
-		idx++
-	}
-	if in.CarryForwardAllowed != nil {
-		sets = append(sets, fmt.Sprintf("carry_forward_allowed=$%d", idx))
-		args = append(args, *in.CarryForwardAllowed)
-		idx++
-	}
-	if in.MaxCarryForwardDays != nil {
-		if *in.MaxCarryForwardDays < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "max_carry_forward_days cannot be negative"})
-			return
-		}
-		sets = append(sets, fmt.Sprintf("max_carry_forward_days=$%d", idx))
-		args = append(args, *in.MaxCarryForwardDays)
-		idx++
+	patch, ok := bindMergePatch(c)
+	if !ok {
+		return
 	}
-	if in.IsActive != nil {
-		sets = append(sets, fmt.Sprintf("is_active=$%d", idx))
-		args = append(args, *in.IsActive)
-		idx++
+	sets, args, fieldErrs := patch.assignments(leaveTypePatchFields, 1)
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
 	}
 	if len(sets) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "no fields to update")
 		return
 	}
-	query := "UPDATE leave_types SET " + strings.Join(sets, ", ") + ", updated_at=NOW() WHERE id=$" + fmt.Sprintf("%d", idx)
+	query := "UPDATE leave_types SET " + strings.Join(sets, ", ") + ", updated_at=NOW() WHERE id=$" + fmt.Sprintf("%d", len(args)+1)
 	args = append(args, id)
-	ct, err := h.pool.Exec(context.Background(), query, args...)
+	ct, err := h.pool.Exec(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "update leave type failed", "details": err.Error()})
+		apierror.Database(c, err, "update leave type failed")
 		return
 	}
 	if ct.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "leave type not found"})
+		apierror.Respond(c, apierror.NotFound, "leave type not found")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "leave type updated"})
+	h.cache.Delete(c.Request.Context(), cache.LeaveTypesKey(db.OrgID(c.Request.Context())))
+	respond(c, http.StatusOK, gin.H{"message": "leave type updated"})
 }
 
 // DELETE /leave-types/:id (soft delete)
 func (h *LeaveTypeHandler) DeleteLeaveType(c *gin.Context) {
 	id := c.Param("id")
-	ct, err := h.pool.Exec(context.Background(), `UPDATE leave_types SET is_active=false, updated_at=NOW() WHERE id=$1`, id)
+	n, err := h.leaveTypes.DeactivateLeaveType(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete leave type failed"})
+		apierror.Database(c, err, "delete leave type failed")
 		return
 	}
-	if ct.RowsAffected() == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "leave type not found"})
+	if n == 0 {
+		apierror.Respond(c, apierror.NotFound, "leave type not found")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "leave type deactivated"})
+	h.cache.Delete(c.Request.Context(), cache.LeaveTypesKey(db.OrgID(c.Request.Context())))
+	respond(c, http.StatusOK, gin.H{"message": "leave type deactivated"})
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/maintenance"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// readyTimeout bounds the database check of GET /readyz
+const readyTimeout = 2 * time.Second
+
+// MaintenanceHandler serves the readiness probe and the switch of the
+// read-only maintenance mode
+type MaintenanceHandler struct {
+	pool  *pgxpool.Pool
+	state *maintenance.State
+}
+
+func NewMaintenanceHandler(pool *pgxpool.Pool, state *maintenance.State) *MaintenanceHandler {
+	return &MaintenanceHandler{pool: pool, state: state}
+}
+
+// GET /readyz
+// 200 while the database answers, 503 otherwise. An instance in read-only
+// mode is still ready, it serves reads; read_only and message tell clients
+// and dashboards that changes are refused.
+func (h *MaintenanceHandler) Ready(c *gin.Context) {
+	readOnly, message := h.state.ReadOnly()
+	resp := gin.H{"status": "ready", "read_only": readOnly}
+	if readOnly {
+		resp["message"] = message
+	}
+	ctx, cancel := context.WithTimeout(db.AsService(c.Request.Context()), readyTimeout)
+	defer cancel()
+	if err := h.pool.Ping(ctx); err != nil {
+		resp["status"] = "unavailable"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /admin/maintenance
+// GET /operator/maintenance
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	respond(c, http.StatusOK, h.state.Get())
+}
+
+// PUT /operator/maintenance
+// Switches read-only mode on or off for every instance and organization, so
+// it is behind the operator token (middleware.OperatorAuth) rather than an
+// admin role. message, when given, is what clients get instead of the default
+// one.
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+	var in struct {
+		ReadOnly *bool  `json:"read_only" binding:"required"`
+		Message  string `json:"message" binding:"max=500"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	m, err := h.state.Set(c.Request.Context(), *in.ReadOnly, strings.TrimSpace(in.Message))
+	if errors.Is(err, maintenance.ErrNotAllowed) {
+		apierror.Respond(c, apierror.Forbidden, err.Error())
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err, "failed to switch maintenance mode")
+		return
+	}
+	respond(c, http.StatusOK, m)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MeHandler serves /me: the caller's own employee profile, leave balances and
+// leave requests, resolved from the token, so clients need not know their
+// employee id. The responses are those of the matching employee and leave
+// request endpoints.
+type MeHandler struct {
+	employees     *EmployeeHandler
+	leaveRequests *LeaveRequestHandler
+}
+
+func NewMeHandler(employees *EmployeeHandler, leaveRequests *LeaveRequestHandler) *MeHandler {
+	return &MeHandler{employees: employees, leaveRequests: leaveRequests}
+}
+
+// self points the :id route parameter at the caller's employee record, so
+// the per-employee handlers can serve the request. Users without an employee
+// record get a 404.
+func self(c *gin.Context) bool {
+	id := c.GetString("employee_uuid")
+	if id == "" {
+		apierror.Respond(c, apierror.NotFound, "no employee record for this user")
+		return false
+	}
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: id})
+	return true
+}
+
+// GET /me/profile
+func (h *MeHandler) GetProfile(c *gin.Context) {
+	if self(c) {
+		h.employees.GetEmployeeByID(c)
+	}
+}
+
+// GET /me/leave-balances
+func (h *MeHandler) GetLeaveBalances(c *gin.Context) {
+	if self(c) {
+		h.employees.GetLeaveBalances(c)
+	}
+}
+
+// GET /me/leave-requests (the filters, paging, sort and fields of GET /leave-requests)
+func (h *MeHandler) ListLeaveRequests(c *gin.Context) {
+	if self(c) {
+		h.leaveRequests.listLeaveRequests(c, true)
+	}
+}
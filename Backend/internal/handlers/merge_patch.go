@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MergePatchContentType is the RFC 7386 media type. Plain application/json is
+// accepted as well so existing clients keep working.
+const MergePatchContentType = "application/merge-patch+json"
+
+// mergePatch is a decoded RFC 7386 document: member name -> raw JSON value.
+// A member with a JSON null value clears the column, an absent member leaves it
+// untouched.
+type mergePatch map[string]json.RawMessage
+
+// patchField describes how one patch member maps onto a column
+type patchField struct {
+	column   string
+	nullable bool
+	parse    func(raw json.RawMessage) (interface{}, error)
+}
+
+// bindMergePatch reads the request body as a merge patch. It responds and
+// returns false when the body is not a JSON object.
+func bindMergePatch(c *gin.Context) (mergePatch, bool) {
+	if ct := c.GetHeader("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != MergePatchContentType && mt != "application/json") {
+			apierror.Respond(c, apierror.UnsupportedMediaType, "content type must be "+MergePatchContentType)
+			return nil, false
+		}
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBindError(c, err, nil)
+		return nil, false
+	}
+	// a non-object patch would replace the whole resource, which we never allow
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '{' {
+		apierror.Respond(c, apierror.InvalidInput, "merge patch must be a JSON object")
+		return nil, false
+	}
+	var patch mergePatch
+	if err := json.Unmarshal(body, &patch); err != nil {
+		apierror.Fields(c, "invalid input", fieldErrors(err, &patch))
+		return nil, false
+	}
+	return patch, true
+}
+
+// assignments turns the patch into "column=$n" fragments starting at placeholder
+// idx. Members are processed in name order so the generated SQL is stable; every
+// invalid member is reported.
+func (p mergePatch) assignments(fields map[string]patchField, idx int) ([]string, []interface{}, []apierror.FieldError) {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sets := []string{}
+	args := []interface{}{}
+	var errs []apierror.FieldError
+	for _, name := range names {
+		f, ok := fields[name]
+		if !ok {
+			errs = append(errs, apierror.FieldError{Field: name, Error: "is not a known field"})
+			continue
+		}
+		raw := p[name]
+		if isJSONNull(raw) {
+			if !f.nullable {
+				errs = append(errs, apierror.FieldError{Field: name, Error: "cannot be null"})
+				continue
+			}
+			sets = append(sets, f.column+"=NULL")
+			continue
+		}
+		v, err := f.parse(raw)
+		if err != nil {
+			errs = append(errs, apierror.FieldError{Field: name, Error: err.Error()})
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s=$%d", f.column, idx))
+		args = append(args, v)
+		idx++
+	}
+	return sets, args, errs
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// patchString decodes a string member, trims it and optionally rejects blanks
+func patchString(required bool) func(json.RawMessage) (interface{}, error) {
+	return func(raw json.RawMessage) (interface{}, error) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, errors.New("must be a string")
+		}
+		s = strings.TrimSpace(s)
+		if required && s == "" {
+			return nil, errors.New("cannot be empty")
+		}
+		return s, nil
+	}
+}
+
+// patchNonNegativeInt decodes an integer member that must be >= 0
+func patchNonNegativeInt(raw json.RawMessage) (interface{}, error) {
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, errors.New("must be an integer")
+	}
+	if n < 0 {
+		return nil, errors.New("cannot be negative")
+	}
+	return n, nil
+}
+
+// patchPositiveInt decodes an integer member that must be > 0
+func patchPositiveInt(raw json.RawMessage) (interface{}, error) {
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, errors.New("must be an integer")
+	}
+	if n <= 0 {
+		return nil, errors.New("must be positive")
+	}
+	return n, nil
+}
+
+// patchPositiveNumber decodes a number member that must be > 0
+func patchPositiveNumber(raw json.RawMessage) (interface{}, error) {
+	var n float64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, errors.New("must be a number")
+	}
+	if n <= 0 {
+		return nil, errors.New("must be positive")
+	}
+	return n, nil
+}
+
+// patchDate decodes a YYYY-MM-DD member
+func patchDate(raw json.RawMessage) (interface{}, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("must be a string")
+	}
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, errors.New("must be YYYY-MM-DD")
+	}
+	return d, nil
+}
+
+func patchBool(raw json.RawMessage) (interface{}, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, errors.New("must be a boolean")
+	}
+	return b, nil
+}
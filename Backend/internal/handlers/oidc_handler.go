@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/models"
+	"leave-management/internal/oidc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// OIDCHandler signs users in with Google or Microsoft Entra ID (see
+// internal/oidc). The provider account finds the user it was linked to. On
+// its first sign-in it is linked to the user with its email address, or that
+// of the employee with it, but only when the provider vouches for the
+// address; any other account is linked from the session of the user it
+// belongs to, through POST /auth/oidc/link. An employee without a user gets
+// one, when provisioning is on.
+type OIDCHandler struct {
+	auth      *AuthHandler
+	providers *oidc.Providers
+	provision func() bool // OIDC_PROVISION_USERS
+}
+
+func NewOIDCHandler(auth *AuthHandler, providers *oidc.Providers, provision func() bool) *OIDCHandler {
+	return &OIDCHandler{auth: auth, providers: providers, provision: provision}
+}
+
+// oidcLoginTTL is how long a sign-in may take at the provider
+const oidcLoginTTL = 10 * time.Minute
+
+// GET /auth/oidc/login (query: provider, which may be left out when only one
+// is configured)
+// Redirects the browser to the provider's sign-in page, which sends it back
+// to OIDC_REDIRECT_URL with the code and state for POST /auth/oidc/callback.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	if target, ok := h.start(c, nil); ok {
+		c.Redirect(http.StatusFound, target)
+	}
+}
+
+// POST /auth/oidc/link (query: provider, as at GET /auth/oidc/login)
+// Answers with the URL of the provider's sign-in page, for the signed-in
+// caller to open. The provider sends the browser back to OIDC_REDIRECT_URL,
+// and POST /auth/oidc/link/callback, with the caller's access token, links
+// the account the caller signed in with there to the caller's user.
+func (h *OIDCHandler) Link(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if target, ok := h.start(c, &userID); ok {
+		respond(c, http.StatusOK, gin.H{"url": target})
+	}
+}
+
+// start records a new sign-in, for linking to the user userID when not nil,
+// and returns the provider's sign-in page for it
+func (h *OIDCHandler) start(c *gin.Context, userID *string) (string, bool) {
+	names := h.providers.Names()
+	if len(names) == 0 {
+		apierror.Respond(c, apierror.NotFound, "Single sign-on is not configured")
+		return "", false
+	}
+	name := c.Query("provider")
+	if name == "" && len(names) == 1 {
+		name = names[0]
+	}
+	if !h.providers.Enabled(name) {
+		apierror.Respond(c, apierror.InvalidQuery, "provider must be one of: "+strings.Join(names, ", "))
+		return "", false
+	}
+	var secrets [3]string // state, nonce, code verifier
+	for i := range secrets {
+		s, err := oidc.Secret()
+		if err != nil {
+			apierror.Respond(c, apierror.Internal, "Failed to start sign-in")
+			return "", false
+		}
+		secrets[i] = s
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+	target, err := h.providers.AuthURL(name, state, nonce, verifier)
+	if err != nil {
+		apierror.Respond(c, apierror.Internal, "Failed to start sign-in")
+		return "", false
+	}
+
+	// the caller has no organization yet, nor does the sign-in
+	ctx := db.AsService(c.Request.Context())
+	err = db.WithTx(ctx, h.auth.pool, func(tx pgx.Tx) error {
+		// unfinished sign-ins are cleared out as new ones start
+		if _, err := tx.Exec(ctx, "DELETE FROM oidc_logins WHERE created_at < $1", time.Now().Add(-oidcLoginTTL)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO oidc_logins (state, provider, nonce, code_verifier, user_id) VALUES ($1, $2, $3, $4, $5)`,
+			state, name, nonce, verifier, userID)
+		return err
+	})
+	if err != nil {
+		apierror.Database(c, err, "Failed to start sign-in")
+		return "", false
+	}
+	return target, true
+}
+
+// errNoAccount ends a sign-in whose email address is neither a user's nor,
+// with provisioning on, an active employee's
+var errNoAccount = errors.New("no account")
+
+// errNotVerified ends the first sign-in of an account whose email address the
+// provider does not vouch for: it could be anyone's, so the account is only
+// linked from its user's session
+var errNotVerified = errors.New("email address not verified")
+
+// errLinkedElsewhere ends linking an account already linked to another user
+var errLinkedElsewhere = errors.New("linked to another user")
+
+// POST /auth/oidc/callback
+// Finishes a sign-in started at GET /auth/oidc/login with the code and state
+// the provider sent to OIDC_REDIRECT_URL, and answers like POST /auth/login.
+// Each sign-in can be finished once, within 10 minutes.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	ctx := db.AsService(c.Request.Context())
+	id, ok := h.finish(c, nil)
+	if !ok {
+		return
+	}
+	details := gin.H{"email": id.Email, "method": "oidc", "provider": id.Provider}
+
+	user, provisioned, err := h.user(ctx, id)
+	switch {
+	case errors.Is(err, errNoAccount):
+		details["reason"] = "unknown_user"
+		h.auth.authEvent(c, "login", "failure", user, details)
+		apierror.Respond(c, apierror.InvalidCredentials, "No account matches this email address")
+		return
+	case errors.Is(err, errNotVerified):
+		details["reason"] = "email_not_verified"
+		h.auth.authEvent(c, "login", "failure", user, details)
+		apierror.Respond(c, apierror.InvalidCredentials,
+			"The provider does not vouch for this email address; sign in with your password and link the account from your profile")
+		return
+	case err != nil:
+		apierror.Database(c, err, "Failed to load user")
+		return
+	case !user.IsActive:
+		details["reason"] = "account_deactivated"
+		h.auth.authEvent(c, "login", "failure", user, details)
+		apierror.Respond(c, apierror.AccountDeactivated, "Account is deactivated")
+		return
+	}
+	if provisioned {
+		details["provisioned"] = true
+	}
+	h.auth.signIn(ctx, c, user, details)
+}
+
+// POST /auth/oidc/link/callback
+// Finishes a sign-in started by the caller at POST /auth/oidc/link, with the
+// code and state the provider sent to OIDC_REDIRECT_URL, and links the
+// provider account to the caller's user.
+func (h *OIDCHandler) LinkCallback(c *gin.Context) {
+	ctx := db.AsService(c.Request.Context())
+	userID := c.GetString("user_id")
+	id, ok := h.finish(c, &userID)
+	if !ok {
+		return
+	}
+	caller := models.User{ID: userID, OrgID: c.GetString("org_id"), Role: c.GetString("role")}
+	details := gin.H{"email": id.Email, "method": "oidc", "provider": id.Provider}
+
+	err := db.WithTx(ctx, h.auth.pool, func(tx pgx.Tx) error {
+		return linkIdentity(ctx, tx, caller.OrgID, userID, id)
+	})
+	switch {
+	case errors.Is(err, errLinkedElsewhere):
+		details["reason"] = "linked_elsewhere"
+		h.auth.authEvent(c, "link_identity", "failure", caller, details)
+		apierror.Respond(c, apierror.AlreadyExists, "This account is linked to another user")
+		return
+	case err != nil:
+		apierror.Database(c, err, "Failed to link account")
+		return
+	}
+	h.auth.authEvent(c, "link_identity", "success", caller, details)
+	respond(c, http.StatusOK, gin.H{"provider": id.Provider, "email": id.Email})
+}
+
+// finish ends the sign-in with the state in the request, which userID must
+// have started at POST /auth/oidc/link, or nobody at GET /auth/oidc/login
+// when nil, and returns the identity the provider vouches for
+func (h *OIDCHandler) finish(c *gin.Context, userID *string) (oidc.Identity, bool) {
+	var input struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+	if !bindJSON(c, &input) {
+		return oidc.Identity{}, false
+	}
+	ctx := db.AsService(c.Request.Context())
+
+	var provider, nonce, verifier string
+	var startedAt time.Time
+	err := h.auth.pool.QueryRow(ctx, `
+		DELETE FROM oidc_logins WHERE state = $1 AND user_id IS NOT DISTINCT FROM $2
+		RETURNING provider, nonce, code_verifier, created_at`, input.State, userID).Scan(&provider, &nonce, &verifier, &startedAt)
+	if err == nil && time.Since(startedAt) > oidcLoginTTL {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		if apierror.IsNoRows(err) {
+			h.auth.authEvent(c, "login", "failure", models.User{}, gin.H{"method": "oidc", "reason": "invalid_state"})
+		}
+		apierror.Lookup(c, err, apierror.InvalidToken, "Sign-in expired or already finished; start again", "Failed to load sign-in")
+		return oidc.Identity{}, false
+	}
+
+	id, err := h.providers.Exchange(ctx, provider, input.Code, verifier, nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "single sign-on failed", "provider", provider, "error", err, "request_id", c.GetString("request_id"))
+		h.auth.authEvent(c, "login", "failure", models.User{}, gin.H{"method": "oidc", "provider": provider,
+			"reason": "provider_rejected"})
+		apierror.Respond(c, apierror.InvalidCredentials, "Sign-in with the provider failed")
+		return oidc.Identity{}, false
+	}
+	return id, true
+}
+
+// oidcUserColumns are read into a models.User; is_active is false in an
+// inactive organization, as at POST /auth/login
+const oidcUserColumns = `u.id, u.org_id, u.employee_id, u.email, u.password_hash, u.role,
+	u.is_active AND (SELECT o.is_active FROM organizations o WHERE o.id = u.org_id), u.last_login_at,
+	u.created_at, u.updated_at`
+
+func scanOIDCUser(row pgx.Row) (models.User, error) {
+	var u models.User
+	err := row.Scan(&u.ID, &u.OrgID, &u.EmployeeID, &u.Email, &u.PasswordHash, &u.Role, &u.IsActive, &u.LastLoginAt,
+		&u.CreatedAt, &u.UpdatedAt)
+	return u, err
+}
+
+// user finds the user id signs in as, linking the provider account to it on
+// its first sign-in when the provider vouches for its email address, and
+// reports whether the user was created for it. A created user has no
+// password: it signs in with the provider only.
+func (h *OIDCHandler) user(ctx context.Context, id oidc.Identity) (models.User, bool, error) {
+	var user models.User
+	var provisioned bool
+	err := db.WithTx(ctx, h.auth.pool, func(tx pgx.Tx) error {
+		provisioned = false
+		var err error
+		user, err = scanOIDCUser(tx.QueryRow(ctx, `
+			SELECT `+oidcUserColumns+`
+			FROM user_identities i
+			JOIN users u ON u.id = i.user_id
+			WHERE i.provider = $1 AND i.subject = $2`, id.Provider, id.Subject))
+		if err == nil {
+			_, err = tx.Exec(ctx, `
+				UPDATE user_identities SET email = $3, last_login_at = NOW()
+				WHERE provider = $1 AND subject = $2`, id.Provider, id.Subject, id.Email)
+			return err
+		}
+		if !apierror.IsNoRows(err) {
+			return err
+		}
+		if !id.EmailVerified {
+			return errNotVerified
+		}
+
+		// first sign-in with the account: emails are unique across
+		// organizations, so at most one user has it and one employee
+		user, err = scanOIDCUser(tx.QueryRow(ctx, `
+			SELECT `+oidcUserColumns+`
+			FROM users u
+			WHERE lower(u.email) = lower($1)
+			   OR (u.org_id, u.employee_id) IN (SELECT org_id, employee_id FROM employees WHERE lower(email) = lower($1))
+			ORDER BY lower(u.email) = lower($1) DESC
+			LIMIT 1`, id.Email))
+		if apierror.IsNoRows(err) {
+			if !h.provision() {
+				return errNoAccount
+			}
+			var userID string
+			err = tx.QueryRow(ctx, `
+				INSERT INTO users (org_id, employee_id, email, password_hash, role, is_active)
+				SELECT org_id, employee_id, email, '!', role::text, true
+				FROM employees
+				WHERE lower(email) = lower($1) AND is_active
+				RETURNING id`, id.Email).Scan(&userID)
+			if apierror.IsNoRows(err) {
+				return errNoAccount
+			}
+			if err != nil {
+				return err
+			}
+			provisioned = true
+			user, err = scanOIDCUser(tx.QueryRow(ctx, "SELECT "+oidcUserColumns+" FROM users u WHERE u.id = $1", userID))
+		}
+		if err != nil {
+			return err
+		}
+		return linkIdentity(ctx, tx, user.OrgID, user.ID, id)
+	})
+	return user, provisioned, err
+}
+
+// linkIdentity links the provider account of id to the user userID, unless it
+// is linked to another user
+func linkIdentity(ctx context.Context, tx pgx.Tx, orgID, userID string, id oidc.Identity) error {
+	var linked string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO user_identities (org_id, user_id, provider, subject, email) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, subject) DO UPDATE SET email = EXCLUDED.email
+		RETURNING user_id`,
+		orgID, userID, id.Provider, id.Subject, id.Email).Scan(&linked)
+	if err == nil && linked != userID {
+		return errLinkedElsewhere
+	}
+	return err
+}
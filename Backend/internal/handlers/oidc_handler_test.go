@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+	"leave-management/internal/dbtest"
+	"leave-management/internal/models"
+	"leave-management/internal/oidc"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// An account whose address the provider does not vouch for is not linked to
+// the user with that address, while a verified one is, and linking from the
+// user's session works whatever the address
+func TestOIDCUnverifiedEmailDoesNotLink(t *testing.T) {
+	pool := dbtest.Pool(t)
+	ctx := db.AsService(context.Background())
+	suffix := dbtest.Suffix()
+	email := "victim-" + suffix + "@example.com"
+	var userID string
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO users (org_id, employee_id, email, password_hash, role) VALUES ($1, $2, $3, 'x', $4) RETURNING id`,
+		db.DefaultOrgID, "sso"+suffix, email, models.RoleEmployee).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Exec(ctx, "DELETE FROM users WHERE id = $1", userID) })
+	linked := func(subject string) string {
+		t.Helper()
+		var user string
+		err := pool.QueryRow(ctx, "SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2",
+			oidc.Azure, subject).Scan(&user)
+		if err != nil && !apierror.IsNoRows(err) {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	h := NewOIDCHandler(NewAuthHandler(pool, nil, nil), oidc.New(oidc.Config{}), func() bool { return true })
+
+	attacker := oidc.Identity{Provider: oidc.Azure, Subject: "attacker-" + suffix, Email: email}
+	if _, _, err := h.user(ctx, attacker); !errors.Is(err, errNotVerified) {
+		t.Fatalf("unverified address: err = %v, want errNotVerified", err)
+	}
+	if got := linked(attacker.Subject); got != "" {
+		t.Fatalf("unverified address was linked to %s", got)
+	}
+
+	owner := oidc.Identity{Provider: oidc.Azure, Subject: "owner-" + suffix, Email: email, EmailVerified: true}
+	user, _, err := h.user(ctx, owner)
+	if err != nil || user.ID != userID {
+		t.Fatalf("verified address: user %q, err %v; want %s", user.ID, err, userID)
+	}
+	if got := linked(owner.Subject); got != userID {
+		t.Fatalf("verified address linked to %q, want %s", got, userID)
+	}
+
+	// from the session, an unverified account links, and not to a second user
+	session := oidc.Identity{Provider: oidc.Azure, Subject: "session-" + suffix, Email: "other-" + suffix + "@example.com"}
+	if err := db.WithTx(ctx, pool, func(tx pgx.Tx) error { return linkIdentity(ctx, tx, db.DefaultOrgID, userID, session) }); err != nil {
+		t.Fatal(err)
+	}
+	if got := linked(session.Subject); got != userID {
+		t.Fatalf("session link went to %q, want %s", got, userID)
+	}
+	other := "00000000-0000-4000-8000-0000" + suffix
+	if err := db.WithTx(ctx, pool, func(tx pgx.Tx) error { return linkIdentity(ctx, tx, db.DefaultOrgID, other, session) }); !errors.Is(err, errLinkedElsewhere) {
+		t.Fatalf("linking to a second user: err = %v, want errLinkedElsewhere", err)
+	}
+}
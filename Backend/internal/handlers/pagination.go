@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pagination holds the limit/offset (or page/page_size, or cursor) query params
+// shared by list endpoints
+type pagination struct {
+	Limit  int
+	Offset int
+	Cursor *pageCursor // set when the client pages with ?cursor= instead of ?offset=
+}
+
+// pageCursor is the decoded form of the opaque keyset cursor: the ordering
+// timestamp and id of the last row of the previous page
+type pageCursor struct {
+	At time.Time `json:"t"`
+	ID string    `json:"id"`
+}
+
+func encodeCursor(at time.Time, id string) string {
+	b, _ := json.Marshal(pageCursor{At: at, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// parsePagination reads ?limit= (default 50, capped at 200) and ?offset= (default 0),
+// or their page-numbered form ?page_size= and ?page= (from 1)
+func parsePagination(c *gin.Context) (pagination, error) {
+	if c.Query("cursor") != "" {
+		return pagination{}, errors.New("cursor pagination is not supported on this endpoint")
+	}
+	return parseKeysetPagination(c)
+}
+
+// parseKeysetPagination is parsePagination that also accepts an opaque ?cursor=
+// (meta.next_cursor of a previous page) in place of ?offset=
+func parseKeysetPagination(c *gin.Context) (pagination, error) {
+	p := pagination{Limit: defaultPageLimit}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, errors.New("limit must be a positive integer")
+		}
+		if n > maxPageLimit {
+			n = maxPageLimit
+		}
+		p.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, errors.New("offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+	if v := c.Query("page_size"); v != "" {
+		if c.Query("limit") != "" {
+			return p, errors.New("page_size and limit cannot be combined")
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, errors.New("page_size must be a positive integer")
+		}
+		if n > maxPageLimit {
+			n = maxPageLimit
+		}
+		p.Limit = n
+	}
+	if v := c.Query("page"); v != "" {
+		if c.Query("offset") != "" {
+			return p, errors.New("page and offset cannot be combined")
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > math.MaxInt32/p.Limit {
+			return p, errors.New("page must be a positive integer")
+		}
+		p.Offset = (n - 1) * p.Limit
+	}
+	if v := c.Query("cursor"); v != "" {
+		if p.Offset != 0 || c.Query("page") != "" {
+			return p, errors.New("cursor cannot be combined with offset or page")
+		}
+		cur, err := decodeCursor(v)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		p.Cursor = cur
+	}
+	return p, nil
+}
+
+// keyset returns the WHERE fragment selecting rows after the cursor for results
+// ordered by (tsCol, idCol) DESC; empty when paging by offset
+func (p pagination) keyset(tsCol, idCol string, args []interface{}) (string, []interface{}) {
+	if p.Cursor == nil {
+		return "", args
+	}
+	n := len(args)
+	return fmt.Sprintf(" AND (%s, %s) < ($%d, $%d)", tsCol, idCol, n+1, n+2), append(args, p.Cursor.At, p.Cursor.ID)
+}
+
+// clause returns the LIMIT/OFFSET SQL using the next placeholders and appends their args.
+// In cursor mode one extra row is fetched to detect whether another page exists.
+func (p pagination) clause(args []interface{}) (string, []interface{}) {
+	n := len(args)
+	if p.Cursor != nil {
+		return fmt.Sprintf(" LIMIT $%d", n+1), append(args, p.Limit+1)
+	}
+	return fmt.Sprintf(" LIMIT $%d OFFSET $%d", n+1, n+2), append(args, p.Limit, p.Offset)
+}
+
+// meta builds the page info block returned next to list data
+func (p pagination) meta(total, count int) gin.H {
+	pages := (total + p.Limit - 1) / p.Limit
+	return gin.H{
+		"total":       total,
+		"count":       count,
+		"limit":       p.Limit,
+		"page_size":   p.Limit,
+		"offset":      p.Offset,
+		"page":        p.Offset/p.Limit + 1,
+		"total_pages": pages,
+		"has_more":    p.Offset+count < total,
+	}
+}
+
+// keysetMeta builds the meta block for keyset-capable endpoints and returns how many
+// of the n fetched rows to keep. key returns the ordering timestamp and id of row i.
+// total is ignored (and omitted) in cursor mode, where counting is skipped.
+func (p pagination) keysetMeta(total, n int, key func(i int) (time.Time, string)) (int, gin.H) {
+	var meta gin.H
+	hasMore := false
+	if p.Cursor != nil {
+		hasMore = n > p.Limit
+		if hasMore {
+			n = p.Limit
+		}
+		meta = gin.H{"count": n, "limit": p.Limit, "page_size": p.Limit, "has_more": hasMore}
+	} else {
+		meta = p.meta(total, n)
+		hasMore = p.Offset+n < total
+	}
+	if hasMore && n > 0 {
+		at, id := key(n - 1)
+		meta["next_cursor"] = encodeCursor(at, id)
+	}
+	return n, meta
+}
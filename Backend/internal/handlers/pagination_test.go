@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func queryContext(query string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/list?"+query, nil)
+	return c
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query         string
+		limit, offset int
+		wantErr       bool
+	}{
+		{query: "", limit: 50, offset: 0},
+		{query: "limit=10&offset=20", limit: 10, offset: 20},
+		{query: "limit=1000", limit: 200, offset: 0},
+		{query: "page=2&page_size=50", limit: 50, offset: 50},
+		{query: "page=3", limit: 50, offset: 100},
+		{query: "page_size=500&page=2", limit: 200, offset: 200},
+		{query: "page=1&page_size=1", limit: 1, offset: 0},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=abc", wantErr: true},
+		{query: "offset=-1", wantErr: true},
+		{query: "page=0", wantErr: true},
+		{query: "page=-2", wantErr: true},
+		{query: "page=99999999999", wantErr: true},
+		{query: "page_size=0", wantErr: true},
+		{query: "page=2&offset=10", wantErr: true},
+		{query: "page_size=10&limit=10", wantErr: true},
+		{query: "cursor=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := parsePagination(queryContext(tt.query))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePagination(%q) = %+v, want an error", tt.query, p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePagination(%q) error: %v", tt.query, err)
+			continue
+		}
+		if p.Limit != tt.limit || p.Offset != tt.offset {
+			t.Errorf("parsePagination(%q) = limit %d offset %d, want limit %d offset %d",
+				tt.query, p.Limit, p.Offset, tt.limit, tt.offset)
+		}
+	}
+}
+
+func TestPaginationMeta(t *testing.T) {
+	meta := pagination{Limit: 50, Offset: 50}.meta(134, 50)
+	want := gin.H{"total": 134, "count": 50, "limit": 50, "page_size": 50, "offset": 50, "page": 2, "total_pages": 3, "has_more": true}
+	for k, v := range want {
+		if meta[k] != v {
+			t.Errorf("meta[%q] = %v, want %v", k, meta[k], v)
+		}
+	}
+	if last := (pagination{Limit: 50, Offset: 100}).meta(134, 34); last["has_more"] != false || last["page"] != 3 {
+		t.Errorf("last page meta = %v, want page 3 without more", last)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	const def = "created_at DESC, id ASC"
+	tests := []struct {
+		query   string
+		orderBy string
+		custom  bool
+		wantErr bool
+	}{
+		{query: "", orderBy: def},
+		{query: "sort_by=name", orderBy: "name ASC, id ASC", custom: true},
+		{query: "sort_by=joining_date&order=desc", orderBy: "joining_date DESC, id ASC", custom: true},
+		{query: "sort_by=name&order=ASC", orderBy: "name ASC, id ASC", custom: true},
+		{query: "sort=role:asc,name:desc", orderBy: "role ASC, name DESC, id ASC", custom: true},
+		{query: "sort_by=password", wantErr: true},
+		{query: "sort_by=name%3BDROP%20TABLE%20employees", wantErr: true},
+		{query: "sort_by=name&order=sideways", wantErr: true},
+		{query: "sort_by=name,email", wantErr: true},
+		{query: "sort=name&sort_by=email", wantErr: true},
+		{query: "order=desc", wantErr: true},
+	}
+	for _, tt := range tests {
+		orderBy, custom, err := parseSort(queryContext(tt.query), employeeSorts, def, "id")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSort(%q) = %q, want an error", tt.query, orderBy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSort(%q) error: %v", tt.query, err)
+			continue
+		}
+		if orderBy != tt.orderBy || custom != tt.custom {
+			t.Errorf("parseSort(%q) = %q, %v; want %q, %v", tt.query, orderBy, custom, tt.orderBy, tt.custom)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PolicyHandler publishes leave policy documents and records who acknowledged
+// which version. A policy is identified by its policy_key; publishing it
+// again adds a version, and every employee is expected to acknowledge the
+// latest one.
+type PolicyHandler struct {
+	pool *pgxpool.Pool
+	read *pgxpool.Pool // replica for the acknowledgment lists; same as pool without one
+}
+
+func NewPolicyHandler(pool, read *pgxpool.Pool) *PolicyHandler {
+	return &PolicyHandler{pool: pool, read: read}
+}
+
+type policyDocument struct {
+	ID          string    `json:"id"`
+	PolicyKey   string    `json:"policy_key"`
+	Version     int       `json:"version"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body,omitempty"`
+	PublishedBy string    `json:"published_by"`
+	PublishedAt time.Time `json:"published_at"`
+	// set on lists, for the caller
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+const policyColumns = "id, policy_key, version, title, body, published_by, published_at"
+
+func scanPolicy(row pgx.Row) (policyDocument, error) {
+	var p policyDocument
+	err := row.Scan(&p.ID, &p.PolicyKey, &p.Version, &p.Title, &p.Body, &p.PublishedBy, &p.PublishedAt)
+	return p, err
+}
+
+// POST /policies
+// Publishes the next version of policy_key (version 1 for a new policy).
+func (h *PolicyHandler) PublishPolicy(c *gin.Context) {
+	var in struct {
+		PolicyKey string `json:"policy_key" binding:"required,max=100"`
+		Title     string `json:"title" binding:"required,max=255"`
+		Body      string `json:"body" binding:"required"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	p, err := scanPolicy(h.pool.QueryRow(c.Request.Context(), `
+		INSERT INTO policy_documents (policy_key, version, title, body, published_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4::uuid
+		FROM policy_documents WHERE policy_key = $1
+		RETURNING `+policyColumns, in.PolicyKey, in.Title, in.Body, c.GetString("user_id")))
+	if err != nil {
+		apierror.Database(c, err, "failed to publish policy")
+		return
+	}
+	respond(c, http.StatusCreated, p)
+}
+
+// GET /policies
+// The latest version of every policy, without the body, with the caller's
+// acknowledged_at when they acknowledged that version.
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	rows, err := h.pool.Query(c.Request.Context(), `
+		SELECT DISTINCT ON (d.policy_key) d.id, d.policy_key, d.version, d.title, d.published_by, d.published_at, a.acknowledged_at
+		FROM policy_documents d
+		LEFT JOIN policy_acknowledgments a ON a.document_id = d.id AND a.employee_id = NULLIF($1, '')::uuid
+		ORDER BY d.policy_key, d.version DESC`, c.GetString("employee_uuid"))
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch policies")
+		return
+	}
+	defer rows.Close()
+	list := make([]policyDocument, 0)
+	for rows.Next() {
+		var p policyDocument
+		if err := rows.Scan(&p.ID, &p.PolicyKey, &p.Version, &p.Title, &p.PublishedBy, &p.PublishedAt, &p.AcknowledgedAt); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, p)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch policies")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// GET /policies/:id
+func (h *PolicyHandler) GetPolicy(c *gin.Context) {
+	p, err := scanPolicy(h.pool.QueryRow(c.Request.Context(),
+		"SELECT "+policyColumns+" FROM policy_documents WHERE id = $1", c.Param("id")))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "policy not found", "failed to load policy")
+		return
+	}
+	respond(c, http.StatusOK, p)
+}
+
+type policyAcknowledgment struct {
+	DocumentID     string    `json:"document_id"`
+	EmployeeID     string    `json:"employee_id"`
+	AcknowledgedBy string    `json:"acknowledged_by"`
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+}
+
+// POST /policies/:id/acknowledge
+// Records that the caller read and accepted this version. Acknowledging again
+// keeps the first acknowledgment and answers it.
+func (h *PolicyHandler) AcknowledgePolicy(c *gin.Context) {
+	employeeID := c.GetString("employee_uuid")
+	if employeeID == "" {
+		apierror.Respond(c, apierror.Forbidden, "only users with an employee record can acknowledge policies")
+		return
+	}
+	ctx := c.Request.Context()
+	var exists bool
+	if isUUID(c.Param("id")) {
+		if err := h.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM policy_documents WHERE id = $1)", c.Param("id")).Scan(&exists); err != nil {
+			apierror.Database(c, err, "failed to acknowledge policy")
+			return
+		}
+	}
+	if !exists {
+		apierror.Respond(c, apierror.NotFound, "policy not found")
+		return
+	}
+
+	var a policyAcknowledgment
+	status := http.StatusCreated
+	err := h.pool.QueryRow(ctx, `
+		INSERT INTO policy_acknowledgments (document_id, employee_id, acknowledged_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (document_id, employee_id) DO NOTHING
+		RETURNING document_id, employee_id, acknowledged_by, acknowledged_at`,
+		c.Param("id"), employeeID, c.GetString("user_id")).Scan(&a.DocumentID, &a.EmployeeID, &a.AcknowledgedBy, &a.AcknowledgedAt)
+	if apierror.IsNoRows(err) {
+		status = http.StatusOK
+		err = h.pool.QueryRow(ctx, `
+			SELECT document_id, employee_id, acknowledged_by, acknowledged_at FROM policy_acknowledgments
+			WHERE document_id = $1 AND employee_id = $2`,
+			c.Param("id"), employeeID).Scan(&a.DocumentID, &a.EmployeeID, &a.AcknowledgedBy, &a.AcknowledgedAt)
+	}
+	if err != nil {
+		apierror.Database(c, err, "failed to acknowledge policy")
+		return
+	}
+	respond(c, status, a)
+}
+
+// GET /policies/:id/acknowledgments (paging: limit, offset; sort: acknowledged_at, employee_name)
+// Who acknowledged this version, and when, by default most recent first.
+func (h *PolicyHandler) ListAcknowledgments(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, acknowledgmentSorts, "a.acknowledged_at DESC, a.id", "a.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	var total int
+	if err := h.read.QueryRow(ctx, "SELECT COUNT(*) FROM policy_acknowledgments WHERE document_id = $1", id).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch acknowledgments")
+		return
+	}
+	rows, err := h.read.Query(ctx, `
+		SELECT a.employee_id, e.employee_id, e.name, a.acknowledged_by, a.acknowledged_at
+		FROM policy_acknowledgments a
+		JOIN employees e ON e.id = a.employee_id
+		WHERE a.document_id = $1
+		ORDER BY `+orderBy+`
+		LIMIT $2 OFFSET $3`, id, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch acknowledgments")
+		return
+	}
+	defer rows.Close()
+	list := make([]gin.H, 0)
+	for rows.Next() {
+		var (
+			employeeID, code, name, by string
+			at                         time.Time
+		)
+		if err := rows.Scan(&employeeID, &code, &name, &by, &at); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, gin.H{
+			"employee_id":     employeeID,
+			"employee_code":   code,
+			"employee_name":   name,
+			"acknowledged_by": by,
+			"acknowledged_at": at,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch acknowledgments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// GET /reports/policy-acknowledgments?policy_key=&department_id= (paging: limit, offset)
+// Active employees who have not acknowledged the latest version of a policy,
+// one row per employee and policy (all policies unless policy_key is given).
+func (h *PolicyHandler) GetMissingAcknowledgments(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	department := c.Query("department_id")
+	if department != "" && !isUUID(department) {
+		apierror.Respond(c, apierror.InvalidQuery, "department_id must be a UUID")
+		return
+	}
+
+	const missing = `
+		FROM (SELECT DISTINCT ON (policy_key) id, policy_key, version, title
+		      FROM policy_documents
+		      WHERE $1 = '' OR policy_key = $1
+		      ORDER BY policy_key, version DESC) d
+		CROSS JOIN employees e
+		WHERE e.is_active AND (NULLIF($2, '')::uuid IS NULL OR e.department_id = NULLIF($2, '')::uuid)
+		  AND NOT EXISTS (SELECT 1 FROM policy_acknowledgments a WHERE a.document_id = d.id AND a.employee_id = e.id)`
+	ctx := c.Request.Context()
+	policyKey := c.Query("policy_key")
+	var total int
+	if err := h.read.QueryRow(ctx, "SELECT COUNT(*)"+missing, policyKey, department).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch missing acknowledgments")
+		return
+	}
+	rows, err := h.read.Query(ctx, `
+		SELECT d.id, d.policy_key, d.version, d.title, e.id, e.employee_id, e.name, e.email, e.department_id`+missing+`
+		ORDER BY d.policy_key, e.name, e.id
+		LIMIT $3 OFFSET $4`, policyKey, department, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch missing acknowledgments")
+		return
+	}
+	defer rows.Close()
+	list := make([]gin.H, 0)
+	for rows.Next() {
+		var (
+			documentID, key, title                    string
+			version                                   int
+			employeeID, code, name, email, department string
+		)
+		if err := rows.Scan(&documentID, &key, &version, &title, &employeeID, &code, &name, &email, &department); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, gin.H{
+			"document_id":   documentID,
+			"policy_key":    key,
+			"version":       version,
+			"title":         title,
+			"employee_id":   employeeID,
+			"employee_code": code,
+			"employee_name": name,
+			"email":         email,
+			"department_id": department,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch missing acknowledgments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
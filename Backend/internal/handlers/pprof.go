@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPprof serves the net/http/pprof endpoints on g, which must be
+// mounted at /debug/pprof: pprof.Index derives the profile name from the path.
+// Access control is left to g's middleware.
+func RegisterPprof(g *gin.RouterGroup) {
+	g.GET("/", pprofHandler(pprof.Index))
+	g.GET("/cmdline", pprofHandler(pprof.Cmdline))
+	g.GET("/profile", pprofHandler(pprof.Profile))
+	g.GET("/symbol", pprofHandler(pprof.Symbol))
+	g.POST("/symbol", pprofHandler(pprof.Symbol))
+	g.GET("/trace", pprofHandler(pprof.Trace))
+	g.GET("/:name", pprofHandler(pprof.Index)) // heap, goroutine, allocs, block, mutex, threadcreate
+}
+
+// pprofHandler adapts a pprof handler. CPU profiles and traces record for
+// ?seconds= (30 by default), longer than REQUEST_TIMEOUT allows, so the
+// handler runs without the request deadline.
+func pprofHandler(h http.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithoutCancel(c.Request.Context())
+		h(c.Writer, c.Request.WithContext(ctx))
+	}
+}
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/jobs"
+	"leave-management/internal/reports"
+	"leave-management/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReportHandler struct {
+	pool               *pgxpool.Pool
+	read               *pgxpool.Pool // replica for the report queries; same as pool without one
+	readRepos          repository.Repos
+	anomalySensitivity func() string // default for the manual scan
+}
+
+func NewReportHandler(pool, read *pgxpool.Pool, anomalySensitivity func() string) *ReportHandler {
+	return &ReportHandler{pool: pool, read: read, readRepos: repository.New(read).Repos(), anomalySensitivity: anomalySensitivity}
+}
+
+// yoySeries accumulates approved leave days per year for one leave type or department
+type yoySeries struct {
+	ID   string
+	Name string
+	Days map[int]float64
+}
+
+// GET /reports/yoy?years=2024,2025
+// Compares approved leave consumption by leave type and department across years.
+func (h *ReportHandler) GetYearOverYear(c *gin.Context) {
+	years, err := parseYears(c.Query("years"))
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+
+	rows, err := h.read.Query(c.Request.Context(), `
+		SELECT EXTRACT(YEAR FROM lr.start_date)::INT AS year,
+			lt.id, lt.name, d.id, d.name,
+			SUM(lr.total_days)::FLOAT8
+		FROM leave_requests lr
+		JOIN leave_types lt ON lr.leave_type_id = lt.id
+		JOIN employees e ON lr.employee_id = e.id
+		JOIN departments d ON e.department_id = d.id
+		WHERE lr.status = 'approved'
+		  AND EXTRACT(YEAR FROM lr.start_date)::INT = ANY($1)
+		GROUP BY 1, lt.id, lt.name, d.id, d.name
+	`, years)
+	if err != nil {
+		apierror.Database(c, err, "failed to compute year-over-year report")
+		return
+	}
+	defer rows.Close()
+
+	byType := map[string]*yoySeries{}
+	byDept := map[string]*yoySeries{}
+	totals := map[int]float64{}
+	for rows.Next() {
+		var (
+			year             int
+			typeID, typeName string
+			deptID, deptName string
+			days             float64
+		)
+		if err := rows.Scan(&year, &typeID, &typeName, &deptID, &deptName, &days); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		addToSeries(byType, typeID, typeName, year, days)
+		addToSeries(byDept, deptID, deptName, year, days)
+		totals[year] += days
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"years":         years,
+		"totals":        gin.H{"days": yearKeyed(years, totals), "changes": yoyChanges(years, totals)},
+		"by_leave_type": yoyRows(years, byType, "leave_type_id"),
+		"by_department": yoyRows(years, byDept, "department_id"),
+	})
+}
+
+// parseYears parses a comma separated list of 2-5 distinct years, sorted ascending
+func parseYears(raw string) ([]int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, errors.New("years is required, e.g. years=2024,2025")
+	}
+	seen := map[int]bool{}
+	years := []int{}
+	for _, part := range strings.Split(raw, ",") {
+		y, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || y < 2020 || y > 2050 {
+			return nil, errors.New("years must be comma separated values between 2020 and 2050")
+		}
+		if !seen[y] {
+			seen[y] = true
+			years = append(years, y)
+		}
+	}
+	if len(years) < 2 || len(years) > 5 {
+		return nil, errors.New("provide between 2 and 5 distinct years")
+	}
+	sort.Ints(years)
+	return years, nil
+}
+
+func addToSeries(m map[string]*yoySeries, id, name string, year int, days float64) {
+	s, ok := m[id]
+	if !ok {
+		s = &yoySeries{ID: id, Name: name, Days: map[int]float64{}}
+		m[id] = s
+	}
+	s.Days[year] += days
+}
+
+// yoyRows flattens series into response rows ordered by name
+func yoyRows(years []int, m map[string]*yoySeries, idKey string) []gin.H {
+	list := make([]*yoySeries, 0, len(m))
+	for _, s := range m {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	res := make([]gin.H, 0, len(list))
+	for _, s := range list {
+		res = append(res, gin.H{
+			idKey:     s.ID,
+			"name":    s.Name,
+			"days":    yearKeyed(years, s.Days),
+			"changes": yoyChanges(years, s.Days),
+		})
+	}
+	return res
+}
+
+// yearKeyed returns days for every requested year, including zero years
+func yearKeyed(years []int, days map[int]float64) map[string]float64 {
+	out := make(map[string]float64, len(years))
+	for _, y := range years {
+		out[strconv.Itoa(y)] = days[y]
+	}
+	return out
+}
+
+// yoyChanges computes the delta and percentage change between consecutive years.
+// percent is null when the base year had no consumption.
+func yoyChanges(years []int, days map[int]float64) []gin.H {
+	changes := make([]gin.H, 0, len(years)-1)
+	for i := 1; i < len(years); i++ {
+		from, to := days[years[i-1]], days[years[i]]
+		var percent *float64
+		if from != 0 {
+			p := math.Round((to-from)/from*10000) / 100
+			percent = &p
+		}
+		changes = append(changes, gin.H{
+			"from_year": years[i-1],
+			"to_year":   years[i],
+			"delta":     to - from,
+			"percent":   percent,
+		})
+	}
+	return changes
+}
+
+// GET /reports/leave-types/:id/consumption?year=&threshold=
+// Shows per-employee usage of one leave type, the average usage and the employees
+// who have used at least threshold percent (default 80) of their entitlement.
+func (h *ReportHandler) GetLeaveTypeConsumption(c *gin.Context) {
+	leaveTypeID := c.Param("id")
+
+	year := time.Now().Year()
+	if v := c.Query("year"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2020 || n > 2050 {
+			apierror.Respond(c, apierror.InvalidInput, "year must be between 2020 and 2050")
+			return
+		}
+		year = n
+	}
+	threshold := 80.0
+	if v := c.Query("threshold"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n <= 0 || n > 100 {
+			apierror.Respond(c, apierror.InvalidQuery, "threshold must be a percentage between 0 and 100")
+			return
+		}
+		threshold = n
+	}
+
+	leaveTypeName, err := h.readRepos.LeaveTypes.GetLeaveTypeName(c.Request.Context(), leaveTypeID)
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "leave type not found", "failed to load leave type")
+		return
+	}
+
+	rows, err := h.read.Query(c.Request.Context(), `
+		SELECT e.id, e.employee_id, e.name, e.department_id,
+			elb.allocated_days::FLOAT8, elb.carried_forward_days::FLOAT8,
+			elb.used_days::FLOAT8, elb.available_days::FLOAT8
+		FROM employee_leave_balances elb
+		JOIN employees e ON elb.employee_id = e.id
+		WHERE elb.leave_type_id = $1 AND elb.year = $2 AND e.is_active = true
+		ORDER BY elb.used_days DESC, e.name
+	`, leaveTypeID, year)
+	if err != nil {
+		apierror.Database(c, err, "failed to compute consumption report")
+		return
+	}
+	defer rows.Close()
+
+	employees := make([]gin.H, 0)
+	nearExhaustion := make([]gin.H, 0)
+	var totalUsed, totalEntitled float64
+	for rows.Next() {
+		var (
+			id, empID, name, deptID         string
+			allocated, carried, used, avail float64
+		)
+		if err := rows.Scan(&id, &empID, &name, &deptID, &allocated, &carried, &used, &avail); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		entitled := allocated + carried
+		utilization := 0.0
+		if entitled > 0 {
+			utilization = math.Round(used/entitled*10000) / 100
+		}
+		item := gin.H{
+			"id":                   id,
+			"employee_id":          empID,
+			"name":                 name,
+			"department_id":        deptID,
+			"allocated_days":       allocated,
+			"carried_forward_days": carried,
+			"used_days":            used,
+			"available_days":       avail,
+			"utilization_percent":  utilization,
+		}
+		employees = append(employees, item)
+		if entitled > 0 && utilization >= threshold {
+			nearExhaustion = append(nearExhaustion, item)
+		}
+		totalUsed += used
+		totalEntitled += entitled
+	}
+
+	averageUsed := 0.0
+	averageUtilization := 0.0
+	if len(employees) > 0 {
+		averageUsed = math.Round(totalUsed/float64(len(employees))*100) / 100
+	}
+	if totalEntitled > 0 {
+		averageUtilization = math.Round(totalUsed/totalEntitled*10000) / 100
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"leave_type_id":   leaveTypeID,
+		"leave_type_name": leaveTypeName,
+		"year":            year,
+		"summary": gin.H{
+			"employees":                   len(employees),
+			"total_used_days":             totalUsed,
+			"average_used_days":           averageUsed,
+			"average_utilization_percent": averageUtilization,
+			"threshold_percent":           threshold,
+			"near_exhaustion_count":       len(nearExhaustion),
+		},
+		"employees":       employees,
+		"near_exhaustion": nearExhaustion,
+	})
+}
+
+// GET /reports/absence-anomalies?pattern= (paging: limit, offset; sort: ratio,
+// occurrences, detected_at, employee_name)
+// Confidential HR report of the latest absence anomaly scan.
+func (h *ReportHandler) GetAbsenceAnomalies(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, anomalySorts, "a.ratio DESC, a.occurrences DESC, a.id", "a.id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	from := `
+		FROM absence_anomalies a
+		JOIN employees e ON a.employee_id = e.id`
+	args := []interface{}{}
+	if v := c.Query("pattern"); v != "" {
+		from += " WHERE a.pattern = $1"
+		args = append(args, v)
+	}
+
+	ctx := c.Request.Context()
+	var total int
+	if err := h.read.QueryRow(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch absence anomalies")
+		return
+	}
+	limit, args := page.clause(args)
+	rows, err := h.read.Query(ctx, `SELECT a.id, a.employee_id, e.employee_id, e.name, e.department_id, a.pattern,
+			a.occurrences, a.total_requests, a.ratio, a.window_start, a.window_end, a.detected_at`+from+
+		" ORDER BY "+orderBy+limit, args...)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch absence anomalies")
+		return
+	}
+	defer rows.Close()
+
+	result := make([]gin.H, 0)
+	for rows.Next() {
+		var (
+			id, employeeID, empCode, name, deptID, pattern string
+			occurrences, total                             int
+			ratio                                          float64
+			windowStart, windowEnd, detectedAt             time.Time
+		)
+		if err := rows.Scan(&id, &employeeID, &empCode, &name, &deptID, &pattern, &occurrences, &total, &ratio, &windowStart, &windowEnd, &detectedAt); err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		result = append(result, gin.H{
+			"id":             id,
+			"employee_id":    employeeID,
+			"employee_code":  empCode,
+			"employee_name":  name,
+			"department_id":  deptID,
+			"pattern":        pattern,
+			"occurrences":    occurrences,
+			"total_requests": total,
+			"ratio":          math.Round(ratio*100) / 100,
+			"window_start":   windowStart.Format("2006-01-02"),
+			"window_end":     windowEnd.Format("2006-01-02"),
+			"detected_at":    detectedAt,
+		})
+	}
+
+	// confidential: never let intermediaries cache this
+	c.Header("Cache-Control", "no-store")
+	respond(c, http.StatusOK, gin.H{"confidential": true, "anomalies": result, "meta": page.meta(total, len(result))})
+}
+
+// POST /reports/absence-anomalies/run?sensitivity=low|medium|high
+// Runs the anomaly scan immediately instead of waiting for the scheduled job.
+func (h *ReportHandler) RunAbsenceAnomalyScan(c *gin.Context) {
+	level := c.DefaultQuery("sensitivity", h.anomalySensitivity())
+	s, ok := jobs.SensitivityFor(level)
+	if !ok {
+		apierror.Respond(c, apierror.InvalidQuery, "sensitivity must be low, medium or high")
+		return
+	}
+	flagged, err := jobs.DetectAbsenceAnomalies(c.Request.Context(), h.pool, s)
+	if err != nil {
+		apierror.Database(c, err, "anomaly scan failed")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "anomaly scan completed", "sensitivity": level, "flagged": flagged})
+}
+
+// reportFilter reads the department_id and leave_type_id filters of a report;
+// leave_type_id only where leaveType
+func reportFilter(c *gin.Context, leaveType bool) (reports.Filter, error) {
+	f := reports.Filter{DepartmentID: c.Query("department_id")}
+	if f.DepartmentID != "" && !isUUID(f.DepartmentID) {
+		return f, errors.New("department_id must be a UUID")
+	}
+	if leaveType {
+		f.LeaveTypeID = c.Query("leave_type_id")
+		if f.LeaveTypeID != "" && !isUUID(f.LeaveTypeID) {
+			return f, errors.New("leave_type_id must be a UUID")
+		}
+	}
+	return f, nil
+}
+
+// GET /reports/leave-utilization?year= (default the current year; optional
+// department_id, leave_type_id)
+// Entitled days (allocated plus carried forward) against approved leave taken,
+// in total, per department, per leave type and per month.
+func (h *ReportHandler) GetLeaveUtilization(c *gin.Context) {
+	year := time.Now().Year()
+	if v := c.Query("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2020 || y > 2050 {
+			apierror.Respond(c, apierror.InvalidQuery, "year must be between 2020 and 2050")
+			return
+		}
+		year = y
+	}
+	f, err := reportFilter(c, true)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	res, err := reports.LeaveUtilization(c.Request.Context(), h.read, year, f)
+	if err != nil {
+		apierror.Database(c, err, "failed to compute leave utilization")
+		return
+	}
+	respond(c, http.StatusOK, res)
+}
+
+// GET /reports/absence-trends?from=YYYY-MM&to=YYYY-MM (default the 12 months
+// up to to, by default the current month; optional department_id)
+// Monthly absence rate against the working days of the headcount, and the
+// absence per day of the week over the range.
+func (h *ReportHandler) GetAbsenceTrends(c *gin.Context) {
+	month := func(name string, def time.Time) (time.Time, bool) {
+		v := c.Query(name)
+		if v == "" {
+			return def, true
+		}
+		t, err := time.Parse("2006-01", v)
+		if err != nil || t.Year() < 2020 || t.Year() > 2050 {
+			apierror.Respond(c, apierror.InvalidQuery, "from and to must be YYYY-MM")
+			return t, false
+		}
+		return t, true
+	}
+	now := time.Now()
+	to, ok := month("to", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	if !ok {
+		return
+	}
+	from, ok := month("from", to.AddDate(0, -11, 0))
+	if !ok {
+		return
+	}
+	if from.After(to) {
+		apierror.Respond(c, apierror.InvalidQuery, "from cannot be after to")
+		return
+	}
+	if to.After(from.AddDate(0, 35, 0)) {
+		apierror.Respond(c, apierror.InvalidQuery, "the range cannot exceed 36 months")
+		return
+	}
+	f, err := reportFilter(c, false)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	res, err := reports.Absence(c.Request.Context(), h.read, from, to, f)
+	if err != nil {
+		apierror.Database(c, err, "failed to compute absence trends")
+		return
+	}
+	respond(c, http.StatusOK, res)
+}
+
+// GET /reports/pending-approvals-aging (optional department_id; limit, default
+// 10, of the oldest requests listed)
+// How long the pending requests have waited since they were applied for:
+// overall, in age buckets and per approval stage, with the oldest requests.
+func (h *ReportHandler) GetPendingApprovalsAging(c *gin.Context) {
+	limit := 10
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100 {
+			apierror.Respond(c, apierror.InvalidQuery, "limit must be between 0 and 100")
+			return
+		}
+		limit = n
+	}
+	f, err := reportFilter(c, false)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	res, err := reports.PendingApprovalsAging(c.Request.Context(), h.read, f, limit)
+	if err != nil {
+		apierror.Database(c, err, "failed to compute pending approvals aging")
+		return
+	}
+	respond(c, http.StatusOK, res)
+}
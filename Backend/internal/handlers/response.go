@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respond writes a successful JSON response. When the response envelope is on
+// (see middleware.ResponseEnvelope) the payload is returned as {"data": payload},
+// otherwise in its legacy bare form. Paginated lists always use {data, meta}.
+func respond(c *gin.Context, status int, payload interface{}) {
+	if c.GetBool("response_envelope") {
+		payload = gin.H{"data": payload}
+	}
+	c.JSON(status, payload)
+}
+
+// respondExists answers an existence check: HEAD gets a bare 200 or 404, GET
+// gets {"exists": bool}
+func respondExists(c *gin.Context, exists bool) {
+	if c.Request.Method == http.MethodHead {
+		if exists {
+			c.Status(http.StatusOK)
+		} else {
+			c.Status(http.StatusNotFound)
+		}
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"exists": exists})
+}
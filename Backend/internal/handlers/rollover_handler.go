@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RolloverHandler runs the year-end rollover of the leave balances by hand
+type RolloverHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewRolloverHandler(pool *pgxpool.Pool) *RolloverHandler {
+	return &RolloverHandler{pool: pool}
+}
+
+// POST /admin/leave-balances/rollover (year, default last year; dry_run=true
+// lists what would be carried forward without changing anything)
+// Creates the balances of year + 1 for the caller's organization and sets
+// their carried_forward_days, as the scheduled job does on January 1st. It may
+// run again, for instance after late changes to year.
+func (h *RolloverHandler) RunRollover(c *gin.Context) {
+	dryRun, err := parseBoolQuery(c, "dry_run")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	current := time.Now().Year()
+	year := current - 1
+	if v := c.Query("year"); v != "" {
+		if year, err = strconv.Atoi(v); err != nil || year < 2020 || year > current || year > 2049 {
+			apierror.Respond(c, apierror.InvalidQuery, "year must be between 2020 and the current year")
+			return
+		}
+	}
+	res, err := jobs.RolloverLeaveYear(c.Request.Context(), h.pool, year, false, dryRun, c.GetString("user_id"))
+	if err != nil {
+		apierror.Database(c, err, "leave year rollover failed")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"dry_run": dryRun, "rollover": res})
+}
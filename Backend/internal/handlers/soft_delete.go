@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Employees and leave types are soft-deleted: DELETE sets is_active = false
+// and keeps the row for history. List endpoints hide inactive rows unless the
+// caller asks for them with ?include_inactive=true. The list queries take the
+// flag as a parameter, so the filter cannot be forgotten in one of them.
+
+// parseIncludeInactive reads ?include_inactive=true|false (default false)
+func parseIncludeInactive(c *gin.Context) (bool, error) {
+	return parseBoolQuery(c, "include_inactive")
+}
+
+// parseBoolQuery reads an optional ?name=true|false flag (default false)
+func parseBoolQuery(c *gin.Context, name string) (bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New(name + " must be true or false")
+	}
+	return b, nil
+}
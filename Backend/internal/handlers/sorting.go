@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sortable fields per list endpoint, mapped to their SQL columns
+var (
+	employeeSorts = map[string]string{
+		"name":         "name",
+		"email":        "email",
+		"employee_id":  "employee_id",
+		"role":         "role",
+		"joining_date": "joining_date",
+		"created_at":   "created_at",
+	}
+	leaveRequestSorts = map[string]string{
+		"start_date":      "lr.start_date",
+		"end_date":        "lr.end_date",
+		"total_days":      "lr.total_days",
+		"status":          "lr.status",
+		"applied_at":      "lr.applied_at",
+		"created_at":      "lr.created_at",
+		"employee_name":   "e.name",
+		"leave_type_name": "lt.name",
+	}
+	auditLogSorts = map[string]string{
+		"changed_at": "changed_at",
+		"table_name": "table_name",
+		"action":     "action",
+	}
+	approvalRuleSorts = map[string]string{
+		"priority":   "priority",
+		"name":       "name",
+		"created_at": "created_at",
+	}
+	erasureRequestSorts = map[string]string{
+		"requested_at": "requested_at",
+		"status":       "status",
+	}
+	teamSorts = map[string]string{
+		"name":         "e.name",
+		"employee_id":  "e.employee_id",
+		"joining_date": "e.joining_date",
+	}
+	acknowledgmentSorts = map[string]string{
+		"acknowledged_at": "a.acknowledged_at",
+		"employee_name":   "e.name",
+	}
+	pendingApprovalSorts = map[string]string{
+		"applied_at":    "lr.applied_at",
+		"start_date":    "lr.start_date",
+		"total_days":    "lr.total_days",
+		"employee_name": "e.name",
+	}
+	compOffSorts = map[string]string{
+		"work_date":     "c.work_date",
+		"created_at":    "c.created_at",
+		"status":        "c.status",
+		"employee_name": "e.name",
+	}
+	delegationSorts = map[string]string{
+		"start_date": "d.start_date",
+		"end_date":   "d.end_date",
+		"created_at": "d.created_at",
+	}
+	webhookSorts = map[string]string{
+		"url":        "url",
+		"created_at": "created_at",
+	}
+	chatIntegrationSorts = map[string]string{
+		"provider":   "provider",
+		"created_at": "created_at",
+	}
+	apiKeySorts = map[string]string{
+		"name":         "name",
+		"created_at":   "created_at",
+		"expires_at":   "expires_at",
+		"last_used_at": "last_used_at",
+	}
+	anomalySorts = map[string]string{
+		"ratio":         "a.ratio",
+		"occurrences":   "a.occurrences",
+		"detected_at":   "a.detected_at",
+		"employee_name": "e.name",
+	}
+)
+
+// parseSort reads ?sort=field:asc|desc[,field:asc|desc...], or the single field form
+// ?sort_by=field&order=asc|desc, against a whitelist and returns the ORDER BY expression
+// (without the keywords), with idCol appended as a tie-breaker.
+// custom is false when the client did not ask for a sort and def was used.
+func parseSort(c *gin.Context, allowed map[string]string, def, idCol string) (orderBy string, custom bool, err error) {
+	raw := strings.TrimSpace(c.Query("sort"))
+	if field := strings.TrimSpace(c.Query("sort_by")); field != "" {
+		if raw != "" {
+			return "", false, errors.New("sort and sort_by cannot be combined")
+		}
+		if strings.Contains(field, ",") {
+			return "", false, errors.New("sort_by takes a single field, use sort for several")
+		}
+		raw = field + ":" + c.Query("order")
+	} else if c.Query("order") != "" {
+		return "", false, errors.New("order needs sort_by")
+	}
+	if raw == "" {
+		return def, false, nil
+	}
+	parts := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		field, dir, _ := strings.Cut(strings.TrimSpace(item), ":")
+		col, ok := allowed[field]
+		if !ok {
+			return "", false, errors.New("cannot sort by " + field)
+		}
+		switch strings.ToLower(dir) {
+		case "", "asc":
+			parts = append(parts, col+" ASC")
+		case "desc":
+			parts = append(parts, col+" DESC")
+		default:
+			return "", false, errors.New("sort direction must be asc or desc")
+		}
+	}
+	parts = append(parts, idCol+" ASC")
+	return strings.Join(parts, ", "), true, nil
+}
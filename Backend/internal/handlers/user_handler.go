@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+	"leave-management/internal/db/queries"
+	"leave-management/internal/models"
+	"leave-management/internal/repository"
+	"leave-management/internal/service"
+	"leave-management/internal/siem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserHandler serves the administration of logins (users), which admins manage
+// separately from the employee records they belong to
+type UserHandler struct {
+	store  repository.Store
+	repos  repository.Repos
+	roles  *service.Roles
+	cache  *cache.Cache
+	events *siem.Forwarder // administrative changes; nil when no SIEM is configured
+}
+
+func NewUserHandler(pool *pgxpool.Pool, rc *cache.Cache, events *siem.Forwarder) *UserHandler {
+	store := repository.New(pool)
+	return &UserHandler{store: store, repos: store.Repos(), roles: service.NewRoles(store), cache: rc, events: events}
+}
+
+// adminEvent forwards an administrative change to the login target to the
+// SIEM; the event's user is the admin who made it
+func (h *UserHandler) adminEvent(c *gin.Context, action, target string, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["target_user_id"] = target
+	h.events.Publish(siem.Event{
+		Category: "auth", Action: action, Outcome: "success",
+		OrgID: c.GetString("org_id"), UserID: c.GetString("user_id"), Role: c.GetString("role"),
+		IP: c.ClientIP(), RequestID: c.GetString("request_id"), Details: details,
+	})
+}
+
+// userItem is a login as listed by GET /users. employee_uuid and
+// employee_name are null when its employee record is gone.
+type userItem struct {
+	ID           string     `json:"id"`
+	EmployeeID   string     `json:"employee_id"`
+	EmployeeUUID *string    `json:"employee_uuid"`
+	EmployeeName *string    `json:"employee_name"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	IsActive     bool       `json:"is_active"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	CreatedAt    *time.Time `json:"created_at"`
+}
+
+// GET /users
+// Optional filters: role, active (true/false); paging: limit, offset. Newest
+// first.
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	var filter queries.CountUsersParams
+	if v := c.Query("role"); v != "" {
+		if !models.IsValidRole(v) {
+			apierror.Respond(c, apierror.InvalidQuery, "role must be one of: employee, manager, hr, admin")
+			return
+		}
+		filter.Role = &v
+	}
+	if v := c.Query("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			apierror.Respond(c, apierror.InvalidQuery, "active must be true or false")
+			return
+		}
+		filter.IsActive = &active
+	}
+
+	ctx := c.Request.Context()
+	total, err := h.repos.Users.CountUsers(ctx, filter)
+	if err != nil {
+		apierror.Database(c, err, "failed to count users")
+		return
+	}
+	rows, err := h.repos.Users.ListUsers(ctx, queries.ListUsersParams{
+		Role: filter.Role, IsActive: filter.IsActive, RowLimit: int32(page.Limit), RowOffset: int32(page.Offset),
+	})
+	if err != nil {
+		apierror.Database(c, err, "failed to list users")
+		return
+	}
+	list := make([]userItem, 0, len(rows))
+	for _, u := range rows {
+		list = append(list, userItem{
+			ID: u.ID, EmployeeID: u.EmployeeID, EmployeeUUID: u.EmployeeUuid, EmployeeName: u.EmployeeName,
+			Email: u.Email, Role: u.Role, IsActive: u.IsActive, LastLoginAt: u.LastLoginAt, CreatedAt: u.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(int(total), len(list))})
+}
+
+// PUT /users/:id/activate
+// Lets the user sign in again. A user whose employee record is deactivated
+// stays locked out until the employee is reactivated.
+func (h *UserHandler) ActivateUser(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+// PUT /users/:id/deactivate
+// Locks the user out: their access tokens are refused from the next request
+// on and their refresh tokens are revoked. The employee record is untouched.
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *UserHandler) setActive(c *gin.Context, active bool) {
+	id, ok := h.targetUser(c, "you cannot deactivate your own account")
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	err := h.store.InTx(ctx, func(r repository.Repos) error {
+		n, err := r.Users.SetUserActive(ctx, queries.SetUserActiveParams{IsActive: active, ID: id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return service.ErrNotFound
+		}
+		if active {
+			return nil
+		}
+		return r.Users.RevokeUserRefreshTokens(ctx, id)
+	})
+	if errors.Is(err, service.ErrNotFound) {
+		apierror.Respond(c, apierror.NotFound, "user not found")
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err, "failed to update user")
+		return
+	}
+	h.cache.Delete(ctx, cache.UserKey(id))
+	action, message := "user_deactivate", "user deactivated"
+	if active {
+		action, message = "user_activate", "user activated"
+	}
+	h.adminEvent(c, action, id, nil)
+	respond(c, http.StatusOK, gin.H{"id": id, "is_active": active, "message": message})
+}
+
+// DELETE /users/:id
+// Deletes the login, and with it its refresh tokens, so every session of
+// the user ends. The employee record is kept; the employee can register
+// again.
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := h.targetUser(c, "you cannot delete your own account")
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	n, err := h.repos.Users.DeleteUser(ctx, id)
+	if err != nil {
+		apierror.Database(c, err, "failed to delete user")
+		return
+	}
+	if n == 0 {
+		apierror.Respond(c, apierror.NotFound, "user not found")
+		return
+	}
+	h.cache.Delete(ctx, cache.UserKey(id))
+	h.adminEvent(c, "user_delete", id, nil)
+	respond(c, http.StatusOK, gin.H{"id": id, "message": "user deleted"})
+}
+
+// targetUser returns the :id of a request that changes a login, answering
+// 404 for ids that cannot exist and 403 with self for the caller's own
+func (h *UserHandler) targetUser(c *gin.Context, self string) (string, bool) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "user not found")
+		return "", false
+	}
+	if id == c.GetString("user_id") {
+		apierror.Respond(c, apierror.Forbidden, self)
+		return "", false
+	}
+	return id, true
+}
+
+// PUT /users/:id/role
+// Gives the user the role, and their employee with it, in one transaction;
+// both changes land in the audit trail. Admins cannot change their own role,
+// so an organization is never left without one by accident.
+func (h *UserHandler) SetRole(c *gin.Context) {
+	var in struct {
+		Role string `json:"role" binding:"required,oneof=employee manager hr admin"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	id, ok := h.targetUser(c, "you cannot change your own role")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	previous, err := h.roles.SetUserRole(ctx, id, in.Role)
+	if errors.Is(err, service.ErrNotFound) {
+		apierror.Respond(c, apierror.NotFound, "user not found")
+		return
+	}
+	if err != nil {
+		apierror.Database(c, err, "failed to change role")
+		return
+	}
+	// the auth middleware would otherwise go on using the cached role
+	h.cache.Delete(ctx, cache.UserKey(id))
+	h.adminEvent(c, "role_change", id, map[string]interface{}{"previous_role": previous, "role": in.Role})
+	respond(c, http.StatusOK, gin.H{"id": id, "role": in.Role, "previous_role": previous})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// a typo'd key should fail loudly instead of being silently ignored
+	binding.EnableDecoderDisallowUnknownFields = true
+
+	// report fields by their JSON name rather than the Go struct field
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// isUUID reports whether s is a UUID, so malformed ids can be answered without
+// a database round trip
+func isUUID(s string) bool {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	return ok && v.Var(s, "uuid") == nil
+}
+
+// bindJSON binds the request body into dst. On failure it responds with
+// per-field errors and returns false.
+func bindJSON(c *gin.Context, dst interface{}) bool {
+	if err := c.ShouldBindJSON(dst); err != nil {
+		respondBindError(c, err, dst)
+		return false
+	}
+	return true
+}
+
+// respondBindError reports a failed bind: 413 when the body hit the size limit,
+// per-field errors otherwise
+func respondBindError(c *gin.Context, err error, dst interface{}) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		apierror.Respond(c, apierror.PayloadTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	apierror.Fields(c, "invalid input", fieldErrors(err, dst))
+}
+
+// fieldErrors maps binding and validation failures on dst to field -> message
+// pairs without exposing validator or decoder internals
+func fieldErrors(err error, dst interface{}) []apierror.FieldError {
+	var (
+		verrs     validator.ValidationErrors
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+	)
+	switch {
+	case errors.As(err, &verrs):
+		out := make([]apierror.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			out = append(out, apierror.FieldError{Field: fieldPath(fe, dst), Error: validationMessage(fe)})
+		}
+		return out
+	case errors.As(err, &typeErr):
+		return []apierror.FieldError{{Field: typeErr.Field, Error: "must be " + jsonTypeName(typeErr.Type)}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []apierror.FieldError{{Field: "", Error: "malformed JSON"}}
+	case errors.Is(err, io.EOF):
+		return []apierror.FieldError{{Field: "", Error: "request body is required"}}
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return []apierror.FieldError{{Field: strings.Trim(name, `"`), Error: "is not a known field"}}
+	}
+	return []apierror.FieldError{{Field: "", Error: "invalid request body"}}
+}
+
+// fieldPath drops the root struct name: "attendanceImportDTO.records[0].date" -> "records[0].date".
+// Anonymous structs have no root name to drop.
+func fieldPath(fe validator.FieldError, dst interface{}) string {
+	t := reflect.TypeOf(dst)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return fe.Namespace()
+	}
+	return strings.TrimPrefix(fe.Namespace(), t.Name()+".")
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "datetime":
+		if fe.Param() == "2006-01-02" {
+			return "must be YYYY-MM-DD"
+		}
+		return "must match " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return fmt.Sprintf("must contain at least %s items", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		if fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map {
+			return fmt.Sprintf("must contain at most %s items", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	}
+	return "is invalid"
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookHandler manages the organization's outbound webhooks: URLs that are
+// POSTed a signed JSON payload on the leave and employee events they
+// subscribe to, e.g. to feed payroll or an HRIS. The deliveries are queued by
+// the database and sent by the webhooks job (see internal/webhook).
+type WebhookHandler struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookHandler(pool *pgxpool.Pool) *WebhookHandler {
+	return &WebhookHandler{pool: pool}
+}
+
+type outboundWebhook struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description *string   `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	Secret      string    `json:"secret,omitempty"` // only when created or rotated
+	CreatedBy   *string   `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const webhookColumns = "id, url, events, description, is_active, created_by, created_at, updated_at"
+
+func scanWebhook(row pgx.Row) (outboundWebhook, error) {
+	var w outboundWebhook
+	err := row.Scan(&w.ID, &w.URL, &w.Events, &w.Description, &w.IsActive, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+type webhookDelivery struct {
+	ID             string          `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"` // while pending
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	LastError      *string         `json:"last_error"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"` // the data member of the body
+}
+
+const webhookDeliveryColumns = `id, event, status, attempts, CASE WHEN status = 'pending' THEN next_attempt_at END,
+	last_attempt_at, response_status, last_error, delivered_at, created_at, payload`
+
+func scanWebhookDelivery(row pgx.Row) (webhookDelivery, error) {
+	var d webhookDelivery
+	err := row.Scan(&d.ID, &d.Event, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus,
+		&d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.Payload)
+	return d, err
+}
+
+var webhookDeliveryStatuses = []string{"pending", "delivered", "failed"}
+
+// webhookEvents checks a list of events and drops the duplicates
+func webhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("must list at least one event")
+	}
+	seen := map[string]bool{}
+	out := []string{}
+	for _, e := range events {
+		if !webhook.ValidEvent(e) {
+			return nil, fmt.Errorf("must only contain %s", strings.Join(webhook.Events, ", "))
+		}
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// GET /webhooks (paging: limit, offset; sort: url, created_at)
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	orderBy, _, err := parseSort(c, webhookSorts, "created_at, id", "id")
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM webhooks").Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch webhooks")
+		return
+	}
+	rows, err := h.pool.Query(ctx, "SELECT "+webhookColumns+` FROM webhooks
+		ORDER BY `+orderBy+` LIMIT $1 OFFSET $2`, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch webhooks")
+		return
+	}
+	defer rows.Close()
+	list := make([]outboundWebhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, w)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch webhooks")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// POST /webhooks
+// Registers url for events. The response carries the signing secret, which
+// is not shown again; POST /webhooks/:id/rotate-secret replaces it.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var in struct {
+		URL         string   `json:"url" binding:"required,max=2000"`
+		Events      []string `json:"events" binding:"required"`
+		Description *string  `json:"description"`
+		IsActive    *bool    `json:"is_active"`
+	}
+	if !bindJSON(c, &in) {
+		return
+	}
+	in.URL = strings.TrimSpace(in.URL)
+	var fieldErrs []apierror.FieldError
+	if err := webhook.ValidateURL(in.URL); err != nil {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "url", Error: err.Error()})
+	}
+	events, err := webhookEvents(in.Events)
+	if err != nil {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "events", Error: err.Error()})
+	}
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		apierror.Respond(c, apierror.Internal, "failed to generate webhook secret")
+		return
+	}
+	w, err := scanWebhook(h.pool.QueryRow(c.Request.Context(), `
+		INSERT INTO webhooks (url, secret, events, description, is_active, created_by)
+		VALUES ($1, $2, $3, $4, COALESCE($5, TRUE), $6)
+		RETURNING `+webhookColumns, in.URL, secret, events, in.Description, in.IsActive, c.GetString("user_id")))
+	if err != nil {
+		apierror.Database(c, err, "failed to create webhook")
+		return
+	}
+	w.Secret = secret
+	respond(c, http.StatusCreated, w)
+}
+
+// GET /webhooks/:id
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	w, err := scanWebhook(h.pool.QueryRow(c.Request.Context(), "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", c.Param("id")))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "webhook not found", "failed to fetch webhook")
+		return
+	}
+	respond(c, http.StatusOK, w)
+}
+
+// webhookPatchFields are the members PATCH /webhooks/:id accepts
+var webhookPatchFields = map[string]patchField{
+	"url":         {column: "url", parse: patchWebhookURL},
+	"events":      {column: "events", parse: patchWebhookEvents},
+	"description": {column: "description", nullable: true, parse: patchString(false)},
+	"is_active":   {column: "is_active", parse: patchBool},
+}
+
+// patchWebhookURL decodes a webhook URL member
+func patchWebhookURL(raw json.RawMessage) (interface{}, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("must be a string")
+	}
+	s = strings.TrimSpace(s)
+	if err := webhook.ValidateURL(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// patchWebhookEvents decodes the events member, which replaces the list
+func patchWebhookEvents(raw json.RawMessage) (interface{}, error) {
+	var events []string
+	if err := json.Unmarshal(raw, &events); err != nil {
+		return nil, errors.New("must be an array of strings")
+	}
+	return webhookEvents(events)
+}
+
+// PATCH /webhooks/:id (RFC 7386 merge patch; null clears description)
+// Pending deliveries go to the new url and are signed with the current
+// secret; a new events list only applies to the events that follow.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	patch, ok := bindMergePatch(c)
+	if !ok {
+		return
+	}
+	sets, args, fieldErrs := patch.assignments(webhookPatchFields, 1)
+	if len(fieldErrs) > 0 {
+		apierror.Fields(c, "invalid input", fieldErrs)
+		return
+	}
+	if len(sets) == 0 {
+		apierror.Respond(c, apierror.NoFieldsToUpdate, "no fields to update")
+		return
+	}
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	args = append(args, c.Param("id"))
+	w, err := scanWebhook(h.pool.QueryRow(c.Request.Context(),
+		"UPDATE webhooks SET "+strings.Join(sets, ", ")+fmt.Sprintf(" WHERE id=$%d RETURNING ", len(args))+webhookColumns,
+		args...))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "webhook not found", "failed to update webhook")
+		return
+	}
+	respond(c, http.StatusOK, w)
+}
+
+// DELETE /webhooks/:id
+// Its deliveries, sent or not, go with it.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	ct, err := h.pool.Exec(c.Request.Context(), "DELETE FROM webhooks WHERE id = $1", c.Param("id"))
+	if err != nil {
+		apierror.Database(c, err, "failed to delete webhook")
+		return
+	}
+	if ct.RowsAffected() == 0 {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	respond(c, http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+// POST /webhooks/:id/rotate-secret
+// Replaces the signing secret at once, for the pending deliveries too, and
+// returns the new one.
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	if !isUUID(c.Param("id")) {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		apierror.Respond(c, apierror.Internal, "failed to generate webhook secret")
+		return
+	}
+	w, err := scanWebhook(h.pool.QueryRow(c.Request.Context(),
+		"UPDATE webhooks SET secret = $2 WHERE id = $1 RETURNING "+webhookColumns, c.Param("id"), secret))
+	if err != nil {
+		apierror.Lookup(c, err, apierror.NotFound, "webhook not found", "failed to rotate webhook secret")
+		return
+	}
+	w.Secret = secret
+	respond(c, http.StatusOK, w)
+}
+
+// GET /webhooks/:id/deliveries?status=pending|delivered|failed (paging:
+// limit, offset)
+// The webhook's delivery log, newest first.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id := c.Param("id")
+	if !isUUID(id) {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	page, err := parsePagination(c)
+	if err != nil {
+		apierror.Respond(c, apierror.InvalidQuery, err.Error())
+		return
+	}
+	status := c.Query("status")
+	if status != "" && !containsString(webhookDeliveryStatuses, status) {
+		apierror.Respond(c, apierror.InvalidQuery, "status must be pending, delivered or failed")
+		return
+	}
+	ctx := c.Request.Context()
+	var exists bool
+	if err := h.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1)", id).Scan(&exists); err != nil {
+		apierror.Database(c, err, "failed to fetch webhook deliveries")
+		return
+	}
+	if !exists {
+		apierror.Respond(c, apierror.NotFound, "webhook not found")
+		return
+	}
+	const where = " FROM webhook_deliveries WHERE webhook_id = $1 AND ($2 = '' OR status = $2)"
+	var total int
+	if err := h.pool.QueryRow(ctx, "SELECT COUNT(*)"+where, id, status).Scan(&total); err != nil {
+		apierror.Database(c, err, "failed to fetch webhook deliveries")
+		return
+	}
+	rows, err := h.pool.Query(ctx, "SELECT "+webhookDeliveryColumns+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4`, id, status, page.Limit, page.Offset)
+	if err != nil {
+		apierror.Database(c, err, "failed to fetch webhook deliveries")
+		return
+	}
+	defer rows.Close()
+	list := make([]webhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			apierror.Database(c, err, "row scan failed")
+			return
+		}
+		list = append(list, d)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Database(c, err, "failed to fetch webhook deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": list, "meta": page.meta(total, len(list))})
+}
+
+// POST /webhooks/:id/deliveries/:delivery_id/redeliver
+// Queues a failed or delivered delivery again, with the same id and payload
+// and a fresh set of attempts; the next run of the webhooks job sends it.
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	id, deliveryID := c.Param("id"), c.Param("delivery_id")
+	if !isUUID(id) || !isUUID(deliveryID) {
+		apierror.Respond(c, apierror.NotFound, "webhook delivery not found")
+		return
+	}
+	ctx := c.Request.Context()
+	d, err := scanWebhookDelivery(h.pool.QueryRow(ctx, `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		WHERE id = $1 AND webhook_id = $2 AND status <> 'pending'
+		RETURNING `+webhookDeliveryColumns, deliveryID, id))
+	if err == nil {
+		respond(c, http.StatusOK, d)
+		return
+	}
+	if !apierror.IsNoRows(err) {
+		apierror.Database(c, err, "failed to redeliver webhook delivery")
+		return
+	}
+	var exists bool
+	if err := h.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2)",
+		deliveryID, id).Scan(&exists); err != nil {
+		apierror.Database(c, err, "failed to redeliver webhook delivery")
+		return
+	}
+	if !exists {
+		apierror.Respond(c, apierror.NotFound, "webhook delivery not found")
+		return
+	}
+	apierror.Respond(c, apierror.InvalidState, "webhook delivery is already pending")
+}
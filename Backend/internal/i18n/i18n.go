@@ -0,0 +1,185 @@
+// Package i18n translates client-facing API messages. English is the source
+// language: catalogs map the English message to its translation, so a message
+// missing from a catalog is simply returned in English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the source language of every message
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+// catalog holds one language. Entries whose key contains %s are patterns: each
+// %s matches any text, which is substituted into the translation's %s in order.
+type catalog struct {
+	exact    map[string]string
+	patterns []pattern
+}
+
+type pattern struct {
+	re          *regexp.Regexp
+	translation string
+}
+
+var (
+	mu        sync.RWMutex
+	catalogs  = map[string]*catalog{}
+	supported []language.Tag
+	matcher   language.Matcher
+)
+
+func init() {
+	entries, err := builtin.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		data, err := builtin.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		if err := load(strings.TrimSuffix(e.Name(), ".json"), data); err != nil {
+			panic(fmt.Sprintf("i18n: built-in catalog %s: %v", e.Name(), err))
+		}
+	}
+}
+
+// LoadDir loads every <lang>.json file in dir. Entries are merged over the
+// built-in catalogs, so a custom catalog can add a language or reword messages.
+func LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err := load(strings.TrimSuffix(filepath.Base(f), ".json"), data); err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+func load(lang string, data []byte) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("invalid language %q", lang)
+	}
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	key := tag.String()
+	cat, ok := catalogs[key]
+	if !ok {
+		cat = &catalog{exact: map[string]string{}}
+		catalogs[key] = cat
+	}
+	for msg, translation := range entries {
+		cat.exact[msg] = translation
+	}
+	cat.patterns = compilePatterns(cat.exact)
+	rebuildMatcher()
+	return nil
+}
+
+// compilePatterns builds the %s patterns, longest first so the most specific
+// one wins
+func compilePatterns(exact map[string]string) []pattern {
+	var out []pattern
+	for msg, translation := range exact {
+		if !strings.Contains(msg, "%s") {
+			continue
+		}
+		parts := strings.Split(msg, "%s")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		out = append(out, pattern{
+			re:          regexp.MustCompile("^" + strings.Join(parts, "(.*?)") + "$"),
+			translation: translation,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return len(out[i].re.String()) > len(out[j].re.String()) })
+	return out
+}
+
+// rebuildMatcher must be called with mu held
+func rebuildMatcher() {
+	tags := []language.Tag{language.English}
+	for key := range catalogs {
+		if key != DefaultLanguage {
+			tags = append(tags, language.MustParse(key))
+		}
+	}
+	// English stays first so it is the fallback; the rest are sorted to keep
+	// matching deterministic
+	rest := tags[1:]
+	sort.Slice(rest, func(i, j int) bool { return rest[i].String() < rest[j].String() })
+	supported = tags
+	matcher = language.NewMatcher(tags)
+}
+
+// Match picks the best supported language for an Accept-Language header,
+// falling back to English
+func Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	_, idx, conf := matcher.Match(tags...)
+	if conf == language.No {
+		return DefaultLanguage
+	}
+	return supported[idx].String()
+}
+
+// Translate returns msg in lang, or msg itself when there is no translation
+func Translate(lang, msg string) string {
+	if msg == "" {
+		return msg
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	cat, ok := catalogs[lang]
+	if !ok {
+		return msg
+	}
+	if t, ok := cat.exact[msg]; ok {
+		return t
+	}
+	for _, p := range cat.patterns {
+		m := p.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		args := make([]interface{}, len(m)-1)
+		for i, a := range m[1:] {
+			args[i] = a
+		}
+		return fmt.Sprintf(p.translation, args...)
+	}
+	return msg
+}
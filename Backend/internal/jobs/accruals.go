@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AccrualResult counts what CreditAccruals credited
+type AccrualResult struct {
+	Periods  int     `json:"periods_credited"` // employee, leave type and period triples
+	Days     float64 `json:"days_credited"`    // added to allocated_days, in total
+	Balances int     `json:"balances_updated"` // balance rows created or raised
+}
+
+// CreditAccruals credits every period of asOf's year that has begun by asOf
+// to the balances of the accruing (monthly or quarterly) leave types. An
+// active employee gets a period when they joined by its last day. Without an
+// accrual_rate a leave type spreads max_days_per_year so the periods add up
+// to it exactly. Periods already credited are recorded in leave_accruals and
+// skipped, so the job can run as often as wanted and catches up on the
+// periods it missed.
+func CreditAccruals(ctx context.Context, pool *pgxpool.Pool, asOf time.Time) (AccrualResult, error) {
+	var res AccrualResult
+	err := pool.QueryRow(ctx, `
+		WITH periods AS (
+			SELECT lt.org_id, lt.id AS leave_type_id, lt.accrual_frequency AS frequency, p.period,
+			       make_date($2, (p.period - 1) * m.months + 1, 1) AS starts,
+			       (make_date($2, (p.period - 1) * m.months + 1, 1) + make_interval(months => m.months) - INTERVAL '1 day')::date AS ends,
+			       COALESCE(lt.accrual_rate,
+			                ROUND(lt.max_days_per_year::numeric * p.period * m.months / 12, 2)
+			                - ROUND(lt.max_days_per_year::numeric * (p.period - 1) * m.months / 12, 2)) AS days
+			FROM leave_types lt
+			CROSS JOIN LATERAL (SELECT CASE lt.accrual_frequency WHEN 'monthly' THEN 1 ELSE 3 END AS months) m
+			CROSS JOIN LATERAL generate_series(1, 12 / m.months) AS p(period)
+			WHERE lt.is_active AND lt.accrual_frequency <> 'annual'
+		),
+		credited AS (
+			INSERT INTO leave_accruals (org_id, employee_id, leave_type_id, year, frequency, period, days)
+			SELECT p.org_id, e.id, p.leave_type_id, $2, p.frequency, p.period, p.days
+			FROM periods p
+			JOIN employees e ON e.org_id = p.org_id
+			WHERE p.starts <= $1::date AND p.days > 0 AND e.is_active AND e.joining_date <= p.ends
+			ON CONFLICT ON CONSTRAINT leave_accruals_period_key DO NOTHING
+			RETURNING org_id, employee_id, leave_type_id, days
+		),
+		balances AS (
+			INSERT INTO employee_leave_balances (org_id, employee_id, leave_type_id, year, allocated_days)
+			SELECT org_id, employee_id, leave_type_id, $2, SUM(days)
+			FROM credited
+			GROUP BY org_id, employee_id, leave_type_id
+			ON CONFLICT (employee_id, leave_type_id, year) DO UPDATE
+			SET allocated_days = employee_leave_balances.allocated_days + EXCLUDED.allocated_days, updated_at = NOW()
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM credited), (SELECT COALESCE(SUM(days), 0)::FLOAT8 FROM credited),
+		       (SELECT COUNT(*) FROM balances)`,
+		asOf, asOf.Year()).Scan(&res.Periods, &res.Days, &res.Balances)
+	return res, err
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Anomaly patterns stored in absence_anomalies.pattern
+const (
+	PatternMondayFridaySick = "monday_friday_sick_leave"
+	PatternAdjoiningHoliday = "adjoining_holiday"
+)
+
+// AnomalySensitivity controls how readily a pattern is flagged: an employee is
+// flagged when at least MinOccurrences matching requests make up at least
+// MinRatio of their relevant requests in the lookback window.
+type AnomalySensitivity struct {
+	MinOccurrences int
+	MinRatio       float64
+	LookbackDays   int
+}
+
+var sensitivities = map[string]AnomalySensitivity{
+	"low":    {MinOccurrences: 5, MinRatio: 0.75, LookbackDays: 365},
+	"medium": {MinOccurrences: 3, MinRatio: 0.6, LookbackDays: 365},
+	"high":   {MinOccurrences: 2, MinRatio: 0.5, LookbackDays: 365},
+}
+
+// SensitivityFor returns the thresholds for a level (low, medium, high)
+func SensitivityFor(level string) (AnomalySensitivity, bool) {
+	s, ok := sensitivities[level]
+	return s, ok
+}
+
+// DetectAbsenceAnomalies recomputes absence_anomalies from leave requests in the
+// lookback window and returns the number of flagged employee/pattern pairs.
+// Row level security limits it to the organization ctx is scoped to; with
+// db.AsService it rescans every organization.
+//
+// Patterns:
+//   - short (<= 2 day) sick leave starting or ending on a Monday or Friday
+//   - leave directly adjoining a public holiday (ignoring weekends in between)
+func DetectAbsenceAnomalies(ctx context.Context, pool *pgxpool.Pool, s AnomalySensitivity) (int, error) {
+	windowEnd := time.Now().Truncate(24 * time.Hour)
+	windowStart := windowEnd.AddDate(0, 0, -s.LookbackDays)
+
+	var flagged int
+	err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+		// Replace the previous run; the table always reflects the latest scan
+		if _, err := tx.Exec(ctx, "DELETE FROM absence_anomalies"); err != nil {
+			return err
+		}
+
+		ct, err := tx.Exec(ctx, `
+			INSERT INTO absence_anomalies (org_id, employee_id, pattern, occurrences, total_requests, ratio, window_start, window_end)
+			SELECT org_id, employee_id, pattern, hits, total, hits::FLOAT8 / total, $1, $2
+			FROM (
+				SELECT lr.org_id, lr.employee_id, '`+PatternMondayFridaySick+`' AS pattern,
+					COUNT(*) FILTER (
+						WHERE lr.total_days <= 2
+						  AND (EXTRACT(ISODOW FROM lr.start_date) IN (1, 5) OR EXTRACT(ISODOW FROM lr.end_date) IN (1, 5))
+					) AS hits,
+					COUNT(*) AS total
+				FROM leave_requests lr
+				JOIN leave_types lt ON lt.org_id = lr.org_id AND lt.id = lr.leave_type_id
+				WHERE lr.status IN ('pending', 'approved')
+				  AND lt.name ILIKE '%sick%'
+				  AND lr.start_date BETWEEN $1 AND $2
+				GROUP BY lr.org_id, lr.employee_id
+
+				UNION ALL
+
+				SELECT lr.org_id, lr.employee_id, '`+PatternAdjoiningHoliday+`' AS pattern,
+					COUNT(*) FILTER (
+						WHERE EXISTS (
+							SELECT 1 FROM holidays h
+							WHERE h.org_id = lr.org_id
+							  AND ((h.holiday_date BETWEEN lr.start_date - 3 AND lr.start_date - 1
+							       AND calculate_working_days(h.holiday_date + 1, lr.start_date - 1) = 0)
+							   OR (h.holiday_date BETWEEN lr.end_date + 1 AND lr.end_date + 3
+							       AND calculate_working_days(lr.end_date + 1, h.holiday_date - 1) = 0))
+						)
+					) AS hits,
+					COUNT(*) AS total
+				FROM leave_requests lr
+				WHERE lr.status IN ('pending', 'approved')
+				  AND lr.start_date BETWEEN $1 AND $2
+				GROUP BY lr.org_id, lr.employee_id
+			) p
+			WHERE hits >= $3 AND hits::FLOAT8 / total >= $4
+		`, windowStart, windowEnd, s.MinOccurrences, s.MinRatio)
+		if err != nil {
+			return err
+		}
+		flagged = int(ct.RowsAffected())
+		return nil
+	})
+	return flagged, err
+}
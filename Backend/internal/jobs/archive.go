@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// archiveBatch bounds the rows moved per transaction, so the job never holds
+// locks on the hot tables for long
+const archiveBatch = 1000
+
+// ArchiveResult counts the rows ArchiveOldRecords moved
+type ArchiveResult struct {
+	LeaveRequests int
+	AuditLogs     int
+}
+
+// ArchiveOldRecords moves leave requests that ended more than olderThan ago
+// and are no longer pending, and audit logs written more than olderThan ago,
+// into leave_requests_archive and audit_logs_archive. Rows are moved in
+// batches, each in its own transaction, so a run interrupted half way leaves
+// every row in exactly one of the two tables. Moving a leave request is not
+// recorded in the audit trail, which would otherwise grow with every run.
+func ArchiveOldRecords(ctx context.Context, pool *pgxpool.Pool, olderThan time.Duration) (ArchiveResult, error) {
+	cutoff := time.Now().Add(-olderThan)
+	var res ArchiveResult
+	var err error
+	res.LeaveRequests, err = archiveInBatches(ctx, pool, `
+		WITH moved AS (
+			DELETE FROM leave_requests
+			WHERE id IN (
+				SELECT id FROM leave_requests
+				WHERE status <> 'pending' AND end_date < $1::date
+				LIMIT $2
+			)
+			RETURNING *
+		)
+		INSERT INTO leave_requests_archive SELECT * FROM moved`, cutoff)
+	if err != nil {
+		return res, err
+	}
+	res.AuditLogs, err = archiveInBatches(ctx, pool, `
+		WITH moved AS (
+			DELETE FROM audit_logs
+			WHERE id IN (SELECT id FROM audit_logs WHERE changed_at < $1 LIMIT $2)
+			RETURNING *
+		)
+		INSERT INTO audit_logs_archive SELECT * FROM moved`, cutoff)
+	return res, err
+}
+
+// archiveInBatches runs move until it moves less than a full batch and
+// returns the total
+func archiveInBatches(ctx context.Context, pool *pgxpool.Pool, move string, cutoff time.Time) (int, error) {
+	return inBatches(ctx, pool, func(tx pgx.Tx) (int, error) {
+		// read by audit_trigger_function
+		if _, err := tx.Exec(ctx, "SELECT set_config('lms.archiving', 'on', true)"); err != nil {
+			return 0, err
+		}
+		tag, err := tx.Exec(ctx, move, cutoff, archiveBatch)
+		return int(tag.RowsAffected()), err
+	})
+}
+
+// inBatches runs batch, each time in a new transaction, until it affects
+// fewer than archiveBatch rows, and returns the total
+func inBatches(ctx context.Context, pool *pgxpool.Pool, batch func(pgx.Tx) (int, error)) (int, error) {
+	total := 0
+	for {
+		var n int
+		err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			var err error
+			n, err = batch(tx)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < archiveBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"leave-management/internal/chat"
+	"leave-management/internal/db"
+	"leave-management/internal/webhook"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ChatRun sums up one run of SendChatMessages
+type ChatRun struct {
+	Sent     int
+	Retrying int   // failed, scheduled again
+	Failed   int   // failed for the last time
+	Purged   int64 // finished messages deleted for their age
+}
+
+// SendChatMessages posts every Slack and Teams message that is due, one at a
+// time while its row is locked, like DeliverWebhooks, and with the same
+// backoff. The buttons call back under callbackURL, the API's public base
+// URL; without one the messages go out without buttons, as do those of a
+// Slack integration that lacks its signing secret or bot token. Finished
+// messages older than retention are deleted.
+func SendChatMessages(ctx context.Context, pool *pgxpool.Pool, client *chat.Client, callbackURL string, maxAttempts int, retention time.Duration) (ChatRun, error) {
+	var res ChatRun
+	for {
+		more := false
+		err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			var err error
+			more, err = sendNextChatMessage(ctx, tx, client, callbackURL, maxAttempts, &res)
+			return err
+		})
+		if err != nil {
+			return res, err
+		}
+		if !more {
+			break
+		}
+	}
+	ct, err := pool.Exec(ctx, `
+		DELETE FROM chat_messages
+		WHERE status <> 'pending' AND created_at < $1`, time.Now().Add(-retention))
+	res.Purged = ct.RowsAffected()
+	return res, err
+}
+
+// sendNextChatMessage posts the next due message, if any, and reports whether
+// there was one
+func sendNextChatMessage(ctx context.Context, tx pgx.Tx, client *chat.Client, callbackURL string, maxAttempts int, res *ChatRun) (bool, error) {
+	var (
+		id, requestID, integrationID string
+		provider, target             string
+		m                            chat.Message
+		interactive                  bool
+		attemptsMade                 int
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT m.id, m.kind, m.leave_request_id, m.attempts, ci.id, ci.provider, ci.webhook_url,
+			ci.provider = 'teams' OR (ci.signing_secret IS NOT NULL AND ci.bot_token IS NOT NULL)
+		FROM chat_messages m
+		JOIN chat_integrations ci ON ci.id = m.integration_id
+		WHERE m.status = 'pending' AND m.next_attempt_at <= NOW() AND ci.is_active
+		ORDER BY m.next_attempt_at
+		LIMIT 1
+		FOR UPDATE OF m SKIP LOCKED`).Scan(&id, &m.Kind, &requestID, &attemptsMade, &integrationID, &provider,
+		&target, &interactive)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if m.Leave, err = chat.GetLeave(ctx, tx, requestID); err != nil {
+		return false, err
+	}
+	if interactive {
+		m.ActionURL = chat.ActionURL(callbackURL, integrationID)
+	}
+	body, err := chat.Body(provider, m)
+	if err != nil {
+		return false, err
+	}
+
+	sendErr := client.Post(ctx, target, body)
+	if ctx.Err() != nil {
+		// shutting down: the message stays as it was for the next run
+		return false, ctx.Err()
+	}
+	attemptsMade++
+	switch {
+	case sendErr == nil:
+		_, err = tx.Exec(ctx, `
+			UPDATE chat_messages SET status = 'sent', attempts = $2, sent_at = NOW(), last_error = NULL
+			WHERE id = $1`, id, attemptsMade)
+		res.Sent++
+		chat.Count(provider, chat.Sent)
+	case attemptsMade >= maxAttempts:
+		_, err = tx.Exec(ctx, `
+			UPDATE chat_messages SET status = 'failed', attempts = $2, last_error = $3
+			WHERE id = $1`, id, attemptsMade, sendErr.Error())
+		res.Failed++
+		chat.Count(provider, chat.Failed)
+	default:
+		_, err = tx.Exec(ctx, `
+			UPDATE chat_messages SET attempts = $2, last_error = $3, next_attempt_at = $4
+			WHERE id = $1`, id, attemptsMade, sendErr.Error(), time.Now().Add(webhook.Backoff(attemptsMade)))
+		res.Retrying++
+		chat.Count(provider, chat.Retrying)
+	}
+	return true, err
+}
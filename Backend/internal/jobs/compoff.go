@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CompOffExpiryResult counts what ExpireCompOffs took back
+type CompOffExpiryResult struct {
+	Credits int     `json:"credits_expired"` // approved comp-offs that reached expires_on
+	Days    float64 `json:"days_expired"`    // unused days taken off allocated_days, in total
+}
+
+// ExpireCompOffs expires the approved comp-offs whose expires_on is on or
+// before asOf. The days still unused of each are taken off the allocated_days
+// of the balance they were credited to. Leave is taken from the oldest credits
+// first, so what is left of a credit is the balance's available days less the
+// credits of the same balance that expire after it. Credits are expired in
+// the order they expire, each in its own transaction, and only once.
+func ExpireCompOffs(ctx context.Context, pool *pgxpool.Pool, asOf time.Time) (CompOffExpiryResult, error) {
+	var res CompOffExpiryResult
+	rows, err := pool.Query(ctx, `
+		SELECT id FROM comp_off_requests
+		WHERE status = 'approved' AND expired_at IS NULL AND expires_on <= $1::date
+		ORDER BY expires_on, id`, asOf)
+	if err != nil {
+		return res, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return res, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	for _, id := range ids {
+		var days float64
+		err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			var err error
+			days, err = expireCompOff(ctx, tx, id)
+			return err
+		})
+		if err != nil {
+			return res, fmt.Errorf("comp-off %s: %w", id, err)
+		}
+		res.Credits++
+		res.Days += days
+	}
+	return res, nil
+}
+
+// expireCompOff expires one approved comp-off and returns the days it took
+// back; a comp-off expired meanwhile is left alone
+func expireCompOff(ctx context.Context, tx pgx.Tx, id string) (float64, error) {
+	var employeeID, leaveTypeID string
+	var year int
+	var days float64
+	var expiresOn time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT employee_id, leave_type_id, credited_year, days::FLOAT8, expires_on
+		FROM comp_off_requests
+		WHERE id = $1 AND status = 'approved' AND expired_at IS NULL
+		FOR UPDATE`, id).Scan(&employeeID, &leaveTypeID, &year, &days, &expiresOn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var available, allocated, later float64
+	err = tx.QueryRow(ctx, `
+		SELECT available_days::FLOAT8, allocated_days::FLOAT8
+		FROM employee_leave_balances
+		WHERE employee_id = $1 AND leave_type_id = $2 AND year = $3
+		FOR UPDATE`, employeeID, leaveTypeID, year).Scan(&available, &allocated)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	if err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(days), 0)::FLOAT8 FROM comp_off_requests
+		WHERE employee_id = $1 AND leave_type_id = $2 AND credited_year = $3
+		  AND status = 'approved' AND expired_at IS NULL AND (expires_on, id) > ($4::date, $5::uuid)`,
+		employeeID, leaveTypeID, year, expiresOn, id).Scan(&later); err != nil {
+		return 0, err
+	}
+	unused := math.Max(0, math.Min(math.Min(days, available-later), allocated))
+
+	if unused > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE employee_leave_balances SET allocated_days = allocated_days - $4
+			WHERE employee_id = $1 AND leave_type_id = $2 AND year = $3`,
+			employeeID, leaveTypeID, year, unused); err != nil {
+			return 0, err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE comp_off_requests SET expired_days = $2, expired_at = NOW()
+		WHERE id = $1`, id, unused)
+	return unused, err
+}
@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// erasureBatch bounds the approved requests one run of the job takes on
+const erasureBatch = 100
+
+// ProcessErasureRequests carries out approved erasure requests and returns
+// how many it completed. Each employee is erased in its own transaction, so a
+// failure leaves that request approved, with the error in last_error, for the
+// next run, and does not hold up the others.
+func ProcessErasureRequests(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT id, employee_id FROM erasure_requests
+		WHERE status = 'approved' ORDER BY reviewed_at LIMIT $1`, erasureBatch)
+	if err != nil {
+		return 0, err
+	}
+	type request struct{ id, employeeID string }
+	var pending []request
+	for rows.Next() {
+		var r request
+		if err := rows.Scan(&r.id, &r.employeeID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	done := 0
+	var errs []error
+	for _, r := range pending {
+		err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			if err := eraseEmployee(ctx, tx, r.employeeID); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `
+				UPDATE erasure_requests SET status = 'completed', completed_at = NOW(), last_error = NULL
+				WHERE id = $1`, r.id)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("erasure request %s: %w", r.id, err))
+			if _, uerr := pool.Exec(ctx, "UPDATE erasure_requests SET last_error = $2 WHERE id = $1", r.id, err.Error()); uerr != nil {
+				errs = append(errs, uerr)
+			}
+			continue
+		}
+		done++
+	}
+	return done, errors.Join(errs...)
+}
+
+// eraseEmployee anonymizes an employee and what identifies them: the
+// employee record and login, the provider accounts linked to that login for
+// single sign-on, the free text of their leave requests (live and
+// archived), comp-offs and approval delegations, the comments they wrote or
+// that were made on their requests, their absence anomalies, the name and
+// email in the webhook deliveries about them, and the audit entries about
+// those records or made by them. Attendance records and
+// balances hold no personal data beyond the employee id and are kept. The
+// rows stay, so reports and balances still add up; the employee is
+// deactivated and can no longer sign in.
+func eraseEmployee(ctx context.Context, tx pgx.Tx, employeeID string) error {
+	users, err := collectIDs(ctx, tx, `
+		UPDATE users u SET email = 'erased-' || u.id || '@erased.invalid', password_hash = '!', is_active = false
+		FROM employees e
+		WHERE e.id = $1 AND u.org_id = e.org_id AND u.employee_id = e.employee_id
+		RETURNING u.id`, employeeID)
+	if err != nil {
+		return err
+	}
+
+	records := []string{employeeID}
+	records = append(records, users...)
+	for _, step := range []struct {
+		stmt string
+		arg  interface{}
+	}{
+		{"DELETE FROM refresh_tokens WHERE user_id = ANY($1::uuid[]) RETURNING id", users},
+		{"DELETE FROM user_identities WHERE user_id = ANY($1::uuid[]) RETURNING id", users},
+		{`UPDATE employees SET email = 'erased-' || id || '@erased.invalid', name = 'Erased employee',
+			phone = NULL, address = NULL, is_active = false
+		WHERE id = $1 RETURNING id`, employeeID},
+		// after the update above, which may have queued an employee.deactivated delivery
+		{`UPDATE webhook_deliveries SET payload = payload - 'name' - 'email'
+		WHERE event LIKE 'employee.%' AND payload->>'id' = $1::text RETURNING id`, employeeID},
+		{`UPDATE leave_requests SET reason = '[erased]', comments = NULL, rejection_reason = NULL
+		WHERE employee_id = $1 RETURNING id`, employeeID},
+		{`UPDATE leave_requests_archive SET reason = '[erased]', comments = NULL, rejection_reason = NULL
+		WHERE employee_id = $1 RETURNING id`, employeeID},
+		{`UPDATE comp_off_requests SET reason = '[erased]', rejection_reason = NULL
+		WHERE employee_id = $1 RETURNING id`, employeeID},
+		{`UPDATE approval_delegations SET reason = NULL
+		WHERE reason IS NOT NULL AND delegator_id = $1 RETURNING id`, employeeID},
+		{`UPDATE leave_request_comments SET comment = '[erased]'
+		WHERE author_id = $1 OR leave_request_id IN (SELECT id FROM leave_requests WHERE employee_id = $1) RETURNING id`, employeeID},
+		{`UPDATE approval_steps SET comment = NULL
+		WHERE comment IS NOT NULL AND leave_request_id IN (SELECT id FROM leave_requests WHERE employee_id = $1) RETURNING id`, employeeID},
+		{"DELETE FROM absence_anomalies WHERE employee_id = $1 RETURNING id", employeeID},
+	} {
+		ids, err := collectIDs(ctx, tx, step.stmt, step.arg)
+		if err != nil {
+			return err
+		}
+		records = append(records, ids...)
+	}
+
+	// the statements above were audited too, so this also covers the entries
+	// they just wrote
+	for _, table := range []string{"audit_logs", "audit_logs_archive"} {
+		if _, err := tx.Exec(ctx, recordAnonymization(`
+			UPDATE `+table+` SET old_values = old_values - $2::text[], new_values = new_values - $2::text[],
+				anonymized_at = COALESCE(anonymized_at, NOW())
+			WHERE record_id = ANY($1::uuid[])`), records, personalFields); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, recordAnonymization(`
+			UPDATE `+table+` SET changed_by = NULL, actor_user_id = NULL, ip_address = NULL, request_id = NULL,
+				anonymized_at = COALESCE(anonymized_at, NOW())
+			WHERE actor_user_id = ANY($1::uuid[]) OR changed_by = $2`), users, employeeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectIDs runs stmt, which returns the ids of the rows it touched
+func collectIDs(ctx context.Context, tx pgx.Tx, stmt string, arg interface{}) ([]string, error) {
+	rows, err := tx.Query(ctx, stmt, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every runs fn on a fixed interval until ctx is cancelled.
+// A non-positive interval disables the job.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		slog.Info("job disabled", "job", name)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			if err := fn(ctx); err != nil {
+				slog.Error("job failed", "job", name, "error", err)
+				continue
+			}
+			slog.Info("job finished", "job", name, "duration", time.Since(start))
+		}
+	}
+}
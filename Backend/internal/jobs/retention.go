@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"leave-management/internal/config"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// personalFields are removed from the row images of anonymized audit entries
+var personalFields = []string{"email", "name", "phone", "address", "reason", "comments", "rejection_reason", "comment"}
+
+// recordAnonymization wraps update, an UPDATE of audit entries that
+// anonymizes them, into a statement that also appends an ANONYMIZE entry to
+// the chain for every entry whose content it changed. The new entry holds the
+// hash of the redacted content, which verification then checks the entry
+// against (see service.AuditChains), so setting anonymized_at by hand does
+// not hide an edit. The statement returns the number of entries updated.
+func recordAnonymization(update string) string {
+	return `
+		WITH changed AS (` + update + `
+			RETURNING id, org_id, table_name, chain_seq, content_hash,
+				audit_content_hash(org_id, table_name, chain_seq, record_id, action, old_values, new_values,
+					changed_by, changed_at, actor_user_id, actor_role, ip_address, endpoint, request_id) AS redacted_hash
+		), recorded AS (
+			INSERT INTO audit_logs (org_id, table_name, record_id, action, new_values)
+			SELECT org_id, table_name, id, 'ANONYMIZE',
+				jsonb_build_object('entry_id', id, 'chain_seq', chain_seq, 'content_hash', redacted_hash)
+			FROM changed WHERE redacted_hash <> content_hash
+			ORDER BY org_id, table_name, chain_seq
+		)
+		SELECT COUNT(*) FROM changed`
+}
+
+// RetentionResult is what one rule of the audit retention policy matched
+type RetentionResult struct {
+	Table  string    `json:"table"`
+	Action string    `json:"action"` // delete or anonymize
+	Before time.Time `json:"before"` // entries written before this
+	Rows   int       `json:"rows"`
+}
+
+// ApplyAuditRetention enforces policy on audit_logs and audit_logs_archive:
+// entries older than their rule's age are anonymized (actor, IP, request and
+// the personal fields of the row images are cleared) or deleted. Anonymizing
+// rules run first. With dryRun nothing is changed and the rows that would be
+// are counted instead.
+func ApplyAuditRetention(ctx context.Context, pool *pgxpool.Pool, policy config.RetentionPolicy, dryRun bool) ([]RetentionResult, error) {
+	ordered := make(config.RetentionPolicy, 0, len(policy))
+	for _, anonymize := range []bool{true, false} {
+		for _, r := range policy {
+			if r.Anonymize == anonymize {
+				ordered = append(ordered, r)
+			}
+		}
+	}
+
+	now := time.Now()
+	results := make([]RetentionResult, 0, len(ordered))
+	for _, r := range ordered {
+		res := RetentionResult{Table: r.Table, Action: "delete", Before: now.AddDate(0, 0, -r.Days)}
+		if r.Anonymize {
+			res.Action = "anonymize"
+		}
+		// "*" covers the tables no rule of the same kind names
+		var named []string
+		for _, o := range policy {
+			if o.Table != "*" && o.Anonymize == r.Anonymize {
+				named = append(named, o.Table)
+			}
+		}
+		match := "(table_name = $2 OR ($2 = '*' AND table_name <> ALL($3))) AND changed_at < $1"
+		if r.Anonymize {
+			match += " AND anonymized_at IS NULL"
+		}
+		for _, table := range []string{"audit_logs", "audit_logs_archive"} {
+			n, err := applyRetentionRule(ctx, pool, table, match, r.Anonymize, dryRun, res.Before, r.Table, named)
+			res.Rows += n
+			if err != nil {
+				return results, err
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func applyRetentionRule(ctx context.Context, pool *pgxpool.Pool, table, match string, anonymize, dryRun bool, before time.Time, ruleTable string, named []string) (int, error) {
+	if named == nil {
+		named = []string{}
+	}
+	if dryRun {
+		var n int
+		err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+table+" WHERE "+match, before, ruleTable, named).Scan(&n)
+		return n, err
+	}
+	batch := "SELECT id FROM " + table + " WHERE " + match + " LIMIT $4"
+	args := []interface{}{before, ruleTable, named, archiveBatch}
+	if anonymize {
+		// the hashes are kept, so the chain still links, and the redacted
+		// content is recorded in the chain to be checked instead
+		stmt := recordAnonymization(`UPDATE ` + table + ` SET changed_by = NULL, actor_user_id = NULL, ip_address = NULL, request_id = NULL,
+			old_values = old_values - $5::text[], new_values = new_values - $5::text[], anonymized_at = NOW()
+			WHERE id IN (` + batch + `)`)
+		args = append(args, personalFields)
+		return inBatches(ctx, pool, func(tx pgx.Tx) (int, error) {
+			var n int
+			err := tx.QueryRow(ctx, stmt, args...).Scan(&n)
+			return n, err
+		})
+	}
+	// the chain heads remember the last deleted entry of each chain, from
+	// which verification picks the chain up
+	stmt := `
+		WITH gone AS (
+			DELETE FROM ` + table + ` WHERE id IN (` + batch + `)
+			RETURNING org_id, table_name, chain_seq, row_hash
+		), purged AS (
+			UPDATE audit_chains c SET purged_seq = g.chain_seq, purged_hash = g.row_hash
+			FROM (SELECT DISTINCT ON (org_id, table_name) * FROM gone ORDER BY org_id, table_name, chain_seq DESC) g
+			WHERE c.org_id = g.org_id AND c.table_name = g.table_name AND g.chain_seq > c.purged_seq
+		)
+		SELECT COUNT(*) FROM gone`
+	return inBatches(ctx, pool, func(tx pgx.Tx) (int, error) {
+		var n int
+		err := tx.QueryRow(ctx, stmt, args...).Scan(&n)
+		return n, err
+	})
+}
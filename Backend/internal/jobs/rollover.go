@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RolloverResult sums up a year-end rollover
+type RolloverResult struct {
+	Year               int `json:"year"`                 // the year closed
+	Balances           int `json:"balances"`             // balances of year + 1, existing or to create
+	Changed            int `json:"changed"`              // of those, created or given a new carried_forward_days
+	CarriedForwardDays int `json:"carried_forward_days"` // in total
+	// only filled by a dry run
+	Items []RolloverItem `json:"items,omitempty"`
+}
+
+// RolloverItem is what one balance of year + 1 would carry forward
+type RolloverItem struct {
+	EmployeeID         string   `json:"employee_id"`
+	EmployeeName       string   `json:"employee_name"`
+	LeaveTypeID        string   `json:"leave_type_id"`
+	LeaveTypeName      string   `json:"leave_type_name"`
+	AvailableDays      *float64 `json:"available_days"` // left in year; null without a balance
+	CarriedForwardDays int      `json:"carried_forward_days"`
+}
+
+// rolloverSource lists, for every active employee and active leave type, the
+// days carried from year $1 into the next: the whole days still available, up
+// to max_carry_forward_days, for the types that allow it. With $2 the
+// organizations already rolled over are left out.
+const rolloverSource = `
+	SELECT e.org_id, e.id AS employee_id, e.name AS employee_name, lt.id AS leave_type_id, lt.name AS leave_type_name,
+	       lt.accrual_frequency, lt.max_days_per_year, b.available_days::FLOAT8 AS available_days,
+	       CASE WHEN COALESCE(lt.carry_forward_allowed, FALSE) AND b.available_days > 0
+	            THEN LEAST(FLOOR(b.available_days)::INT, COALESCE(lt.max_carry_forward_days, 0))
+	            ELSE 0 END AS carry
+	FROM employees e
+	JOIN leave_types lt ON lt.org_id = e.org_id
+	LEFT JOIN employee_leave_balances b ON b.employee_id = e.id AND b.leave_type_id = lt.id AND b.year = $1
+	WHERE e.is_active AND lt.is_active
+	  AND NOT ($2 AND EXISTS (SELECT 1 FROM leave_year_rollovers r WHERE r.org_id = e.org_id AND r.year = $1))`
+
+// RolloverLeaveYear closes year: every active employee gets a balance for
+// each active leave type in year + 1, allocated as a new employee's would be,
+// with carried_forward_days computed from what was left in year. Balances of
+// year + 1 that exist already keep their allocation and only get the new
+// carried_forward_days, so running it again after late changes to year
+// recomputes the carry. Each organization's run is recorded in
+// leave_year_rollovers; skipDone leaves out the organizations recorded for
+// year. The changes go through the audit triggers like any other. runBy is
+// the user who asked for it, empty for the scheduled job. With dryRun nothing
+// changes and the items are listed instead.
+func RolloverLeaveYear(ctx context.Context, pool *pgxpool.Pool, year int, skipDone, dryRun bool, runBy string) (RolloverResult, error) {
+	res := RolloverResult{Year: year}
+	if dryRun {
+		rows, err := pool.Query(ctx, `
+			SELECT s.employee_id, s.employee_name, s.leave_type_id, s.leave_type_name, s.available_days, s.carry,
+			       n.id IS NULL OR n.carried_forward_days <> s.carry
+			FROM (`+rolloverSource+`) s
+			LEFT JOIN employee_leave_balances n ON n.employee_id = s.employee_id AND n.leave_type_id = s.leave_type_id AND n.year = $1 + 1
+			ORDER BY s.employee_name, s.employee_id, s.leave_type_name`, year, skipDone)
+		if err != nil {
+			return res, err
+		}
+		defer rows.Close()
+		res.Items = make([]RolloverItem, 0)
+		for rows.Next() {
+			var it RolloverItem
+			var changed bool
+			if err := rows.Scan(&it.EmployeeID, &it.EmployeeName, &it.LeaveTypeID, &it.LeaveTypeName,
+				&it.AvailableDays, &it.CarriedForwardDays, &changed); err != nil {
+				return res, err
+			}
+			res.Balances++
+			res.CarriedForwardDays += it.CarriedForwardDays
+			if changed {
+				res.Changed++
+			}
+			res.Items = append(res.Items, it)
+		}
+		return res, rows.Err()
+	}
+
+	err := pool.QueryRow(ctx, `
+		WITH src AS (`+rolloverSource+`),
+		upserted AS (
+			INSERT INTO employee_leave_balances (org_id, employee_id, leave_type_id, year, allocated_days, carried_forward_days)
+			SELECT org_id, employee_id, leave_type_id, $1 + 1,
+			       CASE WHEN accrual_frequency = 'annual' THEN max_days_per_year ELSE 0 END, carry
+			FROM src
+			ON CONFLICT (employee_id, leave_type_id, year) DO UPDATE
+			SET carried_forward_days = EXCLUDED.carried_forward_days, updated_at = NOW()
+			WHERE employee_leave_balances.carried_forward_days <> EXCLUDED.carried_forward_days
+			RETURNING 1
+		),
+		recorded AS (
+			INSERT INTO leave_year_rollovers (org_id, year, balances, carried_forward_days, run_by)
+			SELECT org_id, $1, COUNT(*), SUM(carry), NULLIF($3, '')::UUID
+			FROM src GROUP BY org_id
+			ON CONFLICT ON CONSTRAINT leave_year_rollovers_year_key DO UPDATE
+			SET balances = EXCLUDED.balances, carried_forward_days = EXCLUDED.carried_forward_days,
+			    run_by = EXCLUDED.run_by, run_at = NOW()
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM src), (SELECT COALESCE(SUM(carry), 0) FROM src),
+		       (SELECT COUNT(*) FROM upserted), (SELECT COUNT(*) FROM recorded)`,
+		year, skipDone, runBy).Scan(&res.Balances, &res.CarriedForwardDays, &res.Changed, new(int))
+	return res, err
+}
@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"leave-management/internal/db"
+	"leave-management/internal/siem"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ForwardAuditLogs sends the audit entries written since its last run to the
+// SIEM and returns how many it sent. siem_cursors records, per hash chain,
+// the last entry delivered; a batch is sent while its cursor is locked and
+// the cursor only moves once the sink accepted it, so entries are delivered
+// at least once, also with several instances running, and a sink that is
+// down holds the job back instead of losing entries. Chains start from the
+// beginning, so the first run also sends the existing trail.
+func ForwardAuditLogs(ctx context.Context, pool *pgxpool.Pool, f *siem.Forwarder) (int, error) {
+	if _, err := pool.Exec(ctx, `
+		INSERT INTO siem_cursors (org_id, table_name)
+		SELECT org_id, table_name FROM audit_chains
+		ON CONFLICT DO NOTHING`); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		sent, more := 0, false
+		err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			var err error
+			sent, more, err = forwardChainBatch(ctx, tx, f)
+			return err
+		})
+		total += sent
+		if err != nil || !more {
+			return total, err
+		}
+	}
+}
+
+// forwardChainBatch sends the next entries of one chain that has some, and
+// reports whether there was such a chain
+func forwardChainBatch(ctx context.Context, tx pgx.Tx, f *siem.Forwarder) (int, bool, error) {
+	var org, table string
+	var seq, head int64
+	err := tx.QueryRow(ctx, `
+		SELECT c.org_id, c.table_name, c.last_seq, h.last_seq
+		FROM siem_cursors c JOIN audit_chains h USING (org_id, table_name)
+		WHERE h.last_seq > c.last_seq
+		LIMIT 1
+		FOR UPDATE OF c SKIP LOCKED`).Scan(&org, &table, &seq, &head)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, record_id, action, old_values, new_values, changed_by::text, changed_at,
+			actor_user_id::text, actor_role, ip_address, endpoint, request_id, chain_seq, row_hash
+		FROM (
+			SELECT `+auditColumns+` FROM audit_logs WHERE org_id = $1 AND table_name = $2 AND chain_seq > $3
+			UNION ALL
+			SELECT `+auditColumns+` FROM audit_logs_archive WHERE org_id = $1 AND table_name = $2 AND chain_seq > $3
+		) e
+		ORDER BY chain_seq
+		LIMIT $4`, org, table, seq, f.BatchSize())
+	if err != nil {
+		return 0, false, err
+	}
+	batch := []siem.Event{}
+	last := head // when the entries were purged before they could be sent
+	for rows.Next() {
+		var (
+			id, recordID, action                            string
+			oldValues, newValues                            json.RawMessage
+			changedBy, actor, role, ip, endpoint, requestID *string
+			changedAt                                       time.Time
+			chainSeq                                        int64
+			rowHash                                         string
+		)
+		if err := rows.Scan(&id, &recordID, &action, &oldValues, &newValues, &changedBy, &changedAt,
+			&actor, &role, &ip, &endpoint, &requestID, &chainSeq, &rowHash); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		batch = append(batch, siem.Event{
+			ID: id, Time: changedAt.UTC(), Category: "audit", Action: action, OrgID: org,
+			UserID: deref(actor), Role: deref(role), IP: deref(ip), RequestID: deref(requestID),
+			Details: map[string]interface{}{
+				"table_name": table, "record_id": recordID, "changed_by": changedBy,
+				"old_values": oldValues, "new_values": newValues, "endpoint": endpoint,
+				"chain_seq": chainSeq, "row_hash": rowHash,
+			},
+		})
+		last = chainSeq
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+
+	if len(batch) > 0 {
+		if err := f.Send(ctx, batch); err != nil {
+			return 0, false, err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE siem_cursors SET last_seq = $3, forwarded_at = NOW()
+		WHERE org_id = $1 AND table_name = $2`, org, table, last)
+	return len(batch), true, err
+}
+
+// auditColumns are the columns of audit_logs, which audit_logs_archive
+// shares
+const auditColumns = `id, org_id, table_name, record_id, action, old_values, new_values, changed_by, changed_at,
+	actor_user_id, actor_role, ip_address, endpoint, request_id, anonymized_at,
+	chain_seq, prev_hash, content_hash, row_hash`
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
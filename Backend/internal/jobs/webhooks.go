@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"leave-management/internal/db"
+	"leave-management/internal/webhook"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRun sums up one run of DeliverWebhooks
+type WebhookRun struct {
+	Delivered int   // accepted by the receiver
+	Retrying  int   // failed, scheduled again
+	Failed    int   // failed for the last time
+	Purged    int64 // finished deliveries deleted for their age
+}
+
+// DeliverWebhooks sends every webhook delivery that is due, one at a time,
+// each while its row is locked, so several instances never send the same
+// one at once. A delivery the receiver did not accept with a 2xx status is
+// scheduled again after webhook.Backoff, or marked failed once it has had
+// maxAttempts. Deliveries of inactive webhooks wait until the webhook is
+// active again. Finished deliveries older than retention are deleted.
+func DeliverWebhooks(ctx context.Context, pool *pgxpool.Pool, client *webhook.Client, maxAttempts int, retention time.Duration) (WebhookRun, error) {
+	var res WebhookRun
+	for {
+		more := false
+		err := db.WithTx(ctx, pool, func(tx pgx.Tx) error {
+			var err error
+			more, err = deliverNextWebhook(ctx, tx, client, maxAttempts, &res)
+			return err
+		})
+		if err != nil {
+			return res, err
+		}
+		if !more {
+			break
+		}
+	}
+	ct, err := pool.Exec(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE status <> 'pending' AND created_at < $1`, time.Now().Add(-retention))
+	res.Purged = ct.RowsAffected()
+	return res, err
+}
+
+// deliverNextWebhook sends the next due delivery, if any, and reports
+// whether there was one
+func deliverNextWebhook(ctx context.Context, tx pgx.Tx, client *webhook.Client, maxAttempts int, res *WebhookRun) (bool, error) {
+	var (
+		p            webhook.Payload
+		target, key  string
+		attemptsMade int
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT d.id, d.event, d.org_id, d.created_at, d.payload, d.attempts, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.is_active
+		ORDER BY d.next_attempt_at
+		LIMIT 1
+		FOR UPDATE OF d SKIP LOCKED`).Scan(&p.ID, &p.Event, &p.OrgID, &p.CreatedAt, &p.Data,
+		&attemptsMade, &target, &key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	p.CreatedAt = p.CreatedAt.UTC()
+	status, sendErr := client.Send(ctx, target, key, p)
+	if ctx.Err() != nil {
+		// shutting down: the delivery stays as it was for the next run
+		return false, ctx.Err()
+	}
+	attemptsMade++
+	var code *int
+	if status != 0 {
+		code = &status
+	}
+	switch {
+	case sendErr == nil:
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET status = 'delivered', attempts = $2, last_attempt_at = NOW(),
+				delivered_at = NOW(), response_status = $3, last_error = NULL
+			WHERE id = $1`, p.ID, attemptsMade, code)
+		res.Delivered++
+		webhook.Count(p.Event, webhook.Delivered)
+	case attemptsMade >= maxAttempts:
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET status = 'failed', attempts = $2, last_attempt_at = NOW(),
+				response_status = $3, last_error = $4
+			WHERE id = $1`, p.ID, attemptsMade, code, sendErr.Error())
+		res.Failed++
+		webhook.Count(p.Event, webhook.Failed)
+	default:
+		_, err = tx.Exec(ctx, `
+			UPDATE webhook_deliveries SET attempts = $2, last_attempt_at = NOW(), next_attempt_at = $5,
+				response_status = $3, last_error = $4
+			WHERE id = $1`, p.ID, attemptsMade, code, sendErr.Error(), time.Now().Add(webhook.Backoff(attemptsMade)))
+		res.Retrying++
+		webhook.Count(p.Event, webhook.Retrying)
+	}
+	return true, err
+}
@@ -0,0 +1,139 @@
+// Package jwks fetches and caches the RSA signing keys an identity provider
+// publishes as a JSON Web Key Set, for checking the tokens it issues.
+package jwks
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keysTTL is how long the keys are kept; a token signed with an unknown key
+// fetches them again, at most every keysRetry
+const (
+	keysTTL   = 24 * time.Hour
+	keysRetry = 5 * time.Minute
+)
+
+// Set is the key set published at a URL
+type Set struct {
+	url  string
+	http *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey // by kid
+	fetched time.Time
+}
+
+// New returns the Set published at url, fetched with client when first needed
+func New(client *http.Client, url string) *Set {
+	return &Set{url: url, http: client}
+}
+
+// Key returns the signing key kid, fetching the keys when they are stale or
+// do not have it
+func (s *Set) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[kid]
+	age := time.Since(s.fetched)
+	switch {
+	case ok && age < keysTTL:
+		return k, nil
+	case !ok && s.keys != nil && age < keysRetry:
+		return nil, errors.New("unknown signing key")
+	}
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		if ok {
+			return k, nil // a stale key beats none while the provider is unreachable
+		}
+		return nil, err
+	}
+	s.keys, s.fetched = keys, time.Now()
+	if k, ok = keys[kid]; !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return k, nil
+}
+
+// fetch reads the key set
+func (s *Set) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signing keys: HTTP %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []struct {
+			Kty string   `json:"kty"`
+			Kid string   `json:"kid"`
+			N   string   `json:"n"`
+			E   string   `json:"e"`
+			X5c []string `json:"x5c"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("signing keys: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		if pub, err := rsaKey(k.N, k.E, k.X5c); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("signing keys: no RSA key")
+	}
+	return keys, nil
+}
+
+// rsaKey decodes an RSA JWK from its modulus and exponent, or else its
+// certificate
+func rsaKey(n, e string, x5c []string) (*rsa.PublicKey, error) {
+	if n != "" && e != "" {
+		nb, err := base64.RawURLEncoding.DecodeString(n)
+		if err != nil {
+			return nil, err
+		}
+		eb, err := base64.RawURLEncoding.DecodeString(e)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(new(big.Int).SetBytes(eb).Int64())}, nil
+	}
+	if len(x5c) == 0 {
+		return nil, errors.New("no key material")
+	}
+	der, err := base64.StdEncoding.DecodeString(x5c[0])
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA certificate")
+	}
+	return pub, nil
+}
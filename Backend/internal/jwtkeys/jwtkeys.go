@@ -0,0 +1,116 @@
+// Package jwtkeys signs and verifies access tokens: it holds the HMAC
+// secrets and the issuer, audience and lifetime of the tokens. Every token
+// names the secret that signed it in its kid header, so a secret can be
+// rotated without signing everyone out: the new one signs from then on, while
+// the old one, kept as a previous secret, still verifies the tokens it signed
+// until they expire.
+package jwtkeys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MinSecretLength is the shortest secret accepted, in bytes: HS256 wants at
+// least as many bits of key as the hash has
+const MinSecretLength = 32
+
+var (
+	ErrNoKeyID      = errors.New("token has no key id")
+	ErrUnknownKeyID = errors.New("token signed with an unknown key")
+)
+
+// Options configure a Keyring. The secrets are expected to be validated by
+// the configuration.
+type Options struct {
+	Secret          string   // signs new tokens
+	PreviousSecrets []string // still verify the tokens they signed
+	Issuer          string   // iss of new tokens, required of verified ones
+	Audience        string   // aud of new tokens, required of verified ones
+	TTL             time.Duration
+}
+
+// Keyring signs with its current secret and verifies with it and the
+// previous ones
+type Keyring struct {
+	currentID string
+	secrets   map[string][]byte // by key id
+	issuer    string
+	audience  string
+	ttl       time.Duration
+	parser    *jwt.Parser
+}
+
+func New(o Options) *Keyring {
+	k := &Keyring{currentID: KeyID(o.Secret), secrets: map[string][]byte{}, issuer: o.Issuer, audience: o.Audience, ttl: o.TTL}
+	for _, s := range o.PreviousSecrets {
+		k.secrets[KeyID(s)] = []byte(s)
+	}
+	k.secrets[k.currentID] = []byte(o.Secret)
+	k.parser = jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(o.Issuer),
+		jwt.WithAudience(o.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	return k
+}
+
+// KeyID identifies secret in the kid header. It is derived from the secret,
+// so a rotation needs no ids to be assigned, and reveals nothing a signed
+// token does not.
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Registered returns the registered claims of a new token for subject: the
+// issuer and audience, and a lifetime of the TTL from now
+func (k *Keyring) Registered(subject string) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    k.issuer,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{k.audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(k.ttl)),
+	}
+}
+
+// Sign returns claims as an HS256 token signed with the current secret
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = k.currentID
+	return token.SignedString(k.secrets[k.currentID])
+}
+
+// Parse verifies tokenString into claims: its signature, that it expires and
+// has not, that it is valid already (nbf, iat) and its issuer and audience.
+// An expired token fails with an error matching jwt.ErrTokenExpired.
+func (k *Keyring) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return k.parser.ParseWithClaims(tokenString, claims, k.keyfunc)
+}
+
+// keyfunc returns the secret named by the token's kid and rejects tokens that
+// are not HMAC-signed or name no known secret
+func (k *Keyring) keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrNoKeyID
+	}
+	secret, ok := k.secrets[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	return secret, nil
+}
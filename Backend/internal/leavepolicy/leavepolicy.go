@@ -0,0 +1,96 @@
+// Package leavepolicy applies the policy rules of a leave type to a leave
+// request: which of its days are charged (the sandwich rule), how much notice
+// it must be applied for with and how many days it may cover at most.
+package leavepolicy
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rules are the policy rules of a leave type
+type Rules struct {
+	// Sandwich charges the weekends and holidays that fall between two
+	// working days of a leave; without it only working days are charged
+	Sandwich bool
+	// MinNoticeDays is the fewest calendar days between applying and the
+	// first day of leave
+	MinNoticeDays int
+	// MaxConsecutiveDays is the most days one request may charge; 0 for no
+	// limit
+	MaxConsecutiveDays int
+}
+
+// Rules a request can break, as named in a Violation
+const (
+	RuleWorkingDays    = "working_days"
+	RuleMinNotice      = "min_notice_days"
+	RuleMaxConsecutive = "max_consecutive_days"
+)
+
+// Violation is a rule a leave request breaks
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string { return v.Message }
+
+// Calendar holds the holidays, as YYYY-MM-DD dates; weekends are Saturday and
+// Sunday
+type Calendar map[string]bool
+
+// NewCalendar returns the calendar of the given holidays
+func NewCalendar(holidays []time.Time) Calendar {
+	c := make(Calendar, len(holidays))
+	for _, d := range holidays {
+		c[d.Format("2006-01-02")] = true
+	}
+	return c
+}
+
+// Working reports whether d is a weekday that is not a holiday
+func (c Calendar) Working(d time.Time) bool {
+	if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return !c[d.Format("2006-01-02")]
+}
+
+// Days returns the days a full-day leave from start to end charges: its
+// working days and, under the sandwich rule, the days between the first and
+// the last of them. Weekends and holidays at either end are never charged. A
+// leave without working days breaks RuleWorkingDays.
+func (r Rules) Days(start, end time.Time, cal Calendar) (float64, error) {
+	var first, last time.Time
+	working := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if !cal.Working(d) {
+			continue
+		}
+		if working == 0 {
+			first = d
+		}
+		last = d
+		working++
+	}
+	if working == 0 {
+		return 0, &Violation{RuleWorkingDays, "the leave falls on weekends and holidays only"}
+	}
+	if r.Sandwich {
+		return float64(int(last.Sub(first).Hours()/24) + 1), nil
+	}
+	return float64(working), nil
+}
+
+// Check applies the notice and length rules to a leave starting on start and
+// charging days, applied for on today
+func (r Rules) Check(today, start time.Time, days float64) error {
+	if notice := int(start.Sub(today).Hours() / 24); notice < r.MinNoticeDays {
+		return &Violation{RuleMinNotice, fmt.Sprintf("this leave type must be applied for at least %d days before it starts", r.MinNoticeDays)}
+	}
+	if r.MaxConsecutiveDays > 0 && days > float64(r.MaxConsecutiveDays) {
+		return &Violation{RuleMaxConsecutive, fmt.Sprintf("this leave type allows at most %d consecutive days per request", r.MaxConsecutiveDays)}
+	}
+	return nil
+}
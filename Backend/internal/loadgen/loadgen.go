@@ -0,0 +1,389 @@
+// Package loadgen fills a database with synthetic employees and leave requests
+// so list and report endpoints can be measured at realistic volumes. Generated
+// employees have codes starting with "LG" and emails under example.com, which
+// keeps them apart from real data.
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Options sizes a run. The same Seed against the same reference data yields
+// the same distributions.
+type Options struct {
+	Employees int
+	Requests  int
+	Seed      int64
+}
+
+// Result counts what a run inserted
+type Result struct {
+	Departments   int
+	LeaveTypes    int
+	Employees     int
+	LeaveRequests int
+}
+
+// Departments and leave types created when the database has none
+var (
+	defaultDepartments = []string{"Engineering", "Sales", "Operations", "Finance", "Human Resources", "Support"}
+	defaultLeaveTypes  = []struct {
+		name    string
+		maxDays int
+	}{{"Annual Leave", 20}, {"Sick Leave", 10}, {"Casual Leave", 8}}
+)
+
+// weighted picks an index with probability proportional to its weight
+func weighted(rnd *rand.Rand, weights []int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := rnd.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(weights) - 1
+}
+
+var (
+	roles       = []string{"employee", "manager", "hr", "admin"}
+	roleWeights = []int{80, 12, 5, 3}
+
+	statuses       = []string{"approved", "pending", "rejected", "cancelled"}
+	statusWeights  = []int{60, 20, 10, 10}
+	durations      = []int{1, 2, 3, 5, 10} // working days
+	durationWeight = []int{45, 20, 15, 15, 5}
+
+	firstNames = []string{"Aarav", "Priya", "Rahul", "Ananya", "Vikram", "Sneha", "Arjun", "Kavya", "Rohan", "Meera", "James", "Maria", "Chen", "Fatima", "Lucas", "Aisha"}
+	lastNames  = []string{"Sharma", "Patel", "Iyer", "Reddy", "Nair", "Gupta", "Singh", "Khan", "Smith", "Garcia", "Wang", "Silva", "Okafor", "Müller"}
+)
+
+type employee struct {
+	id, deptID, role string
+}
+
+// Run inserts the synthetic data in one transaction, so a failed run leaves
+// nothing behind
+func Run(ctx context.Context, pool *pgxpool.Pool, opts Options) (Result, error) {
+	var res Result
+	rnd := rand.New(rand.NewSource(opts.Seed))
+
+	tx, err := db.Begin(ctx, pool)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	deptIDs, created, err := ensureDepartments(ctx, tx)
+	if err != nil {
+		return res, fmt.Errorf("departments: %w", err)
+	}
+	res.Departments = created
+	typeIDs, created, err := ensureLeaveTypes(ctx, tx)
+	if err != nil {
+		return res, fmt.Errorf("leave types: %w", err)
+	}
+	res.LeaveTypes = created
+
+	employees, err := insertEmployees(ctx, tx, rnd, deptIDs, opts.Employees)
+	if err != nil {
+		return res, fmt.Errorf("employees: %w", err)
+	}
+	res.Employees = len(employees)
+
+	if len(employees) > 0 && opts.Requests > 0 {
+		n, err := insertLeaveRequests(ctx, tx, rnd, employees, typeIDs, opts.Requests)
+		if err != nil {
+			return res, fmt.Errorf("leave requests: %w", err)
+		}
+		res.LeaveRequests = n
+	}
+	return res, tx.Commit(ctx)
+}
+
+func ensureDepartments(ctx context.Context, tx pgx.Tx) ([]string, int, error) {
+	ids, err := queryIDs(ctx, tx, "SELECT id FROM departments")
+	if err != nil || len(ids) > 0 {
+		return ids, 0, err
+	}
+	for _, name := range defaultDepartments {
+		var id string
+		if err := tx.QueryRow(ctx, "INSERT INTO departments (name) VALUES ($1) RETURNING id", name).Scan(&id); err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, len(ids), nil
+}
+
+func ensureLeaveTypes(ctx context.Context, tx pgx.Tx) ([]string, int, error) {
+	ids, err := queryIDs(ctx, tx, "SELECT id FROM leave_types WHERE is_active = true ORDER BY max_days_per_year DESC")
+	if err != nil || len(ids) > 0 {
+		return ids, 0, err
+	}
+	for _, lt := range defaultLeaveTypes {
+		var id string
+		err := tx.QueryRow(ctx, "INSERT INTO leave_types (name, max_days_per_year) VALUES ($1, $2) RETURNING id", lt.name, lt.maxDays).Scan(&id)
+		if err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, len(ids), nil
+}
+
+func queryIDs(ctx context.Context, tx pgx.Tx, query string) ([]string, error) {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// insertEmployees adds n employees. Departments get a skewed share of the
+// headcount and joining dates spread over the last eight years. The
+// trg_init_leave_balances trigger allocates their current-year balances.
+func insertEmployees(ctx context.Context, tx pgx.Tx, rnd *rand.Rand, deptIDs []string, n int) ([]employee, error) {
+	deptWeights := make([]int, len(deptIDs))
+	for i := range deptWeights {
+		deptWeights[i] = len(deptIDs) - i + rnd.Intn(3)
+	}
+	run := time.Now().Unix() % 1000000
+	today := time.Now().Truncate(24 * time.Hour)
+
+	// one array per column, inserted with unnest: a single statement, and the
+	// enum casts happen in SQL
+	codes := make([]string, n)
+	emails := make([]string, n)
+	names := make([]string, n)
+	depts := make([]string, n)
+	empRoles := make([]string, n)
+	joining := make([]time.Time, n)
+	for i := 0; i < n; i++ {
+		codes[i] = fmt.Sprintf("LG%06d-%07d", run, i)
+		emails[i] = fmt.Sprintf("loadgen.%d.%d@example.com", run, i)
+		names[i] = firstNames[rnd.Intn(len(firstNames))] + " " + lastNames[rnd.Intn(len(lastNames))]
+		depts[i] = deptIDs[weighted(rnd, deptWeights)]
+		empRoles[i] = roles[weighted(rnd, roleWeights)]
+		joining[i] = today.AddDate(0, 0, -1-rnd.Intn(8*365))
+	}
+	dbRows, err := tx.Query(ctx, `
+		INSERT INTO employees (employee_id, email, name, department_id, role, joining_date)
+		SELECT code, email, name, dept::uuid, role::employee_role, joining
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::date[])
+			AS t(code, email, name, dept, role, joining)
+		RETURNING id, department_id, role::TEXT`,
+		codes, emails, names, depts, empRoles, joining)
+	if err != nil {
+		return nil, err
+	}
+	employees, err := pgx.CollectRows(dbRows, func(r pgx.CollectableRow) (employee, error) {
+		var e employee
+		err := r.Scan(&e.id, &e.deptID, &e.role)
+		return e, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// everyone but a manager reports to a manager of their department
+	_, err = tx.Exec(ctx, `
+		UPDATE employees e SET manager_id = m.id
+		FROM (
+			SELECT DISTINCT ON (department_id) id, department_id FROM employees
+			WHERE employee_id = ANY($1) AND role = 'manager'
+			ORDER BY department_id, employee_id
+		) m
+		WHERE e.employee_id = ANY($1) AND e.department_id = m.department_id AND e.role <> 'manager'`, codes)
+	return employees, err
+}
+
+// insertLeaveRequests adds up to n requests for the current year. A few
+// employees take most of the leave, most requests are short, and an employee
+// never has overlapping requests or approved days beyond their allocation.
+func insertLeaveRequests(ctx context.Context, tx pgx.Tx, rnd *rand.Rand, employees []employee, typeIDs []string, n int) (int, error) {
+	year := time.Now().Year()
+	available := map[string]int{} // employee_id|leave_type_id -> days left
+	balances, err := tx.Query(ctx, `
+		SELECT employee_id, leave_type_id, available_days FROM employee_leave_balances
+		WHERE year = $1 AND employee_id = ANY($2)`, year, employeeIDs(employees))
+	if err != nil {
+		return 0, err
+	}
+	for balances.Next() {
+		var empID, typeID string
+		var days float64
+		if err := balances.Scan(&empID, &typeID, &days); err != nil {
+			return 0, err
+		}
+		available[empID+"|"+typeID] = int(days) // generated requests are whole days
+	}
+	if err := balances.Err(); err != nil {
+		return 0, err
+	}
+
+	var approvers []string
+	for _, e := range employees {
+		if e.role == "manager" || e.role == "hr" {
+			approvers = append(approvers, e.id)
+		}
+	}
+	if len(approvers) == 0 {
+		approvers = []string{employees[0].id}
+	}
+	typeWeights := make([]int, len(typeIDs))
+	for i := range typeWeights {
+		typeWeights[i] = len(typeIDs) - i
+	}
+
+	now := time.Now()
+	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	busy := map[string]map[time.Time]bool{}
+	var r requestColumns
+	for attempts := 0; len(r.employeeIDs) < n && attempts < n*5; attempts++ {
+		// squaring skews the pick towards the front of the list
+		f := rnd.Float64()
+		emp := employees[int(f*f*float64(len(employees)))]
+		typeID := typeIDs[weighted(rnd, typeWeights)]
+
+		start := jan1.AddDate(0, 0, rnd.Intn(365))
+		for start.Weekday() == time.Saturday || start.Weekday() == time.Sunday {
+			start = start.AddDate(0, 0, 1)
+		}
+		days := durations[weighted(rnd, durationWeight)]
+		dates := workingDays(start, days)
+		end := dates[len(dates)-1]
+		if end.Year() != year || overlaps(busy[emp.id], dates) {
+			continue
+		}
+
+		status := statuses[weighted(rnd, statusWeights)]
+		key := emp.id + "|" + typeID
+		if status == "approved" {
+			if available[key] < days {
+				status = "rejected"
+			} else {
+				available[key] -= days
+			}
+		}
+
+		applied := earliest(start.AddDate(0, 0, -(1+rnd.Intn(30))), now)
+		var approvedBy, rejection *string
+		var approvedAt *time.Time
+		switch status {
+		case "approved":
+			approver := approvers[rnd.Intn(len(approvers))]
+			at := earliest(applied.Add(time.Duration(1+rnd.Intn(72))*time.Hour), now)
+			approvedBy, approvedAt = &approver, &at
+		case "rejected":
+			reason := "team capacity"
+			rejection = &reason
+		}
+		if status != "cancelled" && status != "rejected" {
+			if busy[emp.id] == nil {
+				busy[emp.id] = map[time.Time]bool{}
+			}
+			for _, d := range dates {
+				busy[emp.id][d] = true
+			}
+		}
+		r.employeeIDs = append(r.employeeIDs, emp.id)
+		r.typeIDs = append(r.typeIDs, typeID)
+		r.starts = append(r.starts, start)
+		r.ends = append(r.ends, end)
+		r.days = append(r.days, int32(days))
+		r.statuses = append(r.statuses, status)
+		r.applied = append(r.applied, applied)
+		r.approvedBy = append(r.approvedBy, approvedBy)
+		r.approvedAt = append(r.approvedAt, approvedAt)
+		r.rejections = append(r.rejections, rejection)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO leave_requests (employee_id, leave_type_id, start_date, end_date, total_days, reason,
+			status, applied_at, approved_by, approved_at, rejection_reason)
+		SELECT emp::uuid, lt::uuid, s, e, d, 'loadgen', st::leave_status, ap, ab::uuid, aa, rr
+		FROM unnest($1::text[], $2::text[], $3::date[], $4::date[], $5::int[], $6::text[],
+			$7::timestamptz[], $8::text[], $9::timestamptz[], $10::text[])
+			AS t(emp, lt, s, e, d, st, ap, ab, aa, rr)`,
+		r.employeeIDs, r.typeIDs, r.starts, r.ends, r.days, r.statuses,
+		r.applied, r.approvedBy, r.approvedAt, r.rejections)
+	if err != nil {
+		return 0, err
+	}
+
+	// charge the approved days and reserve the pending ones, as the approval
+	// and apply endpoints would have
+	_, err = tx.Exec(ctx, `
+		UPDATE employee_leave_balances elb SET used_days = elb.used_days + s.days, pending_days = elb.pending_days + s.pending
+		FROM (
+			SELECT employee_id, leave_type_id,
+			       COALESCE(SUM(total_days) FILTER (WHERE status = 'approved' AND EXTRACT(YEAR FROM start_date)::INT = $2), 0) AS days,
+			       COALESCE(SUM(total_days) FILTER (WHERE status = 'pending' AND EXTRACT(YEAR FROM applied_at)::INT = $2), 0) AS pending
+			FROM leave_requests
+			WHERE status IN ('approved', 'pending') AND employee_id = ANY($1)
+			GROUP BY employee_id, leave_type_id
+		) s
+		WHERE elb.employee_id = s.employee_id AND elb.leave_type_id = s.leave_type_id AND elb.year = $2`,
+		employeeIDs(employees), year)
+	return len(r.employeeIDs), err
+}
+
+// requestColumns holds generated leave requests column by column for unnest
+type requestColumns struct {
+	employeeIDs, typeIDs []string
+	starts, ends         []time.Time
+	days                 []int32
+	statuses             []string
+	applied              []time.Time
+	approvedBy           []*string
+	approvedAt           []*time.Time
+	rejections           []*string
+}
+
+func employeeIDs(employees []employee) []string {
+	ids := make([]string, len(employees))
+	for i, e := range employees {
+		ids[i] = e.id
+	}
+	return ids
+}
+
+// workingDays returns n consecutive weekdays starting at start
+func workingDays(start time.Time, n int) []time.Time {
+	dates := make([]time.Time, 0, n)
+	for d := start; len(dates) < n; d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func overlaps(busy map[time.Time]bool, dates []time.Time) bool {
+	for _, d := range dates {
+		if busy[d] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+// Package logging configures the process-wide slog logger: JSON lines on
+// stdout at a level that can be changed while the server runs.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var level = new(slog.LevelVar)
+
+func init() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})))
+}
+
+// SetLevel changes the minimum level of the default logger
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// ParseLevel accepts debug, info, warn or error (case-insensitive)
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// Fatal logs msg at error level and exits, for startup failures the process
+// cannot recover from
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
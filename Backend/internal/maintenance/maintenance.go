@@ -0,0 +1,129 @@
+// Package maintenance holds the read-only maintenance mode: while it is on the
+// API refuses changes (see middleware.ReadOnly) but keeps serving reads, for
+// example during a migration or the year-end rollover. The mode is a single
+// row in the maintenance_mode table, so switching it on one instance reaches
+// the others at their next poll. It is switched by the operator of the
+// deployment, never by the admins of an organization.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// pollInterval is how long other instances may take to follow a change
+const pollInterval = 5 * time.Second
+
+// ErrNotAllowed is returned by Set for a caller that may not switch the mode
+var ErrNotAllowed = errors.New("only the operator may switch maintenance mode")
+
+// DefaultMessage is shown to clients when the mode was switched on without one
+const DefaultMessage = "The system is in read-only mode for maintenance; changes are disabled for now, please try again later."
+
+var readOnlyGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "lms_read_only",
+	Help: "1 while the API is in read-only maintenance mode.",
+})
+
+// Mode is the current state of the maintenance mode
+type Mode struct {
+	ReadOnly  bool      `json:"read_only"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// State is this instance's view of the mode, refreshed by Run
+type State struct {
+	pool *pgxpool.Pool
+	mode atomic.Pointer[Mode]
+}
+
+func New(pool *pgxpool.Pool) *State {
+	s := &State{pool: pool}
+	s.mode.Store(&Mode{})
+	return s
+}
+
+// Get returns the last known mode
+func (s *State) Get() Mode {
+	return *s.mode.Load()
+}
+
+// ReadOnly reports whether changes are refused, and the message to give
+func (s *State) ReadOnly() (bool, string) {
+	m := s.Get()
+	if m.Message == "" {
+		return m.ReadOnly, DefaultMessage
+	}
+	return m.ReadOnly, m.Message
+}
+
+// Run reloads the mode every pollInterval until ctx is done. When the
+// database cannot be read the last known mode stays in force.
+func (s *State) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.load(db.AsService(ctx)); err != nil && ctx.Err() == nil {
+			slog.Warn("maintenance mode: reload failed, keeping the last known mode", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *State) load(ctx context.Context) error {
+	var m Mode
+	var message *string
+	if err := s.pool.QueryRow(ctx,
+		"SELECT read_only, message, updated_at FROM maintenance_mode").
+		Scan(&m.ReadOnly, &message, &m.UpdatedAt); err != nil {
+		return err
+	}
+	if message != nil {
+		m.Message = *message
+	}
+	s.store(m)
+	return nil
+}
+
+// Set switches the mode on or off for every instance. ctx must carry a
+// service caller (db.AsService), the only one allowed to change the row; for
+// any other the update matches no row and Set returns ErrNotAllowed.
+func (s *State) Set(ctx context.Context, readOnly bool, message string) (Mode, error) {
+	m := Mode{ReadOnly: readOnly, Message: message}
+	err := s.pool.QueryRow(ctx, `
+		UPDATE maintenance_mode SET read_only = $1, message = NULLIF($2, ''), updated_at = NOW()
+		RETURNING updated_at`, readOnly, message).Scan(&m.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Mode{}, ErrNotAllowed
+	}
+	if err != nil {
+		return Mode{}, err
+	}
+	s.store(m)
+	slog.Warn("maintenance mode switched", "read_only", readOnly, "message", message)
+	return m, nil
+}
+
+func (s *State) store(m Mode) {
+	s.mode.Store(&m)
+	if m.ReadOnly {
+		readOnlyGauge.Set(1)
+	} else {
+		readOnlyGauge.Set(0)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"leave-management/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit tags the database changes of mutating requests with the client IP,
+// the endpoint and the request ID. audit_trigger_function records them, with
+// the authenticated caller, next to the before and after image of every row
+// the request changes, so each handler is audited the same way without
+// writing audit rows itself. It must run after RequestID.
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = c.Request.URL.Path
+		}
+		c.Request = c.Request.WithContext(db.WithRequestInfo(c.Request.Context(), db.RequestInfo{
+			IP:        c.ClientIP(),
+			Endpoint:  c.Request.Method + " " + endpoint,
+			RequestID: c.GetString("request_id"),
+		}))
+		c.Next()
+	}
+}
@@ -2,120 +2,288 @@ package middleware
 
 import (
 	"context"
-	"fmt"
-	"net/http"
-	"os"
+	"errors"
+	"log/slog"
 	"strings"
 	"time"
 
+	"leave-management/internal/apierror"
+	"leave-management/internal/apikey"
+	"leave-management/internal/cache"
+	"leave-management/internal/db"
+	"leave-management/internal/jwtkeys"
 	"leave-management/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type AuthMiddleware struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	cache     *cache.Cache
+	statusTTL time.Duration           // lifetime of cached user status, kept short as it gates every request
+	keys      func() *jwtkeys.Keyring // read per request, so rotated secrets apply on reload
 }
 
-func NewAuthMiddleware(pool *pgxpool.Pool) *AuthMiddleware {
-	return &AuthMiddleware{pool: pool}
+func NewAuthMiddleware(pool *pgxpool.Pool, rc *cache.Cache, statusTTL time.Duration, keys func() *jwtkeys.Keyring) *AuthMiddleware {
+	return &AuthMiddleware{pool: pool, cache: rc, statusTTL: statusTTL, keys: keys}
 }
 
-// JWT secret key (in production, use environment variable)
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-
-// Authenticate middleware validates JWT token and sets user context
+// Authenticate middleware validates JWT token and sets user context. A
+// request with an X-API-Key header is authenticated with the key instead.
 func (am *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(apikey.Header); key != "" {
+			am.authenticateAPIKey(c, key)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			apierror.Respond(c, apierror.Unauthenticated, "Authorization header required")
 			return
 		}
 
 		// Check if it's a Bearer token
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			apierror.Respond(c, apierror.InvalidToken, "Invalid authorization header format")
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate JWT token
-		token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return jwtSecret, nil
-		})
+		// Parse and validate JWT token, expiry, issuer and audience included
+		token, err := am.keys().Parse(tokenString, &models.JWTClaims{})
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			apierror.Respond(c, apierror.TokenExpired, "Token expired")
+			return
+		}
 
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "details": err.Error()})
-			c.Abort()
+			apierror.RespondWithDetails(c, apierror.InvalidToken, "Invalid token", err.Error())
 			return
 		}
 
 		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			apierror.Respond(c, apierror.InvalidToken, "Invalid token")
 			return
 		}
 
 		// Extract claims
 		claims, ok := token.Claims.(*models.JWTClaims)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
-
-		// Check if token is expired
-		if time.Now().Unix() > claims.Exp {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
-			c.Abort()
+			apierror.Respond(c, apierror.InvalidToken, "Invalid token claims")
 			return
 		}
 
 		// Verify user still exists and is active
-		var isActive bool
-		err = am.pool.QueryRow(context.Background(), 
-			"SELECT is_active FROM users WHERE id = $1 AND email = $2", 
-			claims.UserID, claims.Email).Scan(&isActive)
-		
+		user, err := am.userStatus(c.Request.Context(), claims.UserID)
+		if err == nil && (user.Email != claims.Email || user.OrgID != claims.OrgID) {
+			err = pgx.ErrNoRows
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			c.Abort()
+			apierror.Lookup(c, err, apierror.Unauthenticated, "User not found", "Failed to verify user")
 			return
 		}
 
-		if !isActive {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User account is deactivated"})
-			c.Abort()
+		if !user.IsActive {
+			apierror.Respond(c, apierror.AccountDeactivated, "User account is deactivated")
 			return
 		}
 
-		// Set user context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
-		c.Set("employee_id", claims.EmployeeID)
-
+		// Set user context. The role is the current one rather than the one
+		// the token was issued with, so a role change applies without a new login.
+		setCaller(c, claims.UserID, claims.EmployeeID, user)
 		c.Next()
 	}
 }
 
+// setCaller makes user the caller of the request
+func setCaller(c *gin.Context, userID, employeeID string, user userStatus) {
+	c.Set("user_id", userID)
+	c.Set("org_id", user.OrgID)
+	c.Set("email", user.Email)
+	c.Set("role", user.Role)
+	c.Set("employee_id", employeeID)
+	c.Set("employee_uuid", user.EmployeeUUID)
+
+	// from here on the database acts as this user: row level security
+	// shows the user's organization only and applies the role's limits,
+	// and the audit trail records the user as the author of every change
+	c.Request = c.Request.WithContext(db.WithClaims(c.Request.Context(), db.Claims{
+		UserID:       userID,
+		Role:         user.Role,
+		OrgID:        user.OrgID,
+		EmployeeID:   employeeID,
+		EmployeeUUID: user.EmployeeUUID,
+	}))
+}
+
+// apiKeyUseResolution is how often last_used_at of a key in use is moved
+const apiKeyUseResolution = time.Minute
+
+// authenticateAPIKey is Authenticate for a request with an API key. The key
+// acts as the user who created it, with their current role, but only on the
+// routes its scopes cover; it stops working with the user. Keys are looked
+// up on every request, so a revoked key is refused at once.
+func (am *AuthMiddleware) authenticateAPIKey(c *gin.Context, key string) {
+	ctx := c.Request.Context()
+	var id, orgID, userID string
+	var scopes []string
+	var expiresAt, lastUsedAt *time.Time
+	var active bool
+	err := am.pool.QueryRow(db.AsService(ctx), `
+		SELECT id, org_id, created_by, scopes, expires_at, last_used_at, is_active
+		FROM api_keys WHERE key_hash = $1`, apikey.Hash(key)).Scan(&id, &orgID, &userID, &scopes, &expiresAt,
+		&lastUsedAt, &active)
+	if err == nil && !active {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		apierror.Lookup(c, err, apierror.InvalidToken, "Invalid API key", "Failed to verify API key")
+		return
+	}
+	if expiresAt != nil && time.Now().After(*expiresAt) {
+		apierror.Respond(c, apierror.TokenExpired, "API key expired")
+		return
+	}
+
+	scope, ok := apikey.Scope(c.Request.Method, c.FullPath())
+	if !ok {
+		apierror.Respond(c, apierror.Forbidden, "Not available to API keys")
+		return
+	}
+	if !containsScope(scopes, scope) {
+		apierror.RespondWithDetails(c, apierror.Forbidden, "API key lacks the required scope", gin.H{"scope": scope})
+		return
+	}
+
+	user, err := am.userStatus(ctx, userID)
+	if err == nil && user.OrgID != orgID {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		apierror.Lookup(c, err, apierror.Unauthenticated, "User not found", "Failed to verify user")
+		return
+	}
+	if !user.IsActive {
+		apierror.Respond(c, apierror.AccountDeactivated, "User account is deactivated")
+		return
+	}
+
+	if lastUsedAt == nil || time.Since(*lastUsedAt) >= apiKeyUseResolution {
+		if _, err := am.pool.Exec(db.AsService(ctx), "UPDATE api_keys SET last_used_at = NOW() WHERE id = $1", id); err != nil {
+			// Log error but don't fail the request
+			slog.WarnContext(ctx, "failed to update API key last use", "error", err, "request_id", c.GetString("request_id"))
+		}
+	}
+
+	setCaller(c, userID, user.EmployeeID, user)
+	c.Set("api_key_id", id)
+	c.Next()
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type userStatus struct {
+	OrgID        string `json:"org_id"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	IsActive     bool   `json:"is_active"`
+	EmployeeID   string `json:"employee_id"`   // users.employee_id, the employee's code
+	EmployeeUUID string `json:"employee_uuid"` // employees.id, "" for a user without an employee record
+}
+
+// userStatus runs on every authenticated request, so it goes through the cache.
+// A user whose employee record or organization is deactivated counts as
+// inactive. The organization is not known yet, so the lookup runs as a service
+// request. Writers that deactivate an employee or change a role delete the
+// entry (see handlers.EmployeeHandler.forgetUserStatus); statusTTL bounds any
+// other drift.
+func (am *AuthMiddleware) userStatus(ctx context.Context, userID string) (userStatus, error) {
+	var u userStatus
+	if am.cache.Get(ctx, cache.UserKey(userID), &u) {
+		return u, nil
+	}
+	err := am.pool.QueryRow(db.AsService(ctx), `
+		SELECT u.org_id, u.email, u.role, u.is_active AND COALESCE(e.is_active, true) AND o.is_active,
+			u.employee_id, COALESCE(e.id::text, '')
+		FROM users u
+		JOIN organizations o ON o.id = u.org_id
+		LEFT JOIN employees e ON e.org_id = u.org_id AND e.employee_id = u.employee_id
+		WHERE u.id = $1`, userID).Scan(&u.OrgID, &u.Email, &u.Role, &u.IsActive, &u.EmployeeID, &u.EmployeeUUID)
+	if err != nil {
+		return u, err
+	}
+	am.cache.SetTTL(ctx, cache.UserKey(userID), u, am.statusTTL)
+	return u, nil
+}
+
+// employeeManager returns the manager_id of an employee ("" when it has none)
+// and whether the employee exists
+func (am *AuthMiddleware) employeeManager(ctx context.Context, employeeID string) (string, bool) {
+	var managerID string
+	if am.cache.Get(ctx, cache.EmployeeManagerKey(employeeID), &managerID) {
+		return managerID, true
+	}
+	var m *string
+	if err := am.pool.QueryRow(ctx, "SELECT manager_id FROM employees WHERE id = $1", employeeID).Scan(&m); err != nil {
+		return "", false
+	}
+	if m != nil {
+		managerID = *m
+	}
+	am.cache.Set(ctx, cache.EmployeeManagerKey(employeeID), managerID)
+	return managerID, true
+}
+
+// isApprovalDelegate reports whether a manager delegated their approvals to
+// the employee for today. It is not cached: delegations start, end and are
+// revoked at any time, and only callers whose role lacks the permission get
+// here.
+func (am *AuthMiddleware) isApprovalDelegate(ctx context.Context, employeeID string) bool {
+	if employeeID == "" {
+		return false
+	}
+	var delegate bool
+	err := am.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM approval_delegations
+			WHERE delegate_id = $1 AND revoked_at IS NULL AND CURRENT_DATE BETWEEN start_date AND end_date
+		)`, employeeID).Scan(&delegate)
+	return err == nil && delegate
+}
+
+// leaveRequestOwner returns the employee_id of a leave request and whether the
+// request exists. The owner never changes, so the cached value stays valid.
+func (am *AuthMiddleware) leaveRequestOwner(ctx context.Context, requestID string) (string, bool) {
+	var employeeID string
+	if am.cache.Get(ctx, cache.LeaveRequestOwnerKey(requestID), &employeeID) {
+		return employeeID, true
+	}
+	err := am.pool.QueryRow(ctx, "SELECT employee_id FROM leave_requests WHERE id = $1", requestID).Scan(&employeeID)
+	if err != nil {
+		return "", false
+	}
+	am.cache.Set(ctx, cache.LeaveRequestOwnerKey(requestID), employeeID)
+	return employeeID, true
+}
+
 // RequireRole middleware checks if user has the required role
 func (am *AuthMiddleware) RequireRole(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-			c.Abort()
+			apierror.Respond(c, apierror.Unauthenticated, "User not authenticated")
 			return
 		}
 
@@ -129,8 +297,7 @@ func (am *AuthMiddleware) RequireRole(requiredRoles ...string) gin.HandlerFunc {
 		}
 
 		if !hasRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-			c.Abort()
+			apierror.Respond(c, apierror.Forbidden, "Insufficient permissions")
 			return
 		}
 
@@ -138,20 +305,21 @@ func (am *AuthMiddleware) RequireRole(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
-// RequirePermission middleware checks if user has the required permission
+// RequirePermission middleware checks if user has the required permission,
+// through their role or, for the approval permissions, an active approval
+// delegation
 func (am *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-			c.Abort()
+			apierror.Respond(c, apierror.Unauthenticated, "User not authenticated")
 			return
 		}
 
 		role := userRole.(string)
-		if !models.HasPermission(role, permission) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-			c.Abort()
+		if !models.HasPermission(role, permission) &&
+			!(models.IsDelegable(permission) && am.isApprovalDelegate(c.Request.Context(), c.GetString("employee_uuid"))) {
+			apierror.Respond(c, apierror.Forbidden, "Insufficient permissions")
 			return
 		}
 
@@ -162,15 +330,16 @@ func (am *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
 // RequireOwnership middleware ensures user can only access their own data
 func (am *AuthMiddleware) RequireOwnership(resourceType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-			c.Abort()
+		if _, exists := c.Get("user_id"); !exists {
+			apierror.Respond(c, apierror.Unauthenticated, "User not authenticated")
 			return
 		}
 
 		userRole, _ := c.Get("role")
 		role := userRole.(string)
+		// resources belong to employees, so the caller is matched by their
+		// employees.id, not their users.id
+		self := c.GetString("employee_uuid")
 
 		// Admin and HR can access all data
 		if role == models.RoleAdmin || role == models.RoleHR {
@@ -180,7 +349,7 @@ func (am *AuthMiddleware) RequireOwnership(resourceType string) gin.HandlerFunc
 
 		// For managers, check if they're accessing their team's data
 		if role == models.RoleManager {
-			if am.canManagerAccessResource(c, userID.(string), resourceType) {
+			if self != "" && am.canManagerAccessResource(c, self, resourceType) {
 				c.Next()
 				return
 			}
@@ -188,14 +357,13 @@ func (am *AuthMiddleware) RequireOwnership(resourceType string) gin.HandlerFunc
 
 		// For employees, ensure they're accessing their own data
 		if role == models.RoleEmployee {
-			if am.canEmployeeAccessResource(c, userID.(string), resourceType) {
+			if self != "" && am.canEmployeeAccessResource(c, self, resourceType) {
 				c.Next()
 				return
 			}
 		}
 
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to this resource"})
-		c.Abort()
+		apierror.Respond(c, apierror.Forbidden, "Access denied to this resource")
 	}
 }
 
@@ -209,14 +377,12 @@ func (am *AuthMiddleware) canManagerAccessResource(c *gin.Context, managerID, re
 		}
 
 		// Check if the leave request belongs to a team member
-		var employeeID string
-		err := am.pool.QueryRow(context.Background(),
-			`SELECT lr.employee_id FROM leave_requests lr 
-			 JOIN employees e ON lr.employee_id = e.id 
-			 WHERE lr.id = $1 AND e.manager_id = $2`,
-			requestID, managerID).Scan(&employeeID)
-		
-		return err == nil
+		employeeID, ok := am.leaveRequestOwner(c.Request.Context(), requestID)
+		if !ok {
+			return false
+		}
+		manager, ok := am.employeeManager(c.Request.Context(), employeeID)
+		return ok && manager != "" && manager == managerID
 
 	case "employee":
 		employeeID := c.Param("id")
@@ -225,12 +391,8 @@ func (am *AuthMiddleware) canManagerAccessResource(c *gin.Context, managerID, re
 		}
 
 		// Check if the employee reports to this manager
-		var id string
-		err := am.pool.QueryRow(context.Background(),
-			"SELECT id FROM employees WHERE id = $1 AND manager_id = $2",
-			employeeID, managerID).Scan(&id)
-		
-		return err == nil
+		manager, ok := am.employeeManager(c.Request.Context(), employeeID)
+		return ok && manager != "" && manager == managerID
 
 	default:
 		return false
@@ -247,12 +409,8 @@ func (am *AuthMiddleware) canEmployeeAccessResource(c *gin.Context, employeeID,
 		}
 
 		// Check if the leave request belongs to this employee
-		var id string
-		err := am.pool.QueryRow(context.Background(),
-			"SELECT id FROM leave_requests WHERE id = $1 AND employee_id = $2",
-			requestID, employeeID).Scan(&id)
-		
-		return err == nil
+		owner, ok := am.leaveRequestOwner(c.Request.Context(), requestID)
+		return ok && owner == employeeID
 
 	case "employee":
 		paramEmployeeID := c.Param("id")
@@ -280,16 +438,21 @@ func (am *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 
 		// Try to authenticate, but don't fail if it doesn't work
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		token, err := am.keys().Parse(tokenString, &models.JWTClaims{})
 
 		if err == nil && token.Valid {
 			if claims, ok := token.Claims.(*models.JWTClaims); ok {
 				c.Set("user_id", claims.UserID)
+				c.Set("org_id", claims.OrgID)
 				c.Set("email", claims.Email)
 				c.Set("role", claims.Role)
 				c.Set("employee_id", claims.EmployeeID)
+				c.Request = c.Request.WithContext(db.WithClaims(c.Request.Context(), db.Claims{
+					UserID:     claims.UserID,
+					Role:       claims.Role,
+					OrgID:      claims.OrgID,
+					EmployeeID: claims.EmployeeID,
+				}))
 			}
 		}
 
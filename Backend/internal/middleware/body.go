@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestBody caps request bodies at limit() bytes and rejects bodies whose
+// Content-Type is not one of contentTypes. Requests without a body pass through.
+func RequestBody(limit func() int64, contentTypes ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(contentTypes))
+	for _, t := range contentTypes {
+		allowed[t] = true
+	}
+	return func(c *gin.Context) {
+		maxBytes := limit()
+		if c.Request.ContentLength > maxBytes {
+			apierror.Respond(c, apierror.PayloadTooLarge, fmt.Sprintf("request body must not exceed %d bytes", maxBytes))
+			return
+		}
+		if hasBody(c.Request) {
+			mt, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			if err != nil || !allowed[mt] {
+				apierror.Respond(c, apierror.UnsupportedMediaType, "content type must be one of: "+strings.Join(contentTypes, ", "))
+				return
+			}
+		}
+		// chunked bodies have no Content-Length, so the limit is also enforced while reading
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnvelopeHeader lets a client pick the response shape for a single request
+// while the API migrates to the {data, meta} envelope
+const EnvelopeHeader = "X-Response-Envelope"
+
+// ResponseEnvelope records whether handlers should wrap single-resource and
+// action responses as {"data": ...}. The server default, enabled(), comes from
+// RESPONSE_ENVELOPE; a valid X-Response-Envelope header (true/false) overrides it.
+func ResponseEnvelope(enabled func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		on := enabled()
+		if v, err := strconv.ParseBool(c.GetHeader(EnvelopeHeader)); err == nil {
+			on = v
+		}
+		c.Set("response_envelope", on)
+		c.Next()
+	}
+}
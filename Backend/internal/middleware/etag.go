@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagWriter holds the response body back so it can be hashed before sending
+type etagWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *etagWriter) Write(b []byte) (int, error)       { return w.body.Write(b) }
+func (w *etagWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+// ETag adds a strong ETag and the Cache-Control returned by cacheControl to
+// successful GET/HEAD responses and answers matching If-None-Match requests
+// with 304 Not Modified. Only use it on reference data: the whole body is
+// buffered.
+func ETag(cacheControl func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &etagWriter{ResponseWriter: original}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+
+		if w.Status() != http.StatusOK {
+			original.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(w.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		original.Header().Set("ETag", etag)
+		if cc := cacheControl(); cc != "" {
+			original.Header().Set("Cache-Control", cc)
+		}
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.Write(w.body.Bytes())
+	}
+}
+
+// etagMatches implements the weak comparison If-None-Match uses
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Feature hides the routes of an optional module behind enabled(), read per
+// request so the module can be switched on or off by a config reload. While
+// it is off the routes answer 404 as if they were not registered.
+func Feature(enabled func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled() {
+			apierror.Respond(c, apierror.NotFound, "not found")
+			return
+		}
+		c.Next()
+	}
+}
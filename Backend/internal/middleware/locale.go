@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"leave-management/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale picks the response language from Accept-Language. Error messages are
+// translated from it; codes and types stay the same in every language.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := i18n.Match(c.GetHeader("Accept-Language"))
+		c.Set("locale", lang)
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OperatorAuth admits requests carrying "Authorization: Bearer <token()>",
+// the credential of whoever runs the deployment, as opposed to the admins of
+// one organization. The handlers behind it act as a service request
+// (db.AsService), across organizations. While token() is empty every request
+// is refused, so the routes are off until OPERATOR_TOKEN is set.
+func OperatorAuth(token func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		want := token()
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if want == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			apierror.Respond(c, apierror.Unauthenticated, "operator token required")
+			return
+		}
+		c.Request = c.Request.WithContext(db.AsService(c.Request.Context()))
+		c.Next()
+	}
+}
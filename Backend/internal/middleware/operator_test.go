@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"leave-management/internal/jwtkeys"
+	"leave-management/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testOperatorToken = "operator-token-0123456789abcdef0123456789"
+
+// operatorEngine serves PUT /operator/maintenance behind OperatorAuth, as
+// router.Setup does
+func operatorEngine(token string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/operator/maintenance", OperatorAuth(func() string { return token }), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func switchMaintenance(r *gin.Engine, authorization string) int {
+	req := httptest.NewRequest("PUT", "/operator/maintenance", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestOperatorAuthRefusesOrgAdmin(t *testing.T) {
+	keys := jwtkeys.New(jwtkeys.Options{Secret: "jwt-secret-0123456789abcdef0123456789ab", Issuer: "lms", Audience: "lms", TTL: time.Hour})
+	adminToken, err := keys.Sign(models.JWTClaims{UserID: "u1", OrgID: "org-1", Role: models.RoleAdmin, RegisteredClaims: keys.Registered("u1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := operatorEngine(testOperatorToken)
+	if code := switchMaintenance(r, "Bearer "+adminToken); code != http.StatusUnauthorized {
+		t.Fatalf("an organization admin's token = %d, want 401", code)
+	}
+	if code := switchMaintenance(r, ""); code != http.StatusUnauthorized {
+		t.Fatalf("no token = %d, want 401", code)
+	}
+	if code := switchMaintenance(r, "Bearer "+testOperatorToken); code != http.StatusNoContent {
+		t.Fatalf("operator token = %d, want 204", code)
+	}
+}
+
+func TestOperatorAuthOffWithoutToken(t *testing.T) {
+	r := operatorEngine("")
+	for _, auth := range []string{"", "Bearer ", "Bearer " + testOperatorToken} {
+		if code := switchMaintenance(r, auth); code != http.StatusUnauthorized {
+			t.Fatalf("Authorization %q with no OPERATOR_TOKEN = %d, want 401", auth, code)
+		}
+	}
+}
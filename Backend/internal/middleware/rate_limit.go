@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimits are the attempts a client may make on a rate-limited route per
+// window; a limit of 0 disables it
+type RateLimits struct {
+	PerIP      int
+	PerAccount int
+	Window     time.Duration
+}
+
+// RateLimiter throttles sign-in style routes with fixed windows counted per
+// client IP and per account, the email in the JSON body. The counters are
+// kept in Redis when it is configured, so the limits hold across instances,
+// and in memory otherwise or while Redis is unreachable.
+type RateLimiter struct {
+	cache  *cache.Cache
+	limits func() RateLimits
+	local  *windowCounter
+}
+
+func NewRateLimiter(rc *cache.Cache, limits func() RateLimits) *RateLimiter {
+	return &RateLimiter{cache: rc, limits: limits, local: &windowCounter{windows: map[string]fixedWindow{}}}
+}
+
+// Limit counts the request against the limits of route and answers 429 with
+// a Retry-After header, without running the handler, once either is exceeded.
+// Every attempt counts, successful or not.
+func (l *RateLimiter) Limit(route string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limits := l.limits()
+		if limits.Window <= 0 {
+			c.Next()
+			return
+		}
+		if limits.PerIP > 0 && !l.allow(c, cache.RateLimitKey(route, "ip:"+c.ClientIP()), limits.PerIP, limits.Window) {
+			return
+		}
+		if limits.PerAccount > 0 {
+			if email := peekEmail(c); email != "" {
+				// hashed so that Redis holds no email addresses
+				sum := sha256.Sum256([]byte(email))
+				if !l.allow(c, cache.RateLimitKey(route, "account:"+hex.EncodeToString(sum[:])), limits.PerAccount, limits.Window) {
+					return
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// allow counts a hit on key and, past limit, responds 429 and reports false
+func (l *RateLimiter) allow(c *gin.Context, key string, limit int, window time.Duration) bool {
+	n, ttl, ok := l.cache.Incr(c.Request.Context(), key, window)
+	if !ok {
+		n, ttl = l.local.incr(key, window, time.Now())
+	}
+	if n <= int64(limit) {
+		return true
+	}
+	if ttl <= 0 {
+		ttl = window
+	}
+	retry := int(math.Ceil(ttl.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retry))
+	apierror.Respond(c, apierror.TooManyAttempts, fmt.Sprintf("too many attempts; try again in %d seconds", retry))
+	return false
+}
+
+// peekEmail returns the lower-cased email of a JSON body and leaves the body
+// in place for the handler. Bodies that are not JSON objects have none.
+func peekEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	var body struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(data, &body) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
+
+type fixedWindow struct {
+	hits  int64
+	reset time.Time
+}
+
+// windowCounter is the in-memory fallback for the Redis counters
+type windowCounter struct {
+	mu      sync.Mutex
+	windows map[string]fixedWindow
+	swept   time.Time
+}
+
+// incr counts a hit on key at now and returns the count of its window and the
+// time left in it. Expired windows are dropped once per window length.
+func (w *windowCounter) incr(key string, window time.Duration, now time.Time) (int64, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Sub(w.swept) >= window {
+		for k, fw := range w.windows {
+			if !now.Before(fw.reset) {
+				delete(w.windows, k)
+			}
+		}
+		w.swept = now
+	}
+	fw, ok := w.windows[key]
+	if !ok || !now.Before(fw.reset) {
+		fw = fixedWindow{reset: now.Add(window)}
+	}
+	fw.hits++
+	w.windows[key] = fw
+	return fw.hits, fw.reset.Sub(now)
+}
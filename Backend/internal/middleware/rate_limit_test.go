@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitedEngine serves /auth/login behind a per-IP limit of 2, trusting
+// the given proxies as router.Setup does with TRUSTED_PROXIES
+func rateLimitedEngine(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	limiter := NewRateLimiter(nil, func() RateLimits { return RateLimits{PerIP: 2, Window: time.Minute} })
+	r.POST("/auth/login", limiter.Limit("login"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return r
+}
+
+func login(r *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest("POST", "/auth/login", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	r := rateLimitedEngine(t, nil)
+	for i, xff := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		code := login(r, "203.0.113.7:40000", xff)
+		if i < 2 && code != http.StatusNoContent {
+			t.Fatalf("attempt %d = %d, want 204", i+1, code)
+		}
+		if i == 2 && code != http.StatusTooManyRequests {
+			t.Fatalf("attempt %d with a new X-Forwarded-For = %d, want 429", i+1, code)
+		}
+	}
+}
+
+func TestRateLimitUsesForwardedForFromTrustedProxy(t *testing.T) {
+	r := rateLimitedEngine(t, []string{"10.0.0.0/8"})
+	for i := 0; i < 2; i++ {
+		if code := login(r, "10.0.0.5:40000", "198.51.100.1"); code != http.StatusNoContent {
+			t.Fatalf("attempt %d = %d, want 204", i+1, code)
+		}
+	}
+	if code := login(r, "10.0.0.5:40000", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("third attempt from the same client = %d, want 429", code)
+	}
+	// another client behind the same proxy has its own counter
+	if code := login(r, "10.0.0.5:40000", "198.51.100.2"); code != http.StatusNoContent {
+		t.Fatalf("other client = %d, want 204", code)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"leave-management/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnly refuses changes with a 503 while readOnly() reports the
+// maintenance mode on, read per request. Safe methods always pass, and so do
+// the exempt routes: those that only read despite their method, the ones
+// needed to sign in, and the switch itself.
+func ReadOnly(readOnly func() (bool, string), exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if on, message := readOnly(); on && !slices.Contains(exempt, c.FullPath()) {
+			apierror.Respond(c, apierror.ReadOnly, message)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/errreport"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic in a handler into a 500 with the standard error
+// envelope, logs it with its stack trace and reports it to errreport. The
+// client only sees the request ID, which ties its response to the log entry.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// net/http uses this panic to abort a response on purpose
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			ctx := c.Request.Context()
+			requestID := c.GetString("request_id")
+			slog.ErrorContext(ctx, "panic recovered",
+				"panic", fmt.Sprint(r),
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"request_id", requestID,
+				"stack", string(debug.Stack()),
+			)
+			errreport.Panic(ctx, r, map[string]string{
+				"request_id": requestID,
+				"method":     c.Request.Method,
+				"route":      c.FullPath(),
+			})
+			if c.Writer.Written() {
+				// the status line is already out; all we can do is stop
+				c.Abort()
+				return
+			}
+			apierror.Respond(c, apierror.Internal, "internal server error")
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"leave-management/internal/apierror"
+	"leave-management/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout gives every request a deadline of timeout(), read per request so it
+// can be reloaded. Database calls made on the request context are cancelled
+// once it passes, so a slow query releases its connection; a request that ran
+// out of time without responding gets a 504. A non-positive value disables the
+// deadline. The export routes stream a whole result set, which takes longer
+// than other requests, and get exportTimeout() instead; streaming routes, which
+// stay open by design, are exempt.
+func Timeout(timeout, exportTimeout func() time.Duration, exports []string, streaming ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(streaming, c.FullPath()) {
+			c.Next()
+			return
+		}
+		d := timeout()
+		if slices.Contains(exports, c.FullPath()) {
+			d = exportTimeout()
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			apierror.Respond(c, apierror.Timeout, "request timed out")
+		}
+	}
+}
+
+// QueryTimeout limits each database statement a request runs to timeout(),
+// read per request, so one slow query answers 504 "query timed out" rather
+// than holding its connection until the request deadline. A non-positive
+// value keeps the pool's DB_STATEMENT_TIMEOUT. The exempt routes run one long
+// statement by design (the CSV exports, the event stream).
+func QueryTimeout(timeout func() time.Duration, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d := timeout(); d > 0 && !slices.Contains(exempt, c.FullPath()) {
+			c.Request = c.Request.WithContext(db.WithQueryTimeout(c.Request.Context(), d))
+		}
+		c.Next()
+	}
+}
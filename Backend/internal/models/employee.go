@@ -20,3 +20,14 @@ type Employee struct {
 	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// Attendance statuses for the optional attendance module
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+)
+
+// IsValidAttendanceStatus checks if the attendance status is valid
+func IsValidAttendanceStatus(status string) bool {
+	return status == AttendancePresent || status == AttendanceAbsent
+}
@@ -2,11 +2,14 @@ package models
 
 import (
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // User represents an authenticated user in the system
 type User struct {
 	ID           string    `json:"id" db:"id"`
+	OrgID        string    `json:"org_id" db:"org_id"`
 	EmployeeID   string    `json:"employee_id" db:"employee_id"`
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"` // Never expose in JSON
@@ -41,14 +44,15 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
-// JWTClaims represents the JWT token claims
+// JWTClaims represents the JWT token claims. The subject is the user ID;
+// expiry, issuer and audience are checked by the jwt library.
 type JWTClaims struct {
-	UserID   string `json:"user_id"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
+	UserID     string `json:"user_id"`
+	OrgID      string `json:"org_id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
 	EmployeeID string `json:"employee_id"`
-	Exp      int64  `json:"exp"`
-	Iat      int64  `json:"iat"`
+	jwt.RegisteredClaims
 }
 
 // User roles constants
@@ -100,3 +104,14 @@ func HasPermission(role, action string) bool {
 		return false
 	}
 }
+
+// IsDelegable checks if an action also passes to whoever a manager delegated
+// their approvals to, whatever the delegate's role
+func IsDelegable(action string) bool {
+	switch action {
+	case "approve_team_requests", "reject_team_requests":
+		return true
+	default:
+		return false
+	}
+}
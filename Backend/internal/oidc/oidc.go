@@ -0,0 +1,319 @@
+// Package oidc signs users in with Google or Microsoft Entra ID (Azure AD)
+// through the OpenID Connect authorization code flow, with PKCE.
+//
+// GET /auth/oidc/login sends the browser to the provider at AuthURL, which
+// sends it back to the deployment's redirect URL with a code and the state.
+// That page posts both to POST /auth/oidc/callback, where Exchange trades the
+// code for an ID token and returns the identity the token vouches for. Which
+// user that is, is up to the caller: see handlers.OIDCHandler.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"leave-management/internal/jwks"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Providers
+const (
+	Google = "google"
+	Azure  = "azure"
+)
+
+// timeout is the longest a call to a provider may take
+const timeout = 10 * time.Second
+
+// Config registers the deployment with the providers; one without a client
+// ID is off
+type Config struct {
+	RedirectURL string // where the providers send the browser back to
+
+	GoogleClientID     string
+	GoogleClientSecret string
+	// Google Workspace domains (the hd claim) allowed to sign in; empty
+	// allows any Google account with a verified email address
+	GoogleHostedDomains []string
+
+	AzureClientID     string
+	AzureClientSecret string
+	AzureTenants      []string // directory (tenant) IDs allowed to sign in
+	// email domains verified in those tenants: their addresses are trusted
+	// without the xms_edov claim
+	AzureVerifiedDomains []string
+}
+
+// Identity is who signed in, as the provider's ID token says
+type Identity struct {
+	Provider string
+	Subject  string // the provider's ID of the account, stable for our client
+	Email    string
+	// EmailVerified is set when the provider vouches that Email belongs to
+	// the account's owner; only then may the account be linked by address
+	EmailVerified bool
+}
+
+// provider is one registration
+type provider struct {
+	clientID     string
+	clientSecret string
+	authURL      string
+	tokenURL     string
+	keys         *jwks.Set
+	// identity checks the provider-specific claims of a verified ID token
+	// and returns the email address in it, and whether it is verified
+	identity func(claims jwt.MapClaims) (string, bool, error)
+}
+
+// Providers are the providers the deployment is registered with
+type Providers struct {
+	redirectURL string
+	http        *http.Client
+	byName      map[string]*provider
+}
+
+// New returns the providers cfg registers
+func New(cfg Config) *Providers {
+	client := &http.Client{Timeout: timeout}
+	p := &Providers{redirectURL: cfg.RedirectURL, http: client, byName: map[string]*provider{}}
+	if cfg.GoogleClientID != "" {
+		p.byName[Google] = &provider{
+			clientID:     cfg.GoogleClientID,
+			clientSecret: cfg.GoogleClientSecret,
+			authURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+			tokenURL:     "https://oauth2.googleapis.com/token",
+			keys:         jwks.New(client, "https://www.googleapis.com/oauth2/v3/certs"),
+			identity:     googleIdentity(cfg.GoogleHostedDomains),
+		}
+	}
+	if cfg.AzureClientID != "" {
+		// a single tenant signs in at its own endpoints, several at the
+		// endpoints shared by all work and school accounts
+		tenant := "organizations"
+		if len(cfg.AzureTenants) == 1 {
+			tenant = cfg.AzureTenants[0]
+		}
+		base := "https://login.microsoftonline.com/" + tenant
+		p.byName[Azure] = &provider{
+			clientID:     cfg.AzureClientID,
+			clientSecret: cfg.AzureClientSecret,
+			authURL:      base + "/oauth2/v2.0/authorize",
+			tokenURL:     base + "/oauth2/v2.0/token",
+			keys:         jwks.New(client, base+"/discovery/v2.0/keys"),
+			identity:     azureIdentity(cfg.AzureTenants, cfg.AzureVerifiedDomains),
+		}
+	}
+	return p
+}
+
+// Names lists the providers that are on
+func (p *Providers) Names() []string {
+	names := make([]string, 0, len(p.byName))
+	for name := range p.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled reports whether provider name is on
+func (p *Providers) Enabled(name string) bool {
+	_, ok := p.byName[name]
+	return ok
+}
+
+// Secret returns a random value for a state, nonce or PKCE code verifier
+func Secret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthURL is where the browser signs in with provider name. The provider
+// sends back state unchanged, puts nonce into the ID token and wants the
+// verifier when the code is exchanged.
+func (p *Providers) AuthURL(name, state, nonce, verifier string) (string, error) {
+	pr, ok := p.byName[name]
+	if !ok {
+		return "", fmt.Errorf("provider %q is not configured", name)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {pr.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"prompt":                {"select_account"},
+	}
+	return pr.authURL + "?" + q.Encode(), nil
+}
+
+// Exchange trades the code the provider sent back for an ID token, checks
+// the token's signature, audience, expiry and nonce, and returns the identity
+// in it
+func (p *Providers) Exchange(ctx context.Context, name, code, verifier, nonce string) (Identity, error) {
+	pr, ok := p.byName[name]
+	if !ok {
+		return Identity{}, fmt.Errorf("provider %q is not configured", name)
+	}
+	raw, err := p.idToken(ctx, pr, code, verifier)
+	if err != nil {
+		return Identity{}, err
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return pr.keys.Key(ctx, kid)
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(pr.clientID), jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute))
+	if err != nil {
+		return Identity{}, fmt.Errorf("id token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return Identity{}, errors.New("id token: nonce mismatch")
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Identity{}, errors.New("id token: no subject")
+	}
+	email, verified, err := pr.identity(claims)
+	if err != nil {
+		return Identity{}, fmt.Errorf("id token: %w", err)
+	}
+	return Identity{Provider: name, Subject: subject, Email: email, EmailVerified: verified}, nil
+}
+
+// maxTokenResponse caps what is read of the token endpoint's answer
+const maxTokenResponse = 64 << 10
+
+// idToken calls the token endpoint
+func (p *Providers) idToken(ctx context.Context, pr *provider, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {pr.clientID},
+		"client_secret": {pr.clientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pr.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxTokenResponse)).Decode(&out); err != nil {
+		return "", fmt.Errorf("token endpoint: HTTP %d", resp.StatusCode)
+	}
+	switch {
+	case out.Error != "":
+		return "", fmt.Errorf("token endpoint: %s: %s", out.Error, out.Description)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("token endpoint: HTTP %d", resp.StatusCode)
+	case out.IDToken == "":
+		return "", errors.New("token endpoint: no id_token")
+	}
+	return out.IDToken, nil
+}
+
+// googleIdentity accepts tokens of Google's issuer with a verified email
+// address, of one of domains when there are any
+func googleIdentity(domains []string) func(jwt.MapClaims) (string, bool, error) {
+	return func(claims jwt.MapClaims) (string, bool, error) {
+		if iss, _ := claims["iss"].(string); iss != "https://accounts.google.com" && iss != "accounts.google.com" {
+			return "", false, errors.New("not issued by Google")
+		}
+		if !isTrue(claims["email_verified"]) {
+			return "", false, errors.New("email address not verified")
+		}
+		if len(domains) > 0 {
+			hd, _ := claims["hd"].(string)
+			if !containsFold(domains, hd) {
+				return "", false, errors.New("account not in an allowed Google Workspace domain")
+			}
+		}
+		email, _ := claims["email"].(string)
+		if email == "" {
+			return "", false, errors.New("no email address")
+		}
+		return email, true, nil
+	}
+}
+
+// azureIdentity accepts tokens of one of tenants. The email claim is optional
+// in Entra ID, so without it the sign-in name is taken, which tenants use as
+// the email address by convention.
+//
+// Neither is verified: a tenant's admins, or the users of some tenants, can
+// set them to any address. The address counts as verified only when the
+// token says its domain is verified by the tenant (the optional xms_edov
+// claim, which covers the email claim), or when its domain is one of
+// verifiedDomains.
+func azureIdentity(tenants, verifiedDomains []string) func(jwt.MapClaims) (string, bool, error) {
+	return func(claims jwt.MapClaims) (string, bool, error) {
+		tid, _ := claims["tid"].(string)
+		if !containsFold(tenants, tid) {
+			return "", false, errors.New("tenant not allowed")
+		}
+		if iss, _ := claims["iss"].(string); iss != "https://login.microsoftonline.com/"+tid+"/v2.0" {
+			return "", false, errors.New("not issued by the tenant")
+		}
+		email, _ := claims["email"].(string)
+		verified := email != "" && isTrue(claims["xms_edov"])
+		if email == "" {
+			if name, _ := claims["preferred_username"].(string); strings.Contains(name, "@") {
+				email = name
+			}
+		}
+		if email == "" {
+			return "", false, errors.New("no email address")
+		}
+		if _, domain, ok := strings.Cut(email, "@"); ok && containsFold(verifiedDomains, domain) {
+			verified = true
+		}
+		return email, verified, nil
+	}
+}
+
+// isTrue reads a boolean claim, which some tokens carry as a string
+func isTrue(v interface{}) bool {
+	return v == true || v == "true" || v == "1"
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if s != "" && strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package oidc
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testTenant = "7d3c1c2e-0000-4000-8000-000000000001"
+
+func azureClaims(extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{"tid": testTenant, "iss": "https://login.microsoftonline.com/" + testTenant + "/v2.0"}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return claims
+}
+
+func TestAzureIdentityVerification(t *testing.T) {
+	identity := azureIdentity([]string{testTenant}, []string{"corp.example.com"})
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		email    string
+		verified bool
+	}{
+		{"email without xms_edov", azureClaims(jwt.MapClaims{"email": "ceo@example.com"}), "ceo@example.com", false},
+		{"email with xms_edov false", azureClaims(jwt.MapClaims{"email": "ceo@example.com", "xms_edov": false}), "ceo@example.com", false},
+		{"email with xms_edov", azureClaims(jwt.MapClaims{"email": "ceo@example.com", "xms_edov": true}), "ceo@example.com", true},
+		{"email with xms_edov as a string", azureClaims(jwt.MapClaims{"email": "ceo@example.com", "xms_edov": "1"}), "ceo@example.com", true},
+		{"sign-in name", azureClaims(jwt.MapClaims{"preferred_username": "ceo@example.com"}), "ceo@example.com", false},
+		// xms_edov covers the email claim, not the sign-in name
+		{"sign-in name with xms_edov", azureClaims(jwt.MapClaims{"preferred_username": "ceo@example.com", "xms_edov": true}), "ceo@example.com", false},
+		{"verified domain", azureClaims(jwt.MapClaims{"email": "ceo@Corp.Example.com"}), "ceo@Corp.Example.com", true},
+		{"sign-in name in a verified domain", azureClaims(jwt.MapClaims{"preferred_username": "ceo@corp.example.com"}), "ceo@corp.example.com", true},
+		{"subdomain of a verified domain", azureClaims(jwt.MapClaims{"email": "ceo@evil.corp.example.com"}), "ceo@evil.corp.example.com", false},
+	}
+	for _, tt := range tests {
+		email, verified, err := identity(tt.claims)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if email != tt.email || verified != tt.verified {
+			t.Errorf("%s: got %q, verified %v; want %q, verified %v", tt.name, email, verified, tt.email, tt.verified)
+		}
+	}
+}
+
+func TestAzureIdentityRejects(t *testing.T) {
+	identity := azureIdentity([]string{testTenant}, nil)
+	tests := map[string]jwt.MapClaims{
+		"other tenant": {"tid": "other", "iss": "https://login.microsoftonline.com/other/v2.0", "email": "a@example.com"},
+		"other issuer": {"tid": testTenant, "iss": "https://evil.example.com/v2.0", "email": "a@example.com"},
+		"no address":   azureClaims(jwt.MapClaims{"preferred_username": "not-an-address"}),
+	}
+	for name, claims := range tests {
+		if _, _, err := identity(claims); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestGoogleIdentityNeedsVerifiedEmail(t *testing.T) {
+	identity := googleIdentity(nil)
+	if _, _, err := identity(jwt.MapClaims{"iss": "https://accounts.google.com", "email": "a@example.com", "email_verified": false}); err == nil {
+		t.Fatal("unverified Google address accepted")
+	}
+	email, verified, err := identity(jwt.MapClaims{"iss": "https://accounts.google.com", "email": "a@example.com", "email_verified": true})
+	if err != nil || email != "a@example.com" || !verified {
+		t.Fatalf("verified Google address = %q, %v, %v", email, verified, err)
+	}
+}
@@ -0,0 +1,150 @@
+package reports
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"leave-management/internal/db"
+)
+
+// agingBuckets are the lower bounds, in whole days since the request was
+// applied for, of the age buckets after the first (0-1 days)
+var agingBuckets = []int{2, 4, 8, 15, 31}
+
+// AgeBucket counts the pending requests of one age range; MaxDays is null
+// for the last, open-ended one
+type AgeBucket struct {
+	Label    string `json:"label"`
+	MinDays  int    `json:"min_days"`
+	MaxDays  *int   `json:"max_days"`
+	Requests int    `json:"requests"`
+}
+
+// StageAging is the age of the requests waiting at one approval stage
+type StageAging struct {
+	ApprovalStage  string  `json:"approval_stage"` // pending_manager or pending_hr
+	Requests       int     `json:"requests"`
+	AverageAgeDays float64 `json:"average_age_days"`
+	MedianAgeDays  float64 `json:"median_age_days"`
+	OldestAgeDays  int     `json:"oldest_age_days"`
+}
+
+// OldRequest is one of the longest waiting requests
+type OldRequest struct {
+	LeaveRequestID string    `json:"leave_request_id"`
+	EmployeeID     string    `json:"employee_id"`
+	EmployeeName   string    `json:"employee_name"`
+	LeaveTypeName  string    `json:"leave_type_name"`
+	StartDate      string    `json:"start_date"`
+	AppliedAt      time.Time `json:"applied_at"`
+	AgeDays        int       `json:"age_days"`
+	ApprovalStage  string    `json:"approval_stage"`
+	// days until the leave starts; negative once it has started undecided
+	StartsInDays int `json:"starts_in_days"`
+}
+
+// PendingAging is how long the pending leave requests have been waiting
+type PendingAging struct {
+	Total          int          `json:"total"`
+	AverageAgeDays float64      `json:"average_age_days"`
+	MedianAgeDays  float64      `json:"median_age_days"`
+	Buckets        []AgeBucket  `json:"buckets"`
+	ByStage        []StageAging `json:"by_stage"`
+	Oldest         []OldRequest `json:"oldest"`
+}
+
+// pendingAges lists the pending requests, narrowed to department $1, with
+// their age in whole days and the stage they wait at; requests without
+// approval steps wait for a manager
+const pendingAges = `
+	WITH p AS (
+		SELECT lr.id, lr.employee_id, e.name AS employee_name, lt.name AS leave_type_name, lr.start_date, lr.applied_at,
+		       CURRENT_DATE - lr.applied_at::DATE AS age,
+		       COALESCE(leave_request_stage(lr.id), 'pending_manager') AS stage
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		JOIN leave_types lt ON lt.id = lr.leave_type_id
+		WHERE lr.status = 'pending'
+		  AND ($1 = '' OR e.department_id = NULLIF($1, '')::UUID)
+	)`
+
+// PendingApprovalsAging reports the age of the pending requests, narrowed by
+// f's department, with the oldest limit of them
+func PendingApprovalsAging(ctx context.Context, q db.Querier, f Filter, limit int) (PendingAging, error) {
+	res := PendingAging{Buckets: make([]AgeBucket, len(agingBuckets)+1), ByStage: []StageAging{}, Oldest: []OldRequest{}}
+	for i := range res.Buckets {
+		b := &res.Buckets[i]
+		if i > 0 {
+			b.MinDays = agingBuckets[i-1]
+		}
+		if i < len(agingBuckets) {
+			max := agingBuckets[i] - 1
+			b.MaxDays = &max
+			b.Label = strconv.Itoa(b.MinDays) + "-" + strconv.Itoa(max)
+		} else {
+			b.Label = strconv.Itoa(b.MinDays) + "+"
+		}
+	}
+
+	// the overall row, one per stage and one per bucket
+	rows, err := q.Query(ctx, pendingAges+`
+		SELECT GROUPING(stage) = 0, GROUPING(bucket) = 0, COALESCE(stage, ''), COALESCE(bucket, 0),
+		       COUNT(*), COALESCE(AVG(age), 0)::FLOAT8,
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY age::FLOAT8), 0)::FLOAT8, COALESCE(MAX(age), 0)
+		FROM (SELECT stage, age, width_bucket(age, $2::INT[]) AS bucket FROM p) a
+		GROUP BY GROUPING SETS ((stage), (bucket), ())`, f.DepartmentID, agingBuckets)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var byStage, byBucket bool
+		var stage string
+		var bucket, n, oldest int
+		var avg, median float64
+		if err := rows.Scan(&byStage, &byBucket, &stage, &bucket, &n, &avg, &median, &oldest); err != nil {
+			return res, err
+		}
+		switch {
+		case byStage:
+			res.ByStage = append(res.ByStage, StageAging{ApprovalStage: stage, Requests: n,
+				AverageAgeDays: round2(avg), MedianAgeDays: median, OldestAgeDays: oldest})
+		case byBucket:
+			if bucket >= 0 && bucket < len(res.Buckets) {
+				res.Buckets[bucket].Requests = n
+			}
+		default:
+			res.Total, res.AverageAgeDays, res.MedianAgeDays = n, round2(avg), median
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	rows, err = q.Query(ctx, pendingAges+`
+		SELECT id, employee_id, employee_name, leave_type_name, start_date, applied_at, age, stage, start_date - CURRENT_DATE
+		FROM p
+		ORDER BY applied_at, id
+		LIMIT $2`, f.DepartmentID, limit)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r OldRequest
+		var start time.Time
+		if err := rows.Scan(&r.LeaveRequestID, &r.EmployeeID, &r.EmployeeName, &r.LeaveTypeName, &start, &r.AppliedAt,
+			&r.AgeDays, &r.ApprovalStage, &r.StartsInDays); err != nil {
+			return res, err
+		}
+		r.StartDate = start.Format("2006-01-02")
+		res.Oldest = append(res.Oldest, r)
+	}
+	return res, rows.Err()
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
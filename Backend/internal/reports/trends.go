@@ -0,0 +1,132 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"leave-management/internal/db"
+)
+
+// MonthAbsence is the absence of one month. The rate relates the days of
+// approved leave to the working days (weekdays that are not holidays) of the
+// headcount.
+type MonthAbsence struct {
+	Month              string   `json:"month"` // YYYY-MM
+	Headcount          int      `json:"headcount"`
+	WorkingDays        int      `json:"working_days"`
+	AbsenceDays        float64  `json:"absence_days"`
+	EmployeesOnLeave   int      `json:"employees_on_leave"`
+	AbsenceRatePercent *float64 `json:"absence_rate_percent"` // null without headcount or working days
+	// change of the rate from the month before, in percentage points
+	RateChange *float64 `json:"rate_change"`
+}
+
+// WeekdayAbsence is the absence falling on one day of the week over the range
+type WeekdayAbsence struct {
+	Weekday     string  `json:"weekday"`
+	AbsenceDays float64 `json:"absence_days"`
+}
+
+// AbsenceTrends is the absence of a range of months
+type AbsenceTrends struct {
+	From      string           `json:"from"`
+	To        string           `json:"to"`
+	Months    []MonthAbsence   `json:"months"`
+	ByWeekday []WeekdayAbsence `json:"by_weekday"`
+}
+
+var weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// Absence reports the absence of every month from the month of from to the
+// month of to, narrowed by f's department. Each approved request counts its
+// total_days spread evenly over its dates. The headcount of a month is the
+// active employees who had joined by its end.
+func Absence(ctx context.Context, q db.Querier, from, to time.Time, f Filter) (AbsenceTrends, error) {
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(to.Year(), to.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	res := AbsenceTrends{From: first.Format("2006-01"), To: last.Format("2006-01"), Months: []MonthAbsence{}, ByWeekday: make([]WeekdayAbsence, 7)}
+	for i, d := range weekdays {
+		res.ByWeekday[i].Weekday = d
+	}
+
+	index := map[string]int{}
+	rows, err := q.Query(ctx, `
+		SELECT m::DATE,
+		       (SELECT COUNT(*) FROM employees e
+		        WHERE e.is_active AND e.joining_date < (m + INTERVAL '1 month')::DATE
+		          AND ($3 = '' OR e.department_id = NULLIF($3, '')::UUID)),
+		       (SELECT COUNT(*) FROM generate_series(m, m + INTERVAL '1 month' - INTERVAL '1 day', INTERVAL '1 day') d
+		        WHERE EXTRACT(ISODOW FROM d) < 6
+		          AND NOT EXISTS (SELECT 1 FROM holidays h WHERE h.holiday_date = d::DATE))
+		FROM generate_series($1::DATE, $2::DATE, INTERVAL '1 month') m
+		ORDER BY m`, first, last, f.DepartmentID)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var month time.Time
+		var m MonthAbsence
+		if err := rows.Scan(&month, &m.Headcount, &m.WorkingDays); err != nil {
+			return res, err
+		}
+		m.Month = month.Format("2006-01")
+		index[m.Month] = len(res.Months)
+		res.Months = append(res.Months, m)
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	// one row per month and one per day of the week, told apart by which of
+	// the two is NULL
+	rows, err = q.Query(ctx, `
+		SELECT date_trunc('month', d)::DATE, EXTRACT(ISODOW FROM d)::INT,
+		       SUM(lr.total_days / (lr.end_date - lr.start_date + 1))::FLOAT8, COUNT(DISTINCT lr.employee_id)
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		CROSS JOIN generate_series(GREATEST(lr.start_date, $1::DATE), LEAST(lr.end_date, $2::DATE), INTERVAL '1 day') d
+		WHERE lr.status = 'approved' AND lr.start_date <= $2 AND lr.end_date >= $1
+		  AND ($3 = '' OR e.department_id = NULLIF($3, '')::UUID)
+		GROUP BY GROUPING SETS ((date_trunc('month', d)), (EXTRACT(ISODOW FROM d)))`, first, last, f.DepartmentID)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var month *time.Time
+		var dow *int
+		var days float64
+		var employees int
+		if err := rows.Scan(&month, &dow, &days, &employees); err != nil {
+			return res, err
+		}
+		switch {
+		case month != nil:
+			if i, ok := index[month.Format("2006-01")]; ok {
+				res.Months[i].AbsenceDays = round2(days)
+				res.Months[i].EmployeesOnLeave = employees
+			}
+		case dow != nil && *dow >= 1 && *dow <= 7:
+			res.ByWeekday[*dow-1].AbsenceDays = round2(days)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	for i := range res.Months {
+		m := &res.Months[i]
+		m.AbsenceRatePercent = percent(m.AbsenceDays, float64(m.Headcount*m.WorkingDays))
+		if i > 0 && m.AbsenceRatePercent != nil && res.Months[i-1].AbsenceRatePercent != nil {
+			change := round2(*m.AbsenceRatePercent - *res.Months[i-1].AbsenceRatePercent)
+			m.RateChange = &change
+		}
+	}
+	return res, nil
+}
+
+func monthLabel(year, month int) string {
+	return fmt.Sprintf("%04d-%02d", year, month)
+}
@@ -0,0 +1,149 @@
+// Package reports computes the leave analytics served under /reports with
+// aggregate SQL, so the database does the counting and only the totals travel.
+// Approved leave is attributed as in the other reports: to the year and month
+// it starts in, except for the absence trends, which spread each request's
+// days evenly over its dates.
+package reports
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"leave-management/internal/db"
+)
+
+// Filter narrows a report to one department or leave type; empty fields
+// match all
+type Filter struct {
+	DepartmentID string
+	LeaveTypeID  string
+}
+
+// UtilizationRow compares the days a group of employees was entitled to in a
+// year, allocated plus carried forward, with the approved leave they took
+type UtilizationRow struct {
+	ID                 string   `json:"id,omitempty"`
+	Name               string   `json:"name,omitempty"`
+	Employees          int      `json:"employees"`
+	EntitledDays       float64  `json:"entitled_days"`
+	UsedDays           float64  `json:"used_days"`
+	UtilizationPercent *float64 `json:"utilization_percent"` // null without entitlement
+}
+
+// MonthUsage is the approved leave starting in one month
+type MonthUsage struct {
+	Month     string  `json:"month"` // YYYY-MM
+	Requests  int     `json:"requests"`
+	Employees int     `json:"employees"`
+	UsedDays  float64 `json:"used_days"`
+}
+
+// Utilization is the leave utilization of a year overall, per department,
+// per leave type and per month
+type Utilization struct {
+	Year         int              `json:"year"`
+	Total        UtilizationRow   `json:"total"`
+	ByDepartment []UtilizationRow `json:"by_department"`
+	ByLeaveType  []UtilizationRow `json:"by_leave_type"`
+	ByMonth      []MonthUsage     `json:"by_month"`
+}
+
+// utilizationBase pairs, per employee and leave type, the entitlement of year
+// $1 with the approved days starting in it; either side may be missing
+const utilizationBase = `
+	WITH entitled AS (
+		SELECT employee_id, leave_type_id, SUM(allocated_days + carried_forward_days) AS days
+		FROM employee_leave_balances WHERE year = $1
+		GROUP BY employee_id, leave_type_id
+	), used AS (
+		SELECT employee_id, leave_type_id, SUM(total_days) AS days
+		FROM leave_requests
+		WHERE status = 'approved' AND start_date >= make_date($1, 1, 1) AND start_date < make_date($1 + 1, 1, 1)
+		GROUP BY employee_id, leave_type_id
+	), base AS (
+		SELECT COALESCE(en.employee_id, u.employee_id) AS employee_id, COALESCE(en.leave_type_id, u.leave_type_id) AS leave_type_id,
+		       COALESCE(en.days, 0) AS entitled, COALESCE(u.days, 0) AS used
+		FROM entitled en FULL JOIN used u ON u.employee_id = en.employee_id AND u.leave_type_id = en.leave_type_id
+	)`
+
+// LeaveUtilization reports the utilization of year, narrowed by f
+func LeaveUtilization(ctx context.Context, q db.Querier, year int, f Filter) (Utilization, error) {
+	res := Utilization{Year: year, ByDepartment: []UtilizationRow{}, ByLeaveType: []UtilizationRow{}}
+	rows, err := q.Query(ctx, utilizationBase+`
+		SELECT GROUPING(d.id) = 0, GROUPING(lt.id) = 0, COALESCE(d.id::TEXT, lt.id::TEXT, ''), COALESCE(d.name, lt.name, ''),
+		       COUNT(DISTINCT b.employee_id), SUM(b.entitled)::FLOAT8, SUM(b.used)::FLOAT8
+		FROM base b
+		JOIN employees e ON e.id = b.employee_id
+		JOIN departments d ON d.id = e.department_id
+		JOIN leave_types lt ON lt.id = b.leave_type_id
+		WHERE ($2 = '' OR e.department_id = NULLIF($2, '')::UUID)
+		  AND ($3 = '' OR b.leave_type_id = NULLIF($3, '')::UUID)
+		GROUP BY GROUPING SETS ((d.id, d.name), (lt.id, lt.name), ())`, year, f.DepartmentID, f.LeaveTypeID)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var byDepartment, byLeaveType bool
+		var r UtilizationRow
+		if err := rows.Scan(&byDepartment, &byLeaveType, &r.ID, &r.Name, &r.Employees, &r.EntitledDays, &r.UsedDays); err != nil {
+			return res, err
+		}
+		r.UtilizationPercent = percent(r.UsedDays, r.EntitledDays)
+		switch {
+		case byDepartment:
+			res.ByDepartment = append(res.ByDepartment, r)
+		case byLeaveType:
+			res.ByLeaveType = append(res.ByLeaveType, r)
+		default:
+			res.Total = r
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	byName := func(list []UtilizationRow) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	byName(res.ByDepartment)
+	byName(res.ByLeaveType)
+
+	// every month of the year, those without leave included
+	res.ByMonth = make([]MonthUsage, 12)
+	for m := range res.ByMonth {
+		res.ByMonth[m].Month = monthLabel(year, m+1)
+	}
+	rows, err = q.Query(ctx, `
+		SELECT EXTRACT(MONTH FROM lr.start_date)::INT, COUNT(*), COUNT(DISTINCT lr.employee_id), SUM(lr.total_days)::FLOAT8
+		FROM leave_requests lr
+		JOIN employees e ON e.id = lr.employee_id
+		WHERE lr.status = 'approved' AND lr.start_date >= make_date($1, 1, 1) AND lr.start_date < make_date($1 + 1, 1, 1)
+		  AND ($2 = '' OR e.department_id = NULLIF($2, '')::UUID)
+		  AND ($3 = '' OR lr.leave_type_id = NULLIF($3, '')::UUID)
+		GROUP BY 1`, year, f.DepartmentID, f.LeaveTypeID)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var month int
+		var u MonthUsage
+		if err := rows.Scan(&month, &u.Requests, &u.Employees, &u.UsedDays); err != nil {
+			return res, err
+		}
+		u.Month = res.ByMonth[month-1].Month
+		res.ByMonth[month-1] = u
+	}
+	return res, rows.Err()
+}
+
+// percent is part of whole in percent with two decimals, or nil when whole
+// is zero
+func percent(part, whole float64) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	p := math.Round(part/whole*10000) / 100
+	return &p
+}
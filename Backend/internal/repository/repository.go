@@ -0,0 +1,146 @@
+// Package repository declares the data access the services and handlers
+// depend on, one interface per table family. The sqlc queries
+// (*queries.Queries) implement all of them against Postgres; a test can hand
+// a service or handler its own implementation instead.
+package repository
+
+import (
+	"context"
+	"time"
+
+	"leave-management/internal/db"
+	"leave-management/internal/db/queries"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmployeeRepo reads and writes employees
+type EmployeeRepo interface {
+	CreateEmployee(ctx context.Context, arg queries.CreateEmployeeParams) (string, error)
+	CountEmployees(ctx context.Context, arg queries.CountEmployeesParams) (int64, error)
+	GetEmployee(ctx context.Context, id string) (queries.GetEmployeeRow, error)
+	GetEmployeeName(ctx context.Context, id string) (string, error)
+	GetEmployeeUserID(ctx context.Context, employeeID string) (string, error)
+	EmployeeExists(ctx context.Context, id string) (bool, error)
+	EmployeeCodeExists(ctx context.Context, employeeID string) (bool, error)
+	EmployeeEmailExists(ctx context.Context, email string) (bool, error)
+	DepartmentExists(ctx context.Context, id string) (bool, error)
+	DeactivateEmployee(ctx context.Context, id string) (int64, error)
+}
+
+// LeaveRequestRepo reads leave requests and records the decisions on them
+type LeaveRequestRepo interface {
+	LeaveRequestExists(ctx context.Context, id string) (bool, error)
+	ListEmployeeLeaveRequests(ctx context.Context, arg queries.ListEmployeeLeaveRequestsParams) ([]queries.ListEmployeeLeaveRequestsRow, error)
+	GetLeaveRequestForApproval(ctx context.Context, id string) (queries.GetLeaveRequestForApprovalRow, error)
+	IsApprovalDelegate(ctx context.Context, arg queries.IsApprovalDelegateParams) (bool, error)
+	GetCurrentApprovalStep(ctx context.Context, leaveRequestID string) (queries.GetCurrentApprovalStepRow, error)
+	DecideApprovalStep(ctx context.Context, arg queries.DecideApprovalStepParams) error
+	RecordManagerApproval(ctx context.Context, arg queries.RecordManagerApprovalParams) error
+	ApproveLeaveRequest(ctx context.Context, arg queries.ApproveLeaveRequestParams) (int64, error)
+	RejectLeaveRequest(ctx context.Context, arg queries.RejectLeaveRequestParams) (int64, error)
+	GetLeaveRequestForCancel(ctx context.Context, id string) (queries.GetLeaveRequestForCancelRow, error)
+	CancelLeaveRequest(ctx context.Context, id string) (int64, error)
+	CreateDecisionSnapshot(ctx context.Context, arg queries.CreateDecisionSnapshotParams) error
+}
+
+// LeaveBalanceRepo reads and writes employee_leave_balances
+type LeaveBalanceRepo interface {
+	AllocateLeaveBalances(ctx context.Context, arg queries.AllocateLeaveBalancesParams) error
+	ListLeaveBalances(ctx context.Context, arg queries.ListLeaveBalancesParams) ([]queries.ListLeaveBalancesRow, error)
+	QueryLeaveBalances(ctx context.Context, arg queries.QueryLeaveBalancesParams) ([]queries.QueryLeaveBalancesRow, error)
+	UpsertLeaveBalance(ctx context.Context, arg queries.UpsertLeaveBalanceParams) error
+	ChargeLeaveBalance(ctx context.Context, arg queries.ChargeLeaveBalanceParams) error
+	RefundLeaveBalance(ctx context.Context, arg queries.RefundLeaveBalanceParams) error
+	GetLeaveBalanceForReservation(ctx context.Context, arg queries.GetLeaveBalanceForReservationParams) (float64, error)
+	ReserveLeaveBalance(ctx context.Context, arg queries.ReserveLeaveBalanceParams) error
+	ReleaseLeaveBalance(ctx context.Context, arg queries.ReleaseLeaveBalanceParams) error
+	LockLeaveBalances(ctx context.Context, year int32) (int64, error)
+	ListLeaveBalanceDrift(ctx context.Context, year int32) ([]queries.ListLeaveBalanceDriftRow, error)
+	SetLeaveBalanceDays(ctx context.Context, arg queries.SetLeaveBalanceDaysParams) error
+}
+
+// LeaveTypeRepo reads and writes leave types
+type LeaveTypeRepo interface {
+	ListLeaveTypes(ctx context.Context, includeInactive bool) ([]queries.ListLeaveTypesRow, error)
+	GetLeaveType(ctx context.Context, id string) (queries.GetLeaveTypeRow, error)
+	GetLeavePolicy(ctx context.Context, id string) (queries.GetLeavePolicyRow, error)
+	GetLeaveTypeName(ctx context.Context, id string) (string, error)
+	CreateLeaveType(ctx context.Context, arg queries.CreateLeaveTypeParams) (string, error)
+	DeactivateLeaveType(ctx context.Context, id string) (int64, error)
+}
+
+// HolidayRepo reads the holiday calendar
+type HolidayRepo interface {
+	ListHolidaysByYear(ctx context.Context, year int32) ([]queries.ListHolidaysByYearRow, error)
+	ListHolidayDatesBetween(ctx context.Context, arg queries.ListHolidayDatesBetweenParams) ([]time.Time, error)
+}
+
+// UserRepo reads and writes logins (users)
+type UserRepo interface {
+	GetUserForRoleChange(ctx context.Context, id string) (queries.GetUserForRoleChangeRow, error)
+	SetUserRole(ctx context.Context, arg queries.SetUserRoleParams) error
+	SetEmployeeRoleByCode(ctx context.Context, arg queries.SetEmployeeRoleByCodeParams) error
+	SyncUserRole(ctx context.Context, employeeID string) error
+	CountUsers(ctx context.Context, arg queries.CountUsersParams) (int64, error)
+	ListUsers(ctx context.Context, arg queries.ListUsersParams) ([]queries.ListUsersRow, error)
+	SetUserActive(ctx context.Context, arg queries.SetUserActiveParams) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	DeleteUser(ctx context.Context, id string) (int64, error)
+}
+
+var (
+	_ EmployeeRepo     = (*queries.Queries)(nil)
+	_ LeaveRequestRepo = (*queries.Queries)(nil)
+	_ LeaveBalanceRepo = (*queries.Queries)(nil)
+	_ LeaveTypeRepo    = (*queries.Queries)(nil)
+	_ HolidayRepo      = (*queries.Queries)(nil)
+	_ UserRepo         = (*queries.Queries)(nil)
+)
+
+// Repos is a set of repositories that share one connection or transaction
+type Repos struct {
+	Employees     EmployeeRepo
+	LeaveRequests LeaveRequestRepo
+	LeaveBalances LeaveBalanceRepo
+	LeaveTypes    LeaveTypeRepo
+	Holidays      HolidayRepo
+	Users         UserRepo
+}
+
+// Store hands out Repos outside a transaction or bound to one
+type Store interface {
+	// Repos runs each call on its own; calls failing on a connection
+	// error are retried
+	Repos() Repos
+	// InTx runs fn with Repos bound to a new transaction, committed when fn
+	// returns nil. As with db.WithTx, fn may run more than once.
+	InTx(ctx context.Context, fn func(Repos) error) error
+	// Bind returns Repos bound to a transaction the caller opened itself
+	Bind(tx pgx.Tx) Repos
+}
+
+// New returns the Store of pool
+func New(pool *pgxpool.Pool) Store {
+	return pgStore{pool: pool, repos: reposOf(queries.New(db.WithRetry(pool)))}
+}
+
+type pgStore struct {
+	pool  *pgxpool.Pool
+	repos Repos
+}
+
+func (s pgStore) Repos() Repos { return s.repos }
+
+func (s pgStore) InTx(ctx context.Context, fn func(Repos) error) error {
+	return db.WithTx(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(s.Bind(tx))
+	})
+}
+
+func (s pgStore) Bind(tx pgx.Tx) Repos { return reposOf(queries.New(tx)) }
+
+func reposOf(q *queries.Queries) Repos {
+	return Repos{Employees: q, LeaveRequests: q, LeaveBalances: q, LeaveTypes: q, Holidays: q, Users: q}
+}
@@ -1,37 +1,143 @@
 package router
 
 import (
-	"leave-managemen/internal/handlers"
+	"time"
+
+	"leave-management/internal/buildinfo"
+	"leave-management/internal/cache"
+	"leave-management/internal/config"
+	"leave-management/internal/docs"
+	"leave-management/internal/handlers"
+	"leave-management/internal/jwtkeys"
+	"leave-management/internal/logging"
+	"leave-management/internal/maintenance"
 	"leave-management/internal/middleware"
 	"leave-management/internal/models"
+	"leave-management/internal/oidc"
+	"leave-management/internal/siem"
+	"leave-management/internal/stream"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func Setup(r *gin.Engine, pool *pgxpool.Pool) {
+// Setup registers the routes. read is the pool for reports and list endpoints:
+// a read replica when configured, otherwise pool itself. Settings that can be
+// reloaded are read from live on every request; the rest are fixed here.
+// Authentication events go to events (nil when no SIEM is configured); broker
+// feeds the /events stream; mode is the read-only maintenance mode.
+func Setup(r *gin.Engine, pool, read *pgxpool.Pool, rc *cache.Cache, live *config.Live, events *siem.Forwarder, broker *stream.Broker, mode *maintenance.State) {
+	cfg := live.Get()
+
+	// gin believes X-Forwarded-For from any peer by default, which would let a
+	// client pick its own IP for the rate limits, audit trail and sessions
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logging.Fatal("trusted proxies", "error", err)
+	}
+
 	// Initialize handlers
-	eh := handlers.NewEmployeeHandler(pool)
-	lh := handlers.NewLeaveTypeHandler(pool)
-	ah := handlers.NewAuditHandler(pool)
-	lrh := handlers.NewLeaveRequestHandler(pool)
-	authHandler := handlers.NewAuthHandler(pool)
+	eh := handlers.NewEmployeeHandler(pool, read, rc)
+	lh := handlers.NewLeaveTypeHandler(pool, rc)
+	ah := handlers.NewAuditHandler(pool, read, func() config.RetentionPolicy { return live.Get().AuditRetention })
+	lrh := handlers.NewLeaveRequestHandler(pool, read, func() handlers.NoticePeriodRule {
+		cfg := live.Get()
+		return handlers.NoticePeriodRule{Days: cfg.NoticePeriodDays, Leave: cfg.NoticePeriodLeave}
+	}, func() int { return live.Get().WorkdayHours })
+	keys := func() *jwtkeys.Keyring {
+		cfg := live.Get()
+		return jwtkeys.New(jwtkeys.Options{Secret: cfg.JWTSecret, PreviousSecrets: cfg.JWTPreviousSecrets,
+			Issuer: cfg.JWTIssuer, Audience: cfg.JWTAudience, TTL: cfg.AccessTokenTTL})
+	}
+	authHandler := handlers.NewAuthHandler(pool, events, keys)
+	oh := handlers.NewOIDCHandler(authHandler, oidc.New(oidc.Config{
+		RedirectURL:          cfg.OIDCRedirectURL,
+		GoogleClientID:       cfg.OIDCGoogleClientID,
+		GoogleClientSecret:   cfg.OIDCGoogleClientSecret,
+		GoogleHostedDomains:  cfg.OIDCGoogleHostedDomains,
+		AzureClientID:        cfg.OIDCAzureClientID,
+		AzureClientSecret:    cfg.OIDCAzureClientSecret,
+		AzureTenants:         cfg.OIDCAzureTenants,
+		AzureVerifiedDomains: cfg.OIDCAzureVerifiedDomains,
+	}), func() bool { return live.Get().OIDCProvisionUsers })
+	rh := handlers.NewReportHandler(pool, read, func() string { return live.Get().AnomalySensitivity })
+	hh := handlers.NewHolidayHandler(pool, rc)
+	bh := handlers.NewBatchHandler(r, func() int { return live.Get().BatchMaxRequests })
+	ch := handlers.NewConfigHandler(live)
+	erh := handlers.NewErasureHandler(pool)
+	mh := handlers.NewMeHandler(eh, lrh)
+	ph := handlers.NewPolicyHandler(pool, read)
+	evh := handlers.NewEventsHandler(broker)
+	arh := handlers.NewApprovalRuleHandler(pool)
+	mth := handlers.NewMaintenanceHandler(pool, mode)
+	ach := handlers.NewAccrualHandler(pool)
+	roh := handlers.NewRolloverHandler(pool)
+	blh := handlers.NewBalanceHandler(pool)
+	cah := handlers.NewCalendarHandler(read)
+	uh := handlers.NewUserHandler(pool, rc, events)
+	adh := handlers.NewDelegationHandler(pool)
+	whh := handlers.NewWebhookHandler(pool)
+	akh := handlers.NewAPIKeyHandler(pool)
+	chh := handlers.NewChatHandler(pool, lrh, cfg.WebhookTimeout, func() string { return live.Get().ChatCallbackURL })
+	coh := handlers.NewCompOffHandler(pool, rc, func() int { return live.Get().CompOffExpiryDays })
+	gh := handlers.NewGraphQLHandler(pool)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(pool)
+	authMiddleware := middleware.NewAuthMiddleware(pool, rc, cfg.AuthCacheTTL, keys)
+	limiter := middleware.NewRateLimiter(rc, func() middleware.RateLimits {
+		cfg := live.Get()
+		return middleware.RateLimits{PerIP: cfg.AuthRateLimitIP, PerAccount: cfg.AuthRateLimitAccount, Window: cfg.AuthRateLimitWindow}
+	})
+	if cfg.CompressionEnabled {
+		r.Use(middleware.Compress(cfg.CompressionMinSize))
+	}
+	r.Use(middleware.RequestID(), middleware.Locale(), middleware.ResponseEnvelope(func() bool { return live.Get().ResponseEnvelope }))
+	r.Use(middleware.Audit())
+	// multipart/form-data carries the file of POST /employees/import, a form
+	// the Slack button clicks of POST /integrations/chat/:id/actions
+	r.Use(middleware.RequestBody(func() int64 { return live.Get().MaxBodyBytes }, "application/json", handlers.MergePatchContentType,
+		"multipart/form-data", "application/x-www-form-urlencoded"))
+	// the CSV exports get EXPORT_TIMEOUT: a deadline passing mid-stream would cut the file short
+	r.Use(middleware.Timeout(func() time.Duration { return live.Get().RequestTimeout },
+		func() time.Duration { return live.Get().ExportTimeout },
+		[]string{"/audit-logs/export", "/leave-requests/export", "/employees/leave-balances/export"}, "/events"))
+	r.Use(middleware.QueryTimeout(func() time.Duration { return live.Get().QueryTimeout },
+		"/audit-logs/export", "/leave-requests/export", "/employees/leave-balances/export", "/events"))
+	// POSTs that only read, signing in and out, and the switch itself stay open
+	// in read-only mode; /batch sub-requests are checked one by one
+	r.Use(middleware.ReadOnly(mode.ReadOnly, "/auth/login", "/auth/oidc/callback", "/auth/refresh", "/auth/logout",
+		"/auth/sessions/:id", "/batch", "/graphql", "/leave-balances/query", "/operator/maintenance"))
 
 	// Public routes (no authentication required)
 	public := r.Group("/")
 	{
 		public.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok"}) })
+		public.GET("/readyz", mth.Ready)
+		public.GET("/version", func(c *gin.Context) { c.JSON(200, buildinfo.Get()) })
+		public.GET("/metrics", gin.WrapH(promhttp.Handler()))
+		public.GET("/openapi.json", docs.Spec)
+		public.GET("/docs", docs.UI)
+		// Slack and Teams button clicks, verified by the handler
+		public.POST("/integrations/chat/:id/actions", chh.HandleAction)
+	}
+
+	// Deployment-wide operations, for the operator of the deployment with
+	// OPERATOR_TOKEN: an admin only administers their own organization
+	operator := r.Group("/operator", middleware.OperatorAuth(func() string { return live.Get().OperatorToken }))
+	{
+		operator.GET("/maintenance", mth.GetMaintenance)
+		operator.PUT("/maintenance", mth.SetMaintenance)
 	}
 
 	// Authentication routes
 	auth := r.Group("/auth")
 	{
-		auth.POST("/register", authHandler.Register)
-		auth.POST("/login", authHandler.Login)
+		auth.POST("/register", limiter.Limit("register"), authHandler.Register)
+		auth.POST("/login", limiter.Limit("login"), authHandler.Login)
 		auth.POST("/refresh", authHandler.RefreshToken)
+		// single sign-on with Google or Microsoft Entra ID
+		auth.GET("/oidc/login", limiter.Limit("oidc_login"), oh.Login)
+		auth.POST("/oidc/callback", limiter.Limit("oidc_callback"), oh.Callback)
 	}
 
 	// Protected routes (authentication required)
@@ -44,6 +150,19 @@ func Setup(r *gin.Engine, pool *pgxpool.Pool) {
 			authProtected.GET("/profile", authHandler.GetProfile)
 			authProtected.POST("/change-password", authHandler.ChangePassword)
 			authProtected.POST("/logout", authHandler.Logout)
+			// link a single sign-on account to the caller
+			authProtected.POST("/oidc/link", limiter.Limit("oidc_link"), oh.Link)
+			authProtected.POST("/oidc/link/callback", limiter.Limit("oidc_link_callback"), oh.LinkCallback)
+			authProtected.GET("/sessions", authHandler.ListSessions)
+			authProtected.DELETE("/sessions/:id", authHandler.RevokeSession)
+		}
+
+		// The caller's own records, whatever their role
+		me := protected.Group("/me")
+		{
+			me.GET("/profile", mh.GetProfile)
+			me.GET("/leave-balances", mh.GetLeaveBalances)
+			me.GET("/leave-requests", mh.ListLeaveRequests)
 		}
 
 		// Leave Requests (role-based access)
@@ -54,42 +173,240 @@ func Setup(r *gin.Engine, pool *pgxpool.Pool) {
 
 			// Employees can view their own requests, managers can view team requests, HR/Admin can view all
 			leaveRequests.GET("", authMiddleware.RequirePermission("view_own_requests"), lrh.ListLeaveRequests)
+			leaveRequests.GET("/export", authMiddleware.RequirePermission("view_own_requests"), lrh.ExportLeaveRequests)
 
 			// Employees can view their own request details
 			leaveRequests.GET("/:id", authMiddleware.RequireOwnership("leave_request"), lrh.GetLeaveRequestByID)
+			leaveRequests.HEAD("/:id", authMiddleware.RequireOwnership("leave_request"), lrh.HeadLeaveRequest)
 
 			// Managers can approve/reject team requests, HR/Admin can approve/reject any
 			leaveRequests.PUT("/:id/approve", authMiddleware.RequirePermission("approve_team_requests"), lrh.ApproveLeaveRequest)
 			leaveRequests.PUT("/:id/reject", authMiddleware.RequirePermission("reject_team_requests"), lrh.RejectLeaveRequest)
 
+			// The approval chain, and its steps acted on one by one
+			leaveRequests.GET("/:id/approval-steps", authMiddleware.RequireOwnership("leave_request"), lrh.ListApprovalSteps)
+			leaveRequests.PUT("/:id/approval-steps/:step/approve", authMiddleware.RequirePermission("approve_team_requests"), lrh.ApproveStep)
+			leaveRequests.PUT("/:id/approval-steps/:step/reject", authMiddleware.RequirePermission("reject_team_requests"), lrh.RejectStep)
+
+			// Comment thread: the employee, their approvers and HR
+			leaveRequests.GET("/:id/comments", lrh.ListComments)
+			leaveRequests.POST("/:id/comments", lrh.AddComment)
+
+			// Audit trail and decision snapshots (HR/Admin only)
+			leaveRequests.GET("/:id/history", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), lrh.GetLeaveRequestHistory)
+
 			// Employees can cancel their own requests
 			leaveRequests.PUT("/:id/cancel", authMiddleware.RequireOwnership("leave_request"), lrh.CancelLeaveRequest)
 		}
 
+		// The approval steps waiting for the caller
+		protected.GET("/approvals/pending", authMiddleware.RequirePermission("approve_team_requests"), lrh.ListPendingApprovals)
+
+		// Approval delegations: a manager's approvals handed to another
+		// employee for a date range
+		delegations := protected.Group("/approval-delegations")
+		{
+			delegations.POST("", authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), adh.CreateDelegation)
+			delegations.GET("", adh.ListDelegations)
+			delegations.DELETE("/:id", adh.RevokeDelegation)
+		}
+
+		// Who is away when, day by day (Manager: their team, HR/Admin: everyone)
+		protected.GET("/calendar/team", authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), cah.GetTeamCalendar)
+
 		// Leave Types (HR/Admin only)
 		leaveTypes := protected.Group("/leave-types")
 		{
-			leaveTypes.GET("", lh.GetLeaveTypes) // Anyone can view leave types
+			leaveTypes.GET("", middleware.ETag(func() string { return live.Get().LeaveTypesCacheControl }), lh.GetLeaveTypes) // Anyone can view leave types
 			leaveTypes.POST("", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), lh.CreateLeaveType)
-			leaveTypes.PUT("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), lh.UpdateLeaveType)
+			leaveTypes.PATCH("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), lh.UpdateLeaveType)
+			leaveTypes.PUT("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), lh.UpdateLeaveType) // deprecated alias of PATCH
 			leaveTypes.DELETE("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), lh.DeleteLeaveType)
 		}
 
+		// Batch: sub-requests are re-authenticated individually
+		protected.POST("/batch", bh.Batch)
+
+		// GraphQL: resolvers apply the same visibility rules as the REST endpoints
+		protected.POST("/graphql", gh.Serve)
+		protected.GET("/graphql", gh.Serve)
+
+		// Holidays (anyone can view)
+		protected.GET("/holidays", middleware.ETag(func() string { return live.Get().HolidaysCacheControl }), hh.ListHolidays)
+
 		// Audit Logs (HR/Admin only)
 		protected.GET("/audit-logs", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ah.GetAuditLogs)
+		protected.GET("/audit-logs/export", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ah.ExportAuditLogs)
+		protected.GET("/audit-logs/verify", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ah.VerifyAuditLogs)
+		protected.GET("/audit-logs/:id/diff", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ah.GetAuditLogDiff)
+		protected.POST("/audit-logs/retention/run", authMiddleware.RequireRole(models.RoleAdmin), ah.RunRetention)
+
+		// Reports (HR/Admin only)
+		reports := protected.Group("/reports")
+		reports.Use(authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin))
+		{
+			reports.GET("/yoy", rh.GetYearOverYear)
+			reports.GET("/leave-utilization", rh.GetLeaveUtilization)
+			reports.GET("/absence-trends", rh.GetAbsenceTrends)
+			reports.GET("/pending-approvals-aging", rh.GetPendingApprovalsAging)
+			reports.GET("/leave-types/:id/consumption", rh.GetLeaveTypeConsumption)
+			reports.GET("/absence-anomalies", rh.GetAbsenceAnomalies)
+			reports.POST("/absence-anomalies/run", rh.RunAbsenceAnomalyScan)
+			reports.GET("/policy-acknowledgments", ph.GetMissingAcknowledgments)
+		}
+
+		// Resolved configuration (admin only)
+		protected.GET("/admin/config", authMiddleware.RequireRole(models.RoleAdmin), ch.GetConfig)
+
+		// Read-only maintenance mode (admin only); the operator switches it
+		protected.GET("/admin/maintenance", authMiddleware.RequireRole(models.RoleAdmin), mth.GetMaintenance)
+
+		// Leave accruals, credited by a job; HR can run it early
+		protected.POST("/admin/accruals/run", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ach.RunAccruals)
+
+		// Year-end rollover of the balances, run by a job; HR can run it again
+		protected.POST("/admin/leave-balances/rollover", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), roh.RunRollover)
+
+		// Reconciliation of the balances with the approved leave requests
+		protected.POST("/admin/leave-balances/recalculate", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), blh.RecalculateBalances)
+
+		// Profiling (admin only, off unless PPROF_ENABLED)
+		pprofGroup := protected.Group("/debug/pprof")
+		pprofGroup.Use(middleware.Feature(func() bool { return live.Get().PprofEnabled }), authMiddleware.RequireRole(models.RoleAdmin))
+		handlers.RegisterPprof(pprofGroup)
+
+		// Attendance (optional module, off unless ATTENDANCE_ENABLED)
+		attendanceOn := middleware.Feature(func() bool { return live.Get().AttendanceEnabled })
+		atth := handlers.NewAttendanceHandler(pool)
+		attendance := protected.Group("/attendance")
+		attendance.Use(attendanceOn)
+		{
+			attendance.POST("/check-in", atth.CheckIn)
+			attendance.POST("/check-out", atth.CheckOut)
+			attendance.POST("/import", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), atth.ImportAttendance)
+		}
+		protected.GET("/reports/attendance/discrepancies", attendanceOn,
+			authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), atth.GetDiscrepancies)
 
 		// Employee Management (HR/Admin only)
 		employees := protected.Group("/employees")
 		{
 			employees.POST("", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.CreateEmployee)
+			employees.POST("/import", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.ImportEmployees)
 			employees.GET("", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.ListEmployees)
+			employees.GET("/exists", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.EmployeeExists)
+			employees.HEAD("/exists", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.EmployeeExists)
+			employees.GET("/leave-balances/export", authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), eh.ExportLeaveBalances)
 			employees.GET("/:id", authMiddleware.RequireOwnership("employee"), eh.GetEmployeeByID)
-			employees.PUT("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.UpdateEmployee)
+			employees.HEAD("/:id", authMiddleware.RequireOwnership("employee"), eh.HeadEmployee)
+			employees.PATCH("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.UpdateEmployee)
+			employees.PUT("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.UpdateEmployee) // deprecated alias of PATCH
 			employees.DELETE("/:id", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.DeactivateEmployee)
 
+			// Reporting lines (team and org chart: HR/Admin anyone's, others their own or those below them)
+			employees.PUT("/:id/manager", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.SetManager)
+			employees.GET("/:id/team", eh.GetTeam)
+			employees.GET("/:id/org-chart", eh.GetOrgChart)
+
 			// Leave Balances
 			employees.GET("/:id/leave-balances", authMiddleware.RequireOwnership("leave_balance"), eh.GetLeaveBalances)
 			employees.PUT("/:id/leave-balances", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.UpdateLeaveBalances)
 		}
+
+		protected.GET("/org-chart", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), eh.GetFullOrgChart)
+
+		// User Management (Admin only)
+		users := protected.Group("/users")
+		users.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			users.GET("", uh.ListUsers)
+			users.PUT("/:id/role", uh.SetRole)
+			users.PUT("/:id/activate", uh.ActivateUser)
+			users.PUT("/:id/deactivate", uh.DeactivateUser)
+			users.DELETE("/:id", uh.DeleteUser)
+		}
+
+		// Live leave request updates (Server-Sent Events)
+		protected.GET("/events", evh.Stream)
+
+		// Balances of many employees at once (managers: their direct reports)
+		protected.POST("/leave-balances/query", authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), eh.QueryLeaveBalances)
+
+		// Erasure requests: filed by anyone for themselves, reviewed by admins
+		erasure := protected.Group("/erasure-requests")
+		{
+			erasure.POST("", erh.CreateErasureRequest)
+			erasure.GET("", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), erh.ListErasureRequests)
+			erasure.GET("/:id", erh.GetErasureRequest)
+			erasure.PUT("/:id/approve", authMiddleware.RequireRole(models.RoleAdmin), erh.ApproveErasureRequest)
+			erasure.PUT("/:id/reject", authMiddleware.RequireRole(models.RoleAdmin), erh.RejectErasureRequest)
+		}
+
+		// Comp-off: logged by employees for weekends and holidays worked,
+		// reviewed by their manager or HR
+		compOff := protected.Group("/comp-off")
+		{
+			compOff.POST("", coh.LogCompOff)
+			compOff.GET("", coh.ListCompOffs)
+			compOff.PUT("/:id/approve", authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), coh.ApproveCompOff)
+			compOff.PUT("/:id/reject", authMiddleware.RequireRole(models.RoleManager, models.RoleHR, models.RoleAdmin), coh.RejectCompOff)
+			compOff.PUT("/:id/cancel", coh.CancelCompOff)
+		}
+
+		// Leave policies: published by HR, acknowledged by every employee
+		policies := protected.Group("/policies")
+		{
+			policies.POST("", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ph.PublishPolicy)
+			policies.GET("", ph.ListPolicies)
+			policies.GET("/:id", ph.GetPolicy)
+			policies.POST("/:id/acknowledge", ph.AcknowledgePolicy)
+			policies.GET("/:id/acknowledgments", authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin), ph.ListAcknowledgments)
+		}
+
+		// Approval routing rules, evaluated when a leave request is created
+		approvalRules := protected.Group("/approval-rules")
+		approvalRules.Use(authMiddleware.RequireRole(models.RoleHR, models.RoleAdmin))
+		{
+			approvalRules.GET("", arh.ListApprovalRules)
+			approvalRules.POST("", arh.CreateApprovalRule)
+			approvalRules.PATCH("/:id", arh.UpdateApprovalRule)
+			approvalRules.DELETE("/:id", arh.DeleteApprovalRule)
+		}
+
+		// Outbound webhooks for integrations (admin only)
+		webhooks := protected.Group("/webhooks")
+		webhooks.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			webhooks.GET("", whh.ListWebhooks)
+			webhooks.POST("", whh.CreateWebhook)
+			webhooks.GET("/:id", whh.GetWebhook)
+			webhooks.PATCH("/:id", whh.UpdateWebhook)
+			webhooks.DELETE("/:id", whh.DeleteWebhook)
+			webhooks.POST("/:id/rotate-secret", whh.RotateWebhookSecret)
+			webhooks.GET("/:id/deliveries", whh.ListDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", whh.RedeliverDelivery)
+		}
+
+		// Slack and Microsoft Teams channels for leave requests (admin only)
+		chatIntegrations := protected.Group("/chat-integrations")
+		chatIntegrations.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			chatIntegrations.GET("", chh.ListChatIntegrations)
+			chatIntegrations.POST("", chh.CreateChatIntegration)
+			chatIntegrations.GET("/:id", chh.GetChatIntegration)
+			chatIntegrations.PATCH("/:id", chh.UpdateChatIntegration)
+			chatIntegrations.DELETE("/:id", chh.DeleteChatIntegration)
+		}
+
+		// API keys of integration clients (admin only); keys cannot manage keys
+		apiKeys := protected.Group("/api-keys")
+		apiKeys.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			apiKeys.GET("", akh.ListAPIKeys)
+			apiKeys.POST("", akh.CreateAPIKey)
+			apiKeys.GET("/:id", akh.GetAPIKey)
+			apiKeys.PATCH("/:id", akh.UpdateAPIKey)
+			apiKeys.DELETE("/:id", akh.DeleteAPIKey)
+		}
 	}
 }
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxChainProblems bounds the problems listed per chain; Valid still reflects
+// all of them
+const maxChainProblems = 100
+
+// AuditChains checks the hash chains of the audit trail (see audit_chains in
+// Database/db.sql)
+type AuditChains struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditChains(pool *pgxpool.Pool) *AuditChains {
+	return &AuditChains{pool: pool}
+}
+
+// ChainProblem is one place where a chain does not hold
+type ChainProblem struct {
+	Seq     int64  `json:"seq"`
+	ID      string `json:"id,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// ChainReport is the outcome of checking the chain of one audited table
+type ChainReport struct {
+	Table         string         `json:"table_name"`
+	Valid         bool           `json:"valid"`
+	Entries       int64          `json:"entries"`        // entries checked
+	Anonymized    int64          `json:"anonymized"`     // of which checked against the redacted content recorded when anonymized
+	PurgedThrough int64          `json:"purged_through"` // last entry deleted by the retention job
+	HeadSeq       int64          `json:"head_seq"`
+	HeadHash      string         `json:"head_hash"`
+	Problems      []ChainProblem `json:"problems"`
+}
+
+func (r *ChainReport) problem(seq int64, id, format string, args ...interface{}) {
+	r.Valid = false
+	if len(r.Problems) < maxChainProblems {
+		r.Problems = append(r.Problems, ChainProblem{Seq: seq, ID: id, Problem: fmt.Sprintf(format, args...)})
+	}
+}
+
+// chainEntries lists a chain's entries, live and archived, from after the
+// last purged one, with the content hash recomputed from the stored columns
+const chainEntries = `
+	SELECT id, chain_seq, prev_hash, content_hash, row_hash, anonymized_at IS NOT NULL,
+		audit_content_hash(org_id, table_name, chain_seq, record_id, action, old_values, new_values,
+			changed_by, changed_at, actor_user_id, actor_role, ip_address, endpoint, request_id)
+	FROM (
+		SELECT ` + chainColumns + ` FROM audit_logs WHERE org_id = $1 AND table_name = $2 AND chain_seq > $3
+		UNION ALL
+		SELECT ` + chainColumns + ` FROM audit_logs_archive WHERE org_id = $1 AND table_name = $2 AND chain_seq > $3
+	) e
+	ORDER BY chain_seq`
+
+const chainColumns = `id, org_id, table_name, record_id, action, old_values, new_values, changed_by, changed_at,
+	actor_user_id, actor_role, ip_address, endpoint, request_id, anonymized_at,
+	chain_seq, prev_hash, content_hash, row_hash`
+
+// chainAnonymizations lists the ANONYMIZE entries of a chain: the retention
+// and erasure jobs append one for each entry they anonymize, with the
+// chain_seq of that entry and the hash of its redacted content
+const chainAnonymizations = `
+	SELECT chain_seq, new_values FROM (
+		SELECT chain_seq, new_values FROM audit_logs
+		WHERE org_id = $1 AND table_name = $2 AND chain_seq > $3 AND action = 'ANONYMIZE'
+		UNION ALL
+		SELECT chain_seq, new_values FROM audit_logs_archive
+		WHERE org_id = $1 AND table_name = $2 AND chain_seq > $3 AND action = 'ANONYMIZE'
+	) e
+	ORDER BY chain_seq`
+
+// chainEntry is one audit entry as the chain check reads it
+type chainEntry struct {
+	ID          string
+	Seq         int64
+	PrevHash    string
+	ContentHash string
+	RowHash     string
+	Anonymized  bool
+	Content     string // hash of the content as stored now
+}
+
+// Verify checks the chains of the caller's organization, or only the one of
+// table when it is not empty. Everything is read from one snapshot, so
+// entries written meanwhile are neither half seen nor reported missing.
+func (s *AuditChains) Verify(ctx context.Context, table string) ([]ChainReport, error) {
+	tx, err := db.BeginTx(ctx, s.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	type head struct {
+		org, table           string
+		lastSeq, purgedSeq   int64
+		lastHash, purgedHash string
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT org_id, table_name, last_seq, last_hash, purged_seq, purged_hash
+		FROM audit_chains WHERE $1 = '' OR table_name = $1
+		ORDER BY table_name`, table)
+	if err != nil {
+		return nil, err
+	}
+	var heads []head
+	for rows.Next() {
+		var h head
+		if err := rows.Scan(&h.org, &h.table, &h.lastSeq, &h.lastHash, &h.purgedSeq, &h.purgedHash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		heads = append(heads, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reports := make([]ChainReport, 0, len(heads))
+	for _, h := range heads {
+		r := ChainReport{
+			Table: h.table, Valid: true, PurgedThrough: h.purgedSeq,
+			HeadSeq: h.lastSeq, HeadHash: h.lastHash, Problems: []ChainProblem{},
+		}
+		if err := verifyChain(ctx, tx, h.org, h.purgedHash, &r); err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// verifyChain walks one chain from its purge point to its head
+func verifyChain(ctx context.Context, tx pgx.Tx, org, purgedHash string, r *ChainReport) error {
+	rows, err := tx.Query(ctx, chainAnonymizations, org, r.Table, r.PurgedThrough)
+	if err != nil {
+		return err
+	}
+	redacted := map[int64]string{}
+	for rows.Next() {
+		var seq int64
+		var values []byte
+		if err := rows.Scan(&seq, &values); err != nil {
+			rows.Close()
+			return err
+		}
+		addRedaction(redacted, seq, values)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(ctx, chainEntries, org, r.Table, r.PurgedThrough)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	check := newChainCheck(r, purgedHash, redacted)
+	for rows.Next() {
+		var e chainEntry
+		if err := rows.Scan(&e.ID, &e.Seq, &e.PrevHash, &e.ContentHash, &e.RowHash, &e.Anonymized, &e.Content); err != nil {
+			return err
+		}
+		check.entry(e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	check.end()
+	return nil
+}
+
+// addRedaction records in redacted the content hash the ANONYMIZE entry seq,
+// with new_values values, gives the entry it anonymized. A later
+// anonymization of the same entry replaces an earlier one.
+func addRedaction(redacted map[int64]string, seq int64, values []byte) {
+	var v struct {
+		Seq         int64  `json:"chain_seq"`
+		ContentHash string `json:"content_hash"`
+	}
+	if json.Unmarshal(values, &v) == nil && v.Seq < seq {
+		redacted[v.Seq] = v.ContentHash
+	}
+}
+
+// chainCheck checks the entries of a chain, fed in chain_seq order, against
+// each other and the chain head
+type chainCheck struct {
+	r        *ChainReport
+	redacted map[int64]string // chain_seq -> content hash after anonymization
+	next     int64
+	prev     string
+}
+
+func newChainCheck(r *ChainReport, purgedHash string, redacted map[int64]string) *chainCheck {
+	return &chainCheck{r: r, redacted: redacted, next: r.PurgedThrough + 1, prev: purgedHash}
+}
+
+// entry checks the next entry. An anonymized entry no longer matches its
+// content_hash; it must match the redacted content hash recorded for it
+// instead, so marking an edited entry anonymized is reported like any edit.
+func (c *chainCheck) entry(e chainEntry) {
+	r := c.r
+	r.Entries++
+	switch {
+	case e.Seq < c.next:
+		r.problem(e.Seq, e.ID, "entry appears more than once")
+		return
+	case e.Seq > c.next:
+		if e.Seq == c.next+1 {
+			r.problem(c.next, "", "entry %d is missing", c.next)
+		} else {
+			r.problem(c.next, "", "entries %d to %d are missing", c.next, e.Seq-1)
+		}
+	case e.PrevHash != c.prev:
+		r.problem(e.Seq, e.ID, "does not link to the entry before")
+	}
+	want := e.ContentHash
+	if h, ok := c.redacted[e.Seq]; ok && e.Anonymized {
+		want = h
+		r.Anonymized++
+	}
+	switch {
+	case e.Content == want:
+	case e.Anonymized:
+		r.problem(e.Seq, e.ID, "content does not match its hash, nor a recorded anonymization")
+	default:
+		r.problem(e.Seq, e.ID, "content does not match its hash")
+	}
+	if e.RowHash != chainHash(e.PrevHash, e.ContentHash) {
+		r.problem(e.Seq, e.ID, "row hash does not match")
+	}
+	if e.Seq > r.HeadSeq {
+		r.problem(e.Seq, e.ID, "entry is past the chain head")
+	}
+	c.next, c.prev = e.Seq+1, e.RowHash
+}
+
+// end checks that the chain reached its head
+func (c *chainCheck) end() {
+	r := c.r
+	switch {
+	case c.next <= r.HeadSeq:
+		r.problem(c.next, "", "entries %d to %d are missing", c.next, r.HeadSeq)
+	case c.next-1 == r.HeadSeq && c.prev != r.HeadHash:
+		r.problem(r.HeadSeq, "", "last entry does not match the chain head")
+	}
+}
+
+// chainHash is row_hash as audit_chain_function computes it
+func chainHash(prevHash, contentHash string) string {
+	sum := sha256.Sum256([]byte(prevHash + contentHash))
+	return hex.EncodeToString(sum[:])
+}
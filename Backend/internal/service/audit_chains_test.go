@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testChain builds a well-formed chain of n entries on top of the purge
+// point, each with its content intact
+func testChain(n int) ([]chainEntry, *ChainReport) {
+	r := &ChainReport{Table: "leave_requests", Valid: true}
+	prev := ""
+	var entries []chainEntry
+	for seq := int64(1); seq <= int64(n); seq++ {
+		content := fmt.Sprintf("content-%d", seq)
+		row := chainHash(prev, content)
+		entries = append(entries, chainEntry{
+			ID: fmt.Sprintf("id-%d", seq), Seq: seq, PrevHash: prev,
+			ContentHash: content, RowHash: row, Content: content,
+		})
+		prev = row
+	}
+	r.HeadSeq, r.HeadHash = int64(n), prev
+	return entries, r
+}
+
+func runChainCheck(entries []chainEntry, r *ChainReport, redacted map[int64]string) {
+	check := newChainCheck(r, "", redacted)
+	for _, e := range entries {
+		check.entry(e)
+	}
+	check.end()
+}
+
+func TestChainCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(entries []chainEntry, redacted map[int64]string)
+		valid   bool
+		problem string
+	}{
+		{
+			name:   "untouched",
+			change: func([]chainEntry, map[int64]string) {},
+			valid:  true,
+		},
+		{
+			name: "edited",
+			change: func(entries []chainEntry, _ map[int64]string) {
+				entries[1].Content = "edited"
+			},
+			problem: "content does not match its hash",
+		},
+		{
+			name: "edited and marked anonymized",
+			change: func(entries []chainEntry, _ map[int64]string) {
+				entries[1].Content, entries[1].Anonymized = "edited", true
+			},
+			problem: "nor a recorded anonymization",
+		},
+		{
+			name: "edited, marked anonymized, with another entry's anonymization",
+			change: func(entries []chainEntry, redacted map[int64]string) {
+				entries[1].Content, entries[1].Anonymized = "edited", true
+				redacted[3] = "redacted"
+			},
+			problem: "nor a recorded anonymization",
+		},
+		{
+			name: "anonymized with a recorded anonymization",
+			change: func(entries []chainEntry, redacted map[int64]string) {
+				entries[1].Content, entries[1].Anonymized = "redacted", true
+				redacted[2] = "redacted"
+			},
+			valid: true,
+		},
+		{
+			name: "anonymized, then edited",
+			change: func(entries []chainEntry, redacted map[int64]string) {
+				entries[1].Content, entries[1].Anonymized = "edited", true
+				redacted[2] = "redacted"
+			},
+			problem: "nor a recorded anonymization",
+		},
+		{
+			name: "missing entry",
+			change: func(entries []chainEntry, _ map[int64]string) {
+				entries[1] = entries[2]
+			},
+			problem: "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, r := testChain(4)
+			redacted := map[int64]string{}
+			tt.change(entries, redacted)
+			runChainCheck(entries, r, redacted)
+			if r.Valid != tt.valid {
+				t.Fatalf("Valid = %v, want %v; problems %+v", r.Valid, tt.valid, r.Problems)
+			}
+			if tt.problem == "" {
+				return
+			}
+			for _, p := range r.Problems {
+				if strings.Contains(p.Problem, tt.problem) {
+					return
+				}
+			}
+			t.Fatalf("problems %+v, want one containing %q", r.Problems, tt.problem)
+		})
+	}
+}
+
+func TestAddRedaction(t *testing.T) {
+	redacted := map[int64]string{}
+	addRedaction(redacted, 5, []byte(`{"entry_id": "id-2", "chain_seq": 2, "content_hash": "first"}`))
+	addRedaction(redacted, 7, []byte(`{"entry_id": "id-2", "chain_seq": 2, "content_hash": "second"}`))
+	// an ANONYMIZE entry cannot vouch for itself or a later entry
+	addRedaction(redacted, 8, []byte(`{"chain_seq": 8, "content_hash": "self"}`))
+	addRedaction(redacted, 9, []byte(`{"chain_seq": 12, "content_hash": "later"}`))
+	addRedaction(redacted, 10, []byte(`not json`))
+	if len(redacted) != 1 || redacted[2] != "second" {
+		t.Fatalf("redacted = %v, want only 2: second", redacted)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+
+	"leave-management/internal/db/queries"
+	"leave-management/internal/repository"
+)
+
+// Discrepancy kinds of a leave balance
+const (
+	// DriftUsedDays: used_days differ from the days of the approved requests
+	// charged to the balance
+	DriftUsedDays = "used_days"
+	// DriftPendingDays: pending_days differ from the days reserved by the
+	// pending requests
+	DriftPendingDays = "pending_days"
+	// DriftNoBalance: requests were charged to, or reserved days in, a
+	// balance that does not exist
+	DriftNoBalance = "no_balance"
+)
+
+// AvailableDays is what a balance leaves to take: the days allocated and
+// carried forward less the days used. The database computes
+// employee_leave_balances.available_days with the same formula. Of those,
+// pending_days are reserved by pending requests.
+func AvailableDays(allocated, carriedForward, used float64) float64 {
+	return allocated + carriedForward - used
+}
+
+// BalanceDiscrepancy is one balance that does not add up. The counts are
+// null for DriftNoBalance.
+type BalanceDiscrepancy struct {
+	BalanceID          *string  `json:"balance_id"`
+	EmployeeID         string   `json:"employee_id"`
+	EmployeeName       string   `json:"employee_name"`
+	LeaveTypeID        string   `json:"leave_type_id"`
+	LeaveTypeName      string   `json:"leave_type_name"`
+	Kind               string   `json:"kind"`
+	AllocatedDays      *float64 `json:"allocated_days"`
+	CarriedForwardDays *int32   `json:"carried_forward_days"`
+	UsedDays           *float64 `json:"used_days"`
+	AvailableDays      *float64 `json:"available_days"`
+	PendingDays        *float64 `json:"pending_days"`
+	ChargedDays        float64  `json:"charged_days"`  // of the approved requests
+	ReservedDays       float64  `json:"reserved_days"` // of the pending requests
+	// what used_days, pending_days and available_days should be; null for
+	// DriftNoBalance
+	ExpectedUsedDays      *float64 `json:"expected_used_days"`
+	ExpectedPendingDays   *float64 `json:"expected_pending_days"`
+	ExpectedAvailableDays *float64 `json:"expected_available_days"`
+	Fixed                 bool     `json:"fixed"`
+	// why it was not fixed, outside a dry run
+	Reason string `json:"reason,omitempty"`
+}
+
+// BalanceRecalculation sums up a recalculation of the balances of one year
+type BalanceRecalculation struct {
+	Year          int                  `json:"year"`
+	Balances      int64                `json:"balances"` // balances checked
+	Discrepancies []BalanceDiscrepancy `json:"discrepancies"`
+	Fixed         int                  `json:"fixed"`
+}
+
+// Balances keeps the leave balances consistent with the leave requests
+type Balances struct {
+	store repository.Store
+}
+
+func NewBalances(store repository.Store) *Balances {
+	return &Balances{store: store}
+}
+
+// Recalculate checks every balance of year in the caller's organization
+// against the approved leave requests charged to it and the pending ones
+// that reserved days in it and, unless dryRun, sets the pending_days that
+// drifted to those days. used_days may have been set by hand (PUT
+// /employees/:id/leave-balances), so a difference there is only reported,
+// unless overwriteUsed; available_days follows used_days. The balances are
+// locked meanwhile, so no request slips between the check and the fix. A
+// balance's used_days are left as they are when the days charged exceed its
+// allocated and carried forward days, and requests without a balance are
+// only reported.
+func (s *Balances) Recalculate(ctx context.Context, year int, dryRun, overwriteUsed bool) (BalanceRecalculation, error) {
+	var res BalanceRecalculation
+	err := s.store.InTx(ctx, func(r repository.Repos) error {
+		res = BalanceRecalculation{Year: year, Discrepancies: []BalanceDiscrepancy{}}
+		n, err := r.LeaveBalances.LockLeaveBalances(ctx, int32(year))
+		if err != nil {
+			return err
+		}
+		res.Balances = n
+		rows, err := r.LeaveBalances.ListLeaveBalanceDrift(ctx, int32(year))
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			d := BalanceDiscrepancy{
+				BalanceID: row.BalanceID, EmployeeID: row.EmployeeID, EmployeeName: row.EmployeeName,
+				LeaveTypeID: row.LeaveTypeID, LeaveTypeName: row.LeaveTypeName,
+				AllocatedDays: row.AllocatedDays, CarriedForwardDays: row.CarriedForwardDays,
+				UsedDays: row.UsedDays, AvailableDays: row.AvailableDays, PendingDays: row.PendingDays,
+				ChargedDays: row.ChargedDays, ReservedDays: row.ReservedDays,
+			}
+			if row.BalanceID == nil {
+				d.Kind = DriftNoBalance
+				if !dryRun {
+					d.Reason = "no balance to charge"
+				}
+				res.Discrepancies = append(res.Discrepancies, d)
+				continue
+			}
+			allocated, carried := *row.AllocatedDays, float64(*row.CarriedForwardDays)
+			used, pending, available := row.ChargedDays, row.ReservedDays, AvailableDays(allocated, carried, row.ChargedDays)
+			d.ExpectedUsedDays, d.ExpectedPendingDays, d.ExpectedAvailableDays = &used, &pending, &available
+			// why used_days that differ are kept
+			var reason string
+			d.Kind = DriftPendingDays
+			if *row.UsedDays != used {
+				d.Kind = DriftUsedDays
+				switch {
+				case !overwriteUsed:
+					reason = "used_days differ from the days charged; overwrite_used_days=true replaces them"
+				case available < 0:
+					reason = "charged days exceed allocated plus carried forward days"
+				}
+			}
+			switch {
+			case dryRun:
+			case reason != "" && *row.PendingDays == pending:
+				d.Reason = reason
+			default:
+				setUsed := used
+				if reason != "" {
+					// pending_days are fixed alone; the balance still does
+					// not add up
+					setUsed, d.Reason = *row.UsedDays, reason
+				}
+				if err := r.LeaveBalances.SetLeaveBalanceDays(ctx, queries.SetLeaveBalanceDaysParams{
+					UsedDays: setUsed, PendingDays: pending, ID: *row.BalanceID,
+				}); err != nil {
+					return err
+				}
+				if reason == "" {
+					d.Fixed = true
+					res.Fixed++
+				}
+			}
+			res.Discrepancies = append(res.Discrepancies, d)
+		}
+		return nil
+	})
+	return res, err
+}
@@ -0,0 +1,406 @@
+// Package service holds business operations that span several queries. Every
+// method takes the caller's context so a cancelled request or an expired
+// deadline stops the work in the database too.
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"leave-management/internal/db/queries"
+	"leave-management/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotFound is returned when the target row does not exist
+var ErrNotFound = errors.New("not found")
+
+var (
+	// ErrAwaitingManager is returned when HR acts on a manager step that is
+	// not the last of the chain while the employee has a manager
+	ErrAwaitingManager = errors.New("awaiting manager approval")
+	// ErrAlreadyManagerApproved is returned when a manager acts on an hr
+	// step, i.e. once the manager steps are done
+	ErrAlreadyManagerApproved = errors.New("manager approval already recorded")
+	// ErrNotPending is returned when the request was already decided or
+	// cancelled
+	ErrNotPending = errors.New("leave request not pending")
+	// ErrStepNotCurrent is returned when the step acted on is not the
+	// request's first pending step
+	ErrStepNotCurrent = errors.New("approval step not current")
+	// ErrNotCancellable is returned when cancelling a request that was
+	// rejected or cancelled already
+	ErrNotCancellable = errors.New("leave request cannot be cancelled")
+	// ErrLeaveEnded is returned when cancelling an approved request whose
+	// end_date has passed
+	ErrLeaveEnded = errors.New("leave already ended")
+	// ErrOwnRequest is returned when approvers act on their own request
+	ErrOwnRequest = errors.New("cannot decide own leave request")
+	// ErrNotManager is returned when an approver other than HR or an admin
+	// is neither the employee's manager nor their active approval delegate
+	ErrNotManager = errors.New("approver does not manage the employee")
+	// ErrNoBalance is returned when reserving days in a balance that does
+	// not exist
+	ErrNoBalance = errors.New("no leave balance")
+	// ErrInsufficientBalance is returned when a balance, less the days
+	// reserved by pending requests, leaves fewer days than requested
+	ErrInsufficientBalance = errors.New("insufficient leave balance")
+)
+
+// Approval outcomes: approved, or the stage the request moved on to
+const (
+	Approved            = "approved"        // the request is approved and charged
+	StagePendingManager = "pending_manager" // a manager step is next
+	StagePendingHR      = "pending_hr"      // an hr step is next
+)
+
+// Approval routes of a leave request, decided by the approval routing rules
+// when it is created. The database turns each into the request's chain of
+// approval steps.
+const (
+	RouteAuto      = "auto"       // approved on creation, no steps
+	RouteManager   = "manager"    // a manager step
+	RouteHR        = "hr"         // an hr step
+	RouteManagerHR = "manager_hr" // a manager step, then an hr step
+)
+
+// Statuses of a leave request (leave_status)
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+)
+
+// transitions is the leave request state machine: the statuses a request in
+// each status may move to. Rejected and cancelled requests are final.
+var transitions = map[string][]string{
+	StatusPending:  {StatusApproved, StatusRejected, StatusCancelled},
+	StatusApproved: {StatusCancelled},
+}
+
+// CanTransition reports whether a request in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition returns the error for a request in status from that may not
+// move to status to, nil when it may: ErrNotCancellable for a cancellation,
+// ErrNotPending for a decision
+func transition(from, to string) error {
+	switch {
+	case CanTransition(from, to):
+		return nil
+	case to == StatusCancelled:
+		return ErrNotCancellable
+	default:
+		return ErrNotPending
+	}
+}
+
+// Approver roles of approval steps
+const (
+	StepManager = "manager" // the employee's manager, or HR
+	StepHR      = "hr"      // HR or an admin
+)
+
+// LeaveRequests implements the leave request workflow: approve, reject, cancel
+type LeaveRequests struct {
+	store repository.Store
+}
+
+func NewLeaveRequests(store repository.Store) *LeaveRequests {
+	return &LeaveRequests{store: store}
+}
+
+// Approve records approvedBy's approval of the request's current step and
+// reports the outcome. approvedBy is the authenticated approver's employee id;
+// nobody approves their own request. step, when not 0, is the step_no the
+// approver means to act on; it must be the current one. hr says whether the
+// approver is HR or an admin, who may approve any request; other approvers
+// must be the employee's manager, or stand in for them through an active
+// approval delegation. Only HR and admins act on hr steps, and on a manager
+// step that is not the last one only when the employee has no manager (the
+// step is then skipped and they act on the next). The approval of the last
+// step approves the request and charges its days to the employee's balance for
+// the current year, atomically, with a decision snapshot of the balance as it
+// was before the charge; the days it reserved are released.
+func (s *LeaveRequests) Approve(ctx context.Context, id string, step int, approvedBy string, hr bool) (string, error) {
+	var outcome string
+	err := s.store.InTx(ctx, func(r repository.Repos) error {
+		qtx := r.LeaveRequests
+		req, cur, err := current(ctx, qtx, id, step, approvedBy, hr, StatusApproved)
+		if err != nil {
+			return err
+		}
+		if cur == nil {
+			// a request without a chain needs any one approval
+			outcome = Approved
+			return approve(ctx, r, id, &approvedBy, req)
+		}
+		if cur.ApproverRole == StepManager && hr && !cur.IsLast && !req.HasManager {
+			if err := qtx.DecideApprovalStep(ctx, queries.DecideApprovalStepParams{Status: "skipped", ID: cur.ID}); err != nil {
+				return err
+			}
+			if cur, err = currentStep(ctx, qtx, id); err != nil {
+				return err
+			}
+		}
+		switch {
+		case cur.ApproverRole == StepHR && !hr:
+			return ErrAlreadyManagerApproved
+		case cur.ApproverRole == StepManager && hr && !cur.IsLast:
+			return ErrAwaitingManager
+		}
+		if err := qtx.DecideApprovalStep(ctx, queries.DecideApprovalStepParams{Status: "approved", ActedBy: &approvedBy, ID: cur.ID}); err != nil {
+			return err
+		}
+		if cur.ApproverRole == StepManager && !cur.IsLast {
+			if err := qtx.RecordManagerApproval(ctx, queries.RecordManagerApprovalParams{ManagerApprovedBy: &approvedBy, ID: id}); err != nil {
+				return err
+			}
+		}
+		next, err := currentStep(ctx, qtx, id)
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			outcome = "pending_" + next.ApproverRole
+			return nil
+		}
+		outcome = Approved
+		return approve(ctx, r, id, &approvedBy, req)
+	})
+	return outcome, err
+}
+
+// current locks the request id, which must be able to move to status to, and
+// returns it with its current approval step, nil when it has none. A step
+// other than 0 must be the current one. approver, the employee id of the
+// caller, may not decide their own request and, unless hr, must be the
+// employee's manager or the manager's active approval delegate.
+func current(ctx context.Context, qtx repository.LeaveRequestRepo, id string, step int, approver string, hr bool, to string) (queries.GetLeaveRequestForApprovalRow, *queries.GetCurrentApprovalStepRow, error) {
+	req, err := qtx.GetLeaveRequestForApproval(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return req, nil, ErrNotFound
+	}
+	if err != nil {
+		return req, nil, err
+	}
+	if approver != "" && req.EmployeeID == approver {
+		return req, nil, ErrOwnRequest
+	}
+	if !hr && (req.ManagerID == nil || *req.ManagerID != approver) {
+		delegate := false
+		if req.ManagerID != nil && approver != "" {
+			if delegate, err = qtx.IsApprovalDelegate(ctx, queries.IsApprovalDelegateParams{
+				Delegate: approver, Manager: *req.ManagerID,
+			}); err != nil {
+				return req, nil, err
+			}
+		}
+		if !delegate {
+			return req, nil, ErrNotManager
+		}
+	}
+	if err := transition(req.Status, to); err != nil {
+		return req, nil, err
+	}
+	cur, err := currentStep(ctx, qtx, id)
+	if err != nil {
+		return req, nil, err
+	}
+	if step != 0 && (cur == nil || int(cur.StepNo) != step) {
+		return req, nil, ErrStepNotCurrent
+	}
+	return req, cur, nil
+}
+
+func currentStep(ctx context.Context, qtx repository.LeaveRequestRepo, id string) (*queries.GetCurrentApprovalStepRow, error) {
+	cur, err := qtx.GetCurrentApprovalStep(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// Reserve sets aside the days of request id, created in tx, in the
+// employee's balance for the year it was applied for in: they count as
+// pending_days until the request is decided or cancelled. The balance must
+// leave enough days besides those reserved already, and stays locked until tx
+// ends, so concurrent requests cannot together overdraw it.
+func (s *LeaveRequests) Reserve(ctx context.Context, tx pgx.Tx, id string) error {
+	r := s.store.Bind(tx)
+	req, err := r.LeaveRequests.GetLeaveRequestForApproval(ctx, id)
+	if err != nil {
+		return err
+	}
+	left, err := r.LeaveBalances.GetLeaveBalanceForReservation(ctx, queries.GetLeaveBalanceForReservationParams{
+		EmployeeID:  req.EmployeeID,
+		LeaveTypeID: req.LeaveTypeID,
+		Year:        req.ReservedYear,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNoBalance
+	}
+	if err != nil {
+		return err
+	}
+	if req.TotalDays > left {
+		return ErrInsufficientBalance
+	}
+	return r.LeaveBalances.ReserveLeaveBalance(ctx, queries.ReserveLeaveBalanceParams{
+		PendingDays: req.TotalDays,
+		EmployeeID:  req.EmployeeID,
+		LeaveTypeID: req.LeaveTypeID,
+		Year:        req.ReservedYear,
+	})
+}
+
+// release gives back the days a pending request reserved
+func release(ctx context.Context, r repository.Repos, employeeID, leaveTypeID string, days float64, year int32) error {
+	return r.LeaveBalances.ReleaseLeaveBalance(ctx, queries.ReleaseLeaveBalanceParams{
+		PendingDays: days,
+		EmployeeID:  employeeID,
+		LeaveTypeID: leaveTypeID,
+		Year:        year,
+	})
+}
+
+// AutoApprove approves a request on the auto route inside the transaction
+// that created it; it has no approver
+func (s *LeaveRequests) AutoApprove(ctx context.Context, tx pgx.Tx, id string) error {
+	r := s.store.Bind(tx)
+	req, err := r.LeaveRequests.GetLeaveRequestForApproval(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := transition(req.Status, StatusApproved); err != nil {
+		return err
+	}
+	return approve(ctx, r, id, nil, req)
+}
+
+func approve(ctx context.Context, r repository.Repos, id string, by *string, req queries.GetLeaveRequestForApprovalRow) error {
+	year := int32(time.Now().Year())
+	if err := r.LeaveRequests.CreateDecisionSnapshot(ctx, queries.CreateDecisionSnapshotParams{
+		Decision: "approved", DecidedBy: by, Year: year, ID: id,
+	}); err != nil {
+		return err
+	}
+	n, err := r.LeaveRequests.ApproveLeaveRequest(ctx, queries.ApproveLeaveRequestParams{ApprovedBy: by, ID: id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotPending
+	}
+	if err := release(ctx, r, req.EmployeeID, req.LeaveTypeID, req.TotalDays, req.ReservedYear); err != nil {
+		return err
+	}
+	return r.LeaveBalances.ChargeLeaveBalance(ctx, queries.ChargeLeaveBalanceParams{
+		UsedDays:    req.TotalDays,
+		EmployeeID:  req.EmployeeID,
+		LeaveTypeID: req.LeaveTypeID,
+		Year:        year,
+	})
+}
+
+// Reject rejects the request's current step, and with it the request, for
+// the given reason, and records a decision snapshot; the steps after it are
+// skipped, and the days it reserved released. step and hr are as for
+// Approve, except that HR may reject at any step. rejectedBy is the
+// authenticated approver's employee id, or empty when they have none, which
+// only HR and admins may be.
+func (s *LeaveRequests) Reject(ctx context.Context, id string, step int, reason, rejectedBy string, hr bool) error {
+	var decidedBy *string
+	if rejectedBy != "" {
+		decidedBy = &rejectedBy
+	}
+	return s.store.InTx(ctx, func(r repository.Repos) error {
+		qtx := r.LeaveRequests
+		req, cur, err := current(ctx, qtx, id, step, rejectedBy, hr, StatusRejected)
+		if err != nil {
+			return err
+		}
+		if cur != nil {
+			if cur.ApproverRole == StepHR && !hr {
+				return ErrAlreadyManagerApproved
+			}
+			if err := qtx.DecideApprovalStep(ctx, queries.DecideApprovalStepParams{
+				Status: "rejected", ActedBy: decidedBy, Comment: &reason, ID: cur.ID,
+			}); err != nil {
+				return err
+			}
+		}
+		n, err := qtx.RejectLeaveRequest(ctx, queries.RejectLeaveRequestParams{RejectionReason: &reason, ID: id})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotPending
+		}
+		if err := qtx.CreateDecisionSnapshot(ctx, queries.CreateDecisionSnapshotParams{
+			Decision: "rejected", DecidedBy: decidedBy, Year: int32(time.Now().Year()), ID: id,
+		}); err != nil {
+			return err
+		}
+		return release(ctx, r, req.EmployeeID, req.LeaveTypeID, req.TotalDays, req.ReservedYear)
+	})
+}
+
+// Cancel marks a pending or approved request cancelled. A pending request
+// releases the days it reserved. An approved request can be cancelled until
+// its end_date; its days go back to the balance they were charged to,
+// atomically. Both changes are recorded by the audit
+// triggers.
+func (s *LeaveRequests) Cancel(ctx context.Context, id string) error {
+	return s.store.InTx(ctx, func(r repository.Repos) error {
+		req, err := r.LeaveRequests.GetLeaveRequestForCancel(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := transition(req.Status, StatusCancelled); err != nil {
+			return err
+		}
+		switch req.Status {
+		case StatusPending:
+			if err := release(ctx, r, req.EmployeeID, req.LeaveTypeID, req.TotalDays, req.ReservedYear); err != nil {
+				return err
+			}
+		case StatusApproved:
+			if req.Ended {
+				return ErrLeaveEnded
+			}
+			if err := r.LeaveBalances.RefundLeaveBalance(ctx, queries.RefundLeaveBalanceParams{
+				UsedDays:    req.TotalDays,
+				EmployeeID:  req.EmployeeID,
+				LeaveTypeID: req.LeaveTypeID,
+				Year:        req.ChargedYear,
+			}); err != nil {
+				return err
+			}
+		}
+		n, err := r.LeaveRequests.CancelLeaveRequest(ctx, id)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotCancellable
+		}
+		return nil
+	})
+}
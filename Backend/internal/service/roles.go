@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"leave-management/internal/db/queries"
+	"leave-management/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Roles keeps the role of a login (users.role), which the permissions are
+// checked against, equal to the role of its employee (employees.role)
+type Roles struct {
+	store repository.Store
+}
+
+func NewRoles(store repository.Store) *Roles {
+	return &Roles{store: store}
+}
+
+// SetUserRole gives user userID the role, and their employee with it,
+// atomically, and returns the role they had before
+func (s *Roles) SetUserRole(ctx context.Context, userID, role string) (string, error) {
+	var previous string
+	err := s.store.InTx(ctx, func(r repository.Repos) error {
+		u, err := r.Users.GetUserForRoleChange(ctx, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		previous = u.Role
+		if err := r.Users.SetUserRole(ctx, queries.SetUserRoleParams{ID: userID, Role: role}); err != nil {
+			return err
+		}
+		return r.Users.SetEmployeeRoleByCode(ctx, queries.SetEmployeeRoleByCodeParams{
+			Role: role, OrgID: u.OrgID, EmployeeCode: u.EmployeeID,
+		})
+	})
+	return previous, err
+}
+
+// SyncUserRole copies the role of employee employeeID to their login, if
+// they have one. It is meant to run in the transaction that changed the
+// employee's role, with r bound to it.
+func SyncUserRole(ctx context.Context, r repository.Repos, employeeID string) error {
+	return r.Users.SyncUserRole(ctx, employeeID)
+}
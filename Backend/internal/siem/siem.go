@@ -0,0 +1,211 @@
+// Package siem forwards audit and authentication events to a central security
+// monitoring system: a syslog collector, Splunk (HTTP Event Collector) or
+// Elasticsearch. Without a sink it does nothing.
+//
+// Authentication events are published by the handlers into a bounded buffer
+// and sent in batches by Run; when the sink falls behind and the buffer is
+// full, new events are dropped and counted rather than slowing down logins.
+// Audit events are read from the audit trail by jobs.ForwardAuditLogs, which
+// sends them with Send and keeps its place per chain, so none are lost while
+// the sink is down.
+package siem
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Event is one audit or authentication event as sent to the sink
+type Event struct {
+	ID        string                 `json:"id"` // unique; lets the sink drop the duplicates of a retried batch
+	Time      time.Time              `json:"@timestamp"`
+	Category  string                 `json:"category"`          // audit or auth
+	Action    string                 `json:"action"`            // INSERT, UPDATE, DELETE, ANONYMIZE; login, refresh, logout, change_password
+	Outcome   string                 `json:"outcome,omitempty"` // success or failure, for auth events
+	OrgID     string                 `json:"org_id,omitempty"`
+	UserID    string                 `json:"user_id,omitempty"`
+	Role      string                 `json:"role,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Config selects and tunes the sink
+type Config struct {
+	Sink          string // syslog, splunk or elastic; empty disables forwarding
+	URL           string
+	Token         string
+	Index         string
+	BatchSize     int
+	FlushInterval time.Duration
+	Buffer        int // auth events held while the sink is slow
+}
+
+// Sink delivers a batch of events
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+var events = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lms_siem_events_total",
+	Help: "Events forwarded to the SIEM by category and status (sent, dropped when the buffer was full, failed after retries).",
+}, []string{"category", "status"})
+
+// sendAttempts and retryBackoff bound how long Send keeps trying a batch
+const (
+	sendAttempts = 3
+	retryBackoff = time.Second
+)
+
+// drainTimeout is how long Run gets on shutdown to send what is buffered
+const drainTimeout = 5 * time.Second
+
+// Forwarder batches events to a sink. A nil Forwarder is valid and drops
+// everything, so callers need not check whether forwarding is configured.
+type Forwarder struct {
+	sink          Sink
+	queue         chan Event
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// New builds the forwarder for cfg, or returns nil when no sink is configured
+func New(cfg Config) (*Forwarder, error) {
+	var sink Sink
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "syslog":
+		s, err := newSyslogSink(cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		sink = s
+	case "splunk":
+		sink = newSplunkSink(cfg.URL, cfg.Token, cfg.Index)
+	case "elastic":
+		sink = newElasticSink(cfg.URL, cfg.Token, cfg.Index)
+	default:
+		return nil, fmt.Errorf("unknown SIEM sink %q", cfg.Sink)
+	}
+	return &Forwarder{
+		sink:          sink,
+		queue:         make(chan Event, cfg.Buffer),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+	}, nil
+}
+
+// Enabled reports whether events go anywhere
+func (f *Forwarder) Enabled() bool {
+	return f != nil
+}
+
+// BatchSize is the most events sent in one call to the sink
+func (f *Forwarder) BatchSize() int {
+	return f.batchSize
+}
+
+// Publish queues an event for Run without ever blocking; the event is
+// dropped when the buffer is full
+func (f *Forwarder) Publish(e Event) {
+	if f == nil {
+		return
+	}
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	select {
+	case f.queue <- e:
+	default:
+		events.WithLabelValues(e.Category, "dropped").Inc()
+	}
+}
+
+// Send delivers events, retrying with backoff, and returns the last error
+// once the attempts are used up
+func (f *Forwarder) Send(ctx context.Context, batch []Event) error {
+	var err error
+	for attempt := 0; attempt < sendAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryBackoff << (attempt - 1)):
+			}
+		}
+		if err = f.sink.Send(ctx, batch); err == nil {
+			for _, e := range batch {
+				events.WithLabelValues(e.Category, "sent").Inc()
+			}
+			return nil
+		}
+	}
+	return err
+}
+
+// Run sends published events, in batches of up to BatchSize or every flush
+// interval, until ctx is done; then it sends what is left in the buffer
+func (f *Forwarder) Run(ctx context.Context) {
+	if f == nil {
+		return
+	}
+	ticker := time.NewTicker(f.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, f.batchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := f.Send(ctx, batch); err != nil {
+			for _, e := range batch {
+				events.WithLabelValues(e.Category, "failed").Inc()
+			}
+			slog.Error("siem: forwarding events failed", "events", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case e := <-f.queue:
+			batch = append(batch, e)
+			if len(batch) >= f.batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer cancel()
+			for {
+				select {
+				case e := <-f.queue:
+					batch = append(batch, e)
+					if len(batch) >= f.batchSize {
+						flush(drainCtx)
+					}
+				default:
+					flush(drainCtx)
+					return
+				}
+			}
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
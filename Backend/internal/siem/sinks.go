@@ -0,0 +1,224 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// sinkTimeout bounds one delivery when the caller's ctx has no deadline
+const sinkTimeout = 10 * time.Second
+
+// syslogSink sends RFC 5424 messages, the event as JSON in the message part,
+// over UDP (one datagram each), TCP or TLS (octet-counted, RFC 6587)
+type syslogSink struct {
+	network  string // udp, tcp or tls
+	addr     string
+	hostname string
+}
+
+// syslogFacility is "log audit" in RFC 5424
+const syslogFacility = 13
+
+func newSyslogSink(raw string) (*syslogSink, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "udp" && u.Scheme != "tcp" && u.Scheme != "tls") {
+		return nil, fmt.Errorf("syslog SIEM_URL must look like udp://, tcp:// or tls://host:port")
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	return &syslogSink{network: u.Scheme, addr: u.Host, hostname: hostname}, nil
+}
+
+func (s *syslogSink) Send(ctx context.Context, events []Event) error {
+	ctx, cancel := withSinkTimeout(ctx)
+	defer cancel()
+	var conn net.Conn
+	var err error
+	if s.network == "tls" {
+		conn, err = (&tls.Dialer{}).DialContext(ctx, "tcp", s.addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, s.network, s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		msg, err := s.format(e)
+		if err != nil {
+			return err
+		}
+		if s.network == "udp" {
+			_, err = conn.Write(msg)
+		} else {
+			_, err = fmt.Fprintf(conn, "%d %s", len(msg), msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// format renders e as <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (s *syslogSink) format(e Event) ([]byte, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	severity := 6 // informational
+	if e.Outcome == "failure" {
+		severity = 4 // warning
+	}
+	header := fmt.Sprintf("<%d>1 %s %s lms %d %s - ",
+		syslogFacility*8+severity, e.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"), s.hostname, os.Getpid(), e.Category)
+	return append([]byte(header), body...), nil
+}
+
+// splunkSink posts to a Splunk HTTP Event Collector; url is the full
+// endpoint, e.g. https://splunk.example.com:8088/services/collector/event
+type splunkSink struct {
+	url, token, index string
+	client            *http.Client
+}
+
+func newSplunkSink(url, token, index string) *splunkSink {
+	return &splunkSink{url: url, token: token, index: index, client: &http.Client{Timeout: sinkTimeout}}
+}
+
+func (s *splunkSink) Send(ctx context.Context, events []Event) error {
+	// HEC takes the events of a batch as concatenated JSON objects
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		if err := enc.Encode(struct {
+			Time       float64 `json:"time"`
+			Source     string  `json:"source"`
+			Sourcetype string  `json:"sourcetype"`
+			Index      string  `json:"index,omitempty"`
+			Event      Event   `json:"event"`
+		}{float64(e.Time.UnixMilli()) / 1000, "lms", "lms:" + e.Category, s.index, e}); err != nil {
+			return err
+		}
+	}
+	_, err := post(ctx, s.client, s.url, "application/json", "Splunk "+s.token, &body)
+	return err
+}
+
+// elasticSink indexes through the Elasticsearch bulk API. Events are created
+// with their ID as the document id, so a retried batch does not duplicate
+// the events that made it the first time.
+type elasticSink struct {
+	url, auth, index string
+	client           *http.Client
+}
+
+func newElasticSink(baseURL, apiKey, index string) *elasticSink {
+	if index == "" {
+		index = "lms-events"
+	}
+	auth := ""
+	if apiKey != "" {
+		auth = "ApiKey " + apiKey
+	}
+	return &elasticSink{
+		url: strings.TrimRight(baseURL, "/") + "/_bulk", auth: auth, index: index,
+		client: &http.Client{Timeout: sinkTimeout},
+	}
+}
+
+func (s *elasticSink) Send(ctx context.Context, events []Event) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		action := map[string]map[string]string{"create": {"_index": s.index, "_id": e.ID}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	resp, err := post(ctx, s.client, s.url, "application/x-ndjson", s.auth, &body)
+	if err != nil {
+		return err
+	}
+
+	// the bulk API answers 200 even when some items failed
+	var res struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &res); err != nil {
+		return fmt.Errorf("elasticsearch bulk response: %w", err)
+	}
+	if !res.Errors {
+		return nil
+	}
+	for _, item := range res.Items {
+		for _, r := range item {
+			// 409: created by an earlier attempt
+			if r.Status >= 300 && r.Status != http.StatusConflict {
+				return fmt.Errorf("elasticsearch rejected an event (%d): %s", r.Status, r.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// post sends body and returns the response body, or an error for a non-2xx
+// status
+func post(ctx context.Context, client *http.Client, url, contentType, auth string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet := data
+		if len(snippet) > 200 {
+			snippet = snippet[:200]
+		}
+		return nil, fmt.Errorf("%s answered %d: %s", req.URL.Host, resp.StatusCode, snippet)
+	}
+	return data, nil
+}
+
+func withSinkTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, sinkTimeout)
+}
@@ -0,0 +1,263 @@
+// Package spreadsheet reads uploaded tables, CSV or the first worksheet of an
+// XLSX workbook, into rows of strings. XLSX is read with archive/zip and
+// encoding/xml: cell values come out as stored, so dates are Excel serial
+// numbers unless the cell is text (see ExcelDate).
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the kind of file a table is read from
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// FormatOf returns the format of a file by its name, or "" when it is neither
+// CSV nor XLSX
+func FormatOf(filename string) Format {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return CSV
+	case ".xlsx":
+		return XLSX
+	}
+	return ""
+}
+
+// ErrMalformed is returned for files that cannot be read as their format
+var ErrMalformed = errors.New("malformed file")
+
+// Read returns the rows of data, trailing empty rows left out. Rows may be of
+// different lengths.
+func Read(data []byte, format Format) ([][]string, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case CSV:
+		rows, err = readCSV(data)
+	case XLSX:
+		rows, err = readXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	for len(rows) > 0 && isBlank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+func isBlank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	// Excel writes a byte order mark in front of UTF-8 CSV
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	return r.ReadAll()
+}
+
+// ExcelDate converts an Excel date serial number, as a date cell of an XLSX
+// file holds it, to a date
+func ExcelDate(v string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(v, 64)
+	if err != nil || serial < 1 || serial > 2958465 { // up to 9999-12-31
+		return time.Time{}, false
+	}
+	// day 0 is 1899-12-30 in the 1900 date system, which counts the
+	// non-existent 1900-02-29
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)), true
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared string or inline string: plain text or runs of rich
+// text
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb xlsxWorkbook
+	if err := decodeXML(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, errors.New("workbook has no worksheets")
+	}
+	var rels xlsxRelationships
+	if err := decodeXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, r := range rels.Relationships {
+		if r.ID == wb.Sheets[0].RID {
+			sheetPath = r.Target
+		}
+	}
+	if sheetPath == "" {
+		return nil, errors.New("first worksheet not found")
+	}
+	// targets are relative to xl/ unless absolute within the package
+	if strings.HasPrefix(sheetPath, "/") {
+		sheetPath = strings.TrimPrefix(sheetPath, "/")
+	} else {
+		sheetPath = path.Join("xl", sheetPath)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXML(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+	var sheet xlsxSheet
+	if err := decodeXML(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		// rows and cells without content may be left out of the file, so
+		// their position is taken from their reference when present
+		n := row.R
+		if n == 0 {
+			n = len(rows) + 1
+		}
+		if n < len(rows)+1 || n > 1048576 {
+			return nil, fmt.Errorf("row %d out of order", i+1)
+		}
+		for len(rows) < n {
+			rows = append(rows, nil)
+		}
+		var values []string
+		for _, cell := range row.Cells {
+			col := len(values)
+			if cell.Ref != "" {
+				if col, err = columnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			var v string
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("cell %s: bad shared string index", cell.Ref)
+				}
+				v = shared.Items[idx].String()
+			case "inlineStr":
+				v = cell.Inline.String()
+			default:
+				v = cell.Value
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = v
+		}
+		rows[n-1] = values
+	}
+	return rows, nil
+}
+
+// columnIndex returns the zero-based column of a cell reference such as "C12"
+func columnIndex(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z' && col <= 16384; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || col > 16384 {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+func decodeXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package stream pushes leave request changes to connected clients (GET
+// /events). Postgres announces every new leave request, every status change
+// and every new comment on the leave_events channel when the transaction
+// commits (see notify_leave_request_event and notify_leave_request_comment in
+// Database/db.sql), so a change made through any instance reaches the clients
+// of all of them.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"leave-management/internal/db"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// channel is the Postgres notification channel the trigger writes to
+const channel = "leave_events"
+
+// Event types
+const (
+	Submitted     = "leave_request.submitted"      // a new request awaits approval
+	StatusChanged = "leave_request.status_changed" // approved, rejected or cancelled
+	Commented     = "leave_request.commented"      // a comment was added to the request's thread
+)
+
+// Event is one change, as the trigger reports it
+type Event struct {
+	Type           string    `json:"type"`
+	OrgID          string    `json:"org_id"`
+	LeaveRequestID string    `json:"leave_request_id"`
+	EmployeeID     string    `json:"employee_id"`
+	ManagerID      string    `json:"manager_id,omitempty"` // the employee's, when they have one
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	CommentID      string    `json:"comment_id,omitempty"` // of Commented events
+	AuthorID       string    `json:"author_id,omitempty"`  // of Commented events: who wrote the comment
+	Time           time.Time `json:"time"`
+}
+
+// bufferSize is how many events a subscriber may fall behind before it is
+// disconnected
+const bufferSize = 64
+
+var (
+	subscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "lms_event_stream_subscribers",
+		Help: "Clients connected to /events.",
+	})
+	slowClients = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "lms_event_stream_slow_disconnects_total",
+		Help: "Clients disconnected from /events for not keeping up.",
+	})
+)
+
+// Subscription receives the events its filter accepts on C. C is closed when
+// the subscriber falls too far behind or the broker shuts down.
+type Subscription struct {
+	C     <-chan Event
+	ch    chan Event
+	match func(Event) bool
+}
+
+// Broker listens for events and hands them to the subscribers
+type Broker struct {
+	pool *pgxpool.Pool
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func New(pool *pgxpool.Pool) *Broker {
+	return &Broker{pool: pool, subs: map[*Subscription]struct{}{}}
+}
+
+// Run listens on one dedicated connection until ctx is done, reconnecting
+// with backoff when it is lost. Events sent while it is reconnecting are not
+// delivered.
+func (b *Broker) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := b.listen(ctx, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("event stream: listening failed, retrying", "error", err, "retry_in", backoff.String())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context, connected func()) error {
+	conn, err := b.pool.Acquire(db.AsService(ctx))
+	if err != nil {
+		return err
+	}
+	// the connection stays LISTENing, so it is not given back to the pool
+	pc := conn.Hijack()
+	defer pc.Close(context.Background())
+	if _, err := pc.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	connected()
+	for {
+		n, err := pc.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			slog.Warn("event stream: ignoring a malformed notification", "error", err)
+			continue
+		}
+		b.publish(e)
+	}
+}
+
+// Subscribe registers a subscriber for the events match accepts. It reports
+// false once the broker is closed.
+func (b *Broker) Subscribe(match func(Event) bool) (*Subscription, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, false
+	}
+	ch := make(chan Event, bufferSize)
+	s := &Subscription{C: ch, ch: ch, match: match}
+	b.subs[s] = struct{}{}
+	subscribers.Inc()
+	return s, true
+}
+
+// Unsubscribe removes s; removing it twice is harmless
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(s)
+}
+
+// Close disconnects every subscriber and refuses new ones, so open streams
+// end when the server shuts down
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.remove(s)
+	}
+}
+
+func (b *Broker) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.match(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			// the client reconnects and reloads what it shows
+			b.remove(s)
+			slowClients.Inc()
+		}
+	}
+}
+
+// remove must be called with mu held
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	close(s.ch)
+	subscribers.Dec()
+}
@@ -0,0 +1,279 @@
+// Package webhook delivers leave and employee events to the URLs an
+// organization registers at /webhooks, for payroll and HRIS integrations.
+//
+// Postgres queues one delivery per subscribed webhook in the transaction that
+// caused the event (see queue_webhook_event in Database/db.sql), so nothing
+// is sent for a change that was rolled back and nothing is lost when a
+// receiver is down. jobs.DeliverWebhooks sends the due deliveries with a
+// Client and schedules the failed ones again after Backoff, until they run
+// out of attempts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Events a webhook can subscribe to
+const (
+	LeaveApplied        = "leave.applied"        // a leave request was created
+	LeaveApproved       = "leave.approved"       // by its last approver, or automatically
+	LeaveRejected       = "leave.rejected"       // by an approver
+	LeaveCancelled      = "leave.cancelled"      // pending or approved, by the employee or HR
+	EmployeeCreated     = "employee.created"     // also by an import
+	EmployeeDeactivated = "employee.deactivated" // also by an erasure
+)
+
+// Events lists every event, in the order the API documents them. The
+// check_webhook_events constraint in Database/db.sql allows the same.
+var Events = []string{LeaveApplied, LeaveApproved, LeaveRejected, LeaveCancelled, EmployeeCreated, EmployeeDeactivated}
+
+// ValidEvent reports whether a webhook can subscribe to event
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Request headers of a delivery
+const (
+	HeaderID        = "X-Webhook-Id" // the delivery's id, the same on every attempt
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix seconds, part of the signed content
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex HMAC>, see Sign
+)
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	ID        string          `json:"id"` // lets the receiver drop the duplicates of a retried delivery
+	Event     string          `json:"event"`
+	OrgID     string          `json:"org_id"`
+	CreatedAt time.Time       `json:"created_at"` // when the event happened
+	Data      json.RawMessage `json:"data"`       // the leave request or employee, as of the event
+}
+
+// Sign returns the X-Webhook-Signature of body sent at t: the hex HMAC-SHA256,
+// keyed with the webhook's secret, of the Unix timestamp, a dot and the body.
+// Receivers compute the same from the X-Webhook-Timestamp header and the raw
+// body, and reject old timestamps to stop replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(t.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// ValidateURL checks that raw is an absolute http or https URL whose host is
+// not obviously internal: localhost or a loopback, private or link-local IP.
+// A name that resolves to such an address is refused when the Client dials.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("must not contain credentials")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("must not point to localhost")
+	}
+	if ip := net.ParseIP(host); ip != nil && !PublicIP(ip) {
+		return errors.New("must not point to a private, loopback or link-local address")
+	}
+	return nil
+}
+
+// nonPublic are the ranges PublicIP refuses on top of the loopback, private,
+// link-local, multicast and unspecified ones the net package knows
+var nonPublic = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // "this" network
+		"100.64.0.0/10", // carrier-grade NAT
+		"192.0.0.0/24",  // IETF protocol assignments
+		"198.18.0.0/15", // benchmarking
+		"240.0.0.0/4",   // reserved, and broadcast
+		"64:ff9b::/96",  // NAT64, which may map to any IPv4 address
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// PublicIP reports whether ip is an address on the internet, as opposed to
+// one of the deployment's own network (loopback, RFC 1918, link-local such as
+// the 169.254.169.254 metadata service, and the like)
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range nonPublic {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// errNotPublic is returned for a connection to a non-public address
+var errNotPublic = errors.New("address is not public")
+
+// NewTransport returns an http.Transport that only connects to public
+// addresses. The check runs on the address actually dialed, after DNS, so a
+// name that resolves (or is later rebound) to an internal address is refused
+// too. It uses no proxy, which would hide the destination.
+func NewTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return fmt.Errorf("%s: %w", host, errNotPublic)
+			}
+			return nil
+		},
+	}
+	return &http.Transport{
+		Proxy:               nil,
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// Delivery outcomes
+const (
+	Delivered = "delivered"
+	Retrying  = "retrying" // failed, another attempt is scheduled
+	Failed    = "failed"   // failed and out of attempts
+)
+
+var attempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "lms_webhook_deliveries_total",
+	Help: "Webhook delivery attempts by event and outcome (delivered, retrying, failed).",
+}, []string{"event", "outcome"})
+
+// Count records the outcome of one attempt in lms_webhook_deliveries_total
+func Count(event, outcome string) {
+	attempts.WithLabelValues(event, outcome).Inc()
+}
+
+// retryBackoff and maxBackoff shape the wait between two attempts
+const (
+	retryBackoff = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+)
+
+// Backoff is how long a delivery waits after its attempt-th failed attempt:
+// 30s, doubling every attempt, at most 6h
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := retryBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// maxErrorBody is how much of a failed response is kept in the delivery log
+const maxErrorBody = 512
+
+// Client sends deliveries
+type Client struct {
+	http *http.Client
+}
+
+// NewClient returns a Client whose attempts give up after timeout. Redirects
+// are not followed: a receiver that moved must be updated at /webhooks. Only
+// public addresses are dialed (see NewTransport), as the response of a failed
+// attempt is kept in the delivery log.
+func NewClient(timeout time.Duration) *Client {
+	return &Client{http: &http.Client{
+		Timeout:   timeout,
+		Transport: NewTransport(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send POSTs p to target, signed with secret, and returns the response's
+// status code, or 0 when there was no response. Any status but 2xx is an
+// error that carries the start of the response body.
+func (c *Client) Send(ctx context.Context, target, secret string, p Payload) (int, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "leave-management-webhooks")
+	req.Header.Set(HeaderID, p.ID)
+	req.Header.Set(HeaderEvent, p.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(secret, now, body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	msg := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(b)); text != "" {
+		msg += ": " + text
+	}
+	return resp.StatusCode, errors.New(msg)
+}
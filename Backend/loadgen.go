@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"leave-management/internal/loadgen"
+	"leave-management/internal/logging"
+)
+
+// runLoadgen implements `loadgen`: it fills DATABASE_URL with synthetic data
+// for performance testing. Never point it at production.
+func runLoadgen(args []string) {
+	fs := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	employees := fs.Int("employees", 1000, "employees to create")
+	requests := fs.Int("requests", 10000, "leave requests to create for the current year")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed, for repeatable distributions")
+	org := fs.String("org", "default", "slug of the organization to fill")
+	_ = fs.Parse(args)
+	if *employees < 0 || *requests < 0 {
+		logging.Fatal("loadgen: -employees and -requests must not be negative")
+	}
+
+	ctx, pool, done := commandPool()
+	defer done()
+
+	start := time.Now()
+	res, err := loadgen.Run(inOrg(ctx, pool, *org), pool, loadgen.Options{Employees: *employees, Requests: *requests, Seed: *seed})
+	if err != nil {
+		slog.Error("loadgen failed, nothing was inserted", "error", err)
+		done()
+		os.Exit(1)
+	}
+	slog.Info("loadgen done",
+		"departments_created", res.Departments,
+		"leave_types_created", res.LeaveTypes,
+		"employees", res.Employees,
+		"leave_requests", res.LeaveRequests,
+		"seed", *seed,
+		"duration", time.Since(start).String(),
+	)
+}
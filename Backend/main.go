@@ -1,28 +1,48 @@
 package main
 
 import (
-	"context"
-	"log"
+	"fmt"
+	"os"
+	"strings"
+)
 
-	"leave-management/internal/config"
-	"leave-management/internal/db"
-	"leave-management/internal/router"
+const usage = `Usage: leave-management [command] [flags]
 
-	"github.com/gin-gonic/gin"
-)
+Commands:
+  serve          run the API server (default)
+  migrate        create the schema from Database/db.sql on an empty database
+  seed           insert the default departments and leave types
+  create-org     create an organization (-name, -slug)
+  create-admin   create an admin employee and login (-email, -password)
+  loadgen        fill the database with synthetic data for load testing
+
+seed, create-admin and loadgen work on the default organization unless -org
+names another. Run "leave-management <command> -h" for the flags of a command.
+`
 
 // main func ready here
 func main() {
-	cfg := config.Load()
-	ctx := context.Background()
-	pool := db.NewPool(ctx, cfg.DatabaseURL)
-	defer pool.Close()
-
-	r := gin.Default()
-	router.Setup(r, pool)
-
-	log.Printf("listening on :%s ...", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatal(err)
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "serve":
+		runServe(args)
+	case "migrate":
+		runMigrate(args)
+	case "seed":
+		runSeed(args)
+	case "create-org":
+		runCreateOrg(args)
+	case "create-admin":
+		runCreateAdmin(args)
+	case "loadgen":
+		runLoadgen(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
 	}
 }
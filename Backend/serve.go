@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"leave-management/internal/cache"
+	"leave-management/internal/chat"
+	"leave-management/internal/config"
+	"leave-management/internal/db"
+	"leave-management/internal/errreport"
+	"leave-management/internal/grpcapi"
+	"leave-management/internal/i18n"
+	"leave-management/internal/jobs"
+	"leave-management/internal/logging"
+	"leave-management/internal/maintenance"
+	"leave-management/internal/middleware"
+	"leave-management/internal/router"
+	"leave-management/internal/siem"
+	"leave-management/internal/stream"
+	"leave-management/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// runServe implements `serve`, the default command: the HTTP API, the optional
+// gRPC server and the background jobs, until SIGINT/SIGTERM.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	cfg := config.Load()
+	live := config.NewLive(cfg)
+	logging.SetLevel(cfg.LogLevel)
+	if cfg.I18nDir != "" {
+		if err := i18n.LoadDir(cfg.I18nDir); err != nil {
+			logging.Fatal("i18n catalogs", "error", err)
+		}
+	}
+	if err := errreport.Init(cfg.SentryDSN, cfg.SentryEnvironment); err != nil {
+		logging.Fatal("sentry init", "error", err)
+	}
+
+	// ctx is cancelled on SIGINT/SIGTERM, which starts the shutdown below
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	poolOptions := func() db.PoolOptions {
+		return db.PoolOptions{
+			MaxConns:         cfg.DBMaxConns,
+			MinConns:         cfg.DBMinConns,
+			MaxConnIdleTime:  cfg.DBMaxConnIdleTime,
+			StatementTimeout: cfg.DBStatementTimeout,
+			SlowQuery:        cfg.DBSlowQuery,
+			Breaker:          db.NewBreaker(cfg.DBBreakerThreshold, cfg.DBBreakerCooldown),
+		}
+	}
+	pool := db.NewPool(context.Background(), cfg.DatabaseURL, poolOptions())
+	defer pool.Close()
+	if role, bypass, err := db.BypassesRLS(ctx, pool); err != nil {
+		slog.Warn("could not check the database role for row level security", "error", err)
+	} else if bypass {
+		slog.Warn("the database role bypasses row level security; tenants are not isolated", "role", role)
+	}
+
+	// reports, lists and the read-only gRPC API go to the replica when there is one
+	read := pool
+	if cfg.ReplicaDatabaseURL != "" {
+		read = db.NewPool(context.Background(), cfg.ReplicaDatabaseURL, poolOptions())
+		defer read.Close()
+	}
+
+	rc, err := cache.New(context.Background(), cfg.RedisURL, cfg.CacheTTL)
+	if err != nil {
+		logging.Fatal("cache", "error", err)
+	}
+	defer rc.Close()
+
+	events, err := siem.New(siem.Config{
+		Sink:          cfg.SIEMSink,
+		URL:           cfg.SIEMURL,
+		Token:         cfg.SIEMToken,
+		Index:         cfg.SIEMIndex,
+		BatchSize:     cfg.SIEMBatchSize,
+		FlushInterval: cfg.SIEMFlushInterval,
+		Buffer:        cfg.SIEMBuffer,
+	})
+	if err != nil {
+		logging.Fatal("siem", "error", err)
+	}
+
+	// Background jobs
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "absence-anomalies", cfg.AnomalyScanInterval, func(ctx context.Context) error {
+			s, _ := jobs.SensitivityFor(live.Get().AnomalySensitivity)
+			_, err := jobs.DetectAbsenceAnomalies(db.AsService(ctx), pool, s)
+			return err
+		})
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "archive", cfg.ArchiveInterval, func(ctx context.Context) error {
+			days := live.Get().ArchiveAfterDays
+			res, err := jobs.ArchiveOldRecords(db.AsService(ctx), pool, time.Duration(days)*24*time.Hour)
+			if res.LeaveRequests > 0 || res.AuditLogs > 0 {
+				slog.Info("archived old records", "leave_requests", res.LeaveRequests, "audit_logs", res.AuditLogs, "older_than_days", days)
+			}
+			return err
+		})
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "audit-retention", cfg.AuditPurgeInterval, func(ctx context.Context) error {
+			results, err := jobs.ApplyAuditRetention(db.AsService(ctx), pool, live.Get().AuditRetention, false)
+			for _, r := range results {
+				if r.Rows > 0 {
+					slog.Info("applied audit retention", "table", r.Table, "action", r.Action, "rows", r.Rows)
+				}
+			}
+			return err
+		})
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "erasure", cfg.ErasureInterval, func(ctx context.Context) error {
+			n, err := jobs.ProcessErasureRequests(db.AsService(ctx), pool)
+			if n > 0 {
+				slog.Info("completed erasure requests", "count", n)
+			}
+			return err
+		})
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "accruals", cfg.AccrualInterval, func(ctx context.Context) error {
+			res, err := jobs.CreditAccruals(db.AsService(ctx), pool, time.Now())
+			if res.Periods > 0 {
+				slog.Info("credited leave accruals", "periods", res.Periods, "days", res.Days, "balances", res.Balances)
+			}
+			return err
+		})
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "leave-year-rollover", cfg.RolloverInterval, func(ctx context.Context) error {
+			// last year, for the organizations it has not been done for yet
+			res, err := jobs.RolloverLeaveYear(db.AsService(ctx), pool, time.Now().Year()-1, true, false, "")
+			if res.Balances > 0 {
+				slog.Info("rolled over leave balances", "year", res.Year, "balances", res.Balances, "changed", res.Changed,
+					"carried_forward_days", res.CarriedForwardDays)
+			}
+			return err
+		})
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "comp-off-expiry", cfg.CompOffExpiryInterval, func(ctx context.Context) error {
+			res, err := jobs.ExpireCompOffs(db.AsService(ctx), pool, time.Now())
+			if res.Credits > 0 {
+				slog.Info("expired comp-off credits", "credits", res.Credits, "days", res.Days)
+			}
+			return err
+		})
+	}()
+	hooks := webhook.NewClient(cfg.WebhookTimeout)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "webhooks", cfg.WebhookInterval, func(ctx context.Context) error {
+			cfg := live.Get()
+			res, err := jobs.DeliverWebhooks(db.AsService(ctx), pool, hooks, cfg.WebhookMaxAttempts,
+				time.Duration(cfg.WebhookRetentionDays)*24*time.Hour)
+			if res.Retrying > 0 || res.Failed > 0 {
+				slog.Warn("webhook deliveries failed", "delivered", res.Delivered, "retrying", res.Retrying, "failed", res.Failed)
+			}
+			return err
+		})
+	}()
+	chatClient := chat.NewClient(cfg.WebhookTimeout)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		jobs.Every(ctx, "chat", cfg.ChatInterval, func(ctx context.Context) error {
+			cfg := live.Get()
+			res, err := jobs.SendChatMessages(db.AsService(ctx), pool, chatClient, cfg.ChatCallbackURL, cfg.WebhookMaxAttempts,
+				time.Duration(cfg.WebhookRetentionDays)*24*time.Hour)
+			if res.Retrying > 0 || res.Failed > 0 {
+				slog.Warn("chat messages failed", "sent", res.Sent, "retrying", res.Retrying, "failed", res.Failed)
+			}
+			return err
+		})
+	}()
+	// auth events are published until the HTTP server has drained, so the
+	// forwarder stops after it rather than with ctx
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	if events.Enabled() {
+		workers.Add(2)
+		go func() {
+			defer workers.Done()
+			events.Run(eventsCtx)
+		}()
+		go func() {
+			defer workers.Done()
+			jobs.Every(ctx, "siem-audit", cfg.SIEMAuditInterval, func(ctx context.Context) error {
+				_, err := jobs.ForwardAuditLogs(db.AsService(ctx), pool, events)
+				return err
+			})
+		}()
+	}
+
+	// leave request changes for GET /events, from every instance
+	broker := stream.New(pool)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		broker.Run(ctx)
+	}()
+
+	// read-only maintenance mode, shared by every instance
+	mode := maintenance.New(pool)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		mode.Run(ctx)
+	}()
+
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), middleware.Recovery())
+	router.Setup(r, pool, read, rc, live, events, broker, mode)
+	go reloadOnHangup(ctx, live)
+
+	// gRPC API for internal services, on its own port
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logging.Fatal("grpc listen", "error", err)
+		}
+		grpcServer = grpcapi.NewServer(read, cfg.GRPCAuthToken,
+			func() time.Duration { return live.Get().RequestTimeout }, func() time.Duration { return live.Get().QueryTimeout })
+		go func() {
+			slog.Info("grpc listening", "port", cfg.GRPCPort)
+			if err := grpcServer.Serve(lis); err != nil {
+				logging.Fatal("grpc serve", "error", err)
+			}
+		}()
+	}
+
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	srv.RegisterOnShutdown(broker.Close) // open streams would hold Shutdown up
+	go func() {
+		slog.Info("listening", "port", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("http serve", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop() // a second signal kills the process immediately
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http shutdown", "error", err)
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer)
+	}
+	stopEvents()
+
+	// jobs see the cancelled ctx and return after their current run
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		slog.Warn("background jobs did not stop in time")
+	}
+	errreport.Flush(2 * time.Second)
+	slog.Info("shutdown complete")
+}
+
+// reloadOnHangup reloads the configuration on every SIGHUP until ctx is done.
+// An invalid configuration is logged and the running one kept.
+func reloadOnHangup(ctx context.Context, live *config.Live) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		changed, restart, err := live.Reload()
+		if err != nil {
+			slog.Error("config reload failed, keeping the current configuration", "error", err)
+			continue
+		}
+		logging.SetLevel(live.Get().LogLevel)
+		if len(restart) > 0 {
+			slog.Warn("config reload: changed settings that need a restart were ignored", "settings", restart)
+		}
+		slog.Info("config reloaded", "changed", changed)
+	}
+}
+
+// stopGRPC lets in-flight RPCs finish, cutting them off when ctx expires
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.Stop()
+	}
+}
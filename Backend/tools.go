@@ -0,0 +1,7 @@
+//go:build tools
+
+// Pins the code generators in go.mod, so that go generate runs the versions
+// the generated code was made with.
+package main
+
+import _ "github.com/99designs/gqlgen"
@@ -11,50 +11,129 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 -- Enums
 CREATE TYPE leave_status AS ENUM ('pending', 'approved', 'rejected', 'cancelled');
+-- who has to approve a leave request (see approval_routing_rules); each
+-- approver is a step of the request's approval chain (see approval_steps)
+CREATE TYPE approval_route AS ENUM ('auto', 'manager', 'hr', 'manager_hr');
+CREATE TYPE approval_step_role AS ENUM ('manager', 'hr');
+CREATE TYPE approval_step_status AS ENUM ('pending', 'approved', 'rejected', 'skipped');
+-- how much of the day a leave request covers; partial days are single-day requests
+CREATE TYPE leave_duration_unit AS ENUM ('full_day', 'half_day_am', 'half_day_pm', 'hours');
+-- how a leave type's allowance is granted: all of it at the start of the year,
+-- or accrued every month or quarter (see leave_accruals)
+CREATE TYPE accrual_frequency AS ENUM ('annual', 'monthly', 'quarterly');
 CREATE TYPE employee_role AS ENUM ('employee', 'hr', 'manager', 'admin');
 
+-- 0. Organizations (tenants). Every other table has an org_id and a row level
+-- security policy, see "Tenant isolation" at the end.
+CREATE TABLE organizations (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT check_slug_format CHECK (slug ~ '^[a-z0-9][a-z0-9-]*$')
+);
+
+-- Single-company deployments keep using this one
+INSERT INTO organizations (id, name, slug) VALUES
+('00000000-0000-0000-0000-000000000001', 'Default Organization', 'default');
+
+-- The backend sets request.jwt.claims to the caller of every request: sub is
+-- the user id and role the user's role, org_id scopes it to one organization,
+-- and role "service" (jobs, login, command line tools) sees all of them.
+CREATE OR REPLACE FUNCTION current_org_id()
+RETURNS UUID AS $$
+    SELECT (NULLIF(current_setting('request.jwt.claims', true), '')::jsonb ->> 'org_id')::uuid;
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION current_app_user_id()
+RETURNS UUID AS $$
+    SELECT (NULLIF(current_setting('request.jwt.claims', true), '')::jsonb ->> 'sub')::uuid;
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION current_app_role()
+RETURNS TEXT AS $$
+    SELECT NULLIF(current_setting('request.jwt.claims', true), '')::jsonb ->> 'role';
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION is_service_request()
+RETURNS BOOLEAN AS $$
+    SELECT COALESCE(current_app_role() = 'service', false);
+$$ LANGUAGE SQL STABLE;
+
+-- HR and admins of the current organization, or a service request
+CREATE OR REPLACE FUNCTION is_hr_request()
+RETURNS BOOLEAN AS $$
+    SELECT COALESCE(current_app_role() IN ('hr', 'admin', 'service'), false);
+$$ LANGUAGE SQL STABLE;
+
 -- 1. Departments
 CREATE TABLE departments (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-    name VARCHAR(100) NOT NULL UNIQUE,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
     description TEXT,
     manager_id UUID,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT departments_name_key UNIQUE (org_id, name),
+    UNIQUE (org_id, id)
 );
 
 
 -- 2. Leave Types
 CREATE TABLE leave_types (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-    name VARCHAR(50) NOT NULL UNIQUE,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
     description TEXT,
     max_days_per_year INTEGER NOT NULL DEFAULT 0,
     carry_forward_allowed BOOLEAN DEFAULT FALSE,
     max_carry_forward_days INTEGER DEFAULT 0,
+    accrual_frequency accrual_frequency NOT NULL DEFAULT 'annual',
+    accrual_rate NUMERIC(5,2), -- days per period; NULL spreads max_days_per_year evenly
+    -- policy rules checked when leave is applied for: whether weekends and
+    -- holidays between two days of leave are charged too, the notice to give
+    -- in calendar days, and the most days one request may charge (NULL: any)
+    sandwich_rule BOOLEAN NOT NULL DEFAULT FALSE,
+    min_notice_days INTEGER NOT NULL DEFAULT 0,
+    max_consecutive_days INTEGER,
     is_active BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT leave_types_name_key UNIQUE (org_id, name),
+    UNIQUE (org_id, id),
     CONSTRAINT check_max_days_positive CHECK (max_days_per_year >= 0),
-    CONSTRAINT check_carry_forward_days CHECK (max_carry_forward_days >= 0)
+    CONSTRAINT check_carry_forward_days CHECK (max_carry_forward_days >= 0),
+    CONSTRAINT check_accrual_rate CHECK (accrual_rate IS NULL OR accrual_rate > 0),
+    CONSTRAINT check_min_notice_days CHECK (min_notice_days >= 0),
+    CONSTRAINT check_max_consecutive_days CHECK (max_consecutive_days IS NULL OR max_consecutive_days > 0)
 );
 
 -- 3. Employees
 CREATE TABLE employees (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-    employee_id VARCHAR(20) NOT NULL UNIQUE,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id VARCHAR(20) NOT NULL,
     email VARCHAR(255) NOT NULL UNIQUE,
     name VARCHAR(255) NOT NULL,
-    department_id UUID NOT NULL REFERENCES departments(id) ON DELETE RESTRICT,
+    department_id UUID NOT NULL,
     role employee_role DEFAULT 'employee',
     joining_date DATE NOT NULL,
+    resignation_date DATE, -- start of the notice period; NULL while employed
     manager_id UUID REFERENCES employees(id) ON DELETE SET NULL,
     is_active BOOLEAN DEFAULT TRUE,
     phone VARCHAR(15),
     address TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT employees_employee_id_key UNIQUE (org_id, employee_id),
+    UNIQUE (org_id, id),
+    CONSTRAINT employees_department_id_fkey FOREIGN KEY (org_id, department_id)
+        REFERENCES departments(org_id, id) ON DELETE RESTRICT,
     CONSTRAINT check_joining_date CHECK (joining_date <= CURRENT_DATE),
+    CONSTRAINT check_resignation_date CHECK (resignation_date IS NULL OR resignation_date >= joining_date),
     CONSTRAINT check_email_format CHECK (email ~* '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$'),
     CONSTRAINT check_phone_format CHECK (phone IS NULL OR phone ~ '^\+?[0-9]{7,15}$')
 );
@@ -62,19 +141,27 @@ CREATE TABLE employees (
 -- 4. Employee Leave Balances
 CREATE TABLE employee_leave_balances (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
-    leave_type_id UUID NOT NULL REFERENCES leave_types(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    leave_type_id UUID NOT NULL,
     year INTEGER NOT NULL,
-    allocated_days INTEGER NOT NULL DEFAULT 0,
-    used_days INTEGER NOT NULL DEFAULT 0,
+    allocated_days NUMERIC(6,2) NOT NULL DEFAULT 0, -- accruals make it fractional
+    used_days NUMERIC(6,2) NOT NULL DEFAULT 0, -- half days and hours make it fractional
     carried_forward_days INTEGER NOT NULL DEFAULT 0,
-    available_days INTEGER GENERATED ALWAYS AS (allocated_days + carried_forward_days - used_days) STORED,
+    available_days NUMERIC(6,2) GENERATED ALWAYS AS (allocated_days + carried_forward_days - used_days) STORED,
+    -- reserved by pending requests (applied for in this year), not yet used
+    pending_days NUMERIC(6,2) NOT NULL DEFAULT 0,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     UNIQUE(employee_id, leave_type_id, year),
+    CONSTRAINT employee_leave_balances_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT employee_leave_balances_leave_type_id_fkey FOREIGN KEY (org_id, leave_type_id)
+        REFERENCES leave_types(org_id, id) ON DELETE CASCADE,
     CONSTRAINT check_allocated_days_positive CHECK (allocated_days >= 0),
     CONSTRAINT check_used_days_positive CHECK (used_days >= 0),
     CONSTRAINT check_carried_forward_positive CHECK (carried_forward_days >= 0),
+    CONSTRAINT check_pending_days_positive CHECK (pending_days >= 0),
     CONSTRAINT check_used_days_limit CHECK (used_days <= allocated_days + carried_forward_days),
     CONSTRAINT check_year_valid CHECK (year >= 2020 AND year <= 2050)
 );
@@ -82,11 +169,12 @@ CREATE TABLE employee_leave_balances (
 -- 5. Leave Requests
 CREATE TABLE leave_requests (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
-    leave_type_id UUID NOT NULL REFERENCES leave_types(id) ON DELETE RESTRICT,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    leave_type_id UUID NOT NULL,
     start_date DATE NOT NULL,
     end_date DATE NOT NULL,
-    total_days INTEGER NOT NULL,
+    total_days NUMERIC(6,2) NOT NULL, -- 0.5 for half days, hours / WORKDAY_HOURS for hours
     reason TEXT NOT NULL,
     status leave_status DEFAULT 'pending',
     applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
@@ -96,11 +184,29 @@ CREATE TABLE leave_requests (
     comments TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- set from approval_routing_rules when the request is created
+    approval_route approval_route NOT NULL DEFAULT 'manager',
+    routing_rule_id UUID, -- the rule that matched; NULL for the default route
+    -- the manager step of a manager_hr request, also in approval_steps
+    manager_approved_by UUID REFERENCES employees(id) ON DELETE SET NULL,
+    manager_approved_at TIMESTAMP WITH TIME ZONE,
+    duration_unit leave_duration_unit NOT NULL DEFAULT 'full_day',
+    hours NUMERIC(4,2), -- only for duration_unit 'hours'
+    UNIQUE (org_id, id),
+    CONSTRAINT leave_requests_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT leave_requests_leave_type_id_fkey FOREIGN KEY (org_id, leave_type_id)
+        REFERENCES leave_types(org_id, id) ON DELETE RESTRICT,
     CONSTRAINT check_date_order CHECK (end_date >= start_date),
     CONSTRAINT check_total_days_positive CHECK (total_days > 0),
     CONSTRAINT check_reason_not_empty CHECK (LENGTH(TRIM(reason)) > 0),
+    CONSTRAINT check_partial_day_single_date CHECK (duration_unit = 'full_day' OR start_date = end_date),
+    CONSTRAINT check_hours CHECK (
+        (duration_unit = 'hours' AND hours > 0 AND hours <= 24) OR
+        (duration_unit != 'hours' AND hours IS NULL)
+    ),
     CONSTRAINT check_approved_status CHECK (
-        (status = 'approved' AND approved_by IS NOT NULL AND approved_at IS NOT NULL) OR
+        (status = 'approved' AND approved_at IS NOT NULL AND (approved_by IS NOT NULL OR approval_route = 'auto')) OR
         (status != 'approved')
     )
 );
@@ -108,27 +214,79 @@ CREATE TABLE leave_requests (
 -- 6. Leave Conflicts
 CREATE TABLE leave_conflicts (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
-    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
-    conflicting_request_id UUID NOT NULL REFERENCES leave_requests(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    conflicting_request_id UUID NOT NULL,
     conflict_start_date DATE NOT NULL,
     conflict_end_date DATE NOT NULL,
     resolved BOOLEAN DEFAULT FALSE,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (org_id, employee_id) REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    FOREIGN KEY (org_id, conflicting_request_id) REFERENCES leave_requests(org_id, id) ON DELETE CASCADE
 );
 
 -- 7. Audit Logs
 CREATE TABLE audit_logs (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
     table_name VARCHAR(50) NOT NULL,
     record_id UUID NOT NULL,
     action VARCHAR(20) NOT NULL,
     old_values JSONB,
     new_values JSONB,
-    changed_by UUID REFERENCES employees(id) ON DELETE SET NULL,
-    changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    -- employees.id of the authenticated user behind the change; no foreign
+    -- key, as the trail outlives the employee and must not be rewritten
+    changed_by UUID,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- the API call behind the change (NULL for direct SQL and background jobs)
+    actor_user_id UUID,
+    actor_role VARCHAR(20),
+    ip_address VARCHAR(45),
+    endpoint VARCHAR(255),
+    request_id VARCHAR(128),
+    -- set when the retention job stripped the actor and personal data
+    anonymized_at TIMESTAMP WITH TIME ZONE,
+    -- hash chain, filled in by audit_chain_function
+    chain_seq BIGINT NOT NULL,
+    prev_hash TEXT NOT NULL,
+    content_hash TEXT NOT NULL,
+    row_hash TEXT NOT NULL
+);
+
+-- Tamper evidence: the audit entries of each table of an organization form a
+-- hash chain. chain_seq numbers them from 1, content_hash covers the entry and
+-- row_hash = sha256(prev_hash || content_hash), prev_hash being the row_hash of
+-- the entry before ('' for the first). audit_chains holds each chain's head,
+-- and the last entry the retention job deleted so the chain can be checked
+-- from there.
+CREATE TABLE audit_chains (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    table_name VARCHAR(50) NOT NULL,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    last_hash TEXT NOT NULL DEFAULT '',
+    purged_seq BIGINT NOT NULL DEFAULT 0,
+    purged_hash TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (org_id, table_name)
+);
+
+-- SIEM forwarding: the last entry of each audit chain sent to the SIEM (see
+-- jobs.ForwardAuditLogs)
+CREATE TABLE siem_cursors (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    table_name VARCHAR(50) NOT NULL,
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    forwarded_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (org_id, table_name)
 );
 
 -- Indexes
+CREATE INDEX idx_employees_org ON employees(org_id);
+CREATE INDEX idx_leave_requests_org ON leave_requests(org_id);
+CREATE INDEX idx_audit_logs_org_changed_at ON audit_logs(org_id, changed_at);
+CREATE INDEX idx_audit_logs_actor ON audit_logs(actor_user_id);
+CREATE INDEX idx_audit_logs_request ON audit_logs(request_id);
+CREATE INDEX idx_audit_logs_table_changed_at ON audit_logs(table_name, changed_at);
+CREATE UNIQUE INDEX idx_audit_logs_chain ON audit_logs(org_id, table_name, chain_seq);
 CREATE INDEX idx_employees_department ON employees(department_id);
 CREATE INDEX idx_employees_manager ON employees(manager_id);
 CREATE INDEX idx_employees_email ON employees(email);
@@ -161,14 +319,16 @@ CREATE TRIGGER trg_generate_employee_id
 BEFORE INSERT ON employees
 FOR EACH ROW EXECUTE FUNCTION generate_employee_id();
 
--- Auto leave balance for new employee
+-- Auto leave balance for new employee; accruing leave types start at 0 and
+-- are credited by the accrual job
 CREATE OR REPLACE FUNCTION init_leave_balances()
 RETURNS TRIGGER AS $$
 BEGIN
-    INSERT INTO employee_leave_balances (employee_id, leave_type_id, year, allocated_days)
-    SELECT NEW.id, lt.id, EXTRACT(YEAR FROM CURRENT_DATE)::INT, lt.max_days_per_year
+    INSERT INTO employee_leave_balances (org_id, employee_id, leave_type_id, year, allocated_days)
+    SELECT NEW.org_id, NEW.id, lt.id, EXTRACT(YEAR FROM CURRENT_DATE)::INT,
+           CASE WHEN lt.accrual_frequency = 'annual' THEN lt.max_days_per_year ELSE 0 END
     FROM leave_types lt
-    WHERE lt.is_active = true;
+    WHERE lt.org_id = NEW.org_id AND lt.is_active = true;
     RETURN NEW;
 END;
 $$ LANGUAGE plpgsql;
@@ -178,32 +338,121 @@ AFTER INSERT ON employees
 FOR EACH ROW EXECUTE FUNCTION init_leave_balances();
 
 -- Audit Trigger
+-- Records the before and after image of a row with the caller and the API
+-- call from request.jwt.claims (see Backend/internal/db/tenant.go). Secrets
+-- (password hashes, refresh tokens, webhook signing secrets, the credentials
+-- of chat integrations) are left out of the images.
 CREATE OR REPLACE FUNCTION audit_trigger_function()
 RETURNS TRIGGER AS $$
+DECLARE
+    claims JSONB := NULLIF(current_setting('request.jwt.claims', true), '')::jsonb;
+    old_row JSONB;
+    new_row JSONB;
 BEGIN
-    IF TG_OP = 'INSERT' THEN
-        INSERT INTO audit_logs (table_name, record_id, action, new_values)
-        VALUES (TG_TABLE_NAME, NEW.id, 'INSERT', row_to_json(NEW));
-        RETURN NEW;
-    ELSIF TG_OP = 'UPDATE' THEN
-        INSERT INTO audit_logs (table_name, record_id, action, old_values, new_values)
-        VALUES (TG_TABLE_NAME, NEW.id, 'UPDATE', row_to_json(OLD), row_to_json(NEW));
-        RETURN NEW;
-    ELSIF TG_OP = 'DELETE' THEN
-        INSERT INTO audit_logs (table_name, record_id, action, old_values)
-        VALUES (TG_TABLE_NAME, OLD.id, 'DELETE', row_to_json(OLD));
-        RETURN OLD;
+    -- the archival job moving rows out of the hot tables is not a change
+    IF current_setting('lms.archiving', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD) - 'password_hash' - 'token_hash' - 'secret'
+            - 'webhook_url' - 'signing_secret' - 'bot_token' - 'key_hash';
     END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW) - 'password_hash' - 'token_hash' - 'secret'
+            - 'webhook_url' - 'signing_secret' - 'bot_token' - 'key_hash';
+    END IF;
+    INSERT INTO audit_logs (org_id, table_name, record_id, action, old_values, new_values, changed_by,
+                            actor_user_id, actor_role, ip_address, endpoint, request_id)
+    VALUES ((COALESCE(new_row, old_row) ->> 'org_id')::uuid, TG_TABLE_NAME,
+            (COALESCE(new_row, old_row) ->> 'id')::uuid, TG_OP, old_row, new_row,
+            (claims ->> 'employee_uuid')::uuid,
+            (claims ->> 'sub')::uuid, claims ->> 'role',
+            claims -> 'request' ->> 'ip', claims -> 'request' ->> 'endpoint',
+            claims -> 'request' ->> 'request_id');
     RETURN NULL;
 END;
 $$ LANGUAGE plpgsql;
 
+-- Hash of an audit entry's content, in a form that does not depend on the
+-- session (the time is in UTC). Verification recomputes it from the stored
+-- columns.
+CREATE OR REPLACE FUNCTION audit_content_hash(
+    p_org_id UUID, p_table_name TEXT, p_chain_seq BIGINT, p_record_id UUID, p_action TEXT,
+    p_old_values JSONB, p_new_values JSONB, p_changed_by UUID, p_changed_at TIMESTAMPTZ,
+    p_actor_user_id UUID, p_actor_role TEXT, p_ip_address TEXT, p_endpoint TEXT, p_request_id TEXT
+)
+RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(jsonb_build_array(
+        p_org_id, p_table_name, p_chain_seq, p_record_id, p_action, p_old_values, p_new_values,
+        p_changed_by, to_char(p_changed_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        p_actor_user_id, p_actor_role, p_ip_address, p_endpoint, p_request_id
+    )::text, 'UTF8')), 'hex');
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Appends each new audit entry to its chain. The head row is locked until the
+-- writing transaction ends, so entries of one chain are numbered in commit
+-- order.
+CREATE OR REPLACE FUNCTION audit_chain_function()
+RETURNS TRIGGER AS $$
+DECLARE
+    head audit_chains%ROWTYPE;
+BEGIN
+    INSERT INTO audit_chains (org_id, table_name) VALUES (NEW.org_id, NEW.table_name)
+        ON CONFLICT DO NOTHING;
+    SELECT * INTO head FROM audit_chains
+        WHERE org_id = NEW.org_id AND table_name = NEW.table_name
+        FOR UPDATE;
+    NEW.chain_seq := head.last_seq + 1;
+    NEW.prev_hash := head.last_hash;
+    NEW.content_hash := audit_content_hash(NEW.org_id, NEW.table_name, NEW.chain_seq, NEW.record_id,
+        NEW.action, NEW.old_values, NEW.new_values, NEW.changed_by, NEW.changed_at,
+        NEW.actor_user_id, NEW.actor_role, NEW.ip_address, NEW.endpoint, NEW.request_id);
+    NEW.row_hash := encode(sha256(convert_to(NEW.prev_hash || NEW.content_hash, 'UTF8')), 'hex');
+    UPDATE audit_chains SET last_seq = NEW.chain_seq, last_hash = NEW.row_hash
+        WHERE org_id = NEW.org_id AND table_name = NEW.table_name;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_chain_trigger BEFORE INSERT ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_chain_function();
+
 CREATE TRIGGER employees_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON employees
     FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
 CREATE TRIGGER leave_requests_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON leave_requests
     FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
 CREATE TRIGGER leave_balances_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON employee_leave_balances
     FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+CREATE TRIGGER departments_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON departments
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+CREATE TRIGGER leave_types_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON leave_types
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Live events: new leave requests and status changes are announced on the
+-- leave_events channel, delivered when the transaction commits. The API
+-- LISTENs and pushes them to GET /events clients (internal/stream).
+CREATE OR REPLACE FUNCTION notify_leave_request_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.status IS NOT DISTINCT FROM OLD.status THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('leave_events', json_build_object(
+        'type', CASE TG_OP WHEN 'INSERT' THEN 'leave_request.submitted' ELSE 'leave_request.status_changed' END,
+        'org_id', NEW.org_id,
+        'leave_request_id', NEW.id,
+        'employee_id', NEW.employee_id,
+        'manager_id', (SELECT manager_id FROM employees WHERE id = NEW.employee_id),
+        'status', NEW.status,
+        'previous_status', CASE TG_OP WHEN 'UPDATE' THEN OLD.status END,
+        'time', NOW()
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER leave_requests_notify_trigger AFTER INSERT OR UPDATE OF status ON leave_requests
+    FOR EACH ROW EXECUTE FUNCTION notify_leave_request_event();
 
 -- Utility functions required by backend
 
@@ -216,11 +465,14 @@ RETURNS INTEGER AS $$
 $$ LANGUAGE SQL STABLE;
 
 -- Check overlapping leave requests for an employee, optionally excluding a specific request
+-- A morning and an afternoon half day of the same date do not overlap; any
+-- other two requests sharing a date do.
 CREATE OR REPLACE FUNCTION check_leave_overlap(
     p_employee_id UUID,
     p_start_date DATE,
     p_end_date DATE,
-    p_exclude_request_id UUID
+    p_exclude_request_id UUID,
+    p_duration_unit leave_duration_unit DEFAULT 'full_day'
 )
 RETURNS BOOLEAN AS $$
     SELECT EXISTS (
@@ -230,9 +482,25 @@ RETURNS BOOLEAN AS $$
           AND lr.status IN ('pending','approved')
           AND (p_exclude_request_id IS NULL OR lr.id <> p_exclude_request_id)
           AND NOT (lr.end_date < p_start_date OR lr.start_date > p_end_date)
+          AND NOT (lr.duration_unit = 'half_day_am' AND p_duration_unit = 'half_day_pm')
+          AND NOT (lr.duration_unit = 'half_day_pm' AND p_duration_unit = 'half_day_am')
     );
 $$ LANGUAGE SQL STABLE;
 
+-- Does a leave from p_start_date to p_end_date fall (partly) in the notice
+-- period that starts on p_resignation_date and lasts p_notice_days?
+CREATE OR REPLACE FUNCTION in_notice_period(
+    p_resignation_date DATE,
+    p_notice_days INTEGER,
+    p_start_date DATE,
+    p_end_date DATE
+)
+RETURNS BOOLEAN AS $$
+    SELECT p_resignation_date IS NOT NULL
+       AND p_end_date >= p_resignation_date
+       AND p_start_date < p_resignation_date + p_notice_days;
+$$ LANGUAGE SQL IMMUTABLE;
+
 -- Update updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
@@ -242,6 +510,8 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+CREATE TRIGGER update_organizations_updated_at BEFORE UPDATE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_departments_updated_at BEFORE UPDATE ON departments
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_leave_types_updated_at BEFORE UPDATE ON leave_types
@@ -253,44 +523,57 @@ CREATE TRIGGER update_leave_balances_updated_at BEFORE UPDATE ON employee_leave_
 CREATE TRIGGER update_leave_requests_updated_at BEFORE UPDATE ON leave_requests
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
--- Default seed data
-INSERT INTO departments (name, description) VALUES
-('Human Resources', 'Manages employee relations and policies'),
-('Engineering', 'Software development and technical operations'),
-('Marketing', 'Brand promotion and customer acquisition'),
-('Sales', 'Revenue generation and client relations'),
-('Finance', 'Financial planning and accounting');
-
-INSERT INTO leave_types (name, description, max_days_per_year, carry_forward_allowed, max_carry_forward_days) VALUES
-('Annual Leave', 'Yearly vacation days', 21, true, 5),
-('Sick Leave', 'Medical leave for illness', 12, false, 0),
-('Maternity Leave', 'Leave for new mothers', 90, false, 0),
-('Paternity Leave', 'Leave for new fathers', 15, false, 0),
-('Emergency Leave', 'Urgent personal matters', 5, false, 0),
-('Compensatory Leave', 'Time off for overtime work', 10, true, 3);
+-- Default seed data, for the default organization
+INSERT INTO departments (org_id, name, description) VALUES
+('00000000-0000-0000-0000-000000000001', 'Human Resources', 'Manages employee relations and policies'),
+('00000000-0000-0000-0000-000000000001', 'Engineering', 'Software development and technical operations'),
+('00000000-0000-0000-0000-000000000001', 'Marketing', 'Brand promotion and customer acquisition'),
+('00000000-0000-0000-0000-000000000001', 'Sales', 'Revenue generation and client relations'),
+('00000000-0000-0000-0000-000000000001', 'Finance', 'Financial planning and accounting');
+
+INSERT INTO leave_types (org_id, name, description, max_days_per_year, carry_forward_allowed, max_carry_forward_days) VALUES
+('00000000-0000-0000-0000-000000000001', 'Annual Leave', 'Yearly vacation days', 21, true, 5),
+('00000000-0000-0000-0000-000000000001', 'Sick Leave', 'Medical leave for illness', 12, false, 0),
+('00000000-0000-0000-0000-000000000001', 'Maternity Leave', 'Leave for new mothers', 90, false, 0),
+('00000000-0000-0000-0000-000000000001', 'Paternity Leave', 'Leave for new fathers', 15, false, 0),
+('00000000-0000-0000-0000-000000000001', 'Emergency Leave', 'Urgent personal matters', 5, false, 0),
+('00000000-0000-0000-0000-000000000001', 'Compensatory Leave', 'Time off for overtime work', 10, true, 3);
 
 -- Authentication tables
 
 -- Users table for authentication
 CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    employee_id VARCHAR(20) UNIQUE NOT NULL,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id VARCHAR(20) NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     role VARCHAR(20) NOT NULL CHECK (role IN ('employee', 'manager', 'hr', 'admin')),
     is_active BOOLEAN DEFAULT true,
     last_login_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT users_employee_id_key UNIQUE (org_id, employee_id)
 );
 
--- Refresh tokens table for JWT refresh functionality
+-- Refresh tokens table for JWT refresh functionality. Only the SHA-256 of a
+-- token is stored. Each refresh replaces the token with a new one of the same
+-- family, which starts at login; a rotated token presented again revokes its
+-- whole family, since either it or its successor was stolen. A family is a
+-- session (GET /auth/sessions): the device, IP address and time of its login
+-- are carried over to each of its tokens.
 CREATE TABLE IF NOT EXISTS refresh_tokens (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    token VARCHAR(255) UNIQUE NOT NULL,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    family_id UUID NOT NULL,
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     expires_at TIMESTAMP NOT NULL,
     is_revoked BOOLEAN DEFAULT false,
+    rotated_at TIMESTAMP,
+    user_agent VARCHAR(512),
+    ip_address VARCHAR(45),
+    signed_in_at TIMESTAMP NOT NULL DEFAULT NOW(),
     created_at TIMESTAMP DEFAULT NOW()
 );
 
@@ -298,7 +581,7 @@ CREATE TABLE IF NOT EXISTS refresh_tokens (
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_users_employee_id ON users(employee_id);
 CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
-CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
 CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
 CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
 
@@ -323,3 +606,859 @@ CREATE TRIGGER audit_users_trigger
 CREATE TRIGGER audit_refresh_tokens_trigger
     AFTER INSERT OR UPDATE OR DELETE ON refresh_tokens
     FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Single sign-on (see Backend/internal/oidc). A sign-in started with GET
+-- /auth/oidc/login waits here for its callback, which deletes it; the state
+-- is the key, sent to the provider and back. Unfinished sign-ins are deleted
+-- after 10 minutes. They belong to no organization yet, so only service
+-- requests see them. A sign-in started with POST /auth/oidc/link links the
+-- provider account to the signed-in user who started it (user_id).
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(20) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(64) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_logins_created_at ON oidc_logins(created_at);
+
+-- The provider accounts users have signed in with. A user is linked to an
+-- account on its first sign-in, by email when the provider vouches for the
+-- address, or else from the user's session; from then on the account finds
+-- the user even when either email address changes.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    subject VARCHAR(255) NOT NULL, -- the provider's ID of the account
+    email VARCHAR(255) NOT NULL,   -- as of the last sign-in
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT user_identities_subject_key UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+CREATE TRIGGER audit_user_identities_trigger
+    AFTER INSERT OR UPDATE OR DELETE ON user_identities
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- API keys of integration clients, sent in X-API-Key instead of a JWT (see
+-- Backend/internal/apikey). A key acts as the admin who created it, on the
+-- routes its scopes cover. Only the SHA-256 of a key is stored, and its first
+-- characters to tell it apart.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(12) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE, -- NULL: never
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_used_at TIMESTAMP WITH TIME ZONE, -- to the minute
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT api_keys_name_key UNIQUE (org_id, name),
+    CONSTRAINT check_api_key_scopes CHECK (cardinality(scopes) > 0 AND scopes <@ ARRAY[
+        'employees:read', 'employees:write', 'leave_balances:read', 'leave_balances:write',
+        'leave_requests:read', 'leave_requests:write', 'leave_types:read', 'leave_types:write',
+        'holidays:read', 'comp_off:read', 'comp_off:write', 'attendance:read', 'attendance:write',
+        'reports:read', 'audit_logs:read'
+    ]::TEXT[])
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_created_by ON api_keys(created_by);
+
+CREATE TRIGGER update_api_keys_updated_at BEFORE UPDATE OF name, scopes, expires_at, is_active ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- every use of a key moves last_used_at, which is not worth an audit entry
+CREATE TRIGGER audit_api_keys_trigger
+    AFTER INSERT OR DELETE OR UPDATE OF name, scopes, expires_at, is_active ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Attendance (optional module, enabled with ATTENDANCE_ENABLED=true)
+CREATE TABLE IF NOT EXISTS attendance_records (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    work_date DATE NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('present', 'absent')),
+    check_in_at TIMESTAMP WITH TIME ZONE,
+    check_out_at TIMESTAMP WITH TIME ZONE,
+    source VARCHAR(20) NOT NULL DEFAULT 'check_in' CHECK (source IN ('check_in', 'import')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(employee_id, work_date),
+    CONSTRAINT attendance_records_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_checkout_after_checkin CHECK (check_out_at IS NULL OR check_out_at >= check_in_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_work_date ON attendance_records(work_date);
+
+CREATE TRIGGER update_attendance_records_updated_at BEFORE UPDATE ON attendance_records
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER attendance_records_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON attendance_records
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Public holidays (used for holiday-adjoining leave detection)
+CREATE TABLE IF NOT EXISTS holidays (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    holiday_date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, holiday_date)
+);
+
+CREATE TRIGGER update_holidays_updated_at BEFORE UPDATE ON holidays
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER holidays_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON holidays
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Absence anomalies (latest scan only, confidential HR report)
+CREATE TABLE IF NOT EXISTS absence_anomalies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    pattern VARCHAR(50) NOT NULL,
+    occurrences INTEGER NOT NULL,
+    total_requests INTEGER NOT NULL,
+    ratio NUMERIC(5,4) NOT NULL,
+    window_start DATE NOT NULL,
+    window_end DATE NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (org_id, employee_id) REFERENCES employees(org_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_absence_anomalies_employee ON absence_anomalies(employee_id);
+
+-- Erasure requests (right to be forgotten): filed by the employee or HR,
+-- approved by an admin, then carried out by the erasure job, which anonymizes
+-- the employee and everything that identifies them
+CREATE TABLE IF NOT EXISTS erasure_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    reason TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'completed')),
+    requested_by UUID NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    reviewed_by UUID,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    rejection_reason TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    -- why the last attempt of the erasure job failed; it retries on its next run
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT erasure_requests_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_erasure_requests_open ON erasure_requests(employee_id)
+    WHERE status IN ('pending', 'approved');
+CREATE INDEX IF NOT EXISTS idx_erasure_requests_status ON erasure_requests(status);
+
+CREATE TRIGGER update_erasure_requests_updated_at BEFORE UPDATE ON erasure_requests
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER erasure_requests_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON erasure_requests
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Decision snapshots: what was in front of the approver when a leave request
+-- was approved or rejected (the employee's balance, the leave type's policy
+-- and the overlapping requests of the department), kept to settle disputes.
+-- Written once, in the transaction of the decision. There is no foreign key
+-- to leave_requests, so snapshots outlive archival.
+CREATE TABLE IF NOT EXISTS leave_decision_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    leave_request_id UUID NOT NULL,
+    employee_id UUID NOT NULL,
+    decision VARCHAR(20) NOT NULL CHECK (decision IN ('approved', 'rejected')),
+    -- employees.id of the approver; NULL when they have no employee record
+    decided_by UUID,
+    decided_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- NULL when the employee had no balance for the leave type and year
+    balance JSONB,
+    policy JSONB NOT NULL,
+    overlapping_requests JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_leave_decision_snapshots_request ON leave_decision_snapshots(leave_request_id, decided_at);
+
+-- Policy documents: leave policies published by HR. Publishing a policy again
+-- under the same key adds a version; employees acknowledge the versions they
+-- have read, and must acknowledge the latest one of each policy.
+CREATE TABLE IF NOT EXISTS policy_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    policy_key VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    published_by UUID NOT NULL,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT policy_documents_version_key UNIQUE (org_id, policy_key, version),
+    UNIQUE (org_id, id),
+    CONSTRAINT check_policy_key_format CHECK (policy_key ~ '^[a-z0-9][a-z0-9-]*$'),
+    CONSTRAINT check_policy_body_not_empty CHECK (LENGTH(TRIM(body)) > 0)
+);
+
+CREATE TABLE IF NOT EXISTS policy_acknowledgments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL,
+    employee_id UUID NOT NULL,
+    acknowledged_by UUID NOT NULL,
+    acknowledged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT policy_acknowledgments_once UNIQUE (document_id, employee_id),
+    CONSTRAINT policy_acknowledgments_document_id_fkey FOREIGN KEY (org_id, document_id)
+        REFERENCES policy_documents(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT policy_acknowledgments_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_acknowledgments_employee ON policy_acknowledgments(employee_id);
+
+CREATE TRIGGER policy_documents_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON policy_documents
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+CREATE TRIGGER policy_acknowledgments_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON policy_acknowledgments
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Approval routing: which approvals a new leave request needs. The active rule
+-- with the lowest priority whose leave type, department and day range match
+-- the request decides; NULL matches anything. Without a match a manager
+-- approves. The route is stored on the request, so editing rules only affects
+-- requests created afterwards.
+CREATE TABLE approval_routing_rules (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 100,
+    leave_type_id UUID,
+    department_id UUID,
+    min_days INTEGER,
+    max_days INTEGER,
+    route approval_route NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, id),
+    CONSTRAINT approval_routing_rules_name_key UNIQUE (org_id, name),
+    CONSTRAINT approval_routing_rules_leave_type_id_fkey FOREIGN KEY (org_id, leave_type_id)
+        REFERENCES leave_types(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT approval_routing_rules_department_id_fkey FOREIGN KEY (org_id, department_id)
+        REFERENCES departments(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_routing_rule_name_not_empty CHECK (LENGTH(TRIM(name)) > 0),
+    CONSTRAINT check_routing_rule_days CHECK (
+        (min_days IS NULL OR min_days > 0) AND
+        (max_days IS NULL OR max_days >= COALESCE(min_days, 1))
+    )
+);
+
+CREATE INDEX idx_approval_routing_rules_priority ON approval_routing_rules(org_id, priority) WHERE is_active;
+
+CREATE TRIGGER update_approval_routing_rules_updated_at BEFORE UPDATE ON approval_routing_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER approval_routing_rules_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON approval_routing_rules
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Approval chains: the steps of a pending leave request, created from its
+-- approval_route when it is inserted, acted on in step_no order. The first
+-- pending step is the request's stage (pending_manager, pending_hr). A manager
+-- step is decided by the employee's manager, or by HR as the last step or when
+-- the employee has no manager; an hr step by HR or an admin. Once the request
+-- leaves pending the steps still pending are skipped.
+CREATE TABLE approval_steps (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    leave_request_id UUID NOT NULL,
+    step_no INTEGER NOT NULL,
+    approver_role approval_step_role NOT NULL,
+    status approval_step_status NOT NULL DEFAULT 'pending',
+    acted_by UUID REFERENCES employees(id) ON DELETE SET NULL, -- NULL for skipped steps
+    acted_at TIMESTAMP WITH TIME ZONE,
+    comment TEXT, -- the rejection reason of a rejected step
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT approval_steps_step_key UNIQUE (leave_request_id, step_no),
+    CONSTRAINT approval_steps_leave_request_id_fkey FOREIGN KEY (org_id, leave_request_id)
+        REFERENCES leave_requests(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_approval_step_acted CHECK ((status = 'pending') = (acted_at IS NULL))
+);
+
+CREATE INDEX idx_approval_steps_pending ON approval_steps(org_id, approver_role) WHERE status = 'pending';
+
+CREATE TRIGGER approval_steps_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON approval_steps
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+CREATE OR REPLACE FUNCTION sync_approval_steps()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.status = 'pending' THEN
+            INSERT INTO approval_steps (org_id, leave_request_id, step_no, approver_role)
+            SELECT NEW.org_id, NEW.id, s.step_no, s.role
+            FROM unnest(CASE NEW.approval_route
+                    WHEN 'manager' THEN ARRAY['manager']
+                    WHEN 'hr' THEN ARRAY['hr']
+                    WHEN 'manager_hr' THEN ARRAY['manager', 'hr']
+                    ELSE ARRAY[]::TEXT[]
+                 END::approval_step_role[]) WITH ORDINALITY AS s(role, step_no);
+        END IF;
+    ELSIF NEW.status <> 'pending' THEN
+        UPDATE approval_steps SET status = 'skipped', acted_at = NOW()
+        WHERE leave_request_id = NEW.id AND status = 'pending';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER leave_requests_approval_steps_trigger AFTER INSERT OR UPDATE OF status ON leave_requests
+    FOR EACH ROW EXECUTE FUNCTION sync_approval_steps();
+
+-- The stage of a leave request: 'pending_' and the approver role of its first
+-- pending step, NULL once it is decided (or when it has no steps)
+CREATE OR REPLACE FUNCTION leave_request_stage(p_request_id UUID)
+RETURNS TEXT AS $$
+    SELECT 'pending_' || approver_role FROM approval_steps
+    WHERE leave_request_id = p_request_id AND status = 'pending'
+    ORDER BY step_no LIMIT 1;
+$$ LANGUAGE SQL STABLE;
+
+-- Accruals: one row per employee, accruing leave type and period (month or
+-- quarter of the year) credited to the balance's allocated_days by the
+-- accrual job. The unique key makes the job safe to run again: a period is
+-- credited once.
+CREATE TABLE leave_accruals (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    leave_type_id UUID NOT NULL,
+    year INTEGER NOT NULL,
+    frequency accrual_frequency NOT NULL,
+    period INTEGER NOT NULL, -- 1-12 for monthly, 1-4 for quarterly
+    days NUMERIC(5,2) NOT NULL,
+    credited_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT leave_accruals_period_key UNIQUE (employee_id, leave_type_id, year, frequency, period),
+    CONSTRAINT leave_accruals_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT leave_accruals_leave_type_id_fkey FOREIGN KEY (org_id, leave_type_id)
+        REFERENCES leave_types(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_accrual_period CHECK (
+        period >= 1 AND period <= CASE frequency WHEN 'monthly' THEN 12 WHEN 'quarterly' THEN 4 ELSE 1 END
+    ),
+    CONSTRAINT check_accrual_days_positive CHECK (days > 0)
+);
+
+CREATE INDEX idx_leave_accruals_org_year ON leave_accruals(org_id, year);
+
+-- Year-end rollovers: one row per organization and year closed, written by the
+-- rollover job or POST /admin/leave-balances/rollover, which create the
+-- balances of the next year with the days carried forward. The job skips the
+-- years recorded here; running a rollover again updates the row.
+CREATE TABLE leave_year_rollovers (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL, -- the year closed; balances are created for year + 1
+    balances INTEGER NOT NULL,
+    carried_forward_days INTEGER NOT NULL,
+    run_by UUID, -- users.id; NULL for the scheduled job
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT leave_year_rollovers_year_key UNIQUE (org_id, year)
+);
+
+CREATE TRIGGER leave_year_rollovers_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON leave_year_rollovers
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Comp-off: a weekend or holiday an employee worked, logged by them (POST
+-- /comp-off) and reviewed by their manager or HR. Approval credits days to the
+-- employee's balance of the "Comp Off" leave type for the year of approval,
+-- to be taken as leave like any other; the comp-off job takes back on
+-- expires_on what is left of them, consuming the oldest credits first.
+CREATE TABLE comp_off_requests (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL,
+    work_date DATE NOT NULL,
+    days NUMERIC(3,1) NOT NULL DEFAULT 1, -- 1, or 0.5 for half a day worked
+    reason TEXT NOT NULL,
+    status leave_status NOT NULL DEFAULT 'pending',
+    reviewed_by UUID REFERENCES employees(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    rejection_reason TEXT,
+    -- set on approval: the balance credited and the day the credit expires
+    leave_type_id UUID,
+    credited_year INTEGER,
+    expires_on DATE,
+    -- set by the comp-off job on expires_on: the days taken back, 0 when all
+    -- were used
+    expired_days NUMERIC(3,1),
+    expired_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT comp_off_requests_employee_id_fkey FOREIGN KEY (org_id, employee_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT comp_off_requests_leave_type_id_fkey FOREIGN KEY (org_id, leave_type_id)
+        REFERENCES leave_types(org_id, id) ON DELETE RESTRICT,
+    CONSTRAINT check_comp_off_days CHECK (days IN (0.5, 1)),
+    CONSTRAINT check_comp_off_reason_not_empty CHECK (LENGTH(TRIM(reason)) > 0),
+    CONSTRAINT check_comp_off_approved CHECK (
+        status != 'approved' OR (leave_type_id IS NOT NULL AND credited_year IS NOT NULL AND expires_on IS NOT NULL)
+    )
+);
+
+-- a day is logged once, unless it was rejected or cancelled
+CREATE UNIQUE INDEX idx_comp_off_requests_work_date ON comp_off_requests(employee_id, work_date)
+    WHERE status IN ('pending', 'approved');
+CREATE INDEX idx_comp_off_requests_expiry ON comp_off_requests(expires_on)
+    WHERE status = 'approved' AND expired_at IS NULL;
+
+CREATE TRIGGER update_comp_off_requests_updated_at BEFORE UPDATE ON comp_off_requests
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER comp_off_requests_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON comp_off_requests
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Approval delegations: a manager hands the decisions on their direct
+-- reports' leave requests to another employee for a date range, e.g. while
+-- on leave themselves (POST /approval-delegations). A delegation is active
+-- from start_date to end_date, inclusive, until it is revoked; the handler
+-- keeps the active periods of a manager's delegations from overlapping.
+CREATE TABLE approval_delegations (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    delegator_id UUID NOT NULL,
+    delegate_id UUID NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT,
+    created_by UUID, -- users.id of the manager, HR or admin who created it
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT approval_delegations_delegator_id_fkey FOREIGN KEY (org_id, delegator_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT approval_delegations_delegate_id_fkey FOREIGN KEY (org_id, delegate_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_delegation_not_self CHECK (delegate_id != delegator_id),
+    CONSTRAINT check_delegation_dates CHECK (end_date >= start_date)
+);
+
+CREATE INDEX idx_approval_delegations_delegator ON approval_delegations(delegator_id, start_date)
+    WHERE revoked_at IS NULL;
+CREATE INDEX idx_approval_delegations_delegate ON approval_delegations(delegate_id, start_date)
+    WHERE revoked_at IS NULL;
+
+CREATE TRIGGER update_approval_delegations_updated_at BEFORE UPDATE ON approval_delegations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER approval_delegations_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON approval_delegations
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- Does p_delegate decide, today, the leave requests of p_manager's direct
+-- reports through an active approval delegation?
+CREATE OR REPLACE FUNCTION is_approval_delegate(p_delegate UUID, p_manager UUID)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM approval_delegations
+        WHERE delegate_id = p_delegate AND delegator_id = p_manager AND revoked_at IS NULL
+          AND CURRENT_DATE BETWEEN start_date AND end_date
+    );
+$$ LANGUAGE SQL STABLE;
+
+-- Comments on a leave request: the thread in which the employee and their
+-- approvers exchange clarifications (POST /leave-requests/{id}/comments).
+-- Each new comment is announced on the leave_events channel, so the other
+-- party gets it on GET /events. Comments go with their request when it is
+-- archived, like its approval steps.
+CREATE TABLE leave_request_comments (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    leave_request_id UUID NOT NULL,
+    author_id UUID NOT NULL,
+    comment TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT leave_request_comments_leave_request_id_fkey FOREIGN KEY (org_id, leave_request_id)
+        REFERENCES leave_requests(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT leave_request_comments_author_id_fkey FOREIGN KEY (org_id, author_id)
+        REFERENCES employees(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_comment_not_empty CHECK (LENGTH(TRIM(comment)) > 0)
+);
+
+CREATE INDEX idx_leave_request_comments_request ON leave_request_comments(leave_request_id, created_at);
+
+CREATE TRIGGER leave_request_comments_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON leave_request_comments
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+CREATE OR REPLACE FUNCTION notify_leave_request_comment()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('leave_events', json_build_object(
+        'type', 'leave_request.commented',
+        'org_id', NEW.org_id,
+        'leave_request_id', NEW.leave_request_id,
+        'employee_id', lr.employee_id,
+        'manager_id', e.manager_id,
+        'status', lr.status,
+        'comment_id', NEW.id,
+        'author_id', NEW.author_id,
+        'time', NOW()
+    )::text)
+    FROM leave_requests lr
+    JOIN employees e ON e.id = lr.employee_id
+    WHERE lr.id = NEW.leave_request_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER leave_request_comments_notify_trigger AFTER INSERT ON leave_request_comments
+    FOR EACH ROW EXECUTE FUNCTION notify_leave_request_comment();
+
+-- Outbound webhooks (admin CRUD at /webhooks): the leave and employee events
+-- an organization's integrations, such as payroll or an HRIS, subscribe to.
+-- The triggers below queue a delivery for every active webhook subscribed to
+-- an event in the transaction that caused it, so a rolled back change sends
+-- nothing; the webhooks job POSTs the deliveries, signed with the webhook's
+-- secret, and retries the failed ones with backoff (see Backend/internal/webhook).
+CREATE TABLE webhooks (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL, -- HMAC-SHA256 key of the X-Webhook-Signature header
+    events TEXT[] NOT NULL,
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID, -- users.id of the admin who created it
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, id),
+    CONSTRAINT check_webhook_events CHECK (
+        cardinality(events) > 0 AND events <@ ARRAY[
+            'leave.applied', 'leave.approved', 'leave.rejected', 'leave.cancelled',
+            'employee.created', 'employee.deactivated'
+        ]::TEXT[]
+    )
+);
+
+CREATE TRIGGER update_webhooks_updated_at BEFORE UPDATE ON webhooks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER webhooks_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON webhooks
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- One event for one webhook, and the delivery log of GET /webhooks/{id}/deliveries.
+-- payload is the event's data; the body sent wraps it with the delivery's id,
+-- event and created_at, so every attempt sends the same body. Finished
+-- deliveries are deleted after WEBHOOK_RETENTION_DAYS.
+CREATE TABLE webhook_deliveries (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    webhook_id UUID NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(), -- while pending
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    response_status INTEGER, -- HTTP status of the last attempt; NULL when there was no response
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (org_id, webhook_id)
+        REFERENCES webhooks(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_webhook_delivery_status CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at);
+
+-- Queue event with data for the active webhooks of p_org subscribed to it
+CREATE OR REPLACE FUNCTION queue_webhook_event(p_org UUID, p_event TEXT, p_data JSONB)
+RETURNS VOID AS $$
+    INSERT INTO webhook_deliveries (org_id, webhook_id, event, payload)
+    SELECT p_org, id, p_event, p_data FROM webhooks
+    WHERE org_id = p_org AND is_active AND p_event = ANY(events);
+$$ LANGUAGE SQL;
+
+-- leave.applied for a new request, leave.approved, leave.rejected or
+-- leave.cancelled when its status changes. The free text (reason, comments)
+-- is left out.
+CREATE OR REPLACE FUNCTION webhook_leave_request_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.status IS NOT DISTINCT FROM OLD.status THEN
+        RETURN NEW;
+    END IF;
+    PERFORM queue_webhook_event(NEW.org_id,
+        CASE TG_OP WHEN 'INSERT' THEN 'leave.applied' ELSE 'leave.' || NEW.status END,
+        jsonb_build_object(
+            'id', NEW.id,
+            'employee_id', NEW.employee_id,
+            'leave_type_id', NEW.leave_type_id,
+            'start_date', NEW.start_date,
+            'end_date', NEW.end_date,
+            'total_days', NEW.total_days,
+            'duration_unit', NEW.duration_unit,
+            'hours', NEW.hours,
+            'status', NEW.status,
+            'previous_status', CASE TG_OP WHEN 'UPDATE' THEN OLD.status END,
+            'approved_by', NEW.approved_by,
+            'approved_at', NEW.approved_at,
+            'applied_at', NEW.applied_at
+        ));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER leave_requests_webhook_trigger AFTER INSERT OR UPDATE OF status ON leave_requests
+    FOR EACH ROW EXECUTE FUNCTION webhook_leave_request_event();
+
+-- employee.created for a new employee, employee.deactivated when one is
+-- deactivated
+CREATE OR REPLACE FUNCTION webhook_employee_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NOT (COALESCE(OLD.is_active, TRUE) AND NOT COALESCE(NEW.is_active, TRUE)) THEN
+        RETURN NEW;
+    END IF;
+    PERFORM queue_webhook_event(NEW.org_id,
+        CASE TG_OP WHEN 'INSERT' THEN 'employee.created' ELSE 'employee.deactivated' END,
+        jsonb_build_object(
+            'id', NEW.id,
+            'employee_id', NEW.employee_id,
+            'name', NEW.name,
+            'email', NEW.email,
+            'department_id', NEW.department_id,
+            'role', NEW.role,
+            'manager_id', NEW.manager_id,
+            'joining_date', NEW.joining_date,
+            'resignation_date', NEW.resignation_date,
+            'is_active', NEW.is_active
+        ));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER employees_webhook_trigger AFTER INSERT OR UPDATE OF is_active ON employees
+    FOR EACH ROW EXECUTE FUNCTION webhook_employee_event();
+
+-- Chat integrations (admin CRUD at /chat-integrations): a Slack or Microsoft
+-- Teams incoming webhook that is posted a message when leave is applied for
+-- and when a request moves on to its next approver, with Approve and Reject
+-- buttons while it is pending. The integration of the employee's department
+-- takes the request, otherwise the one without a department. The buttons
+-- call back POST /integrations/chat/{id}/actions, which decides the request
+-- as the employee who clicked (see Backend/internal/chat).
+CREATE TABLE chat_integrations (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    department_id UUID, -- NULL for the organization's default
+    provider VARCHAR(20) NOT NULL,
+    webhook_url TEXT NOT NULL, -- the incoming webhook; whoever holds it can post to the channel
+    signing_secret VARCHAR(255), -- Slack: the app's signing secret, which verifies the button callbacks
+    bot_token VARCHAR(255), -- Slack: a bot token with users:read.email, to tell who clicked
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID, -- users.id of the admin who created it
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, id),
+    CONSTRAINT chat_integrations_department_id_fkey FOREIGN KEY (org_id, department_id)
+        REFERENCES departments(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_chat_provider CHECK (provider IN ('slack', 'teams')),
+    CONSTRAINT check_chat_slack_credentials CHECK (provider = 'slack' OR (signing_secret IS NULL AND bot_token IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_chat_integrations_department ON chat_integrations(org_id, department_id);
+CREATE UNIQUE INDEX idx_chat_integrations_default ON chat_integrations(org_id) WHERE department_id IS NULL;
+
+CREATE TRIGGER update_chat_integrations_updated_at BEFORE UPDATE ON chat_integrations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER chat_integrations_audit_trigger AFTER INSERT OR UPDATE OR DELETE ON chat_integrations
+    FOR EACH ROW EXECUTE FUNCTION audit_trigger_function();
+
+-- One message about a leave request for one integration. The text is built
+-- from the request as it is when the chat job sends it, so a message sent
+-- late shows the current status. Sent and failed messages are deleted after
+-- WEBHOOK_RETENTION_DAYS.
+CREATE TABLE chat_messages (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    org_id UUID NOT NULL DEFAULT current_org_id() REFERENCES organizations(id) ON DELETE CASCADE,
+    integration_id UUID NOT NULL,
+    leave_request_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(), -- while pending
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT chat_messages_integration_id_fkey FOREIGN KEY (org_id, integration_id)
+        REFERENCES chat_integrations(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT chat_messages_leave_request_id_fkey FOREIGN KEY (org_id, leave_request_id)
+        REFERENCES leave_requests(org_id, id) ON DELETE CASCADE,
+    CONSTRAINT check_chat_message_kind CHECK (kind IN ('leave_applied', 'approval_needed')),
+    CONSTRAINT check_chat_message_status CHECK (status IN ('pending', 'sent', 'failed'))
+);
+
+CREATE INDEX idx_chat_messages_due ON chat_messages(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_chat_messages_leave_request ON chat_messages(leave_request_id);
+
+-- Queue a p_kind message about leave request p_request for the active
+-- integration of the employee's department, or else the organization's default
+CREATE OR REPLACE FUNCTION queue_chat_message(p_org UUID, p_request UUID, p_kind TEXT)
+RETURNS VOID AS $$
+    INSERT INTO chat_messages (org_id, integration_id, leave_request_id, kind)
+    SELECT p_org, ci.id, lr.id, p_kind
+    FROM leave_requests lr
+    JOIN employees e ON e.id = lr.employee_id
+    JOIN chat_integrations ci ON ci.org_id = p_org AND ci.is_active
+        AND (ci.department_id = e.department_id OR ci.department_id IS NULL)
+    WHERE lr.id = p_request
+    ORDER BY ci.department_id NULLS LAST
+    LIMIT 1;
+$$ LANGUAGE SQL;
+
+-- leave_applied for a new request
+CREATE OR REPLACE FUNCTION chat_leave_request_applied()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM queue_chat_message(NEW.org_id, NEW.id, 'leave_applied');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER leave_requests_chat_trigger AFTER INSERT ON leave_requests
+    FOR EACH ROW EXECUTE FUNCTION chat_leave_request_applied();
+
+-- approval_needed when an approval step is approved and a later one waits
+CREATE OR REPLACE FUNCTION chat_approval_step_approved()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM approval_steps
+        WHERE leave_request_id = NEW.leave_request_id AND step_no > NEW.step_no AND status = 'pending'
+    ) THEN
+        PERFORM queue_chat_message(NEW.org_id, NEW.leave_request_id, 'approval_needed');
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER approval_steps_chat_trigger AFTER UPDATE OF status ON approval_steps
+    FOR EACH ROW WHEN (OLD.status = 'pending' AND NEW.status = 'approved')
+    EXECUTE FUNCTION chat_approval_step_approved();
+
+-- Read-only maintenance mode, for the whole deployment: one row, switched by
+-- the operator (PUT /operator/maintenance) and polled by every instance. It
+-- holds no tenant data, so it has no org_id; everyone may read it, only
+-- service requests may change it (see the policies at the end): an admin is
+-- the admin of one organization and must not stop the others.
+CREATE TABLE maintenance_mode (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    read_only BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT, -- shown to clients; NULL for the default message
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO maintenance_mode DEFAULT VALUES;
+
+-- Archive: finished leave requests and audit logs older than ARCHIVE_AFTER_DAYS
+-- are moved here by the archival job, keeping the hot tables small. The
+-- columns are those of the live table followed by archived_at, in the same
+-- order, so a column added to leave_requests or audit_logs must be added here
+-- too. There are no foreign keys to employees or leave types, so the history
+-- outlives them.
+CREATE TABLE IF NOT EXISTS leave_requests_archive (
+    LIKE leave_requests INCLUDING DEFAULTS,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id),
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs_archive (
+    LIKE audit_logs INCLUDING DEFAULTS,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id),
+    FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_leave_requests_archive_employee ON leave_requests_archive(org_id, employee_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_archive_record ON audit_logs_archive(org_id, table_name, record_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_archive_table_changed_at ON audit_logs_archive(table_name, changed_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_archive_chain ON audit_logs_archive(org_id, table_name, chain_seq);
+
+-- Tenant isolation: rows are visible and writable only within the organization
+-- of the request (current_org_id), or to service requests. FORCE applies the
+-- policies to the table owner too; superusers and BYPASSRLS roles still skip
+-- them, so the backend must connect as an ordinary role.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'departments', 'leave_types', 'employees', 'employee_leave_balances',
+        'leave_requests', 'leave_conflicts', 'audit_logs', 'audit_chains', 'siem_cursors', 'users',
+        'refresh_tokens', 'user_identities', 'api_keys', 'attendance_records', 'holidays', 'absence_anomalies', 'erasure_requests',
+        'leave_decision_snapshots', 'policy_documents', 'policy_acknowledgments', 'approval_routing_rules',
+        'approval_steps', 'leave_accruals', 'leave_year_rollovers', 'comp_off_requests',
+        'approval_delegations', 'leave_request_comments', 'webhooks', 'webhook_deliveries',
+        'chat_integrations', 'chat_messages', 'leave_requests_archive', 'audit_logs_archive'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format(
+            'CREATE POLICY tenant_isolation ON %I
+                USING (org_id = current_org_id() OR is_service_request())
+                WITH CHECK (org_id = current_org_id() OR is_service_request())', t);
+    END LOOP;
+END $$;
+
+-- Role limits, on top of tenant isolation (restrictive policies are ANDed
+-- with it): logins, their refresh tokens and their provider accounts belong
+-- to their user, API keys to admins, the audit trail and decision snapshots
+-- can be written by everyone but read by HR only, absence anomalies are HR's
+-- alone, and erasure requests are seen by HR and whoever filed them.
+CREATE POLICY own_user ON users AS RESTRICTIVE
+    USING (id = current_app_user_id() OR is_hr_request());
+CREATE POLICY own_user ON refresh_tokens AS RESTRICTIVE
+    USING (user_id = current_app_user_id() OR is_hr_request());
+CREATE POLICY own_user ON user_identities AS RESTRICTIVE
+    USING (user_id = current_app_user_id() OR is_hr_request());
+CREATE POLICY admin_only ON api_keys AS RESTRICTIVE
+    USING (COALESCE(current_app_role() IN ('admin', 'service'), false));
+CREATE POLICY hr_read ON audit_logs AS RESTRICTIVE FOR SELECT
+    USING (is_hr_request());
+CREATE POLICY hr_read ON audit_logs_archive AS RESTRICTIVE FOR SELECT
+    USING (is_hr_request());
+CREATE POLICY hr_read ON leave_decision_snapshots AS RESTRICTIVE FOR SELECT
+    USING (is_hr_request());
+CREATE POLICY hr_only ON absence_anomalies AS RESTRICTIVE
+    USING (is_hr_request());
+CREATE POLICY own_request ON erasure_requests AS RESTRICTIVE
+    USING (requested_by = current_app_user_id() OR is_hr_request());
+
+ALTER TABLE oidc_logins ENABLE ROW LEVEL SECURITY;
+ALTER TABLE oidc_logins FORCE ROW LEVEL SECURITY;
+CREATE POLICY service_only ON oidc_logins
+    USING (is_service_request())
+    WITH CHECK (is_service_request());
+
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organizations FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON organizations
+    USING (id = current_org_id() OR is_service_request())
+    WITH CHECK (is_service_request());
+
+ALTER TABLE maintenance_mode ENABLE ROW LEVEL SECURITY;
+ALTER TABLE maintenance_mode FORCE ROW LEVEL SECURITY;
+CREATE POLICY read_all ON maintenance_mode FOR SELECT
+    USING (true);
+CREATE POLICY service_update ON maintenance_mode FOR UPDATE
+    USING (is_service_request())
+    WITH CHECK (is_service_request());
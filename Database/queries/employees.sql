@@ -0,0 +1,49 @@
+-- name: DepartmentExists :one
+SELECT EXISTS (SELECT 1 FROM departments WHERE id = $1);
+
+-- name: CreateEmployee :one
+INSERT INTO employees (employee_id, email, name, department_id, joining_date, role)
+VALUES ($1, $2, $3, $4, $5, 'employee')
+RETURNING id;
+
+-- name: AllocateLeaveBalances :exec
+-- Accruing leave types start at 0; the accrual job credits them.
+INSERT INTO employee_leave_balances (employee_id, leave_type_id, year, allocated_days, used_days, carried_forward_days)
+SELECT sqlc.arg(employee_id)::uuid, lt.id, sqlc.arg(year)::int,
+       CASE WHEN lt.accrual_frequency = 'annual' THEN lt.max_days_per_year ELSE 0 END, 0, 0
+FROM leave_types lt
+WHERE lt.is_active = true
+ON CONFLICT (employee_id, leave_type_id, year) DO NOTHING;
+
+-- name: CountEmployees :one
+SELECT COUNT(*) FROM employees
+WHERE (sqlc.narg(department_id)::uuid IS NULL OR department_id = sqlc.narg(department_id)::uuid)
+  AND (sqlc.narg(role)::employee_role IS NULL OR role = sqlc.narg(role)::employee_role)
+  AND (sqlc.narg(is_active)::bool IS NULL OR is_active = sqlc.narg(is_active)::bool)
+  AND (sqlc.arg(include_inactive)::bool OR is_active);
+
+-- name: GetEmployee :one
+SELECT employee_id, email, name, department_id, role, is_active, joining_date, resignation_date, phone, address, manager_id
+FROM employees
+WHERE id = $1;
+
+-- name: GetEmployeeName :one
+SELECT name FROM employees WHERE id = $1;
+
+-- name: GetEmployeeUserID :one
+-- users.employee_id holds the employee code, not the employees.id UUID.
+SELECT u.id FROM users u
+JOIN employees e ON e.employee_id = u.employee_id
+WHERE e.id = sqlc.arg(employee_id);
+
+-- name: EmployeeExists :one
+SELECT EXISTS (SELECT 1 FROM employees WHERE id = $1);
+
+-- name: EmployeeEmailExists :one
+SELECT EXISTS (SELECT 1 FROM employees WHERE email = $1);
+
+-- name: EmployeeCodeExists :one
+SELECT EXISTS (SELECT 1 FROM employees WHERE employee_id = $1);
+
+-- name: DeactivateEmployee :execrows
+UPDATE employees SET is_active = false, updated_at = NOW() WHERE id = $1;
@@ -0,0 +1,10 @@
+-- name: ListHolidaysByYear :many
+SELECT id, holiday_date, name
+FROM holidays
+WHERE EXTRACT(YEAR FROM holiday_date)::INT = sqlc.arg(year)::int
+ORDER BY holiday_date;
+
+-- name: ListHolidayDatesBetween :many
+SELECT holiday_date FROM holidays
+WHERE holiday_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+ORDER BY holiday_date;
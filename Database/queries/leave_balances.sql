@@ -0,0 +1,138 @@
+-- name: ListLeaveBalances :many
+SELECT
+    lt.id AS leave_type_id,
+    lt.name AS leave_type_name,
+    lt.description AS leave_type_description,
+    elb.allocated_days,
+    elb.used_days,
+    elb.carried_forward_days,
+    elb.available_days,
+    elb.pending_days,
+    elb.year
+FROM employee_leave_balances elb
+JOIN leave_types lt ON elb.leave_type_id = lt.id
+WHERE elb.employee_id = $1 AND elb.year = $2
+ORDER BY lt.name;
+
+-- name: QueryLeaveBalances :many
+-- Balances of the listed employees, or of the active employees of a
+-- department, for one year. With manager_id only that manager's direct
+-- reports are included. Employees without balances get one row of NULLs.
+SELECT
+    e.id AS employee_id,
+    e.employee_id AS employee_code,
+    e.name AS employee_name,
+    e.department_id,
+    b.leave_type_id,
+    b.leave_type_name,
+    b.allocated_days,
+    b.used_days,
+    b.carried_forward_days,
+    b.available_days,
+    b.pending_days
+FROM employees e
+LEFT JOIN (
+    SELECT elb.employee_id, lt.id AS leave_type_id, lt.name AS leave_type_name,
+        elb.allocated_days, elb.used_days, elb.carried_forward_days, elb.available_days, elb.pending_days
+    FROM employee_leave_balances elb
+    JOIN leave_types lt ON lt.id = elb.leave_type_id
+    WHERE elb.year = sqlc.arg(year)
+) b ON b.employee_id = e.id
+WHERE (sqlc.narg(employee_ids)::uuid[] IS NULL OR e.id = ANY(sqlc.narg(employee_ids)::uuid[]))
+  AND (sqlc.narg(department_id)::uuid IS NULL OR (e.department_id = sqlc.narg(department_id)::uuid AND e.is_active))
+  AND (sqlc.narg(manager_id)::uuid IS NULL OR e.manager_id = sqlc.narg(manager_id)::uuid)
+ORDER BY e.name, e.id, b.leave_type_name;
+
+-- name: UpsertLeaveBalance :exec
+-- Sets the given day counts, keeping the current value of any count passed as
+-- NULL. A missing balance row is created with 0 for them.
+INSERT INTO employee_leave_balances (employee_id, leave_type_id, year, allocated_days, used_days, carried_forward_days)
+VALUES (
+    sqlc.arg(employee_id), sqlc.arg(leave_type_id), sqlc.arg(year),
+    COALESCE(sqlc.narg(allocated_days)::numeric, 0),
+    COALESCE(sqlc.narg(used_days)::numeric, 0),
+    COALESCE(sqlc.narg(carried_forward_days)::int, 0)
+)
+ON CONFLICT (employee_id, leave_type_id, year) DO UPDATE SET
+    allocated_days = COALESCE(sqlc.narg(allocated_days)::numeric, employee_leave_balances.allocated_days),
+    used_days = COALESCE(sqlc.narg(used_days)::numeric, employee_leave_balances.used_days),
+    carried_forward_days = COALESCE(sqlc.narg(carried_forward_days)::int, employee_leave_balances.carried_forward_days);
+
+-- name: ChargeLeaveBalance :exec
+UPDATE employee_leave_balances SET used_days = used_days + $1
+WHERE employee_id = $2 AND leave_type_id = $3 AND year = $4;
+
+-- name: GetLeaveBalanceForReservation :one
+-- Locks a balance for a new request and returns what it leaves: the
+-- available days less those reserved by pending requests.
+SELECT (available_days - pending_days)::numeric AS unreserved_days
+FROM employee_leave_balances
+WHERE employee_id = $1 AND leave_type_id = $2 AND year = $3
+FOR UPDATE;
+
+-- name: ReserveLeaveBalance :exec
+-- Sets aside the days of a new pending request until it is decided.
+UPDATE employee_leave_balances SET pending_days = pending_days + $1, updated_at = NOW()
+WHERE employee_id = $2 AND leave_type_id = $3 AND year = $4;
+
+-- name: ReleaseLeaveBalance :exec
+-- Gives back the days a pending request reserved, once it is decided or
+-- cancelled.
+UPDATE employee_leave_balances SET pending_days = GREATEST(pending_days - $1, 0), updated_at = NOW()
+WHERE employee_id = $2 AND leave_type_id = $3 AND year = $4;
+
+-- name: RefundLeaveBalance :exec
+-- Gives back the days of a cancelled approval.
+UPDATE employee_leave_balances SET used_days = GREATEST(used_days - $1, 0), updated_at = NOW()
+WHERE employee_id = $2 AND leave_type_id = $3 AND year = $4;
+
+-- name: LockLeaveBalances :execrows
+-- Locks the balances of one year, so that no request reserves or charges
+-- them while they are recalculated.
+SELECT id FROM employee_leave_balances WHERE year = $1 ORDER BY id FOR UPDATE;
+
+-- name: ListLeaveBalanceDrift :many
+-- Balances of one year whose used_days differ from the days of the approved
+-- leave requests charged to them (approved in that year) or whose
+-- pending_days differ from the days of the pending requests reserved in them
+-- (applied for in that year), and the requests charged to no balance at all
+-- (balance_id NULL). available_days is generated from the other counts, so
+-- it always adds up.
+WITH charged AS (
+    SELECT employee_id, leave_type_id,
+        COALESCE(SUM(total_days) FILTER (WHERE status = 'approved'), 0) AS days,
+        COALESCE(SUM(total_days) FILTER (WHERE status = 'pending'), 0) AS reserved
+    FROM leave_requests
+    WHERE (status = 'approved' AND EXTRACT(YEAR FROM approved_at)::int = sqlc.arg(year)::int)
+       OR (status = 'pending' AND EXTRACT(YEAR FROM applied_at)::int = sqlc.arg(year)::int)
+    GROUP BY employee_id, leave_type_id
+), balances AS (
+    SELECT id, employee_id, leave_type_id, allocated_days, used_days, carried_forward_days, available_days, pending_days
+    FROM employee_leave_balances
+    WHERE year = sqlc.arg(year)::int
+)
+SELECT
+    b.id AS balance_id,
+    e.id AS employee_id,
+    e.name AS employee_name,
+    lt.id AS leave_type_id,
+    lt.name AS leave_type_name,
+    b.allocated_days,
+    b.used_days,
+    b.carried_forward_days,
+    b.available_days,
+    b.pending_days,
+    COALESCE(c.days, 0)::numeric AS charged_days,
+    COALESCE(c.reserved, 0)::numeric AS reserved_days
+FROM balances b
+FULL JOIN charged c ON c.employee_id = b.employee_id AND c.leave_type_id = b.leave_type_id
+JOIN employees e ON e.id = COALESCE(b.employee_id, c.employee_id)
+JOIN leave_types lt ON lt.id = COALESCE(b.leave_type_id, c.leave_type_id)
+WHERE b.id IS NULL
+   OR b.used_days <> COALESCE(c.days, 0)
+   OR b.pending_days <> COALESCE(c.reserved, 0)
+ORDER BY e.name, e.id, lt.name;
+
+-- name: SetLeaveBalanceDays :exec
+UPDATE employee_leave_balances SET used_days = $1, pending_days = $2, updated_at = NOW()
+WHERE id = $3;
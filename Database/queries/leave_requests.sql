@@ -0,0 +1,114 @@
+-- name: GetLeaveRequestForApproval :one
+-- Locks the request for the approval and returns what it needs: the charge,
+-- the status, the employee's manager, if any, and the year whose balance the
+-- request reserved its days in, the year it was applied for in.
+SELECT lr.employee_id, lr.leave_type_id, lr.total_days, lr.status::text AS status,
+    e.manager_id IS NOT NULL AS has_manager, e.manager_id,
+    EXTRACT(YEAR FROM lr.applied_at)::int AS reserved_year
+FROM leave_requests lr
+JOIN employees e ON e.id = lr.employee_id
+WHERE lr.id = $1
+FOR UPDATE OF lr;
+
+-- name: IsApprovalDelegate :one
+-- Whether delegate decides, today, the requests of manager's direct reports
+-- through an active approval delegation.
+SELECT is_approval_delegate(sqlc.arg(delegate)::uuid, sqlc.arg(manager)::uuid);
+
+-- name: GetCurrentApprovalStep :one
+-- The first pending step of the request's approval chain, and whether a step
+-- follows it.
+SELECT s.id, s.step_no, s.approver_role::text AS approver_role,
+    NOT EXISTS (
+        SELECT 1 FROM approval_steps l WHERE l.leave_request_id = s.leave_request_id AND l.step_no > s.step_no
+    ) AS is_last
+FROM approval_steps s
+WHERE s.leave_request_id = $1 AND s.status = 'pending'
+ORDER BY s.step_no
+LIMIT 1;
+
+-- name: DecideApprovalStep :exec
+UPDATE approval_steps SET status = sqlc.arg(status)::approval_step_status, acted_by = sqlc.narg(acted_by),
+    acted_at = NOW(), comment = sqlc.narg(comment)
+WHERE id = sqlc.arg(id);
+
+-- name: RecordManagerApproval :exec
+UPDATE leave_requests SET manager_approved_by = $1, manager_approved_at = NOW() WHERE id = $2;
+
+-- name: ApproveLeaveRequest :execrows
+-- Approves the request only while it is pending; 0 rows when it is not.
+UPDATE leave_requests SET status = 'approved', approved_by = $1, approved_at = NOW()
+WHERE id = $2 AND status = 'pending';
+
+-- name: RejectLeaveRequest :execrows
+-- Rejects the request only while it is pending; 0 rows when it is not.
+UPDATE leave_requests SET status = 'rejected', rejection_reason = $1
+WHERE id = $2 AND status = 'pending';
+
+-- name: GetLeaveRequestForCancel :one
+-- Locks the request for the cancellation and returns what it needs: the
+-- status, whether the leave has ended and the charge to refund, which the
+-- approval made against the balance of the year it was approved in, or the
+-- reservation to release, made in the year it was applied for in.
+SELECT employee_id, leave_type_id, total_days, status::text AS status,
+    end_date < CURRENT_DATE AS ended,
+    EXTRACT(YEAR FROM COALESCE(approved_at, NOW()))::int AS charged_year,
+    EXTRACT(YEAR FROM applied_at)::int AS reserved_year
+FROM leave_requests
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CancelLeaveRequest :execrows
+-- Cancels the request only while it is pending or approved; 0 rows when it
+-- is not.
+UPDATE leave_requests SET status = 'cancelled'
+WHERE id = $1 AND status IN ('pending', 'approved');
+
+-- name: CreateDecisionSnapshot :exec
+-- Records what the decision was based on: the employee's balance for the
+-- request's leave type in the given year, the leave type's policy values and
+-- the other pending or approved requests in the employee's department whose
+-- dates overlap the request.
+INSERT INTO leave_decision_snapshots (leave_request_id, employee_id, decision, decided_by, balance, policy, overlapping_requests)
+SELECT lr.id, lr.employee_id, sqlc.arg(decision)::varchar, sqlc.narg(decided_by)::uuid,
+    (SELECT jsonb_build_object(
+            'year', b.year, 'allocated_days', b.allocated_days, 'used_days', b.used_days,
+            'carried_forward_days', b.carried_forward_days, 'available_days', b.available_days,
+            'pending_days', b.pending_days)
+     FROM employee_leave_balances b
+     WHERE b.employee_id = lr.employee_id AND b.leave_type_id = lr.leave_type_id AND b.year = sqlc.arg(year)),
+    jsonb_build_object(
+        'leave_type_id', lt.id, 'name', lt.name, 'max_days_per_year', lt.max_days_per_year,
+        'carry_forward_allowed', lt.carry_forward_allowed, 'max_carry_forward_days', lt.max_carry_forward_days,
+        'is_active', lt.is_active),
+    COALESCE((
+        SELECT jsonb_agg(jsonb_build_object(
+                'id', o.id, 'employee_id', o.employee_id, 'leave_type_id', o.leave_type_id,
+                'start_date', o.start_date, 'end_date', o.end_date, 'total_days', o.total_days, 'status', o.status)
+            ORDER BY o.start_date, o.id)
+        FROM leave_requests o
+        JOIN employees oe ON oe.id = o.employee_id
+        WHERE oe.department_id = e.department_id AND o.id <> lr.id
+          AND o.status IN ('pending', 'approved')
+          AND o.start_date <= lr.end_date AND o.end_date >= lr.start_date
+    ), '[]'::jsonb)
+FROM leave_requests lr
+JOIN leave_types lt ON lt.id = lr.leave_type_id
+JOIN employees e ON e.id = lr.employee_id
+WHERE lr.id = sqlc.arg(id);
+
+-- name: LeaveRequestExists :one
+SELECT EXISTS (SELECT 1 FROM leave_requests WHERE id = $1);
+
+-- name: ListEmployeeLeaveRequests :many
+-- The employee's most recent leave requests, of the given status only when
+-- there is one.
+SELECT lr.id, lr.leave_type_id, lt.name AS leave_type_name, lt.description AS leave_type_description,
+    lr.start_date, lr.end_date, lr.total_days, lr.reason, lr.status::text AS status, lr.applied_at,
+    lr.rejection_reason
+FROM leave_requests lr
+JOIN leave_types lt ON lt.id = lr.leave_type_id
+WHERE lr.employee_id = sqlc.arg(employee_id)
+  AND (sqlc.narg(status)::text IS NULL OR lr.status::text = sqlc.narg(status)::text)
+ORDER BY lr.created_at DESC, lr.id
+LIMIT sqlc.arg(row_limit);
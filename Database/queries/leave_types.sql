@@ -0,0 +1,26 @@
+-- name: ListLeaveTypes :many
+SELECT id, name, description, max_days_per_year, accrual_frequency, accrual_rate,
+       sandwich_rule, min_notice_days, max_consecutive_days, is_active
+FROM leave_types
+WHERE sqlc.arg(include_inactive)::bool OR is_active
+ORDER BY name;
+
+-- name: GetLeaveType :one
+SELECT name, description, max_days_per_year, carry_forward_allowed, max_carry_forward_days,
+       sandwich_rule, min_notice_days, max_consecutive_days, is_active
+FROM leave_types WHERE id = $1;
+
+-- name: GetLeavePolicy :one
+SELECT sandwich_rule, min_notice_days, max_consecutive_days FROM leave_types WHERE id = $1;
+
+-- name: GetLeaveTypeName :one
+SELECT name FROM leave_types WHERE id = $1;
+
+-- name: CreateLeaveType :one
+INSERT INTO leave_types (name, description, max_days_per_year, carry_forward_allowed, max_carry_forward_days, accrual_frequency, accrual_rate,
+                         sandwich_rule, min_notice_days, max_consecutive_days, is_active)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id;
+
+-- name: DeactivateLeaveType :execrows
+UPDATE leave_types SET is_active = false, updated_at = NOW() WHERE id = $1;
@@ -0,0 +1,44 @@
+-- name: GetUserForRoleChange :one
+-- Locks the login so that concurrent role changes take turns.
+SELECT org_id, employee_id, role FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: SetUserRole :exec
+UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: SetEmployeeRoleByCode :exec
+-- users.employee_id holds the employee code, not the employees.id UUID.
+UPDATE employees SET role = sqlc.arg(role)::employee_role, updated_at = NOW()
+WHERE org_id = sqlc.arg(org_id) AND employee_id = sqlc.arg(employee_code);
+
+-- name: SyncUserRole :exec
+-- Copies an employee's role to their login, if they have one.
+UPDATE users u SET role = e.role::text, updated_at = NOW()
+FROM employees e
+WHERE e.id = sqlc.arg(employee_id) AND u.org_id = e.org_id AND u.employee_id = e.employee_id
+  AND u.role IS DISTINCT FROM e.role::text;
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users
+WHERE (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role)::text)
+  AND (sqlc.narg(is_active)::bool IS NULL OR COALESCE(is_active, true) = sqlc.narg(is_active)::bool);
+
+-- name: ListUsers :many
+-- Each login with its employee, if it still has one.
+SELECT u.id, u.employee_id, u.email, u.role, COALESCE(u.is_active, true)::bool AS is_active, u.last_login_at, u.created_at,
+       e.id AS employee_uuid, e.name AS employee_name
+FROM users u
+LEFT JOIN employees e ON e.org_id = u.org_id AND e.employee_id = u.employee_id
+WHERE (sqlc.narg(role)::text IS NULL OR u.role = sqlc.narg(role)::text)
+  AND (sqlc.narg(is_active)::bool IS NULL OR COALESCE(u.is_active, true) = sqlc.narg(is_active)::bool)
+ORDER BY u.created_at DESC, u.id
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: SetUserActive :execrows
+UPDATE users SET is_active = sqlc.arg(is_active)::bool, updated_at = NOW() WHERE id = sqlc.arg(id);
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET is_revoked = true WHERE user_id = $1 AND is_revoked IS NOT TRUE;
+
+-- name: DeleteUser :execrows
+-- The user's refresh tokens go with it (ON DELETE CASCADE).
+DELETE FROM users WHERE id = $1;